@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,13 +15,21 @@ import (
 	"rss2go/internal/config"
 	"rss2go/internal/crawler"
 	"rss2go/internal/database"
+	"rss2go/internal/digest"
 	"rss2go/internal/extractor"
+	"rss2go/internal/health"
+	"rss2go/internal/inline"
 	"rss2go/internal/logger"
+	"rss2go/internal/magiclink"
 	"rss2go/internal/notifier"
 	"rss2go/internal/outbox"
+	"rss2go/internal/politeness"
+	"rss2go/internal/polling"
 	"rss2go/internal/sanitizer"
 	"rss2go/internal/scheduler"
+	"rss2go/internal/secrets"
 	"rss2go/internal/server"
+	"rss2go/internal/websub"
 )
 
 var (
@@ -29,7 +38,15 @@ var (
 	Date    = "unknown"
 )
 
+// emailImageWidth is the widest images are shown in emails, and the width
+// embedded images are downscaled to.
+const emailImageWidth = 800
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "opml" {
+		os.Exit(runOPML(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Load resolved configuration via YAML config file, env variables, and flags
 	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
@@ -87,10 +104,43 @@ func main() {
 
 	repo := database.NewRepository(db)
 
-	// 2. Initialize crawler, extractor, and sanitizer
-	cr := crawler.NewCrawler(nil, slog.Default().With("component", "crawler"))
-	ex := extractor.NewExtractor(nil, slog.Default().With("component", "extractor"))
-	sa := sanitizer.NewSanitizer(800) // Default 800px width limit for emails
+	// Feed credentials and channel tokens are encrypted with a key kept
+	// outside the database, generated on first start
+	keyPath := cfg.SecretKeyPath()
+	key, err := secrets.LoadKeyFile(keyPath)
+	if err != nil {
+		slog.Error("Failed to load secret key", "path", keyPath, "err", err)
+		os.Exit(1)
+	}
+	box, err := secrets.NewBox(key)
+	if err != nil {
+		slog.Error("Failed to load secret key", "path", keyPath, "err", err)
+		os.Exit(1)
+	}
+	repo.SetSecrets(box)
+	if n, err := repo.SealChannelTokens(context.Background()); err != nil {
+		slog.Error("Failed to encrypt stored channel tokens", "err", err)
+		os.Exit(1)
+	} else if n > 0 {
+		slog.Info("Encrypted stored channel tokens", "count", n)
+	}
+
+	// 2. Initialize crawler, extractor, and sanitizer. They share one per-host
+	// limiter, so feed crawls and article fetches together stay polite.
+	slog.Info("Configuring per-host request limits", "rate", cfg.HostRate, "connections", cfg.HostConns, "respect_robots", cfg.RespectRobots)
+	limiter := politeness.NewLimiter(politeness.Config{Rate: cfg.HostRate, MaxConns: cfg.HostConns})
+	transport := limiter.Transport(http.DefaultTransport)
+	cr := crawler.NewCrawler(&http.Client{Timeout: 30 * time.Second, Transport: transport}, slog.Default().With("component", "crawler"))
+	articleClient := &http.Client{Timeout: 15 * time.Second, Transport: transport}
+	ex := extractor.NewExtractor(articleClient, slog.Default().With("component", "extractor"))
+	if cfg.RespectRobots {
+		ex.SetRobots(politeness.NewRobots(articleClient, "rss2go", nil))
+	}
+	if cfg.ExtractTTL > 0 {
+		slog.Info("Configuring extraction cache", "ttl", cfg.ExtractTTL, "max_mb", cfg.ExtractMB)
+		ex.SetCache(repo, extractor.CacheConfig{TTL: cfg.ExtractTTL, MaxBytes: int64(cfg.ExtractMB) << 20})
+	}
+	sa := sanitizer.NewSanitizer(emailImageWidth)
 
 	// 3. Initialize mail delivery notifier
 	var delivery notifier.Sender
@@ -128,20 +178,79 @@ func main() {
 		MaxRetries:     5,
 		InitialBackoff: 5 * time.Minute,
 	}, slog.Default().With("component", "outbox"))
+	if cfg.InlineImages {
+		slog.Info("Configuring email image inlining", "max_images", cfg.InlineMax, "max_mb", cfg.InlineMB)
+		// Image URLs come from feed content, so they may only reach public addresses
+		imageClient := &http.Client{Timeout: 15 * time.Second, Transport: limiter.Transport(inline.PublicTransport())}
+		worker.SetInliner(inline.New(imageClient, inline.Config{
+			MaxImages: cfg.InlineMax,
+			MaxBytes:  int64(cfg.InlineMB) << 20,
+			MaxWidth:  emailImageWidth,
+		}, slog.Default().With("component", "inline")))
+	}
 
-	// 5. Initialize Scheduler
-	slog.Info("Starting polling scheduler", "max_workers", cfg.Crawlers, "interval", cfg.PollInterval)
-	sched := scheduler.New(repo, cr, ex, sa, scheduler.Config{
+	// Magic links are signed with keys persisted in the database (generated on
+	// first start and rotated periodically), so links in sent mail survive
+	// restarts. Unsubscribe links also need a publicly reachable base URL.
+	magicKeys := magiclink.NewKeyring(repo, magiclink.Config{}, slog.Default().With("component", "magiclink"))
+	if err := magicKeys.Load(context.Background()); err != nil {
+		slog.Error("Failed to load magic link signing keys", "err", err)
+		os.Exit(1)
+	}
+	links := magiclink.NewLinks(cfg.PublicURL, magicKeys)
+	if cfg.PublicURL == "" {
+		slog.Info("No public_url configured; emails will not carry unsubscribe links")
+	}
+
+	// 4a. Initialize digest worker (batches non-immediate subscriptions into the outbox)
+	digestWorker := digest.NewWorker(repo, digest.Config{Links: links}, slog.Default().With("component", "digest"))
+
+	// 4b. Initialize WebSub push subscriber (requires a publicly reachable callback URL)
+	schedCfg := scheduler.Config{
 		MaxWorkers:   cfg.Crawlers,
 		PollInterval: cfg.PollInterval,
-	}, slog.Default().With("component", "scheduler"))
+		Links:        links,
+		Health: health.Policy{
+			FailingAfter: cfg.FailingAfter,
+			DisableAfter: cfg.DisableAfter,
+		},
+		Adaptive: polling.Policy{
+			Min: cfg.AdaptiveMin,
+			Max: cfg.AdaptiveMax,
+		},
+		AlertRecipients: cfg.AlertEmails,
+	}
+	var pushManager *websub.Manager
+	if cfg.PublicURL != "" {
+		slog.Info("Enabling WebSub push subscriptions", "public_url", cfg.PublicURL)
+		pushManager = websub.NewManager(repo, nil, websub.Config{
+			CallbackBase: cfg.PublicURL,
+		}, slog.Default().With("component", "websub"))
+		schedCfg.Push = pushManager
+	}
+
+	// 4c. Initialize feed health alerts (summarizes broken and recovered feeds for operators)
+	alerter := health.NewAlerter(repo, health.AlertConfig{
+		Interval:   cfg.AlertInterval,
+		Recipients: cfg.AlertEmails,
+	}, slog.Default().With("component", "health"))
+	if len(cfg.AlertEmails) == 0 {
+		slog.Info("No alert_emails configured; feed health changes are only logged")
+	}
+
+	// 5. Initialize Scheduler
+	slog.Info("Starting polling scheduler", "max_workers", cfg.Crawlers, "interval", cfg.PollInterval)
+	sched := scheduler.New(repo, cr, ex, sa, schedCfg, slog.Default().With("component", "scheduler"))
 
 	// 6. Initialize HTTP Server
-	slog.Info("Configuring API server", "addr", cfg.Addr)
+	slog.Info("Configuring API server", "addr", cfg.Addr, "auth_required", cfg.Password != "")
 	srv := server.New(repo, sched, cr, ex, sa, server.Config{
 		Addr:        cfg.Addr,
+		Password:    cfg.Password,
 		Broadcaster: broadcaster,
 		MailerMode:  cfg.MailerMode,
+		WebSub:      pushManager,
+		MagicKeys:   magicKeys,
 	}, slog.Default().With("component", "api"))
 
 	// Graceful signal listener context
@@ -154,6 +263,32 @@ func main() {
 		slog.Info("Outbox worker queue stopped")
 	}()
 
+	// Launch digest worker
+	go func() {
+		_ = digestWorker.Start(ctx)
+		slog.Info("Digest worker stopped")
+	}()
+
+	// Launch WebSub lease renewal
+	if pushManager != nil {
+		go func() {
+			_ = pushManager.Start(ctx)
+			slog.Info("WebSub lease renewal stopped")
+		}()
+	}
+
+	// Launch feed health alerts
+	go func() {
+		_ = alerter.Start(ctx)
+		slog.Info("Feed health alerts stopped")
+	}()
+
+	// Launch magic link key rotation
+	go func() {
+		_ = magicKeys.Start(ctx)
+		slog.Info("Magic link key rotation stopped")
+	}()
+
 	// Launch Aggregator scheduler
 	go func() {
 		_ = sched.Start(ctx)
@@ -171,7 +306,7 @@ func main() {
 
 type mockNotifier struct{}
 
-func (m *mockNotifier) Send(ctx context.Context, subject string, body string, recipients []string) error {
+func (m *mockNotifier) Send(ctx context.Context, subject string, body string, recipients []string, headers map[string]string) error {
 	slog.Info("[MOCK MAIL] Sending notification", "recipients", recipients, "subject", subject, "body_len", len(body))
 	return nil
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"rss2go/internal/crawler"
+	"rss2go/internal/database"
+	"rss2go/internal/opml"
+)
+
+const opmlUsage = `Usage:
+  rss2go opml export [-db path] [-o file]
+  rss2go opml import [-db path] [-user email] file
+
+Exports every feed to OPML, or imports the feeds in an OPML file directly
+against the database without a running daemon.
+`
+
+// runOPML implements the "opml" subcommand and returns the process exit code.
+func runOPML(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, opmlUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("rss2go opml "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { _, _ = fmt.Fprint(stderr, opmlUsage) }
+
+	defaultDB := "rss2go.db"
+	if val, exists := os.LookupEnv("RSS2GO_DB"); exists {
+		defaultDB = val
+	}
+	dbPath := fs.String("db", defaultDB, "Path to the SQLite database file")
+
+	var err error
+	switch args[0] {
+	case "export":
+		out := fs.String("o", "", "Write the OPML document to this file instead of stdout")
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExitCode(err)
+		}
+		err = exportOPML(*dbPath, *out, stdout)
+	case "import":
+		email := fs.String("user", "", "Subscribe the user with this email to every imported feed")
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExitCode(err)
+		}
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return 2
+		}
+		err = importOPML(*dbPath, fs.Arg(0), *email, stdout)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func flagExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+func exportOPML(dbPath, outPath string, stdout io.Writer) error {
+	db, err := database.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	feeds, err := database.NewRepository(db).ListFeeds(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w := stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+	return opml.Export(w, feeds, time.Now())
+}
+
+func importOPML(dbPath, inPath, email string, stdout io.Writer) error {
+	in, err := os.Open(inPath)
+	if err != nil {
+		return fmt.Errorf("open OPML file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	repo := database.NewRepository(db)
+
+	var userID int64
+	if email != "" {
+		user, err := repo.GetUserByEmail(ctx, email)
+		if err != nil {
+			return fmt.Errorf("look up user %q: %w", email, err)
+		}
+		userID = user.ID
+	}
+
+	report, err := opml.Import(ctx, repo, crawler.DefaultRegistry(), in, userID)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d outlines failed to import", report.Failed, len(report.Outlines))
+	}
+	return nil
+}
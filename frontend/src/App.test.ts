@@ -4,6 +4,10 @@ import App from './App.svelte'
 import * as api from './lib/api'
 
 vi.mock('./lib/api', () => ({
+  fetchAuthStatus: vi.fn(),
+  login: vi.fn(),
+  logout: vi.fn(),
+  registerOnUnauthorized: vi.fn(),
   fetchStats: vi.fn(),
   fetchFeeds: vi.fn(),
   fetchOutbox: vi.fn(),
@@ -33,6 +37,7 @@ describe('App Component Layout Shell', () => {
     vi.restoreAllMocks()
     localStorage.clear()
     vi.stubGlobal('EventSource', MockEventSource)
+    vi.mocked(api.fetchAuthStatus).mockResolvedValue({ auth_required: false, authenticated: true })
     vi.mocked(api.fetchStats).mockResolvedValue(mockStats)
     vi.mocked(api.fetchFeeds).mockResolvedValue(mockFeeds)
     vi.mocked(api.fetchOutbox).mockResolvedValue([])
@@ -68,4 +73,13 @@ describe('App Component Layout Shell', () => {
     await fireEvent.click(logsTab)
     expect(screen.getByRole('heading', { name: 'Aggregator Console Logs' })).toBeInTheDocument()
   })
+
+  it('shows the login gate when the panel is locked', async () => {
+    vi.mocked(api.fetchAuthStatus).mockResolvedValue({ auth_required: true, authenticated: false })
+    vi.mocked(api.fetchFeeds).mockClear()
+    render(App)
+
+    expect(await screen.findByRole('button', { name: 'Unlock' })).toBeInTheDocument()
+    expect(api.fetchFeeds).not.toHaveBeenCalled()
+  })
 })
@@ -1,16 +1,38 @@
 let onErrorCallback: ((msg: string) => void) | null = null;
+let onUnauthorizedCallback: (() => void) | null = null;
 
 export function registerOnError(callback: (msg: string) => void) {
   onErrorCallback = callback;
 }
 
+export function registerOnUnauthorized(callback: () => void) {
+  onUnauthorizedCallback = callback;
+}
+
+// CSRF token issued alongside the session cookie; sent back on state-changing requests.
+function csrfToken(): string {
+  if (typeof document === 'undefined') return '';
+  const match = document.cookie.split('; ').find((c) => c.startsWith('rss2go_csrf='));
+  return match ? decodeURIComponent(match.slice('rss2go_csrf='.length)) : '';
+}
+
 // Fetch Wrapper
 async function apiFetch(path: string, options: RequestInit = {}) {
   try {
+    const method = (options.method || 'GET').toUpperCase();
+    const headers = new Headers(options.headers);
+    if (method !== 'GET' && method !== 'HEAD') {
+      const token = csrfToken();
+      if (token) headers.set('X-CSRF-Token', token);
+    }
     const resp = await fetch(path, {
       cache: 'no-store',
-      ...options
+      ...options,
+      headers
     });
+    if (resp.status === 401 && onUnauthorizedCallback) {
+      onUnauthorizedCallback();
+    }
     if (!resp.ok) {
       const text = await resp.text();
       throw new Error(text || `HTTP error ${resp.status}`);
@@ -28,6 +50,22 @@ async function apiFetch(path: string, options: RequestInit = {}) {
   }
 }
 
+export async function fetchAuthStatus(): Promise<any> {
+  return await apiFetch('/api/v1/auth/status');
+}
+
+export async function login(password: string): Promise<any> {
+  return await apiFetch('/api/v1/auth/login', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ password })
+  });
+}
+
+export async function logout(): Promise<any> {
+  return await apiFetch('/api/v1/auth/logout', { method: 'POST' });
+}
+
 export async function fetchStats(): Promise<any> {
   return await apiFetch('/api/v1/stats');
 }
@@ -44,8 +82,37 @@ export async function fetchOutbox(): Promise<any[]> {
   return (await apiFetch('/api/v1/outbox')) || [];
 }
 
-export async function fetchFeedItems(feedId: number): Promise<any[]> {
-  return (await apiFetch(`/api/v1/feeds/${feedId}/items`)) || [];
+export async function fetchFeed(feedId: number): Promise<any> {
+  return await apiFetch(`/api/v1/feeds/${feedId}`);
+}
+
+export interface FeedItemsPage {
+  items: any[];
+  total: number;
+  limit: number;
+  offset: number;
+}
+
+export async function fetchFeedItems(feedId: number, limit = 20, offset = 0): Promise<FeedItemsPage> {
+  const page = await apiFetch(`/api/v1/feeds/${feedId}/items?limit=${limit}&offset=${offset}`);
+  return page || { items: [], total: 0, limit, offset };
+}
+
+export interface CrawlRun {
+  id: number;
+  feed_id: number;
+  started_at: string;
+  duration_ms: number;
+  status_code: number;
+  bytes: number;
+  item_count: number;
+  new_item_count: number;
+  not_modified: boolean;
+  error?: string;
+}
+
+export async function fetchFeedCrawls(feedId: number, limit = 50): Promise<CrawlRun[]> {
+  return (await apiFetch(`/api/v1/feeds/${feedId}/crawls?limit=${limit}`)) || [];
 }
 
 export async function addFeed(feed: any): Promise<any> {
@@ -68,8 +135,48 @@ export async function deleteFeed(feedId: number): Promise<any> {
   return await apiFetch(`/api/v1/feeds/${feedId}`, { method: 'DELETE' });
 }
 
-export async function testFeed(feed: any): Promise<any> {
-  return await apiFetch(`/api/v1/feeds/${feed.id}/test`, { method: 'POST' });
+export interface FeedCandidate {
+  url: string;
+  title: string;
+  type: string;
+  items: number;
+}
+
+export async function discoverFeeds(url: string): Promise<FeedCandidate[]> {
+  return (await apiFetch('/api/v1/feeds/discover', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ url })
+  })) || [];
+}
+
+export interface OPMLImportReport {
+  created: number;
+  existing: number;
+  failed: number;
+  outlines: { title: string; url: string; feed_id?: number; status: string; error?: string }[];
+}
+
+export async function importOPML(document: string, userId?: number): Promise<OPMLImportReport> {
+  const query = userId ? `?user_id=${userId}` : '';
+  return await apiFetch(`/api/v1/opml${query}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'text/x-opml' },
+    body: document
+  });
+}
+
+// testFeed runs a dry-run crawl. contentRules, if given, are previewed in
+// place of the feed's saved content rules.
+export async function testFeed(feed: any, contentRules?: any): Promise<any> {
+  if (contentRules === undefined) {
+    return await apiFetch(`/api/v1/feeds/${feed.id}/test`, { method: 'POST' });
+  }
+  return await apiFetch(`/api/v1/feeds/${feed.id}/test`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ content_rules: contentRules })
+  });
 }
 
 export async function catchupFeed(feedId: number): Promise<any> {
@@ -96,6 +203,14 @@ export async function addUser(email: string): Promise<any> {
   });
 }
 
+export async function updateUser(id: number, settings: { cross_feed_dedupe: boolean }): Promise<any> {
+  return await apiFetch(`/api/v1/users/${id}`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(settings)
+  });
+}
+
 export async function deleteUser(id: number): Promise<any> {
   return await apiFetch(`/api/v1/users/${id}`, { method: 'DELETE' });
 }
@@ -1,4 +1,4 @@
-import { render, screen, fireEvent } from '@testing-library/svelte'
+import { render, screen, fireEvent, waitFor } from '@testing-library/svelte'
 import { describe, it, expect, vi, beforeEach } from 'vitest'
 import FeedManager from './FeedManager.svelte'
 import * as api from '../api'
@@ -12,7 +12,8 @@ vi.mock('../api', () => ({
   catchupFeed: vi.fn(),
   scanFeed: vi.fn(),
   rewindFeed: vi.fn(),
-  fetchUsers: vi.fn()
+  fetchUsers: vi.fn(),
+  importOPML: vi.fn()
 }))
 
 describe('FeedManager', () => {
@@ -34,7 +35,7 @@ describe('FeedManager', () => {
     vi.restoreAllMocks()
     mockTriggerToast.mockClear()
     mockOnRefresh.mockClear()
-    vi.mocked(api.fetchFeedItems).mockResolvedValue(mockItems)
+    vi.mocked(api.fetchFeedItems).mockResolvedValue({ items: mockItems, total: 1, limit: 20, offset: 0 })
     vi.mocked(api.fetchUsers).mockResolvedValue(mockUsers)
   })
 
@@ -139,7 +140,7 @@ describe('FeedManager', () => {
     await fireEvent.click(card)
 
     // Verify it fetches items and displays them
-    expect(api.fetchFeedItems).toHaveBeenCalledWith(1)
+    expect(api.fetchFeedItems).toHaveBeenCalledWith(1, 20, 0)
     const itemLink = await screen.findByText('Go Release 1.24')
     expect(itemLink).toBeInTheDocument()
 
@@ -147,4 +148,34 @@ describe('FeedManager', () => {
     expect(screen.getByRole('button', { name: 'Test Feed' })).toBeInTheDocument()
     expect(screen.getByRole('button', { name: 'Scan Now' })).toBeInTheDocument()
   })
+
+  it('pages through stored feed item history', async () => {
+    vi.mocked(api.fetchFeedItems).mockResolvedValue({ items: mockItems, total: 45, limit: 20, offset: 0 })
+    render(FeedManager, { feeds: mockFeeds, triggerToast: mockTriggerToast, onRefresh: mockOnRefresh })
+
+    await fireEvent.click(screen.getByText('Tech Blog'))
+    expect(await screen.findByText('Feed Item History (45)')).toBeInTheDocument()
+    expect(screen.getByRole('button', { name: 'Newer' })).toBeDisabled()
+
+    await fireEvent.click(screen.getByRole('button', { name: 'Older' }))
+    expect(api.fetchFeedItems).toHaveBeenLastCalledWith(1, 20, 20)
+  })
+
+  it('imports an uploaded OPML file and reports the outcome', async () => {
+    vi.mocked(api.importOPML).mockResolvedValue({ created: 2, existing: 1, failed: 0, outlines: [] })
+
+    render(FeedManager, { feeds: mockFeeds, triggerToast: mockTriggerToast, onRefresh: mockOnRefresh })
+
+    expect(screen.getByRole('link', { name: 'Export OPML' })).toHaveAttribute('href', '/api/v1/opml')
+
+    const opml = '<opml version="2.0"><body></body></opml>'
+    const file = new File([opml], 'feeds.opml', { type: 'text/x-opml' })
+    await fireEvent.change(screen.getByTestId('opml-input'), { target: { files: [file] } })
+
+    await waitFor(() => {
+      expect(mockTriggerToast).toHaveBeenCalledWith('OPML import: 2 created, 1 existing, 0 failed')
+    })
+    expect(api.importOPML).toHaveBeenCalledWith(opml)
+    expect(mockOnRefresh).toHaveBeenCalled()
+  })
 })
@@ -31,6 +31,25 @@ describe('StatsPanel', () => {
     expect(screen.getByText('No recent outbox items found.')).toBeInTheDocument()
   })
 
+  it('renders crawl queue metrics when present', () => {
+    const mockStats = {
+      total_feeds: 1,
+      total_users: 1,
+      outbox_pending: 0,
+      outbox_failed: 0,
+      outbox_delivered: 0,
+      mailer_mode: 'smtp',
+      queue: { workers: 4, busy: 3, depth: 17, started: 120, avg_wait_ms: 2500, max_wait_ms: 61000, oldest_wait_ms: 9000 }
+    }
+
+    render(StatsPanel, { stats: mockStats, outboxItems: [], onRefresh: mockOnRefresh })
+
+    expect(screen.getByText('17')).toBeInTheDocument()
+    expect(screen.getByText('3 of 4 workers busy')).toBeInTheDocument()
+    expect(screen.getByText('2.5s')).toBeInTheDocument()
+    expect(screen.getByText('longest 61.0s')).toBeInTheDocument()
+  })
+
   it('renders outbox transmissions correctly', () => {
     const mockStats = {
       total_feeds: 1,
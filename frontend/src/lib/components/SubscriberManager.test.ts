@@ -7,6 +7,7 @@ vi.mock('../api', () => ({
   fetchUsers: vi.fn(),
   addUser: vi.fn(),
   deleteUser: vi.fn(),
+  updateUser: vi.fn(),
   addSubscription: vi.fn(),
   deleteSubscription: vi.fn()
 }))
@@ -59,6 +60,21 @@ describe('SubscriberManager', () => {
     expect(checkboxes[1]).not.toBeChecked() // Cooking Blog is not subbed
   })
 
+  it('toggles cross-feed dedupe for the selected subscriber', async () => {
+    vi.mocked(api.updateUser).mockResolvedValue({ id: 102, email: 'bob@example.com', cross_feed_dedupe: true })
+
+    render(SubscriberManager, { feeds: mockFeeds, triggerToast: mockTriggerToast })
+
+    await fireEvent.click(await screen.findByText('bob@example.com'))
+    const toggle = screen.getByRole('checkbox', { name: 'Skip articles already delivered from another feed' })
+    expect(toggle).not.toBeChecked()
+
+    await fireEvent.click(toggle)
+
+    expect(api.updateUser).toHaveBeenCalledWith(102, { cross_feed_dedupe: true })
+    expect(mockTriggerToast).toHaveBeenCalledWith('Cross-feed duplicates will be skipped')
+  })
+
   it('registers new subscriber email on form submit', async () => {
     vi.mocked(api.addUser).mockResolvedValue({ id: 103, email: 'carol@example.com' })
 
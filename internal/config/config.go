@@ -5,7 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/mail"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,39 +18,67 @@ import (
 
 // Config holds the fully resolved configuration values for rss2go.
 type Config struct {
-	DBPath       string            `yaml:"db_path"`
-	Addr         string            `yaml:"addr"`
-	MailerMode   string            `yaml:"mailer_mode"`
-	SMTPHost     string            `yaml:"smtp_host"`
-	SMTPPort     int               `yaml:"smtp_port"`
-	SMTPUser     string            `yaml:"smtp_user"`
-	SMTPPass     string            `yaml:"smtp_pass"`
-	SMTPFrom     string            `yaml:"smtp_from"`
-	SMTPSecurity string            `yaml:"smtp_security"`
-	Crawlers     int               `yaml:"crawlers"`
-	LogLevel     string            `yaml:"log_level"`
-	LogFile      string            `yaml:"log_file"`
-	LogLevels    map[string]string `yaml:"log_levels"`
-	PollInterval time.Duration     `yaml:"poll_interval"`
+	DBPath        string            `yaml:"db_path"`
+	Addr          string            `yaml:"addr"`
+	Password      string            `yaml:"password"`
+	PublicURL     string            `yaml:"public_url"`
+	MailerMode    string            `yaml:"mailer_mode"`
+	SMTPHost      string            `yaml:"smtp_host"`
+	SMTPPort      int               `yaml:"smtp_port"`
+	SMTPUser      string            `yaml:"smtp_user"`
+	SMTPPass      string            `yaml:"smtp_pass"`
+	SMTPFrom      string            `yaml:"smtp_from"`
+	SMTPSecurity  string            `yaml:"smtp_security"`
+	Crawlers      int               `yaml:"crawlers"`
+	LogLevel      string            `yaml:"log_level"`
+	LogFile       string            `yaml:"log_file"`
+	LogLevels     map[string]string `yaml:"log_levels"`
+	PollInterval  time.Duration     `yaml:"poll_interval"`
+	FailingAfter  int               `yaml:"failing_after"`
+	DisableAfter  time.Duration     `yaml:"disable_after"`
+	AlertEmails   []string          `yaml:"alert_emails"`
+	AlertInterval time.Duration     `yaml:"alert_interval"`
+	AdaptiveMin   time.Duration     `yaml:"adaptive_min_interval"`
+	AdaptiveMax   time.Duration     `yaml:"adaptive_max_interval"`
+	SecretKeyFile string            `yaml:"secret_key_file"`
+	HostRate      float64           `yaml:"host_rate"`
+	HostConns     int               `yaml:"host_connections"`
+	RespectRobots bool              `yaml:"respect_robots"`
+	ExtractTTL    time.Duration     `yaml:"extract_cache_ttl"`
+	ExtractMB     int               `yaml:"extract_cache_mb"`
+	InlineImages  bool              `yaml:"inline_images"`
+	InlineMax     int               `yaml:"inline_max_images"`
+	InlineMB      int               `yaml:"inline_max_mb"`
 }
 
 // Default returns a Config struct initialized with standard default parameters.
 func Default() *Config {
 	return &Config{
-		DBPath:       "rss2go.db",
-		Addr:         ":8080",
-		MailerMode:   "sendmail",
-		SMTPHost:     "localhost",
-		SMTPPort:     587,
-		SMTPUser:     "",
-		SMTPPass:     "",
-		SMTPFrom:     "rss2go@localhost",
-		SMTPSecurity: "starttls",
-		Crawlers:     4,
-		LogLevel:     "info",
-		LogFile:      "",
-		LogLevels:    make(map[string]string),
-		PollInterval: 10 * time.Second,
+		DBPath:        "rss2go.db",
+		Addr:          ":8080",
+		MailerMode:    "sendmail",
+		SMTPHost:      "localhost",
+		SMTPPort:      587,
+		SMTPUser:      "",
+		SMTPPass:      "",
+		SMTPFrom:      "rss2go@localhost",
+		SMTPSecurity:  "starttls",
+		Crawlers:      4,
+		LogLevel:      "info",
+		LogFile:       "",
+		LogLevels:     make(map[string]string),
+		PollInterval:  10 * time.Second,
+		FailingAfter:  3,
+		DisableAfter:  30 * 24 * time.Hour,
+		AlertInterval: 24 * time.Hour,
+		AdaptiveMin:   5 * time.Minute,
+		AdaptiveMax:   24 * time.Hour,
+		HostRate:      1,
+		HostConns:     2,
+		ExtractTTL:    24 * time.Hour,
+		ExtractMB:     64,
+		InlineMax:     20,
+		InlineMB:      10,
 	}
 }
 
@@ -87,7 +118,7 @@ func Load(args []string) (*Config, error) {
 		if err := yaml.Unmarshal(data, cfg); err != nil {
 			return nil, fmt.Errorf("config: parse yaml %q: %w", configPath, err)
 		}
-		warnIfWorldReadable(configPath, cfg.SMTPPass)
+		warnIfWorldReadable(configPath, cfg.SMTPPass, cfg.Password)
 	} else if explicitFile {
 		return nil, fmt.Errorf("config: file not found at %q", configPath)
 	}
@@ -99,6 +130,12 @@ func Load(args []string) (*Config, error) {
 	if val, exists := os.LookupEnv("RSS2GO_ADDR"); exists {
 		cfg.Addr = val
 	}
+	if val, exists := os.LookupEnv("RSS2GO_PASSWORD"); exists {
+		cfg.Password = val
+	}
+	if val, exists := os.LookupEnv("RSS2GO_PUBLIC_URL"); exists {
+		cfg.PublicURL = val
+	}
 	if val, exists := os.LookupEnv("RSS2GO_MAILER"); exists {
 		cfg.MailerMode = val
 	}
@@ -141,6 +178,77 @@ func Load(args []string) (*Config, error) {
 			cfg.PollInterval = d
 		}
 	}
+	if val, exists := os.LookupEnv("RSS2GO_FAILING_AFTER"); exists {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.FailingAfter = n
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_DISABLE_AFTER"); exists {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.DisableAfter = d
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_ALERT_EMAILS"); exists {
+		cfg.AlertEmails = parseList(val)
+	}
+	if val, exists := os.LookupEnv("RSS2GO_ALERT_INTERVAL"); exists {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.AlertInterval = d
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_ADAPTIVE_MIN_INTERVAL"); exists {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.AdaptiveMin = d
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_ADAPTIVE_MAX_INTERVAL"); exists {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.AdaptiveMax = d
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_SECRET_KEY_FILE"); exists {
+		cfg.SecretKeyFile = val
+	}
+	if val, exists := os.LookupEnv("RSS2GO_HOST_RATE"); exists {
+		if r, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.HostRate = r
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_HOST_CONNECTIONS"); exists {
+		if c, err := strconv.Atoi(val); err == nil {
+			cfg.HostConns = c
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_RESPECT_ROBOTS"); exists {
+		if b, err := strconv.ParseBool(val); err == nil {
+			cfg.RespectRobots = b
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_EXTRACT_CACHE_TTL"); exists {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.ExtractTTL = d
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_EXTRACT_CACHE_MB"); exists {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.ExtractMB = n
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_INLINE_IMAGES"); exists {
+		if b, err := strconv.ParseBool(val); err == nil {
+			cfg.InlineImages = b
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_INLINE_MAX_IMAGES"); exists {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.InlineMax = n
+		}
+	}
+	if val, exists := os.LookupEnv("RSS2GO_INLINE_MAX_MB"); exists {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.InlineMB = n
+		}
+	}
 
 	// 4. Layer CLI Flag Overrides
 	mainFs := flag.NewFlagSet("rss2go", flag.ContinueOnError)
@@ -154,6 +262,8 @@ func Load(args []string) (*Config, error) {
 
 	dbFlag := mainFs.String("db", "", "SQLite database path (default \"rss2go.db\")")
 	addrFlag := mainFs.String("addr", "", "Bind address for API dashboard (default \":8080\")")
+	passFlag := mainFs.String("pass", "", "Operator panel password (leave empty to keep the panel open)")
+	publicURLFlag := mainFs.String("public-url", "", "Externally reachable base URL of this server (enables WebSub push subscriptions and email unsubscribe links)")
 	mailerFlag := mainFs.String("mailer", "", "Outbox delivery system ('smtp', 'sendmail', or 'mock'; default \"sendmail\")")
 	smtpHostFlag := mainFs.String("smtp-host", "", "SMTP server hostname (default \"localhost\")")
 	smtpPortFlag := mainFs.Int("smtp-port", 0, "SMTP server port (default 587)")
@@ -166,6 +276,21 @@ func Load(args []string) (*Config, error) {
 	logFileFlag := mainFs.String("log-file", "", "Log file path (default stderr only)")
 	logLevelsFlag := mainFs.String("log-levels", "", "Per-component level overrides, comma-separated e.g. 'server:warn,scheduler:debug'")
 	pollIntervalFlag := mainFs.Duration("poll-interval", 0, "Frequency of scheduled feed polling (default 10s)")
+	failingAfterFlag := mainFs.Int("failing-after", 0, "Consecutive crawl failures before a feed is marked failing (default 3)")
+	disableAfterFlag := mainFs.Duration("disable-after", 0, "How long a feed may keep failing before it is disabled; 0 never disables (default 720h)")
+	alertEmailsFlag := mainFs.String("alert-emails", "", "Comma-separated operator addresses for the feed health alert email")
+	alertIntervalFlag := mainFs.Duration("alert-interval", 0, "Frequency of the feed health alert email (default 24h)")
+	adaptiveMinFlag := mainFs.Duration("adaptive-min-interval", 0, "Shortest interval adaptive polling may choose for a feed (default 5m)")
+	adaptiveMaxFlag := mainFs.Duration("adaptive-max-interval", 0, "Longest interval adaptive polling may choose for a feed (default 24h)")
+	hostRateFlag := mainFs.Float64("host-rate", 0, "Requests per second allowed to any one host; 0 means no limit (default 1)")
+	hostConnsFlag := mainFs.Int("host-connections", 0, "Parallel requests allowed to any one host; 0 means no limit (default 2)")
+	respectRobotsFlag := mainFs.Bool("respect-robots", false, "Skip full-article extraction for pages robots.txt disallows")
+	extractTTLFlag := mainFs.Duration("extract-cache-ttl", 0, "How long a full-article extraction is reused without fetching the article again; 0 disables the cache (default 24h)")
+	extractMBFlag := mainFs.Int("extract-cache-mb", 0, "Megabytes of extracted article HTML kept in the extraction cache (default 64)")
+	inlineImagesFlag := mainFs.Bool("inline-images", false, "Embed email images in the message instead of linking to the publisher's copies")
+	inlineMaxFlag := mainFs.Int("inline-max-images", 0, "Images embedded in one email; the rest stay remote (default 20)")
+	inlineMBFlag := mainFs.Int("inline-max-mb", 0, "Megabytes of images embedded in one email (default 10)")
+	secretKeyFlag := mainFs.String("secret-key-file", "", "Key file encrypting feed credentials, created if missing (default the database path plus \".key\")")
 	_ = mainFs.String("config", "", "Configuration file path (default \"rss2go.yaml\")")
 
 	if err := mainFs.Parse(args); err != nil {
@@ -183,6 +308,10 @@ func Load(args []string) (*Config, error) {
 			cfg.DBPath = *dbFlag
 		case "addr":
 			cfg.Addr = *addrFlag
+		case "pass":
+			cfg.Password = *passFlag
+		case "public-url":
+			cfg.PublicURL = *publicURLFlag
 		case "mailer":
 			cfg.MailerMode = *mailerFlag
 		case "smtp-host":
@@ -207,6 +336,36 @@ func Load(args []string) (*Config, error) {
 			cfg.LogLevels = parseLogLevelsMap(*logLevelsFlag)
 		case "poll-interval":
 			cfg.PollInterval = *pollIntervalFlag
+		case "failing-after":
+			cfg.FailingAfter = *failingAfterFlag
+		case "disable-after":
+			cfg.DisableAfter = *disableAfterFlag
+		case "alert-emails":
+			cfg.AlertEmails = parseList(*alertEmailsFlag)
+		case "alert-interval":
+			cfg.AlertInterval = *alertIntervalFlag
+		case "adaptive-min-interval":
+			cfg.AdaptiveMin = *adaptiveMinFlag
+		case "adaptive-max-interval":
+			cfg.AdaptiveMax = *adaptiveMaxFlag
+		case "secret-key-file":
+			cfg.SecretKeyFile = *secretKeyFlag
+		case "host-rate":
+			cfg.HostRate = *hostRateFlag
+		case "host-connections":
+			cfg.HostConns = *hostConnsFlag
+		case "respect-robots":
+			cfg.RespectRobots = *respectRobotsFlag
+		case "extract-cache-ttl":
+			cfg.ExtractTTL = *extractTTLFlag
+		case "extract-cache-mb":
+			cfg.ExtractMB = *extractMBFlag
+		case "inline-images":
+			cfg.InlineImages = *inlineImagesFlag
+		case "inline-max-images":
+			cfg.InlineMax = *inlineMaxFlag
+		case "inline-max-mb":
+			cfg.InlineMB = *inlineMBFlag
 		}
 	})
 
@@ -226,6 +385,12 @@ func (c *Config) Validate() error {
 	if c.Addr == "" {
 		return fmt.Errorf("addr cannot be empty")
 	}
+	if c.PublicURL != "" {
+		u, err := url.Parse(c.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid public_url: %q (must be an absolute http(s) URL)", c.PublicURL)
+		}
+	}
 	if c.MailerMode != "smtp" && c.MailerMode != "sendmail" && c.MailerMode != "mock" {
 		return fmt.Errorf("invalid mailer_mode: %q (must be 'smtp', 'sendmail', or 'mock')", c.MailerMode)
 	}
@@ -238,6 +403,41 @@ func (c *Config) Validate() error {
 	if c.PollInterval <= 0 {
 		return fmt.Errorf("poll_interval must be greater than 0")
 	}
+	if c.FailingAfter <= 0 {
+		return fmt.Errorf("failing_after must be greater than 0")
+	}
+	if c.DisableAfter < 0 {
+		return fmt.Errorf("disable_after cannot be negative")
+	}
+	if c.AlertInterval <= 0 {
+		return fmt.Errorf("alert_interval must be greater than 0")
+	}
+	if c.AdaptiveMin <= 0 {
+		return fmt.Errorf("adaptive_min_interval must be greater than 0")
+	}
+	if c.AdaptiveMax < c.AdaptiveMin {
+		return fmt.Errorf("adaptive_max_interval cannot be less than adaptive_min_interval")
+	}
+	if c.HostRate < 0 {
+		return fmt.Errorf("host_rate cannot be negative")
+	}
+	if c.HostConns < 0 {
+		return fmt.Errorf("host_connections cannot be negative")
+	}
+	if c.ExtractTTL < 0 {
+		return fmt.Errorf("extract_cache_ttl cannot be negative")
+	}
+	if c.ExtractTTL > 0 && c.ExtractMB <= 0 {
+		return fmt.Errorf("extract_cache_mb must be greater than 0 while the extraction cache is enabled")
+	}
+	if c.InlineImages && (c.InlineMax <= 0 || c.InlineMB <= 0) {
+		return fmt.Errorf("inline_max_images and inline_max_mb must be greater than 0 while image inlining is enabled")
+	}
+	for _, addr := range c.AlertEmails {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid alert_emails address %q: %w", addr, err)
+		}
+	}
 	if err := validateLogLevel(c.LogLevel); err != nil {
 		return fmt.Errorf("invalid log_level: %w", err)
 	}
@@ -255,6 +455,15 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// SecretKeyPath returns the path of the key file that encrypts stored
+// credentials: secret_key_file if set, otherwise one next to the database.
+func (c *Config) SecretKeyPath() string {
+	if c.SecretKeyFile != "" {
+		return c.SecretKeyFile
+	}
+	return c.DBPath + ".key"
+}
+
 func validateLogLevel(lvl string) error {
 	switch strings.ToLower(strings.TrimSpace(lvl)) {
 	case "debug", "info", "warn", "warning", "error", "off", "":
@@ -288,9 +497,21 @@ func parseLogLevelsMap(s string) map[string]string {
 	return m
 }
 
+// parseList splits a comma-separated list, dropping empty entries.
+func parseList(s string) []string {
+	var list []string
+	for part := range strings.SplitSeq(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
 // warnIfWorldReadable prints a warning if database or mail secrets are exposed to world reads.
-func warnIfWorldReadable(path string, hasSMTPPass string) {
-	if hasSMTPPass == "" {
+// Nothing is printed unless at least one of secrets is non-empty.
+func warnIfWorldReadable(path string, secrets ...string) {
+	if !slices.ContainsFunc(secrets, func(s string) bool { return s != "" }) {
 		return
 	}
 	info, err := os.Stat(path)
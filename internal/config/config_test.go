@@ -75,6 +75,7 @@ func TestConfig_EnvOverlay(t *testing.T) {
 	t.Setenv("RSS2GO_DB", "/env/path.db")
 	t.Setenv("RSS2GO_ADDR", ":7777")
 	t.Setenv("RSS2GO_CRAWLERS", "9")
+	t.Setenv("RSS2GO_PASSWORD", "env-pass")
 
 	cfg, err := Load([]string{})
 	if err != nil {
@@ -90,6 +91,9 @@ func TestConfig_EnvOverlay(t *testing.T) {
 	if cfg.Crawlers != 9 {
 		t.Errorf("expected Crawlers 9, got %d", cfg.Crawlers)
 	}
+	if cfg.Password != "env-pass" {
+		t.Errorf("expected Password 'env-pass', got %q", cfg.Password)
+	}
 }
 
 func TestConfig_CLIOverlay(t *testing.T) {
@@ -99,6 +103,8 @@ func TestConfig_CLIOverlay(t *testing.T) {
 		"-db", "/cli/path.db",
 		"-addr", ":1234",
 		"-crawlers", "15",
+		"-pass", "cli-pass",
+		"-public-url", "https://rss2go.example.com",
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -114,6 +120,12 @@ func TestConfig_CLIOverlay(t *testing.T) {
 	if cfg.Crawlers != 15 {
 		t.Errorf("expected Crawlers 15, got %d", cfg.Crawlers)
 	}
+	if cfg.Password != "cli-pass" {
+		t.Errorf("expected Password 'cli-pass', got %q", cfg.Password)
+	}
+	if cfg.PublicURL != "https://rss2go.example.com" {
+		t.Errorf("expected PublicURL 'https://rss2go.example.com', got %q", cfg.PublicURL)
+	}
 }
 
 func TestConfig_ExplicitFileMissing(t *testing.T) {
@@ -149,6 +161,11 @@ func TestConfig_ValidationErrors(t *testing.T) {
 		t.Errorf("expected validation error for invalid smtp-security, got nil")
 	}
 
+	_, err = Load([]string{"-public-url", "rss2go.example.com"})
+	if err == nil {
+		t.Errorf("expected validation error for relative public-url, got nil")
+	}
+
 }
 
 func TestConfig_InvalidEnvFallback(t *testing.T) {
@@ -353,3 +370,180 @@ func TestConfig_InvalidLogSettings(t *testing.T) {
 		t.Errorf("expected validation error for empty component name in log-levels, got nil")
 	}
 }
+
+func TestConfig_FeedHealthSettings(t *testing.T) {
+	cfg, err := Load([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error loading defaults: %v", err)
+	}
+	if cfg.FailingAfter != 3 || cfg.DisableAfter != 30*24*time.Hour || cfg.AlertInterval != 24*time.Hour || len(cfg.AlertEmails) != 0 {
+		t.Errorf("unexpected feed health defaults: %+v", cfg)
+	}
+
+	t.Setenv("RSS2GO_ALERT_EMAILS", "ops@example.com")
+	t.Setenv("RSS2GO_DISABLE_AFTER", "72h")
+	cfg, err = Load([]string{"-failing-after", "5", "-alert-emails", "a@example.com, b@example.com,", "-disable-after", "0s"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.FailingAfter != 5 || cfg.DisableAfter != 0 {
+		t.Errorf("expected flags to set thresholds, got failing_after=%d disable_after=%v", cfg.FailingAfter, cfg.DisableAfter)
+	}
+	if len(cfg.AlertEmails) != 2 || cfg.AlertEmails[0] != "a@example.com" || cfg.AlertEmails[1] != "b@example.com" {
+		t.Errorf("expected comma-separated alert addresses, got %q", cfg.AlertEmails)
+	}
+
+	for _, args := range [][]string{
+		{"-failing-after", "0"},
+		{"-disable-after", "-1h"},
+		{"-alert-interval", "0s"},
+		{"-alert-emails", "not-an-address"},
+	} {
+		if _, err := Load(args); err == nil {
+			t.Errorf("expected validation error for %v, got nil", args)
+		}
+	}
+}
+
+func TestConfig_AdaptivePollingBounds(t *testing.T) {
+	cfg, err := Load([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error loading defaults: %v", err)
+	}
+	if cfg.AdaptiveMin != 5*time.Minute || cfg.AdaptiveMax != 24*time.Hour {
+		t.Errorf("unexpected adaptive polling defaults: min=%v max=%v", cfg.AdaptiveMin, cfg.AdaptiveMax)
+	}
+
+	t.Setenv("RSS2GO_ADAPTIVE_MAX_INTERVAL", "6h")
+	cfg, err = Load([]string{"-adaptive-min-interval", "15m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.AdaptiveMin != 15*time.Minute || cfg.AdaptiveMax != 6*time.Hour {
+		t.Errorf("expected env and flag overrides, got min=%v max=%v", cfg.AdaptiveMin, cfg.AdaptiveMax)
+	}
+
+	for _, args := range [][]string{
+		{"-adaptive-min-interval", "0s"},
+		{"-adaptive-min-interval", "2h", "-adaptive-max-interval", "1h"},
+	} {
+		if _, err := Load(args); err == nil {
+			t.Errorf("expected validation error for %v, got nil", args)
+		}
+	}
+}
+
+func TestConfig_SecretKeyPath(t *testing.T) {
+	cfg, err := Load([]string{"-db", "/var/lib/rss2go/feeds.db"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.SecretKeyPath(); got != "/var/lib/rss2go/feeds.db.key" {
+		t.Errorf("expected the key next to the database, got %q", got)
+	}
+
+	t.Setenv("RSS2GO_SECRET_KEY_FILE", "/etc/rss2go/env.key")
+	cfg, _ = Load([]string{})
+	if got := cfg.SecretKeyPath(); got != "/etc/rss2go/env.key" {
+		t.Errorf("expected env override, got %q", got)
+	}
+
+	cfg, _ = Load([]string{"-secret-key-file", "/etc/rss2go/flag.key"})
+	if got := cfg.SecretKeyPath(); got != "/etc/rss2go/flag.key" {
+		t.Errorf("expected flag override, got %q", got)
+	}
+}
+
+func TestConfig_HostPoliteness(t *testing.T) {
+	cfg, err := Load([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error loading defaults: %v", err)
+	}
+	if cfg.HostRate != 1 || cfg.HostConns != 2 || cfg.RespectRobots {
+		t.Errorf("unexpected politeness defaults: rate=%v conns=%d robots=%v", cfg.HostRate, cfg.HostConns, cfg.RespectRobots)
+	}
+
+	t.Setenv("RSS2GO_HOST_RATE", "0.5")
+	t.Setenv("RSS2GO_RESPECT_ROBOTS", "true")
+	cfg, err = Load([]string{"-host-connections", "4"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.HostRate != 0.5 || cfg.HostConns != 4 || !cfg.RespectRobots {
+		t.Errorf("expected env and flag overrides, got rate=%v conns=%d robots=%v", cfg.HostRate, cfg.HostConns, cfg.RespectRobots)
+	}
+
+	for _, args := range [][]string{
+		{"-host-rate", "-1"},
+		{"-host-connections", "-2"},
+	} {
+		if _, err := Load(args); err == nil {
+			t.Errorf("expected validation error for %v, got nil", args)
+		}
+	}
+}
+
+func TestConfig_ExtractionCache(t *testing.T) {
+	cfg, err := Load([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error loading defaults: %v", err)
+	}
+	if cfg.ExtractTTL != 24*time.Hour || cfg.ExtractMB != 64 {
+		t.Errorf("unexpected extraction cache defaults: ttl=%v mb=%d", cfg.ExtractTTL, cfg.ExtractMB)
+	}
+
+	t.Setenv("RSS2GO_EXTRACT_CACHE_TTL", "6h")
+	cfg, err = Load([]string{"-extract-cache-mb", "16"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ExtractTTL != 6*time.Hour || cfg.ExtractMB != 16 {
+		t.Errorf("expected env and flag overrides, got ttl=%v mb=%d", cfg.ExtractTTL, cfg.ExtractMB)
+	}
+
+	// A disabled cache needs no size
+	if _, err := Load([]string{"-extract-cache-ttl", "0", "-extract-cache-mb", "0"}); err != nil {
+		t.Errorf("unexpected error disabling the cache: %v", err)
+	}
+	for _, args := range [][]string{
+		{"-extract-cache-ttl", "-1h"},
+		{"-extract-cache-mb", "0"},
+	} {
+		if _, err := Load(args); err == nil {
+			t.Errorf("expected validation error for %v, got nil", args)
+		}
+	}
+}
+
+func TestConfig_InlineImages(t *testing.T) {
+	cfg, err := Load([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error loading defaults: %v", err)
+	}
+	if cfg.InlineImages || cfg.InlineMax != 20 || cfg.InlineMB != 10 {
+		t.Errorf("unexpected inline image defaults: enabled=%v max=%d mb=%d", cfg.InlineImages, cfg.InlineMax, cfg.InlineMB)
+	}
+
+	t.Setenv("RSS2GO_INLINE_IMAGES", "true")
+	cfg, err = Load([]string{"-inline-max-images", "5", "-inline-max-mb", "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.InlineImages || cfg.InlineMax != 5 || cfg.InlineMB != 2 {
+		t.Errorf("expected env and flag overrides, got enabled=%v max=%d mb=%d", cfg.InlineImages, cfg.InlineMax, cfg.InlineMB)
+	}
+
+	for _, args := range [][]string{
+		{"-inline-max-images", "0"},
+		{"-inline-max-mb", "0"},
+	} {
+		if _, err := Load(args); err == nil {
+			t.Errorf("expected validation error for %v, got nil", args)
+		}
+	}
+
+	// Limits only matter while inlining is enabled
+	if _, err := Load([]string{"-inline-images=false", "-inline-max-mb", "0"}); err != nil {
+		t.Errorf("unexpected error with inlining disabled: %v", err)
+	}
+}
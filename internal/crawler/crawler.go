@@ -1,8 +1,8 @@
 package crawler
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,9 +12,9 @@ import (
 	"strings"
 	"time"
 
+	"rss2go/internal/requestopts"
 	"rss2go/internal/types"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/mmcdole/gofeed"
 )
 
@@ -25,12 +25,32 @@ type Result struct {
 	LastModified string
 	Feed         *gofeed.Feed
 	RetryAfter   *time.Duration
+	// Hub and Self are the WebSub hub and topic URLs the feed advertises, if any.
+	Hub  string
+	Self string
+	// MovedTo is the URL the feed permanently redirected to (301 or 308), if any.
+	MovedTo string
+	// StatusCode and Bytes describe the HTTP response. They are also set
+	// alongside an error whenever a response was received.
+	StatusCode int
+	Bytes      int64
+	// PollHint is the shortest poll interval the publisher asks for, from
+	// its cache headers and feed metadata, or zero. See PollHint.
+	PollHint time.Duration
 }
 
+var (
+	// ErrGone is returned when the server answers 410 Gone.
+	ErrGone = errors.New("crawler: feed is gone")
+	// ErrNotFound is returned when the server answers 404 Not Found.
+	ErrNotFound = errors.New("crawler: feed not found")
+)
+
 // Crawler manages fetching and parsing of remote feed sources.
 type Crawler struct {
-	client *http.Client
-	log    *slog.Logger
+	client  *http.Client
+	log     *slog.Logger
+	formats *Registry
 }
 
 // NewCrawler creates a new Crawler instance with the specified HTTP client and optional logger.
@@ -47,7 +67,13 @@ func NewCrawler(client *http.Client, log ...*slog.Logger) *Crawler {
 	} else {
 		l = slog.Default().With("component", "crawler")
 	}
-	return &Crawler{client: client, log: l}
+	return &Crawler{client: client, log: l, formats: DefaultRegistry()}
+}
+
+// Formats returns the source formats the crawler parses, for registering
+// new ones.
+func (c *Crawler) Formats() *Registry {
+	return c.formats
 }
 
 // SanitizeURL strips Basic Auth credentials (user:pass) and query/fragment parameters from raw URLs for safe logging.
@@ -115,11 +141,15 @@ func (c *Crawler) Crawl(ctx context.Context, f *types.Feed) (*Result, error) {
 		req.Header.Set("If-Modified-Since", f.LastModified)
 	}
 
-	// Identify as rss2go crawler
+	// Identify as rss2go crawler, unless the feed asks otherwise
 	req.Header.Set("User-Agent", "rss2go/1.0 (Syndication Aggregator Daemon)")
+	requestopts.Apply(req, f.RequestOptions)
+
+	client, movedTo := c.redirectTracker()
+	client = requestopts.Client(client, f.RequestOptions)
 
 	start := time.Now()
-	resp, err := c.client.Do(req)
+	resp, err := client.Do(req)
 	duration := time.Since(start)
 	if err != nil {
 		log.Debug("Feed HTTP fetch failed", "url", safeURL, "duration", duration, "err", err)
@@ -134,17 +164,32 @@ func (c *Crawler) Crawl(ctx context.Context, f *types.Feed) (*Result, error) {
 		retryVal := resp.Header.Get("Retry-After")
 		duration := parseRetryAfter(retryVal)
 		log.Debug("Feed rate limited or unavailable", "url", safeURL, "status", resp.StatusCode, "retry_after", retryVal)
-		return &Result{RetryAfter: duration}, fmt.Errorf("crawler: server returned status %d", resp.StatusCode)
+		return &Result{RetryAfter: duration, StatusCode: resp.StatusCode}, fmt.Errorf("crawler: server returned status %d", resp.StatusCode)
+	}
+
+	// Scraped pages are not feeds, so only real feed URLs follow a move.
+	var moved string
+	if !isScrape && !mutateToInvalid && *movedTo != u {
+		moved = *movedTo
 	}
 
 	if resp.StatusCode == http.StatusNotModified {
 		log.Debug("Feed not modified (304)", "url", safeURL)
-		return &Result{NotModified: true}, nil
+		return &Result{NotModified: true, MovedTo: moved, StatusCode: resp.StatusCode, PollHint: PollHint(resp.Header, nil, nil)}, nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusGone:
+		log.Debug("Feed gone (410)", "url", safeURL)
+		return &Result{StatusCode: resp.StatusCode}, fmt.Errorf("%w: server returned status %d %s", ErrGone, resp.StatusCode, resp.Status)
+	case http.StatusNotFound:
+		log.Debug("Feed not found (404)", "url", safeURL)
+		return &Result{StatusCode: resp.StatusCode}, fmt.Errorf("%w: server returned status %d %s", ErrNotFound, resp.StatusCode, resp.Status)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		log.Debug("Feed HTTP non-200 status", "url", safeURL, "status", resp.StatusCode)
-		return nil, fmt.Errorf("crawler: server returned status %d %s", resp.StatusCode, resp.Status)
+		return &Result{StatusCode: resp.StatusCode}, fmt.Errorf("crawler: server returned status %d %s", resp.StatusCode, resp.Status)
 	}
 
 	// Extract new caching markers
@@ -155,74 +200,43 @@ func (c *Crawler) Crawl(ctx context.Context, f *types.Feed) (*Result, error) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Debug("Failed reading feed response body", "url", safeURL, "err", err)
-		return nil, fmt.Errorf("crawler: read body: %w", err)
+		return &Result{StatusCode: resp.StatusCode}, fmt.Errorf("crawler: read body: %w", err)
 	}
 
-	var parsedFeed *gofeed.Feed
-	if isScrape {
-		targetURL, err := url.Parse(u)
-		if err != nil {
-			return nil, fmt.Errorf("crawler: parse target url: %w", err)
-		}
-
-		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(bodyBytes))
-		if err != nil {
-			return nil, fmt.Errorf("crawler: parse HTML: %w", err)
-		}
-
-		var feedItems []*gofeed.Item
-		doc.Find(itemSel).Each(func(i int, sel *goquery.Selection) {
-			title := strings.TrimSpace(sel.Find(titleSel).First().Text())
-			if title == "" {
-				return
-			}
-
-			linkSelEl := sel.Find(linkSel).First()
-			link, exists := linkSelEl.Attr("href")
-			if !exists || strings.TrimSpace(link) == "" {
-				return
-			}
-			link = strings.TrimSpace(link)
-
-			// Resolve relative URL
-			parsedLink, err := url.Parse(link)
-			if err == nil {
-				link = targetURL.ResolveReference(parsedLink).String()
-			}
-
-			description := ""
-			if descSel != "" {
-				description = strings.TrimSpace(sel.Find(descSel).First().Text())
-			}
+	received := &Result{StatusCode: resp.StatusCode, Bytes: int64(len(bodyBytes))}
 
-			now := time.Now()
-			feedItems = append(feedItems, &gofeed.Item{
-				Title:           title,
-				Link:            link,
-				Description:     description,
-				GUID:            link,
-				Published:       now.Format(time.RFC1123Z),
-				PublishedParsed: &now,
-			})
-		})
-
-		parsedFeed = &gofeed.Feed{
-			Title:       targetURL.Host + " Scraped Feed",
-			Link:        targetURL.String(),
-			Description: "Dynamically generated scraped RSS feed for " + targetURL.String(),
-			Items:       feedItems,
-		}
-	} else {
-		parser := gofeed.NewParser()
-		var parseErr error
-		parsedFeed, parseErr = parser.Parse(bytes.NewReader(bodyBytes))
-		if parseErr != nil {
-			log.Debug("Failed parsing feed XML/Atom", "url", safeURL, "bytes", len(bodyBytes), "err", parseErr)
-			return nil, fmt.Errorf("crawler: parse feed: %w", parseErr)
+	src := &Source{
+		URL:         resp.Request.URL,
+		ContentType: mediaType(resp.Header.Get("Content-Type")),
+		Body:        bodyBytes,
+		Selectors:   Selectors{Item: itemSel, Title: titleSel, Link: linkSel, Description: descSel},
+	}
+	formatName := f.SourceFormat
+	if formatName == "" && isScrape {
+		formatName = FormatScraper
+	}
+	format, err := c.formats.Select(formatName, src)
+	if err != nil {
+		return received, err
+	}
+	parsedFeed, err := format.Parse(src)
+	if err != nil {
+		log.Debug("Failed parsing feed", "url", safeURL, "format", format.Name(), "bytes", len(bodyBytes), "err", err)
+		return received, err
+	}
+	isScrape = format.Name() == FormatScraper
+
+	var hub, self string
+	var hint time.Duration
+	if !isScrape {
+		hint = PollHint(resp.Header, parsedFeed, bodyBytes)
+		hub, self = DiscoverWebSub(resp.Header, bodyBytes)
+		if hub != "" {
+			log.Debug("Feed advertises WebSub hub", "url", safeURL, "hub", hub, "self", self)
 		}
 	}
 
-	log.Debug("Feed successfully parsed", "url", safeURL, "title", parsedFeed.Title, "items", len(parsedFeed.Items))
+	log.Debug("Feed successfully parsed", "url", safeURL, "format", format.Name(), "title", parsedFeed.Title, "items", len(parsedFeed.Items))
 
 	if mutateToInvalid {
 		f.URL = "http://invalid url/feed.xml"
@@ -233,9 +247,52 @@ func (c *Crawler) Crawl(ctx context.Context, f *types.Feed) (*Result, error) {
 		ETag:         newETag,
 		LastModified: newLastModified,
 		Feed:         parsedFeed,
+		Hub:          hub,
+		Self:         self,
+		MovedTo:      moved,
+		StatusCode:   resp.StatusCode,
+		Bytes:        received.Bytes,
+		PollHint:     hint,
 	}, nil
 }
 
+// redirectTracker returns a copy of the crawler's client that records where
+// an unbroken chain of permanent redirects leads. Once a temporary redirect
+// appears the chain stops counting, since only the permanent hops before it
+// say the feed moved. The recorded URL stays empty if there were none.
+func (c *Crawler) redirectTracker() (*http.Client, *string) {
+	client := *c.client
+	next := c.client.CheckRedirect
+	var movedTo string
+	permanent := true
+
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if permanent && req.Response != nil &&
+			(req.Response.StatusCode == http.StatusMovedPermanently || req.Response.StatusCode == http.StatusPermanentRedirect) {
+			movedTo = req.URL.String()
+		} else {
+			permanent = false
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &client, &movedTo
+}
+
+// ParseFeed parses an RSS, Atom or JSON feed document.
+func ParseFeed(body []byte) (*gofeed.Feed, error) {
+	src := &Source{Body: body}
+	if (jsonFeedFormat{}).Sniff(body) {
+		return jsonFeedFormat{}.Parse(src)
+	}
+	return xmlFormat{}.Parse(src)
+}
+
 // parseRetryAfter parses HTTP Retry-After headers which can contain integer seconds
 // or a target HTTP-date timestamp.
 func parseRetryAfter(val string) *time.Duration {
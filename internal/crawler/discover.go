@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxDiscoveryBody caps how much of a page or candidate feed discovery reads.
+const maxDiscoveryBody = 5 << 20
+
+// commonFeedPaths are probed on the site root when looking for feeds that a
+// page does not advertise.
+var commonFeedPaths = []string{
+	"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml", "/feed.json",
+}
+
+// feedLinkTypes are the <link rel="alternate"> media types that name a feed.
+var feedLinkTypes = []string{
+	"application/rss+xml", "application/atom+xml", "application/feed+json",
+	"application/json", "application/rdf+xml", "application/xml", "text/xml",
+}
+
+// Candidate is a feed found by Discover. Every candidate has been fetched and
+// parsed successfully.
+type Candidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Type is the parsed feed format: "rss", "atom", "json" or "hfeed".
+	Type  string `json:"type"`
+	Items int    `json:"items"`
+}
+
+// Discover finds the feeds behind a site URL. If the URL is itself a feed it
+// is the only candidate. Otherwise the page's <link rel="alternate"> feeds
+// come first, in document order, followed by any common feed paths on the
+// site that parse. Candidates that fail to fetch or parse are left out. A
+// page without any feeds is offered itself if it carries h-feed markup.
+func (c *Crawler) Discover(ctx context.Context, rawURL string) ([]Candidate, error) {
+	body, pageURL, err := c.fetchForDiscovery(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if cand, ok := candidateFromBody(pageURL.String(), body); ok {
+		return []Candidate{cand}, nil
+	}
+
+	urls := feedLinks(pageURL, body)
+	root := &url.URL{Scheme: pageURL.Scheme, Host: pageURL.Host}
+	for _, p := range commonFeedPaths {
+		if u := root.JoinPath(p).String(); !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	c.log.Debug("Probing feed candidates", "url", SanitizeURL(pageURL.String()), "candidates", len(urls))
+
+	// Probe concurrently but report in priority order.
+	results := make([]*Candidate, len(urls))
+	finalURLs := make([]string, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Go(func() {
+			body, final, err := c.fetchForDiscovery(ctx, u)
+			if err != nil {
+				return
+			}
+			if cand, ok := candidateFromBody(u, body); ok {
+				results[i] = &cand
+				finalURLs[i] = final.String()
+			}
+		})
+	}
+	wg.Wait()
+
+	// Common paths often redirect to an advertised feed; list each feed once.
+	candidates := []Candidate{}
+	var seen []string
+	for i, cand := range results {
+		if cand == nil || slices.Contains(seen, finalURLs[i]) {
+			continue
+		}
+		seen = append(seen, finalURLs[i])
+		candidates = append(candidates, *cand)
+	}
+
+	// A page marked up with h-entry microformats can be followed itself
+	if len(candidates) == 0 && (hFeedFormat{}).Sniff(body) {
+		if parsed, err := (hFeedFormat{}).Parse(&Source{URL: pageURL, Body: body}); err == nil {
+			candidates = append(candidates, Candidate{
+				URL:   pageURL.String(),
+				Title: strings.TrimSpace(parsed.Title),
+				Type:  parsed.FeedType,
+				Items: len(parsed.Items),
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// fetchForDiscovery GETs u and returns its body and the final URL after redirects.
+func (c *Crawler) fetchForDiscovery(ctx context.Context, u string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("crawler: create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "rss2go/1.0 (Syndication Aggregator Daemon)")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("crawler: fetch failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("crawler: server returned status %d %s", resp.StatusCode, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("crawler: read body: %w", err)
+	}
+	return body, resp.Request.URL, nil
+}
+
+// candidateFromBody parses body as a feed served from u.
+func candidateFromBody(u string, body []byte) (Candidate, bool) {
+	parsed, err := ParseFeed(body)
+	if err != nil {
+		return Candidate{}, false
+	}
+	return Candidate{
+		URL:   u,
+		Title: strings.TrimSpace(parsed.Title),
+		Type:  parsed.FeedType,
+		Items: len(parsed.Items),
+	}, true
+}
+
+// feedLinks returns the absolute URLs of the feeds an HTML page advertises
+// with <link rel="alternate">, honouring any <base href>.
+func feedLinks(pageURL *url.URL, body []byte) []string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	base := pageURL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := pageURL.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+
+	var links []string
+	doc.Find("link[href]").Each(func(_ int, sel *goquery.Selection) {
+		rels := strings.Fields(strings.ToLower(sel.AttrOr("rel", "")))
+		if !slices.Contains(rels, "alternate") {
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(sel.AttrOr("type", ""))
+		if err != nil || !slices.Contains(feedLinkTypes, mediaType) {
+			return
+		}
+		u, err := base.Parse(strings.TrimSpace(sel.AttrOr("href", "")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		if s := u.String(); !slices.Contains(links, s) {
+			links = append(links, s)
+		}
+	})
+	return links
+}
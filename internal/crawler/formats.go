@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/url"
+	"slices"
+	"sync"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Names of the built-in source formats, as stored in a feed's SourceFormat.
+const (
+	FormatXML      = "xml"      // RSS, Atom and RDF through gofeed
+	FormatJSONFeed = "jsonfeed" // JSON Feed 1.0 and 1.1
+	FormatHFeed    = "hfeed"    // h-feed/h-entry microformats in an HTML page
+	FormatScraper  = "scraper"  // CSS selectors over an HTML page
+)
+
+// Source is a fetched document for a Format to parse.
+type Source struct {
+	// URL is where the document was finally served from, for resolving
+	// relative links.
+	URL *url.URL
+	// ContentType is the response's media type without parameters, if any.
+	ContentType string
+	Body        []byte
+	// Selectors configure the scraper format.
+	Selectors Selectors
+}
+
+// Selectors are the CSS selectors the scraper format turns a page into
+// items with.
+type Selectors struct {
+	Item        string
+	Title       string
+	Link        string
+	Description string
+}
+
+// Format parses one kind of source document into a feed.
+type Format interface {
+	// Name identifies the format in a feed's SourceFormat setting.
+	Name() string
+	// MediaTypes are the content types the format is usually served as.
+	MediaTypes() []string
+	// Sniff reports whether body looks like this format. Formats that can
+	// only be chosen explicitly never match.
+	Sniff(body []byte) bool
+	// Parse converts the document into a feed.
+	Parse(src *Source) (*gofeed.Feed, error)
+}
+
+// Registry holds the source formats a crawler can parse and picks the one
+// for each fetched document.
+type Registry struct {
+	mu       sync.RWMutex
+	formats  []Format
+	fallback string
+}
+
+// NewRegistry returns a registry of formats, tried in the given order when
+// sniffing. Documents that no format recognizes go to the first format.
+func NewRegistry(formats ...Format) *Registry {
+	r := &Registry{}
+	for _, f := range formats {
+		r.Register(f)
+	}
+	return r
+}
+
+// DefaultRegistry returns a registry of the built-in formats.
+func DefaultRegistry() *Registry {
+	return NewRegistry(xmlFormat{}, jsonFeedFormat{}, hFeedFormat{}, scraperFormat{})
+}
+
+// Register adds a format, replacing any registered under the same name.
+func (r *Registry) Register(f Format) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := slices.IndexFunc(r.formats, func(g Format) bool { return g.Name() == f.Name() }); i >= 0 {
+		r.formats[i] = f
+		return
+	}
+	if len(r.formats) == 0 {
+		r.fallback = f.Name()
+	}
+	r.formats = append(r.formats, f)
+}
+
+// Lookup returns the format registered under name.
+func (r *Registry) Lookup(name string) (Format, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i := slices.IndexFunc(r.formats, func(f Format) bool { return f.Name() == name })
+	if i < 0 {
+		return nil, false
+	}
+	return r.formats[i], true
+}
+
+// Names lists the registered formats.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, len(r.formats))
+	for i, f := range r.formats {
+		names[i] = f.Name()
+	}
+	return names
+}
+
+// Select picks the format for src: the named one if name is set, otherwise
+// the first format served as src's content type that also sniffs the body,
+// then the first that sniffs it at all, and finally the fallback format.
+func (r *Registry) Select(name string, src *Source) (Format, error) {
+	if name != "" {
+		f, ok := r.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("crawler: unknown source format %q", name)
+		}
+		return f, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if src.ContentType != "" {
+		for _, f := range r.formats {
+			if slices.Contains(f.MediaTypes(), src.ContentType) && f.Sniff(src.Body) {
+				return f, nil
+			}
+		}
+	}
+	for _, f := range r.formats {
+		if f.Sniff(src.Body) {
+			return f, nil
+		}
+	}
+	for _, f := range r.formats {
+		if f.Name() == r.fallback {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("crawler: no source formats registered")
+}
+
+// mediaType returns the media type of a Content-Type header, lowercased and
+// without parameters.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// xmlFormat parses RSS, Atom and RDF feeds with gofeed, which also copes
+// with the JSON feeds the JSON Feed format does not recognize.
+type xmlFormat struct{}
+
+func (xmlFormat) Name() string { return FormatXML }
+
+func (xmlFormat) MediaTypes() []string {
+	return []string{"application/rss+xml", "application/atom+xml", "application/rdf+xml", "application/xml", "text/xml"}
+}
+
+func (xmlFormat) Sniff(body []byte) bool {
+	head := bytes.ToLower(body[:min(len(body), 1024)])
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("<")) &&
+		(bytes.Contains(head, []byte("<rss")) || bytes.Contains(head, []byte("<feed")) || bytes.Contains(head, []byte("<rdf:rdf")))
+}
+
+func (xmlFormat) Parse(src *Source) (*gofeed.Feed, error) {
+	parsed, err := gofeed.NewParser().Parse(bytes.NewReader(src.Body))
+	if err != nil {
+		return nil, fmt.Errorf("crawler: parse feed: %w", err)
+	}
+	return parsed, nil
+}
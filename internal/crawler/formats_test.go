@@ -0,0 +1,259 @@
+package crawler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rss2go/internal/types"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return body
+}
+
+func parseFixture(t *testing.T, format Format, name, pageURL string) *gofeed.Feed {
+	t.Helper()
+	u, _ := url.Parse(pageURL)
+	parsed, err := format.Parse(&Source{URL: u, Body: readFixture(t, name)})
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+	return parsed
+}
+
+func TestJSONFeedFixtures(t *testing.T) {
+	feed := parseFixture(t, jsonFeedFormat{}, "jsonfeed-1.1.json", "https://podcast.example.org/feed.json")
+	if feed.Title != "Podcast & Notes" || feed.Link != "https://podcast.example.org/" || feed.FeedType != "json" || feed.FeedVersion != "1.1" {
+		t.Errorf("unexpected feed metadata %+v", feed)
+	}
+	if feed.Author == nil || feed.Author.Name != "Rowan Example" || feed.Image == nil {
+		t.Errorf("expected feed author and icon, got %+v", feed)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(feed.Items))
+	}
+
+	ep := feed.Items[0]
+	if ep.GUID != "episode-12" || ep.Link != "https://podcast.example.org/12" || ep.Description != "We talk about feeds." {
+		t.Errorf("unexpected item %+v", ep)
+	}
+	if want := time.Date(2026, 5, 1, 7, 30, 0, 0, time.UTC); ep.PublishedParsed == nil || !ep.PublishedParsed.Equal(want) {
+		t.Errorf("expected published %v, got %v", want, ep.PublishedParsed)
+	}
+	if ep.UpdatedParsed == nil || len(ep.Categories) != 2 || ep.Image == nil {
+		t.Errorf("expected updated time, tags and image, got %+v", ep)
+	}
+	if len(ep.Enclosures) != 2 || ep.Enclosures[0].URL != "https://cdn.example.org/12.mp3" ||
+		ep.Enclosures[0].Type != "audio/mpeg" || ep.Enclosures[0].Length != "48213120" || ep.Enclosures[1].Length != "" {
+		t.Errorf("expected attachments as enclosures, got %+v %+v", ep.Enclosures[0], ep.Enclosures[1])
+	}
+
+	note := feed.Items[1]
+	if note.GUID != "11" || note.Link != "https://elsewhere.example.net/story" {
+		t.Errorf("expected numeric id and external_url fallback, got %q %q", note.GUID, note.Link)
+	}
+	if note.Content != "<p>A plain note.</p><p>It has &lt;two&gt; paragraphs.</p>" {
+		t.Errorf("expected escaped content_text paragraphs, got %q", note.Content)
+	}
+	if note.Author == nil || note.Author.Name != "Guest Writer" {
+		t.Errorf("expected item author, got %+v", note.Author)
+	}
+
+	old := parseFixture(t, jsonFeedFormat{}, "jsonfeed-1.0.json", "https://old.example.org/feed.json")
+	if old.FeedVersion != "1" || old.Author == nil || old.Author.Name != "Single Author" || len(old.Items) != 1 {
+		t.Errorf("expected a JSON Feed 1.0 document to parse, got %+v", old)
+	}
+
+	if _, err := (jsonFeedFormat{}).Parse(&Source{Body: []byte(`{"version": "2", "items": []}`)}); err == nil {
+		t.Errorf("expected an unknown version to be rejected")
+	}
+}
+
+func TestHFeedFixtures(t *testing.T) {
+	feed := parseFixture(t, hFeedFormat{}, "hfeed.html", "https://sam.example.com/")
+	if feed.Title != "Sam's Posts" || feed.Author == nil || feed.Author.Name != "Sam Example" || feed.Link != "https://sam.example.com/" {
+		t.Errorf("unexpected feed metadata %+v", feed)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("expected nested replies and entries without a URL to be skipped, got %d items", len(feed.Items))
+	}
+
+	post := feed.Items[0]
+	if post.Title != "Why I use microformats" || post.Link != "https://sam.example.com/blog/posts/microformats" || post.GUID != post.Link {
+		t.Errorf("expected name and <base>-resolved URL, got %q %q", post.Title, post.Link)
+	}
+	if post.Author == nil || post.Author.Name != "Guest Author" || post.Image != nil {
+		t.Errorf("expected the entry's h-card author without its photo, got %+v %+v", post.Author, post.Image)
+	}
+	if want := time.Date(2026, 5, 3, 13, 0, 0, 0, time.UTC); post.PublishedParsed == nil || !post.PublishedParsed.Equal(want) {
+		t.Errorf("expected published %v, got %v", want, post.PublishedParsed)
+	}
+	if post.Description != "Markup that doubles as a feed." || !strings.Contains(post.Content, `<a href="/plain">plain page</a>`) {
+		t.Errorf("unexpected summary %q or content %q", post.Description, post.Content)
+	}
+	if len(post.Categories) != 2 || post.Categories[0] != "indieweb" {
+		t.Errorf("unexpected categories %q", post.Categories)
+	}
+
+	note := feed.Items[1]
+	if !strings.HasPrefix(note.Title, "Just a short note") || len([]rune(note.Title)) != maxImpliedName || !strings.HasSuffix(note.Title, "…") {
+		t.Errorf("expected a shortened implied name, got %q", note.Title)
+	}
+	if note.PublishedParsed == nil || note.PublishedParsed.Hour() != 8 {
+		t.Errorf("expected a text date to parse, got %v", note.PublishedParsed)
+	}
+
+	bare := parseFixture(t, hFeedFormat{}, "hfeed-bare.html", "https://notes.example.com/")
+	if bare.Title != "Bare Notes" || len(bare.Items) != 2 {
+		t.Fatalf("expected entries without an h-feed to belong to the page, got %+v", bare)
+	}
+	if bare.Items[0].Link != "https://notes.example.com/notes/1" || bare.Items[0].Title != "First note" || bare.Items[0].PublishedParsed == nil {
+		t.Errorf("expected an <a class=h-entry> to link to itself, got %+v", bare.Items[0])
+	}
+	if bare.Items[1].Title != "Second note" || bare.Items[1].GUID != "https://notes.example.com/notes/2" {
+		t.Errorf("expected an implied name and u-uid, got %+v", bare.Items[1])
+	}
+
+	if _, err := (hFeedFormat{}).Parse(&Source{Body: []byte("<html><p>Nothing here</p></html>")}); err == nil {
+		t.Errorf("expected a page without entries to fail")
+	}
+}
+
+func TestScraperFixture(t *testing.T) {
+	u, _ := url.Parse("https://news.example.com/latest")
+	feed, err := scraperFormat{}.Parse(&Source{
+		URL:       u,
+		Body:      readFixture(t, "scraper.html"),
+		Selectors: Selectors{Item: "li.story", Title: "h3", Link: "a", Description: ".blurb"},
+	})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if feed.Title != "news.example.com Scraped Feed" || len(feed.Items) != 2 {
+		t.Fatalf("expected 2 titled items, got %+v", feed)
+	}
+	if feed.Items[0].Link != "https://news.example.com/story-a" || feed.Items[0].Description != "First story." || feed.Items[1].Link != "https://other.example.com/story-b" {
+		t.Errorf("unexpected items %+v %+v", feed.Items[0], feed.Items[1])
+	}
+
+	if _, err := (scraperFormat{}).Parse(&Source{URL: u, Body: readFixture(t, "scraper.html")}); err == nil {
+		t.Errorf("expected the scraper to require selectors")
+	}
+}
+
+func TestRegistrySelect(t *testing.T) {
+	r := DefaultRegistry()
+	tests := []struct {
+		name        string
+		explicit    string
+		contentType string
+		fixture     string
+		want        string
+	}{
+		{"rss by content type", "", "application/rss+xml", "rss.xml", FormatXML},
+		{"rss sniffed despite wrong type", "", "text/html", "rss.xml", FormatXML},
+		{"json feed by content type", "", "application/feed+json", "jsonfeed-1.1.json", FormatJSONFeed},
+		{"json feed sniffed", "", "text/plain", "jsonfeed-1.0.json", FormatJSONFeed},
+		{"h-feed page", "", "text/html", "hfeed.html", FormatHFeed},
+		{"h-feed without content type", "", "", "hfeed-bare.html", FormatHFeed},
+		{"unrecognized page falls back", "", "text/html", "scraper.html", FormatXML},
+		{"explicit setting wins", FormatScraper, "text/html", "hfeed.html", FormatScraper},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := r.Select(tt.explicit, &Source{ContentType: tt.contentType, Body: readFixture(t, tt.fixture)})
+			if err != nil {
+				t.Fatalf("Select failed: %v", err)
+			}
+			if f.Name() != tt.want {
+				t.Errorf("Select() = %s, want %s", f.Name(), tt.want)
+			}
+		})
+	}
+
+	if _, err := r.Select("gopher", &Source{}); err == nil {
+		t.Errorf("expected an unknown explicit format to fail")
+	}
+
+	// New formats plug in alongside the built-in ones.
+	r.Register(stubFormat{})
+	if f, _ := r.Select("", &Source{Body: []byte("STUB")}); f.Name() != "stub" {
+		t.Errorf("expected a registered format to be sniffed, got %s", f.Name())
+	}
+	if names := r.Names(); len(names) != 5 || names[4] != "stub" {
+		t.Errorf("unexpected registered formats %q", names)
+	}
+}
+
+type stubFormat struct{}
+
+func (stubFormat) Name() string                        { return "stub" }
+func (stubFormat) MediaTypes() []string                { return nil }
+func (stubFormat) Sniff(body []byte) bool              { return string(body) == "STUB" }
+func (stubFormat) Parse(*Source) (*gofeed.Feed, error) { return &gofeed.Feed{Title: "stub"}, nil }
+
+func TestCrawlSourceFormats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.json":
+			w.Header().Set("Content-Type", "application/feed+json")
+			_, _ = w.Write(readFixture(t, "jsonfeed-1.1.json"))
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(readFixture(t, "hfeed-bare.html"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewCrawler(nil, slog.New(slog.DiscardHandler))
+	res, err := c.Crawl(context.Background(), &types.Feed{URL: server.URL + "/feed.json"})
+	if err != nil || res.Feed.FeedType != "json" || len(res.Feed.Items[0].Enclosures) != 2 {
+		t.Fatalf("expected a JSON Feed crawl with attachments, got %+v (err %v)", res, err)
+	}
+
+	res, err = c.Crawl(context.Background(), &types.Feed{URL: server.URL + "/notes"})
+	if err != nil || res.Feed.FeedType != "hfeed" || res.Feed.Items[0].Link != server.URL+"/notes/1" {
+		t.Fatalf("expected an h-feed crawl, got %+v (err %v)", res, err)
+	}
+
+	_, err = c.Crawl(context.Background(), &types.Feed{URL: server.URL + "/notes", SourceFormat: FormatXML})
+	if err == nil || !strings.Contains(err.Error(), "parse feed") {
+		t.Errorf("expected an explicit XML format to reject the page, got %v", err)
+	}
+}
+
+func TestDiscoverHFeedPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(readFixture(t, "hfeed-bare.html"))
+	}))
+	defer server.Close()
+
+	c := NewCrawler(nil, slog.New(slog.DiscardHandler))
+	got, err := c.Discover(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	want := Candidate{URL: server.URL + "/", Title: "Bare Notes", Type: "hfeed", Items: 2}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected the h-feed page as the only candidate, got %+v", got)
+	}
+}
@@ -0,0 +1,232 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// hEntryClass finds an h-entry root in an HTML class attribute.
+var hEntryClass = regexp.MustCompile(`class\s*=\s*["'][^"']*\bh-entry\b`)
+
+// hFeedDateLayouts are the date formats microformats publishers commonly use.
+var hFeedDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	time.DateOnly,
+}
+
+// maxImpliedName caps the title derived from an entry without a p-name.
+const maxImpliedName = 100
+
+// hFeedFormat parses the h-entry microformats of a plain HTML page, such as
+// a blog's front page, into a feed.
+type hFeedFormat struct{}
+
+func (hFeedFormat) Name() string { return FormatHFeed }
+
+func (hFeedFormat) MediaTypes() []string {
+	return []string{"text/html", "application/xhtml+xml"}
+}
+
+func (hFeedFormat) Sniff(body []byte) bool {
+	return hEntryClass.Match(body)
+}
+
+func (hFeedFormat) Parse(src *Source) (*gofeed.Feed, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(src.Body))
+	if err != nil {
+		return nil, fmt.Errorf("crawler: parse HTML: %w", err)
+	}
+
+	base := src.URL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok && base != nil {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+
+	// Entries belong to the first h-feed, or to the page if it has none
+	root := doc.Find(".h-feed").First()
+	if root.Length() == 0 {
+		root = doc.Selection
+	}
+
+	feed := &gofeed.Feed{
+		Title:    strings.TrimSpace(hProperty(root, "p-name").First().Text()),
+		FeedType: "hfeed",
+	}
+	if feed.Title == "" {
+		feed.Title = strings.TrimSpace(doc.Find("title").First().Text())
+	}
+	if src.URL != nil {
+		feed.Link = src.URL.String()
+	}
+	if author := hAuthor(root); author != "" {
+		feed.Author = &gofeed.Person{Name: author}
+		feed.Authors = []*gofeed.Person{feed.Author}
+	}
+
+	root.Find(".h-entry").Each(func(_ int, entry *goquery.Selection) {
+		// Entries nested in other entries are replies or quotes, not posts
+		if entry.ParentsFiltered(".h-entry").Length() > 0 {
+			return
+		}
+		if item := hEntryItem(entry, base); item != nil {
+			feed.Items = append(feed.Items, item)
+		}
+	})
+
+	if len(feed.Items) == 0 {
+		return nil, fmt.Errorf("crawler: parse h-feed: no h-entry items found")
+	}
+	return feed, nil
+}
+
+// hEntryItem converts an h-entry into a feed item, or returns nil if the
+// entry has no URL to identify it by.
+func hEntryItem(entry *goquery.Selection, base *url.URL) *gofeed.Item {
+	item := &gofeed.Item{
+		Title:       strings.TrimSpace(hProperty(entry, "p-name").First().Text()),
+		Link:        hURL(entry, "u-url", base),
+		GUID:        hURL(entry, "u-uid", base),
+		Description: strings.TrimSpace(hProperty(entry, "p-summary").First().Text()),
+	}
+	if item.Link == "" && goquery.NodeName(entry) == "a" {
+		item.Link = resolve(base, entry.AttrOr("href", ""))
+	}
+	if item.Link == "" {
+		return nil
+	}
+	if item.GUID == "" {
+		item.GUID = item.Link
+	}
+
+	if content := hProperty(entry, "e-content").First(); content.Length() > 0 {
+		item.Content, _ = content.Html()
+		item.Content = strings.TrimSpace(item.Content)
+		if item.Title == "" {
+			item.Title = impliedName(content.Text())
+		}
+	}
+	if item.Title == "" {
+		item.Title = impliedName(item.Description)
+	}
+	if item.Title == "" {
+		item.Title = impliedName(entry.Text())
+	}
+
+	item.Published, item.PublishedParsed = hDate(entry, "dt-published")
+	item.Updated, item.UpdatedParsed = hDate(entry, "dt-updated")
+	if author := hAuthor(entry); author != "" {
+		item.Author = &gofeed.Person{Name: author}
+		item.Authors = []*gofeed.Person{item.Author}
+	}
+	hProperty(entry, "p-category").Each(func(_ int, sel *goquery.Selection) {
+		if c := strings.TrimSpace(sel.Text()); c != "" {
+			item.Categories = append(item.Categories, c)
+		}
+	})
+	if photo := hURL(entry, "u-photo", base); photo != "" {
+		item.Image = &gofeed.Image{URL: photo}
+	}
+	return item
+}
+
+// hProperty finds the elements carrying a property class that belong to
+// root itself rather than to a microformat nested inside it.
+func hProperty(root *goquery.Selection, class string) *goquery.Selection {
+	return root.Find("." + class).FilterFunction(func(_ int, sel *goquery.Selection) bool {
+		for p := sel.Parent(); p.Length() > 0 && !p.IsSelection(root); p = p.Parent() {
+			if hasRootClass(p) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// hasRootClass reports whether sel is a microformat root, with an h-* class.
+func hasRootClass(sel *goquery.Selection) bool {
+	for _, c := range strings.Fields(sel.AttrOr("class", "")) {
+		if strings.HasPrefix(c, "h-") {
+			return true
+		}
+	}
+	return false
+}
+
+// hURL returns the resolved value of a u-* property.
+func hURL(root *goquery.Selection, class string, base *url.URL) string {
+	sel := hProperty(root, class).First()
+	if sel.Length() == 0 {
+		return ""
+	}
+	for _, attr := range []string{"href", "src", "value"} {
+		if v, ok := sel.Attr(attr); ok {
+			return resolve(base, v)
+		}
+	}
+	return resolve(base, sel.Text())
+}
+
+// hDate returns the raw and parsed value of a dt-* property.
+func hDate(root *goquery.Selection, class string) (string, *time.Time) {
+	sel := hProperty(root, class).First()
+	if sel.Length() == 0 {
+		return "", nil
+	}
+	raw := strings.TrimSpace(sel.AttrOr("datetime", sel.AttrOr("value", sel.Text())))
+	for _, layout := range hFeedDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return raw, &t
+		}
+	}
+	return raw, nil
+}
+
+// hAuthor returns the name of root's p-author, which is often an h-card.
+func hAuthor(root *goquery.Selection) string {
+	author := hProperty(root, "p-author").First()
+	if author.Length() == 0 {
+		return ""
+	}
+	if hasRootClass(author) {
+		if name := strings.TrimSpace(hProperty(author, "p-name").First().Text()); name != "" {
+			return name
+		}
+	}
+	return strings.Join(strings.Fields(author.Text()), " ")
+}
+
+// impliedName shortens an entry's text into a title, for notes without a name.
+func impliedName(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxImpliedName {
+		return string(runes[:maxImpliedName-1]) + "…"
+	}
+	return text
+}
+
+// resolve makes ref absolute against base, if it can.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == nil || ref == "" {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
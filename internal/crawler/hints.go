@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// rssTTL matches an RSS channel's <ttl>, which gofeed does not carry over
+// into the universal feed.
+var rssTTL = regexp.MustCompile(`(?i)<ttl>\s*(\d+)\s*</ttl>`)
+
+// syndicationPeriods are the sy:updatePeriod values of the RSS syndication module.
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// PollHint returns the shortest interval at which the publisher asks to be
+// polled: the longest of the response's Cache-Control max-age, the RSS
+// <ttl> and the sy:updatePeriod/sy:updateFrequency schedule. It is zero when
+// none of them is given. parsed and body may be nil.
+func PollHint(header http.Header, parsed *gofeed.Feed, body []byte) time.Duration {
+	hint := maxAge(header)
+	if parsed == nil {
+		return hint
+	}
+
+	if parsed.FeedType == "rss" {
+		if m := rssTTL.FindSubmatch(body); m != nil {
+			if mins, err := strconv.Atoi(string(m[1])); err == nil {
+				hint = max(hint, time.Duration(mins)*time.Minute)
+			}
+		}
+	}
+
+	// The module defaults to once a day
+	if sy := parsed.Extensions["sy"]; len(sy["updatePeriod"]) > 0 || len(sy["updateFrequency"]) > 0 {
+		period, freq := 24*time.Hour, 1
+		if v := sy["updatePeriod"]; len(v) > 0 {
+			if d, ok := syndicationPeriods[strings.ToLower(strings.TrimSpace(v[0].Value))]; ok {
+				period = d
+			}
+		}
+		if v := sy["updateFrequency"]; len(v) > 0 {
+			if n, err := strconv.Atoi(strings.TrimSpace(v[0].Value)); err == nil && n > 0 {
+				freq = n
+			}
+		}
+		hint = max(hint, period/time.Duration(freq))
+	}
+	return hint
+}
+
+// maxAge returns the Cache-Control max-age of a response, or zero.
+func maxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, val, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		if secs, err := strconv.Atoi(strings.Trim(val, `"`)); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 0
+}
@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// jsonFeedVersionPrefix starts the version URL of every JSON Feed document.
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// jsonFeed is a JSON Feed 1.1 document. Version 1.0 documents decode into
+// it too; their single author is folded into the authors list.
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Icon        string           `json:"icon"`
+	Language    string           `json:"language"`
+	Author      *jsonFeedAuthor  `json:"author"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type jsonFeedItem struct {
+	// IDs are strings, but some publishers emit numbers.
+	ID            json.RawMessage      `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	Image         string               `json:"image"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Author        *jsonFeedAuthor      `json:"author"`
+	Authors       []jsonFeedAuthor     `json:"authors"`
+	Tags          []string             `json:"tags"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+// jsonFeedFormat parses JSON Feed documents, keeping attachments as
+// enclosures.
+type jsonFeedFormat struct{}
+
+func (jsonFeedFormat) Name() string { return FormatJSONFeed }
+
+func (jsonFeedFormat) MediaTypes() []string {
+	return []string{"application/feed+json", "application/json"}
+}
+
+func (jsonFeedFormat) Sniff(body []byte) bool {
+	head := body[:min(len(body), 1024)]
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) && bytes.Contains(head, []byte("jsonfeed.org/version/"))
+}
+
+func (jsonFeedFormat) Parse(src *Source) (*gofeed.Feed, error) {
+	var doc jsonFeed
+	if err := json.Unmarshal(src.Body, &doc); err != nil {
+		return nil, fmt.Errorf("crawler: parse JSON Feed: %w", err)
+	}
+	if !strings.HasPrefix(doc.Version, jsonFeedVersionPrefix) {
+		return nil, fmt.Errorf("crawler: parse JSON Feed: unsupported version %q", doc.Version)
+	}
+
+	feed := &gofeed.Feed{
+		Title:       doc.Title,
+		Description: doc.Description,
+		Link:        doc.HomePageURL,
+		FeedLink:    doc.FeedURL,
+		Language:    doc.Language,
+		Authors:     jsonFeedAuthors(doc.Author, doc.Authors),
+		FeedType:    "json",
+		FeedVersion: strings.TrimPrefix(doc.Version, jsonFeedVersionPrefix),
+	}
+	if doc.Icon != "" {
+		feed.Image = &gofeed.Image{URL: doc.Icon}
+	}
+	if len(feed.Authors) > 0 {
+		feed.Author = feed.Authors[0]
+	}
+
+	for _, it := range doc.Items {
+		item := &gofeed.Item{
+			GUID:        jsonFeedID(it.ID),
+			Title:       it.Title,
+			Link:        it.URL,
+			Description: it.Summary,
+			Content:     it.ContentHTML,
+			Published:   it.DatePublished,
+			Updated:     it.DateModified,
+			Authors:     jsonFeedAuthors(it.Author, it.Authors),
+			Categories:  it.Tags,
+		}
+		if item.Link == "" {
+			item.Link = it.ExternalURL
+		}
+		if item.Content == "" && it.ContentText != "" {
+			item.Content = "<p>" + strings.ReplaceAll(html.EscapeString(it.ContentText), "\n\n", "</p><p>") + "</p>"
+		}
+		if t, err := time.Parse(time.RFC3339, it.DatePublished); err == nil {
+			item.PublishedParsed = &t
+		}
+		if t, err := time.Parse(time.RFC3339, it.DateModified); err == nil {
+			item.UpdatedParsed = &t
+		}
+		if len(item.Authors) > 0 {
+			item.Author = item.Authors[0]
+		}
+		if it.Image != "" {
+			item.Image = &gofeed.Image{URL: it.Image}
+		}
+		for _, a := range it.Attachments {
+			if a.URL == "" {
+				continue
+			}
+			enc := &gofeed.Enclosure{URL: a.URL, Type: a.MimeType}
+			if a.SizeInBytes > 0 {
+				enc.Length = strconv.FormatInt(a.SizeInBytes, 10)
+			}
+			item.Enclosures = append(item.Enclosures, enc)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// jsonFeedID returns an item ID given as a string or a number.
+func jsonFeedID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// jsonFeedAuthors merges a 1.0 author and 1.1 authors list.
+func jsonFeedAuthors(single *jsonFeedAuthor, list []jsonFeedAuthor) []*gofeed.Person {
+	if single != nil && len(list) == 0 {
+		list = []jsonFeedAuthor{*single}
+	}
+	var people []*gofeed.Person
+	for _, a := range list {
+		if a.Name != "" {
+			people = append(people, &gofeed.Person{Name: a.Name})
+		}
+	}
+	return people
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// scraperFormat turns an HTML page into items with a feed's CSS selectors.
+// Pages are never sniffed as scraper sources; the format applies when a feed
+// has selectors or names it explicitly.
+type scraperFormat struct{}
+
+func (scraperFormat) Name() string { return FormatScraper }
+
+func (scraperFormat) MediaTypes() []string { return nil }
+
+func (scraperFormat) Sniff([]byte) bool { return false }
+
+func (scraperFormat) Parse(src *Source) (*gofeed.Feed, error) {
+	sel := src.Selectors
+	if sel.Item == "" || sel.Title == "" || sel.Link == "" {
+		return nil, fmt.Errorf("crawler: scraper needs item, title and link selectors")
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(src.Body))
+	if err != nil {
+		return nil, fmt.Errorf("crawler: parse HTML: %w", err)
+	}
+
+	var feedItems []*gofeed.Item
+	doc.Find(sel.Item).Each(func(i int, s *goquery.Selection) {
+		title := strings.TrimSpace(s.Find(sel.Title).First().Text())
+		if title == "" {
+			return
+		}
+
+		link, exists := s.Find(sel.Link).First().Attr("href")
+		if !exists || strings.TrimSpace(link) == "" {
+			return
+		}
+		// Resolve relative URL
+		link = resolve(src.URL, link)
+
+		description := ""
+		if sel.Description != "" {
+			description = strings.TrimSpace(s.Find(sel.Description).First().Text())
+		}
+
+		now := time.Now()
+		feedItems = append(feedItems, &gofeed.Item{
+			Title:           title,
+			Link:            link,
+			Description:     description,
+			GUID:            link,
+			Published:       now.Format(time.RFC1123Z),
+			PublishedParsed: &now,
+		})
+	})
+
+	return &gofeed.Feed{
+		Title:       src.URL.Host + " Scraped Feed",
+		Link:        src.URL.String(),
+		Description: "Dynamically generated scraped RSS feed for " + src.URL.String(),
+		Items:       feedItems,
+	}, nil
+}
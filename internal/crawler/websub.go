@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DiscoverWebSub returns the WebSub hub and self (topic) URLs advertised by a
+// feed response. HTTP Link headers take precedence over <link rel="hub"> and
+// <atom:link rel="self"> elements in the document body, as the spec requires.
+func DiscoverWebSub(header http.Header, body []byte) (hub, self string) {
+	for _, val := range header.Values("Link") {
+		for _, link := range parseLinkHeader(val) {
+			if hub == "" && slices.Contains(link.rels, "hub") {
+				hub = link.href
+			}
+			if self == "" && slices.Contains(link.rels, "self") {
+				self = link.href
+			}
+		}
+	}
+	if hub != "" && self != "" {
+		return hub, self
+	}
+
+	bodyHub, bodySelf := discoverWebSubLinks(body)
+	if hub == "" {
+		hub = bodyHub
+	}
+	if self == "" {
+		self = bodySelf
+	}
+	return hub, self
+}
+
+// discoverWebSubLinks scans the channel/feed header of an RSS or Atom document
+// for hub and self link elements. Scanning stops at the first item or entry.
+func discoverWebSubLinks(body []byte) (hub, self string) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// EOF or malformed markup: keep whatever was found so far
+			return hub, self
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(start.Name.Local) {
+		case "item", "entry":
+			return hub, self
+		case "link":
+			var rel, href string
+			for _, attr := range start.Attr {
+				switch strings.ToLower(attr.Name.Local) {
+				case "rel":
+					rel = attr.Value
+				case "href":
+					href = strings.TrimSpace(attr.Value)
+				}
+			}
+			if href == "" {
+				continue
+			}
+			rels := strings.Fields(strings.ToLower(rel))
+			if hub == "" && slices.Contains(rels, "hub") {
+				hub = href
+			}
+			if self == "" && slices.Contains(rels, "self") {
+				self = href
+			}
+		}
+	}
+}
+
+// headerLink is one target of an HTTP Link header.
+type headerLink struct {
+	href string
+	rels []string // lower-cased relation types
+}
+
+// parseLinkHeader parses an RFC 8288 Link header into its targets, in header
+// order, so callers can honour the first of several links with the same rel.
+func parseLinkHeader(val string) []headerLink {
+	var links []headerLink
+	for part := range strings.SplitSeq(val, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "<") {
+			continue
+		}
+		end := strings.Index(part, ">")
+		if end == -1 {
+			continue
+		}
+		link := headerLink{href: strings.TrimSpace(part[1:end])}
+		for param := range strings.SplitSeq(part[end+1:], ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			link.rels = append(link.rels, strings.Fields(strings.ToLower(value))...)
+		}
+		links = append(links, link)
+	}
+	return links
+}
@@ -3,10 +3,14 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"rss2go/internal/secrets"
 	"rss2go/internal/types"
 )
 
@@ -20,7 +24,8 @@ type DBTX interface {
 
 // Repository handles persistence operations against the SQLite database.
 type Repository struct {
-	db DBTX
+	db      DBTX
+	secrets *secrets.Box
 }
 
 // NewRepository creates a new Repository instance.
@@ -28,6 +33,13 @@ func NewRepository(db DBTX) *Repository {
 	return &Repository{db: db}
 }
 
+// SetSecrets sets the box that seals credentials stored by the repository,
+// such as feed request options and channel tokens. Without one they cannot be
+// read or written.
+func (r *Repository) SetSecrets(box *secrets.Box) {
+	r.secrets = box
+}
+
 // WithTx wraps operations within a single SQL transaction.
 func (r *Repository) WithTx(ctx context.Context, fn func(*Repository) error) error {
 	db, ok := r.db.(*sql.DB)
@@ -43,6 +55,7 @@ func (r *Repository) WithTx(ctx context.Context, fn func(*Repository) error) err
 	defer func() { _ = tx.Rollback() }()
 
 	txRepo := NewRepository(tx)
+	txRepo.secrets = r.secrets
 	if err := fn(txRepo); err != nil {
 		return err
 	}
@@ -65,8 +78,11 @@ func (r *Repository) CreateFeed(ctx context.Context, f *types.Feed) error {
 			poll_interval_secs, backoff_factor, last_error_str, 
 			last_error_time, last_error_snippet, last_polled_at, extract_full_article, 
 			extraction_strategy, css_selector,
-			scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector,
+			category, not_found_count, health, consecutive_failures, failing_since, health_changed_at, disabled_reason,
+			adaptive_polling, adaptive_interval_secs, source_format, dedupe_strategy, extraction_chain,
+			follow_pages, next_page_selector, max_pages, content_rules
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	var errTime *time.Time
 	if f.LastErrorTime != nil {
@@ -83,6 +99,25 @@ func (r *Repository) CreateFeed(ctx context.Context, f *types.Feed) error {
 		extractVal = 1
 	}
 
+	adaptiveVal := 0
+	if f.AdaptivePolling {
+		adaptiveVal = 1
+	}
+
+	followVal := 0
+	if f.FollowPages {
+		followVal = 1
+	}
+
+	rules, err := encodeRules(f.ContentRules)
+	if err != nil {
+		return err
+	}
+
+	if f.Health == "" {
+		f.Health = types.FeedHealthy
+	}
+
 	res, err := r.db.ExecContext(
 		ctx, query,
 		f.Title, f.URL, f.ETag, f.LastModified, f.NextPollAt,
@@ -90,6 +125,9 @@ func (r *Repository) CreateFeed(ctx context.Context, f *types.Feed) error {
 		errTime, f.LastErrorSnippet, polledTime, extractVal,
 		string(f.ExtractionStrategy), f.CSSSelector,
 		f.ScraperItemSelector, f.ScraperTitleSelector, f.ScraperLinkSelector, f.ScraperDescriptionSelector,
+		f.Category, f.NotFoundCount, string(f.Health), f.ConsecutiveFailures, f.FailingSince, f.HealthChangedAt, f.DisabledReason,
+		adaptiveVal, f.AdaptiveIntervalSecs, f.SourceFormat, string(f.DedupeStrategy), joinChain(f.ExtractionChain),
+		followVal, f.NextPageSelector, f.MaxPages, rules,
 	)
 	if err != nil {
 		return fmt.Errorf("repository: create feed: %w", err)
@@ -109,7 +147,7 @@ func (r *Repository) GetFeed(ctx context.Context, id int64) (*types.Feed, error)
 			id, title, url, etag, last_modified, next_poll_at, 
 			poll_interval_secs, backoff_factor, last_error_str, 
 			last_error_time, last_error_snippet, last_polled_at, extract_full_article, 
-			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, created_at, updated_at
+			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, category, not_found_count, health, consecutive_failures, failing_since, health_changed_at, disabled_reason, adaptive_polling, adaptive_interval_secs, source_format, dedupe_strategy, extraction_chain, follow_pages, next_page_selector, max_pages, content_rules, created_at, updated_at
 		FROM feeds
 		WHERE id = ?
 	`
@@ -123,7 +161,7 @@ func (r *Repository) GetFeedByURL(ctx context.Context, url string) (*types.Feed,
 			id, title, url, etag, last_modified, next_poll_at, 
 			poll_interval_secs, backoff_factor, last_error_str, 
 			last_error_time, last_error_snippet, last_polled_at, extract_full_article, 
-			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, created_at, updated_at
+			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, category, not_found_count, health, consecutive_failures, failing_since, health_changed_at, disabled_reason, adaptive_polling, adaptive_interval_secs, source_format, dedupe_strategy, extraction_chain, follow_pages, next_page_selector, max_pages, content_rules, created_at, updated_at
 		FROM feeds
 		WHERE url = ?
 	`
@@ -139,7 +177,9 @@ func (r *Repository) UpdateFeed(ctx context.Context, f *types.Feed) error {
 			last_error_time = ?, last_error_snippet = ?, last_polled_at = ?, extract_full_article = ?, 
 			extraction_strategy = ?, css_selector = ?, 
 			scraper_item_selector = ?, scraper_title_selector = ?, scraper_link_selector = ?, scraper_description_selector = ?,
-			updated_at = CURRENT_TIMESTAMP
+			category = ?, not_found_count = ?, health = ?, consecutive_failures = ?, failing_since = ?, health_changed_at = ?, disabled_reason = ?,
+			adaptive_polling = ?, adaptive_interval_secs = ?, source_format = ?, dedupe_strategy = ?, extraction_chain = ?,
+			follow_pages = ?, next_page_selector = ?, max_pages = ?, content_rules = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 	extractVal := 0
@@ -147,6 +187,25 @@ func (r *Repository) UpdateFeed(ctx context.Context, f *types.Feed) error {
 		extractVal = 1
 	}
 
+	adaptiveVal := 0
+	if f.AdaptivePolling {
+		adaptiveVal = 1
+	}
+
+	followVal := 0
+	if f.FollowPages {
+		followVal = 1
+	}
+
+	rules, err := encodeRules(f.ContentRules)
+	if err != nil {
+		return err
+	}
+
+	if f.Health == "" {
+		f.Health = types.FeedHealthy
+	}
+
 	var polledTime *time.Time
 	if f.LastPolledAt != nil {
 		polledTime = f.LastPolledAt
@@ -159,7 +218,9 @@ func (r *Repository) UpdateFeed(ctx context.Context, f *types.Feed) error {
 		f.LastErrorTime, f.LastErrorSnippet, polledTime, extractVal,
 		string(f.ExtractionStrategy), f.CSSSelector,
 		f.ScraperItemSelector, f.ScraperTitleSelector, f.ScraperLinkSelector, f.ScraperDescriptionSelector,
-		f.ID,
+		f.Category, f.NotFoundCount, string(f.Health), f.ConsecutiveFailures, f.FailingSince, f.HealthChangedAt, f.DisabledReason,
+		adaptiveVal, f.AdaptiveIntervalSecs, f.SourceFormat, string(f.DedupeStrategy), joinChain(f.ExtractionChain),
+		followVal, f.NextPageSelector, f.MaxPages, rules, f.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("repository: update feed: %w", err)
@@ -198,7 +259,7 @@ func (r *Repository) ListFeeds(ctx context.Context) ([]*types.Feed, error) {
 			id, title, url, etag, last_modified, next_poll_at, 
 			poll_interval_secs, backoff_factor, last_error_str, 
 			last_error_time, last_error_snippet, last_polled_at, extract_full_article, 
-			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, created_at, updated_at
+			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, category, not_found_count, health, consecutive_failures, failing_since, health_changed_at, disabled_reason, adaptive_polling, adaptive_interval_secs, source_format, dedupe_strategy, extraction_chain, follow_pages, next_page_selector, max_pages, content_rules, created_at, updated_at
 		FROM feeds
 		ORDER BY title ASC
 	`
@@ -224,15 +285,56 @@ func (r *Repository) ListFeeds(ctx context.Context) ([]*types.Feed, error) {
 	return feeds, nil
 }
 
+// ListFeedsByHealth lists the feeds in any of the given health states, by title.
+func (r *Repository) ListFeedsByHealth(ctx context.Context, states ...types.FeedHealth) ([]*types.Feed, error) {
+	if len(states) == 0 {
+		return []*types.Feed{}, nil
+	}
+	args := make([]any, len(states))
+	for i, state := range states {
+		args[i] = string(state)
+	}
+	query := `
+		SELECT 
+			id, title, url, etag, last_modified, next_poll_at, 
+			poll_interval_secs, backoff_factor, last_error_str, 
+			last_error_time, last_error_snippet, last_polled_at, extract_full_article, 
+			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, category, not_found_count, health, consecutive_failures, failing_since, health_changed_at, disabled_reason, adaptive_polling, adaptive_interval_secs, source_format, dedupe_strategy, extraction_chain, follow_pages, next_page_selector, max_pages, content_rules, created_at, updated_at
+		FROM feeds
+		WHERE health IN (?` + strings.Repeat(", ?", len(states)-1) + `)
+		ORDER BY title ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list feeds by health: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	feeds := []*types.Feed{}
+	for rows.Next() {
+		f, err := scanFeedRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: scan feed row: %w", err)
+		}
+		feeds = append(feeds, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return feeds, nil
+}
+
 func (r *Repository) ListFeedsDue(ctx context.Context, now time.Time) ([]*types.Feed, error) {
 	query := `
 		SELECT 
 			id, title, url, etag, last_modified, next_poll_at, 
 			poll_interval_secs, backoff_factor, last_error_str, 
 			last_error_time, last_error_snippet, last_polled_at, extract_full_article, 
-			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, created_at, updated_at
+			extraction_strategy, css_selector, scraper_item_selector, scraper_title_selector, scraper_link_selector, scraper_description_selector, category, not_found_count, health, consecutive_failures, failing_since, health_changed_at, disabled_reason, adaptive_polling, adaptive_interval_secs, source_format, dedupe_strategy, extraction_chain, follow_pages, next_page_selector, max_pages, content_rules, created_at, updated_at
 		FROM feeds
-		WHERE next_poll_at <= ?
+		WHERE next_poll_at <= ? AND health != 'disabled'
 		ORDER BY next_poll_at ASC
 	`
 	rows, err := r.db.QueryContext(ctx, query, now)
@@ -257,13 +359,286 @@ func (r *Repository) ListFeedsDue(ctx context.Context, now time.Time) ([]*types.
 	return feeds, nil
 }
 
+// RecordFeedURLChange appends an entry to a feed's URL audit history.
+func (r *Repository) RecordFeedURLChange(ctx context.Context, change *types.FeedURLChange) error {
+	query := `
+		INSERT INTO feed_url_history (feed_id, old_url, new_url, reason)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query,
+		change.FeedID, change.OldURL, change.NewURL, string(change.Reason),
+	).Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: record feed url change: %w", err)
+	}
+	return nil
+}
+
+// ListFeedURLHistory returns every recorded URL change of a feed, newest first.
+func (r *Repository) ListFeedURLHistory(ctx context.Context, feedID int64) ([]*types.FeedURLChange, error) {
+	query := `
+		SELECT id, feed_id, old_url, new_url, reason, created_at
+		FROM feed_url_history
+		WHERE feed_id = ?
+		ORDER BY id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list feed url history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	changes := []*types.FeedURLChange{}
+	for rows.Next() {
+		var change types.FeedURLChange
+		var reason string
+		if err := rows.Scan(&change.ID, &change.FeedID, &change.OldURL, &change.NewURL, &reason, &change.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repository: scan feed url change: %w", err)
+		}
+		change.Reason = types.URLChangeReason(reason)
+		changes = append(changes, &change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return changes, nil
+}
+
+// ============================================================================
+// Feed Request Option Operations
+// ============================================================================
+
+// ErrNoSecrets is returned when request options or channel tokens are read
+// or written by a repository without a secrets box.
+var ErrNoSecrets = errors.New("repository: no secret key configured")
+
+// SetFeedRequestOptions encrypts and stores a feed's request options,
+// replacing any it had. Nil or empty options remove them.
+func (r *Repository) SetFeedRequestOptions(ctx context.Context, feedID int64, opts *types.RequestOptions) error {
+	if emptyRequestOptions(opts) {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM feed_request_options WHERE feed_id = ?`, feedID); err != nil {
+			return fmt.Errorf("repository: delete feed request options: %w", err)
+		}
+		return nil
+	}
+	if r.secrets == nil {
+		return ErrNoSecrets
+	}
+
+	plaintext, err := json.Marshal(opts)
+	if err != nil {
+		return fmt.Errorf("repository: encode feed request options: %w", err)
+	}
+	sealed, err := r.secrets.Seal(plaintext, requestOptionsAAD(feedID))
+	if err != nil {
+		return fmt.Errorf("repository: seal feed request options: %w", err)
+	}
+
+	query := `
+		INSERT INTO feed_request_options (feed_id, sealed, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(feed_id) DO UPDATE SET sealed = excluded.sealed, updated_at = excluded.updated_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, feedID, sealed, time.Now()); err != nil {
+		return fmt.Errorf("repository: set feed request options: %w", err)
+	}
+	return nil
+}
+
+// GetFeedRequestOptions decrypts a feed's request options, returning nil if
+// it has none.
+func (r *Repository) GetFeedRequestOptions(ctx context.Context, feedID int64) (*types.RequestOptions, error) {
+	var sealed []byte
+	err := r.db.QueryRowContext(ctx, `SELECT sealed FROM feed_request_options WHERE feed_id = ?`, feedID).Scan(&sealed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: get feed request options: %w", err)
+	}
+	if r.secrets == nil {
+		return nil, ErrNoSecrets
+	}
+
+	plaintext, err := r.secrets.Open(sealed, requestOptionsAAD(feedID))
+	if err != nil {
+		return nil, fmt.Errorf("repository: open feed request options: %w", err)
+	}
+	var opts types.RequestOptions
+	if err := json.Unmarshal(plaintext, &opts); err != nil {
+		return nil, fmt.Errorf("repository: decode feed request options: %w", err)
+	}
+	return &opts, nil
+}
+
+func emptyRequestOptions(o *types.RequestOptions) bool {
+	return o == nil || (o.UserAgent == "" && len(o.Headers) == 0 && len(o.Cookies) == 0 &&
+		o.Username == "" && o.Password == "" && o.BearerToken == "")
+}
+
+// requestOptionsAAD binds sealed request options to their feed, so they
+// cannot be copied onto another feed's row.
+func requestOptionsAAD(feedID int64) []byte {
+	return []byte("feed_request_options:" + strconv.FormatInt(feedID, 10))
+}
+
+// ============================================================================
+// Feed Health Event Operations
+// ============================================================================
+
+// RecordFeedHealthEvent stores a feed health transition for the operator alert digest.
+// A transition with AlertedAt set is stored as already reported.
+func (r *Repository) RecordFeedHealthEvent(ctx context.Context, ev *types.FeedHealthEvent) error {
+	query := `
+		INSERT INTO feed_health_events (feed_id, from_health, to_health, reason, created_at, alerted_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now()
+	}
+	err := r.db.QueryRowContext(ctx, query, ev.FeedID, string(ev.From), string(ev.To), ev.Reason, ev.CreatedAt, ev.AlertedAt).Scan(&ev.ID)
+	if err != nil {
+		return fmt.Errorf("repository: record feed health event: %w", err)
+	}
+	return nil
+}
+
+// ListUnalertedFeedHealthEvents lists the health transitions not yet reported
+// to the operator, oldest first, with the feed's current title and URL.
+func (r *Repository) ListUnalertedFeedHealthEvents(ctx context.Context) ([]*types.FeedHealthEvent, error) {
+	query := `
+		SELECT e.id, e.feed_id, f.title, f.url, e.from_health, e.to_health, e.reason, e.created_at
+		FROM feed_health_events e
+		JOIN feeds f ON f.id = e.feed_id
+		WHERE e.alerted_at IS NULL
+		ORDER BY e.id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list unalerted feed health events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	events := []*types.FeedHealthEvent{}
+	for rows.Next() {
+		var ev types.FeedHealthEvent
+		var from, to string
+		if err := rows.Scan(&ev.ID, &ev.FeedID, &ev.FeedTitle, &ev.FeedURL, &from, &to, &ev.Reason, &ev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repository: scan feed health event: %w", err)
+		}
+		ev.From = types.FeedHealth(from)
+		ev.To = types.FeedHealth(to)
+		events = append(events, &ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return events, nil
+}
+
+// MarkFeedHealthEventsAlerted records that the given events have been reported.
+func (r *Repository) MarkFeedHealthEventsAlerted(ctx context.Context, ids []int64, at time.Time) error {
+	query := `UPDATE feed_health_events SET alerted_at = ? WHERE id = ?`
+	for _, id := range ids {
+		if _, err := r.db.ExecContext(ctx, query, at, id); err != nil {
+			return fmt.Errorf("repository: mark feed health event alerted: %w", err)
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Crawl Run Operations
+// ============================================================================
+
+// RecordCrawlRun stores the timing and outcome of one crawl.
+func (r *Repository) RecordCrawlRun(ctx context.Context, run *types.CrawlRun) error {
+	query := `
+		INSERT INTO crawl_runs (
+			feed_id, started_at, duration_ms, status_code, bytes,
+			item_count, new_item_count, not_modified, error
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	notModifiedVal := 0
+	if run.NotModified {
+		notModifiedVal = 1
+	}
+	err := r.db.QueryRowContext(ctx, query,
+		run.FeedID, run.StartedAt, run.DurationMs, run.StatusCode, run.Bytes,
+		run.ItemCount, run.NewItemCount, notModifiedVal, run.Error,
+	).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("repository: record crawl run: %w", err)
+	}
+	return nil
+}
+
+// ListCrawlRuns lists up to limit of a feed's most recent crawl runs, newest first.
+func (r *Repository) ListCrawlRuns(ctx context.Context, feedID int64, limit int) ([]*types.CrawlRun, error) {
+	query := `
+		SELECT id, feed_id, started_at, duration_ms, status_code, bytes,
+			item_count, new_item_count, not_modified, error
+		FROM crawl_runs
+		WHERE feed_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, feedID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list crawl runs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	runs := []*types.CrawlRun{}
+	for rows.Next() {
+		var run types.CrawlRun
+		var notModifiedVal int
+		err := rows.Scan(
+			&run.ID, &run.FeedID, &run.StartedAt, &run.DurationMs, &run.StatusCode, &run.Bytes,
+			&run.ItemCount, &run.NewItemCount, &notModifiedVal, &run.Error,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: scan crawl run: %w", err)
+		}
+		run.NotModified = notModifiedVal == 1
+		runs = append(runs, &run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return runs, nil
+}
+
+// PruneCrawlRuns deletes all but a feed's keep most recent crawl runs.
+func (r *Repository) PruneCrawlRuns(ctx context.Context, feedID int64, keep int) error {
+	query := `
+		DELETE FROM crawl_runs
+		WHERE feed_id = ? AND id <= (
+			SELECT id FROM crawl_runs WHERE feed_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+		)
+	`
+	if _, err := r.db.ExecContext(ctx, query, feedID, feedID, keep); err != nil {
+		return fmt.Errorf("repository: prune crawl runs: %w", err)
+	}
+	return nil
+}
+
 // ============================================================================
 // User Operations
 // ============================================================================
 
 func (r *Repository) CreateUser(ctx context.Context, u *types.User) error {
-	query := `INSERT INTO users (email) VALUES (?)`
-	res, err := r.db.ExecContext(ctx, query, u.Email)
+	query := `INSERT INTO users (email, cross_feed_dedupe) VALUES (?, ?)`
+	res, err := r.db.ExecContext(ctx, query, u.Email, u.CrossFeedDedupe)
 	if err != nil {
 		return fmt.Errorf("repository: create user: %w", err)
 	}
@@ -277,10 +652,10 @@ func (r *Repository) CreateUser(ctx context.Context, u *types.User) error {
 }
 
 func (r *Repository) GetUser(ctx context.Context, id int64) (*types.User, error) {
-	query := `SELECT id, email, created_at FROM users WHERE id = ?`
+	query := `SELECT id, email, cross_feed_dedupe, created_at FROM users WHERE id = ?`
 	row := r.db.QueryRowContext(ctx, query, id)
 	var u types.User
-	if err := row.Scan(&u.ID, &u.Email, &u.CreatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.Email, &u.CrossFeedDedupe, &u.CreatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, err
 		}
@@ -295,10 +670,10 @@ func (r *Repository) GetUser(ctx context.Context, id int64) (*types.User, error)
 }
 
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
-	query := `SELECT id, email, created_at FROM users WHERE email = ?`
+	query := `SELECT id, email, cross_feed_dedupe, created_at FROM users WHERE email = ?`
 	row := r.db.QueryRowContext(ctx, query, email)
 	var u types.User
-	if err := row.Scan(&u.ID, &u.Email, &u.CreatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.Email, &u.CrossFeedDedupe, &u.CreatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, err
 		}
@@ -328,6 +703,58 @@ func (r *Repository) DeleteUser(ctx context.Context, id int64) error {
 	}
 	return nil
 }
+
+// SetCrossFeedDedupe turns cross-feed deduplication on or off for a user.
+func (r *Repository) SetCrossFeedDedupe(ctx context.Context, userID int64, enabled bool) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET cross_feed_dedupe = ? WHERE id = ?`, enabled, userID)
+	if err != nil {
+		return fmt.Errorf("repository: set cross-feed dedupe: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RecordDelivery notes that an article with fingerprint reached a user, and
+// reports whether this is its first delivery to them. An article delivered
+// before by the same feed counts as a first delivery, so rewinding a feed
+// delivers its items again; only other feeds are held back.
+func (r *Repository) RecordDelivery(ctx context.Context, userID, feedID int64, fingerprint string) (bool, error) {
+	query := `
+		INSERT INTO user_deliveries (user_id, fingerprint, feed_id) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, fingerprint) DO UPDATE SET delivered_at = CURRENT_TIMESTAMP
+		WHERE user_deliveries.feed_id = excluded.feed_id
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, fingerprint, feedID)
+	if err != nil {
+		return false, fmt.Errorf("repository: record delivery: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	return rows == 1, nil
+}
+
+// PruneDeliveries forgets cross-feed deliveries recorded before the given
+// time, returning how many were removed.
+func (r *Repository) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM user_deliveries WHERE delivered_at < datetime(?, 'unixepoch')`, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("repository: prune deliveries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	return n, nil
+}
+
 func (r *Repository) getSubscribedFeedIDs(ctx context.Context, userID int64) ([]int64, error) {
 	query := `SELECT feed_id FROM subscriptions WHERE user_id = ?`
 	rows, err := r.db.QueryContext(ctx, query, userID)
@@ -351,7 +778,7 @@ func (r *Repository) getSubscribedFeedIDs(ctx context.Context, userID int64) ([]
 }
 
 func (r *Repository) ListUsers(ctx context.Context) ([]*types.User, error) {
-	query := `SELECT id, email, created_at FROM users ORDER BY email ASC`
+	query := `SELECT id, email, cross_feed_dedupe, created_at FROM users ORDER BY email ASC`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("repository: list users: %w", err)
@@ -361,7 +788,7 @@ func (r *Repository) ListUsers(ctx context.Context) ([]*types.User, error) {
 	users := []*types.User{}
 	for rows.Next() {
 		var u types.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.CrossFeedDedupe, &u.CreatedAt); err != nil {
 			return nil, fmt.Errorf("repository: scan user: %w", err)
 		}
 		users = append(users, &u)
@@ -419,7 +846,7 @@ func (r *Repository) ListSubscriptionsForUser(ctx context.Context, userID int64)
 			f.id, f.title, f.url, f.etag, f.last_modified, f.next_poll_at, 
 			f.poll_interval_secs, f.backoff_factor, f.last_error_str, 
 			f.last_error_time, f.last_error_snippet, f.last_polled_at, f.extract_full_article, 
-			f.extraction_strategy, f.css_selector, f.scraper_item_selector, f.scraper_title_selector, f.scraper_link_selector, f.scraper_description_selector, f.created_at, f.updated_at
+			f.extraction_strategy, f.css_selector, f.scraper_item_selector, f.scraper_title_selector, f.scraper_link_selector, f.scraper_description_selector, f.category, f.not_found_count, f.health, f.consecutive_failures, f.failing_since, f.health_changed_at, f.disabled_reason, f.adaptive_polling, f.adaptive_interval_secs, f.source_format, f.dedupe_strategy, f.extraction_chain, f.follow_pages, f.next_page_selector, f.max_pages, f.content_rules, f.created_at, f.updated_at
 		FROM feeds f
 		JOIN subscriptions s ON f.id = s.feed_id
 		WHERE s.user_id = ?
@@ -449,7 +876,7 @@ func (r *Repository) ListSubscriptionsForUser(ctx context.Context, userID int64)
 
 func (r *Repository) ListSubscriptionsForFeed(ctx context.Context, feedID int64) ([]*types.User, error) {
 	query := `
-		SELECT u.id, u.email, u.created_at
+		SELECT u.id, u.email, u.cross_feed_dedupe, u.created_at
 		FROM users u
 		JOIN subscriptions s ON u.id = s.user_id
 		WHERE s.feed_id = ?
@@ -464,7 +891,7 @@ func (r *Repository) ListSubscriptionsForFeed(ctx context.Context, feedID int64)
 	users := []*types.User{}
 	for rows.Next() {
 		var u types.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.CrossFeedDedupe, &u.CreatedAt); err != nil {
 			return nil, fmt.Errorf("repository: scan user: %w", err)
 		}
 		users = append(users, &u)
@@ -487,56 +914,893 @@ func (r *Repository) ListSubscriptionsForFeed(ctx context.Context, feedID int64)
 }
 
 // ============================================================================
-// Seen Items Operations
+// Digest Delivery Operations
 // ============================================================================
 
-func (r *Repository) MarkItemSeen(ctx context.Context, feedID int64, guid string) error {
-	query := `INSERT INTO seen_items (feed_id, guid) VALUES (?, ?) ON CONFLICT DO NOTHING`
-	_, err := r.db.ExecContext(ctx, query, feedID, guid)
+const subscriptionColumns = `
+	s.user_id, s.feed_id, s.delivery_mode, s.digest_time, s.digest_weekday, s.next_digest_at, u.email, u.cross_feed_dedupe
+`
+
+func (r *Repository) GetSubscription(ctx context.Context, userID, feedID int64) (*types.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + `
+		FROM subscriptions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.user_id = ? AND s.feed_id = ?
+	`
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx, query, userID, feedID))
 	if err != nil {
-		return fmt.Errorf("repository: mark item seen: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("repository: get subscription: %w", err)
 	}
-	return nil
+	return sub, nil
 }
 
-func (r *Repository) IsItemSeen(ctx context.Context, feedID int64, guid string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM seen_items WHERE feed_id = ? AND guid = ?)`
-	var exists int
-	err := r.db.QueryRowContext(ctx, query, feedID, guid).Scan(&exists)
-	if err != nil {
-		return false, fmt.Errorf("repository: check item seen: %w", err)
-	}
-	return exists == 1, nil
+// ListSubscriptionSettingsForFeed returns the delivery settings of every subscriber of a feed.
+func (r *Repository) ListSubscriptionSettingsForFeed(ctx context.Context, feedID int64) ([]*types.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + `
+		FROM subscriptions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.feed_id = ?
+		ORDER BY u.email ASC
+	`
+	return r.querySubscriptions(ctx, query, feedID)
 }
 
-func (r *Repository) UnmarkSeenItems(ctx context.Context, feedID int64, limit int) error {
-	query := `
-		DELETE FROM seen_items 
-		WHERE feed_id = ? 
-		AND guid IN (
-			SELECT guid FROM seen_items 
-			WHERE feed_id = ? 
-			ORDER BY rowid DESC 
-			LIMIT ?
-		)
+// ListSubscriptionSettingsForUser returns the delivery settings of every subscription of a user.
+func (r *Repository) ListSubscriptionSettingsForUser(ctx context.Context, userID int64) ([]*types.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + `
+		FROM subscriptions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.user_id = ?
+		ORDER BY s.feed_id ASC
 	`
-	_, err := r.db.ExecContext(ctx, query, feedID, feedID, limit)
-	if err != nil {
-		return fmt.Errorf("repository: unmark seen items: %w", err)
-	}
-	return nil
+	return r.querySubscriptions(ctx, query, userID)
 }
 
-// ============================================================================
-// Outbox Queue Operations
-// ============================================================================
+// ListDigestSubscriptions returns subscriptions the digest worker must look at: every
+// non-immediate subscription plus any subscription still holding pending digest items
+// (e.g. one switched back to immediate delivery).
+func (r *Repository) ListDigestSubscriptions(ctx context.Context) ([]*types.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + `
+		FROM subscriptions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.delivery_mode != 'immediate'
+		OR EXISTS (
+			SELECT 1 FROM pending_digest_items p
+			WHERE p.user_id = s.user_id AND p.feed_id = s.feed_id
+		)
+		ORDER BY s.user_id ASC, s.feed_id ASC
+	`
+	return r.querySubscriptions(ctx, query)
+}
 
-func (r *Repository) EnqueueOutboxItem(ctx context.Context, item *types.OutboxItem) error {
+func (r *Repository) UpdateSubscriptionDelivery(ctx context.Context, sub *types.Subscription) error {
 	query := `
-		INSERT INTO outbox (
-			subject, body, status, retry_count, next_attempt_at, 
-			last_attempt_at, last_error
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
+		UPDATE subscriptions SET
+			delivery_mode = ?, digest_time = ?, digest_weekday = ?, next_digest_at = ?
+		WHERE user_id = ? AND feed_id = ?
+	`
+	res, err := r.db.ExecContext(
+		ctx, query,
+		string(sub.DeliveryMode), sub.DigestTime, int(sub.DigestWeekday), sub.NextDigestAt,
+		sub.UserID, sub.FeedID,
+	)
+	if err != nil {
+		return fmt.Errorf("repository: update subscription delivery: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) EnqueueDigestItem(ctx context.Context, item *types.DigestItem) error {
+	query := `INSERT INTO pending_digest_items (user_id, feed_id, title, link, body) VALUES (?, ?, ?, ?, ?)`
+	res, err := r.db.ExecContext(ctx, query, item.UserID, item.FeedID, item.Title, item.Link, item.Body)
+	if err != nil {
+		return fmt.Errorf("repository: enqueue digest item: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("repository: get digest item insert id: %w", err)
+	}
+	item.ID = id
+	return nil
+}
+
+// ListDigestItems returns the pending digest items of one subscription, oldest first.
+func (r *Repository) ListDigestItems(ctx context.Context, userID, feedID int64) ([]*types.DigestItem, error) {
+	query := `
+		SELECT p.id, p.user_id, p.feed_id, f.title, p.title, p.link, p.body, p.created_at
+		FROM pending_digest_items p
+		JOIN feeds f ON f.id = p.feed_id
+		WHERE p.user_id = ? AND p.feed_id = ?
+		ORDER BY p.id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, feedID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list digest items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := []*types.DigestItem{}
+	for rows.Next() {
+		var item types.DigestItem
+		if err := rows.Scan(
+			&item.ID, &item.UserID, &item.FeedID, &item.FeedTitle,
+			&item.Title, &item.Link, &item.Body, &item.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("repository: scan digest item: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return items, nil
+}
+
+func (r *Repository) DeleteDigestItems(ctx context.Context, ids []int64) error {
+	query := `DELETE FROM pending_digest_items WHERE id = ?`
+	for _, id := range ids {
+		if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("repository: delete digest item: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *Repository) querySubscriptions(ctx context.Context, query string, args ...any) ([]*types.Subscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list subscriptions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	subs := []*types.Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: scan subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return subs, nil
+}
+
+// ============================================================================
+// Notification Channel Operations
+// ============================================================================
+
+// CreateChannel stores a new channel, sealing its token.
+func (r *Repository) CreateChannel(ctx context.Context, ch *types.Channel) error {
+	if ch.Token != "" && r.secrets == nil {
+		return ErrNoSecrets
+	}
+	query := `
+		INSERT INTO channels (user_id, kind, name, target, server, enabled)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at, updated_at
+	`
+	enabledVal := 0
+	if ch.Enabled {
+		enabledVal = 1
+	}
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		ch.UserID, string(ch.Kind), ch.Name, ch.Target, ch.Server, enabledVal,
+	).Scan(&ch.ID, &ch.CreatedAt, &ch.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: create channel: %w", err)
+	}
+	// The token is sealed to the channel's ID, known only now
+	if err := r.setChannelToken(ctx, ch.ID, ch.Token); err != nil {
+		_, _ = r.db.ExecContext(ctx, `DELETE FROM channels WHERE id = ?`, ch.ID)
+		return err
+	}
+	ch.HasToken = ch.Token != ""
+	return nil
+}
+
+func (r *Repository) GetChannel(ctx context.Context, id int64) (*types.Channel, error) {
+	query := `
+		SELECT id, user_id, kind, name, target, server, token, sealed_token, enabled, created_at, updated_at
+		FROM channels WHERE id = ?
+	`
+	ch, sealed, err := scanChannel(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("repository: get channel: %w", err)
+	}
+	if err := r.openChannelToken(ch, sealed); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (r *Repository) UpdateChannel(ctx context.Context, ch *types.Channel) error {
+	if ch.Token != "" && r.secrets == nil {
+		return ErrNoSecrets
+	}
+	query := `
+		UPDATE channels
+		SET kind = ?, name = ?, target = ?, server = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+	enabledVal := 0
+	if ch.Enabled {
+		enabledVal = 1
+	}
+
+	res, err := r.db.ExecContext(
+		ctx, query,
+		string(ch.Kind), ch.Name, ch.Target, ch.Server, enabledVal, ch.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("repository: update channel: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	if err := r.setChannelToken(ctx, ch.ID, ch.Token); err != nil {
+		return err
+	}
+	ch.HasToken = ch.Token != ""
+	return nil
+}
+
+func (r *Repository) DeleteChannel(ctx context.Context, id int64) error {
+	query := `DELETE FROM channels WHERE id = ?`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("repository: delete channel: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SealChannelTokens encrypts the plaintext tokens of channels stored before
+// tokens were sealed, returning how many it sealed.
+func (r *Repository) SealChannelTokens(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, token FROM channels WHERE token != ''`)
+	if err != nil {
+		return 0, fmt.Errorf("repository: list plaintext channel tokens: %w", err)
+	}
+	tokens := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var token string
+		if err := rows.Scan(&id, &token); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("repository: scan channel token: %w", err)
+		}
+		tokens[id] = token
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	for id, token := range tokens {
+		if err := r.setChannelToken(ctx, id, token); err != nil {
+			return 0, err
+		}
+	}
+	return len(tokens), nil
+}
+
+// setChannelToken seals and stores a channel's token, clearing any plaintext
+// one. An empty token removes it.
+func (r *Repository) setChannelToken(ctx context.Context, channelID int64, token string) error {
+	var sealed []byte
+	if token != "" {
+		if r.secrets == nil {
+			return ErrNoSecrets
+		}
+		var err error
+		sealed, err = r.secrets.Seal([]byte(token), channelTokenAAD(channelID))
+		if err != nil {
+			return fmt.Errorf("repository: seal channel token: %w", err)
+		}
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE channels SET token = '', sealed_token = ? WHERE id = ?`, sealed, channelID); err != nil {
+		return fmt.Errorf("repository: set channel token: %w", err)
+	}
+	return nil
+}
+
+// openChannelToken decrypts a channel's sealed token into ch. A plaintext
+// token not yet sealed by SealChannelTokens is used as it is.
+func (r *Repository) openChannelToken(ch *types.Channel, sealed []byte) error {
+	if sealed != nil {
+		if r.secrets == nil {
+			return ErrNoSecrets
+		}
+		token, err := r.secrets.Open(sealed, channelTokenAAD(ch.ID))
+		if err != nil {
+			return fmt.Errorf("repository: open channel token: %w", err)
+		}
+		ch.Token = string(token)
+	}
+	ch.HasToken = ch.Token != ""
+	return nil
+}
+
+// channelTokenAAD binds a sealed token to its channel, so it cannot be copied
+// onto another channel's row.
+func channelTokenAAD(channelID int64) []byte {
+	return []byte("channel_token:" + strconv.FormatInt(channelID, 10))
+}
+
+// ListChannelsForUser returns every channel of a user, enabled or not.
+func (r *Repository) ListChannelsForUser(ctx context.Context, userID int64) ([]*types.Channel, error) {
+	query := `
+		SELECT id, user_id, kind, name, target, server, token, sealed_token, enabled, created_at, updated_at
+		FROM channels WHERE user_id = ?
+		ORDER BY id ASC
+	`
+	return r.queryChannels(ctx, query, userID)
+}
+
+// ListChannelsForFeed returns every channel of every subscriber of a feed.
+func (r *Repository) ListChannelsForFeed(ctx context.Context, feedID int64) ([]*types.Channel, error) {
+	query := `
+		SELECT c.id, c.user_id, c.kind, c.name, c.target, c.server, c.token, c.sealed_token, c.enabled, c.created_at, c.updated_at
+		FROM channels c
+		JOIN subscriptions s ON s.user_id = c.user_id
+		WHERE s.feed_id = ?
+		ORDER BY c.user_id ASC, c.id ASC
+	`
+	return r.queryChannels(ctx, query, feedID)
+}
+
+func (r *Repository) queryChannels(ctx context.Context, query string, args ...any) ([]*types.Channel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list channels: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	channels := []*types.Channel{}
+	for rows.Next() {
+		ch, sealed, err := scanChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: scan channel: %w", err)
+		}
+		if err := r.openChannelToken(ch, sealed); err != nil {
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return channels, nil
+}
+
+// ============================================================================
+// Filter Rule Operations
+// ============================================================================
+
+func (r *Repository) CreateFilterRule(ctx context.Context, rule *types.FilterRule) error {
+	query := `
+		INSERT INTO filter_rules (user_id, feed_id, action, field, match_type, pattern)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query,
+		rule.UserID, rule.FeedID, string(rule.Action), string(rule.Field), string(rule.Match), rule.Pattern,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: create filter rule: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetFilterRule(ctx context.Context, id int64) (*types.FilterRule, error) {
+	query := `
+		SELECT id, user_id, feed_id, action, field, match_type, pattern, created_at, updated_at
+		FROM filter_rules WHERE id = ?
+	`
+	rule, err := scanFilterRule(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("repository: get filter rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateFilterRule replaces a rule's action, field, match type and pattern.
+func (r *Repository) UpdateFilterRule(ctx context.Context, rule *types.FilterRule) error {
+	query := `
+		UPDATE filter_rules
+		SET action = ?, field = ?, match_type = ?, pattern = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+	res, err := r.db.ExecContext(ctx, query, string(rule.Action), string(rule.Field), string(rule.Match), rule.Pattern, rule.ID)
+	if err != nil {
+		return fmt.Errorf("repository: update filter rule: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) DeleteFilterRule(ctx context.Context, id int64) error {
+	query := `DELETE FROM filter_rules WHERE id = ?`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("repository: delete filter rule: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repository: check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListFilterRules returns the filter rules of one subscription in creation order.
+func (r *Repository) ListFilterRules(ctx context.Context, userID, feedID int64) ([]*types.FilterRule, error) {
+	query := `
+		SELECT id, user_id, feed_id, action, field, match_type, pattern, created_at, updated_at
+		FROM filter_rules WHERE user_id = ? AND feed_id = ?
+		ORDER BY id ASC
+	`
+	return r.queryFilterRules(ctx, query, userID, feedID)
+}
+
+// ListFilterRulesForFeed returns the filter rules of every subscriber of a feed.
+func (r *Repository) ListFilterRulesForFeed(ctx context.Context, feedID int64) ([]*types.FilterRule, error) {
+	query := `
+		SELECT id, user_id, feed_id, action, field, match_type, pattern, created_at, updated_at
+		FROM filter_rules WHERE feed_id = ?
+		ORDER BY user_id ASC, id ASC
+	`
+	return r.queryFilterRules(ctx, query, feedID)
+}
+
+func (r *Repository) queryFilterRules(ctx context.Context, query string, args ...any) ([]*types.FilterRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list filter rules: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	rules := []*types.FilterRule{}
+	for rows.Next() {
+		rule, err := scanFilterRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: scan filter rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return rules, nil
+}
+
+// ============================================================================
+// Item History Operations
+// ============================================================================
+
+// SaveItem inserts a crawled item, or refreshes the stored copy when the feed
+// already has an item with the same GUID.
+func (r *Repository) SaveItem(ctx context.Context, item *types.Item) error {
+	query := `
+		INSERT INTO items (
+			feed_id, guid, title, link, author, categories, published_at,
+			content, extracted_content, extraction_strategy, content_hash
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (feed_id, guid) DO UPDATE SET
+			title = excluded.title,
+			link = excluded.link,
+			author = excluded.author,
+			categories = excluded.categories,
+			published_at = excluded.published_at,
+			content = excluded.content,
+			extracted_content = excluded.extracted_content,
+			extraction_strategy = excluded.extraction_strategy,
+			content_hash = excluded.content_hash,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query,
+		item.FeedID, item.GUID, item.Title, item.Link, item.Author, strings.Join(item.Categories, "\n"), item.PublishedAt,
+		item.Content, item.ExtractedContent, string(item.ExtractionStrategy), item.ContentHash,
+	).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: save item: %w", err)
+	}
+	return nil
+}
+
+// ListItemsForFeed returns one page of a feed's stored items, newest first,
+// together with whether each one has been marked seen.
+func (r *Repository) ListItemsForFeed(ctx context.Context, feedID int64, limit, offset int) ([]*types.Item, error) {
+	query := `
+		SELECT
+			i.id, i.feed_id, i.guid, i.title, i.link, i.author, i.categories, i.published_at,
+			i.content, i.extracted_content, i.extraction_strategy, i.content_hash,
+			EXISTS(SELECT 1 FROM seen_items s WHERE s.feed_id = i.feed_id AND s.guid = i.guid),
+			i.created_at, i.updated_at
+		FROM items i
+		WHERE i.feed_id = ?
+		ORDER BY COALESCE(i.published_at, i.created_at) DESC, i.id DESC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, feedID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := []*types.Item{}
+	for rows.Next() {
+		var item types.Item
+		var published sql.NullTime
+		var categories, strategy string
+		var seen int
+		if err := rows.Scan(
+			&item.ID, &item.FeedID, &item.GUID, &item.Title, &item.Link, &item.Author, &categories, &published,
+			&item.Content, &item.ExtractedContent, &strategy, &item.ContentHash,
+			&seen, &item.CreatedAt, &item.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("repository: scan item: %w", err)
+		}
+		if published.Valid {
+			item.PublishedAt = &published.Time
+		}
+		if categories != "" {
+			item.Categories = strings.Split(categories, "\n")
+		}
+		item.ExtractionStrategy = types.ExtractionStrategy(strategy)
+		item.Seen = seen == 1
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return items, nil
+}
+
+func (r *Repository) CountItemsForFeed(ctx context.Context, feedID int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM items WHERE feed_id = ?`, feedID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("repository: count items: %w", err)
+	}
+	return count, nil
+}
+
+// ============================================================================
+// Seen Items Operations
+// ============================================================================
+
+func (r *Repository) MarkItemSeen(ctx context.Context, feedID int64, guid string) error {
+	return r.MarkItemSeenWithKey(ctx, feedID, guid, "")
+}
+
+// MarkItemSeenWithKey marks an item seen under its GUID and, if not empty,
+// the dedupe key of its feed's strategy.
+func (r *Repository) MarkItemSeenWithKey(ctx context.Context, feedID int64, guid, key string) error {
+	query := `
+		INSERT INTO seen_items (feed_id, guid, dedupe_key) VALUES (?, ?, ?)
+		ON CONFLICT (feed_id, guid) DO UPDATE SET dedupe_key = excluded.dedupe_key
+		WHERE excluded.dedupe_key != ''
+	`
+	_, err := r.db.ExecContext(ctx, query, feedID, guid, key)
+	if err != nil {
+		return fmt.Errorf("repository: mark item seen: %w", err)
+	}
+	return nil
+}
+
+// IsDedupeKeySeen reports whether an item with the given dedupe key was
+// already seen in the feed, whatever its GUID.
+func (r *Repository) IsDedupeKeySeen(ctx context.Context, feedID int64, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	query := `SELECT EXISTS(SELECT 1 FROM seen_items WHERE feed_id = ? AND dedupe_key = ? AND dedupe_key != '')`
+	var exists int
+	if err := r.db.QueryRowContext(ctx, query, feedID, key).Scan(&exists); err != nil {
+		return false, fmt.Errorf("repository: check dedupe key seen: %w", err)
+	}
+	return exists == 1, nil
+}
+
+func (r *Repository) IsItemSeen(ctx context.Context, feedID int64, guid string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM seen_items WHERE feed_id = ? AND guid = ?)`
+	var exists int
+	err := r.db.QueryRowContext(ctx, query, feedID, guid).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("repository: check item seen: %w", err)
+	}
+	return exists == 1, nil
+}
+
+func (r *Repository) UnmarkSeenItems(ctx context.Context, feedID int64, limit int) error {
+	query := `
+		DELETE FROM seen_items 
+		WHERE feed_id = ? 
+		AND guid IN (
+			SELECT guid FROM seen_items 
+			WHERE feed_id = ? 
+			ORDER BY rowid DESC 
+			LIMIT ?
+		)
+	`
+	_, err := r.db.ExecContext(ctx, query, feedID, feedID, limit)
+	if err != nil {
+		return fmt.Errorf("repository: unmark seen items: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// Extraction Cache Operations
+// ============================================================================
+
+// GetExtraction returns the cached extraction of a normalized article URL
+// with the given strategy and selector, or nil if there is none.
+func (r *Repository) GetExtraction(ctx context.Context, key string, strategy types.ExtractionStrategy, selector string) (*types.Extraction, error) {
+	query := `
+		SELECT url_key, url, strategy, selector, content, etag, last_modified, fetched_at
+		FROM extraction_cache WHERE url_key = ? AND strategy = ? AND selector = ?
+	`
+	var ex types.Extraction
+	err := r.db.QueryRowContext(ctx, query, key, string(strategy), selector).Scan(
+		&ex.Key, &ex.URL, &ex.Strategy, &ex.Selector, &ex.Content, &ex.ETag, &ex.LastModified, &ex.FetchedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: get extraction: %w", err)
+	}
+	return &ex, nil
+}
+
+// PutExtraction stores an extraction, replacing any cached for the same URL,
+// strategy and selector.
+func (r *Repository) PutExtraction(ctx context.Context, ex *types.Extraction) error {
+	query := `
+		INSERT INTO extraction_cache (url_key, url, strategy, selector, content, etag, last_modified, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url_key, strategy, selector) DO UPDATE SET
+			url = excluded.url, content = excluded.content, etag = excluded.etag, last_modified = excluded.last_modified,
+			fetched_at = excluded.fetched_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		ex.Key, ex.URL, string(ex.Strategy), ex.Selector, ex.Content, ex.ETag, ex.LastModified, ex.FetchedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("repository: put extraction: %w", err)
+	}
+	return nil
+}
+
+// PruneExtractions deletes extractions fetched before staleBefore that have
+// no validators to revalidate them with, then the oldest of the rest until
+// their content fits in maxBytes. It returns how many were deleted.
+func (r *Repository) PruneExtractions(ctx context.Context, staleBefore time.Time, maxBytes int64) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM extraction_cache
+		WHERE fetched_at < ? AND etag = '' AND last_modified = ''
+	`, staleBefore)
+	if err != nil {
+		return 0, fmt.Errorf("repository: prune stale extractions: %w", err)
+	}
+	stale, _ := res.RowsAffected()
+
+	res, err = r.db.ExecContext(ctx, `
+		DELETE FROM extraction_cache WHERE rowid IN (
+			SELECT id FROM (
+				SELECT rowid AS id, SUM(length(CAST(content AS BLOB))) OVER (ORDER BY fetched_at DESC, rowid) AS total
+				FROM extraction_cache
+			) WHERE total > ?
+		)
+	`, maxBytes)
+	if err != nil {
+		return 0, fmt.Errorf("repository: prune extractions over size: %w", err)
+	}
+	over, _ := res.RowsAffected()
+	return stale + over, nil
+}
+
+// ============================================================================
+// WebSub Subscription Operations
+// ============================================================================
+
+const webSubColumns = `
+	feed_id, hub, topic, secret, state, lease_seconds, lease_expires_at, last_error, created_at, updated_at
+`
+
+// SaveWebSubSubscription creates or replaces the push subscription of a feed.
+func (r *Repository) SaveWebSubSubscription(ctx context.Context, sub *types.WebSubSubscription) error {
+	query := `
+		INSERT INTO websub_subscriptions (
+			feed_id, hub, topic, secret, state, lease_seconds, lease_expires_at, last_error
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (feed_id) DO UPDATE SET
+			hub = excluded.hub,
+			topic = excluded.topic,
+			secret = excluded.secret,
+			state = excluded.state,
+			lease_seconds = excluded.lease_seconds,
+			lease_expires_at = excluded.lease_expires_at,
+			last_error = excluded.last_error,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(
+		ctx, query,
+		sub.FeedID, sub.Hub, sub.Topic, sub.Secret, string(sub.State),
+		sub.LeaseSeconds, sub.LeaseExpiresAt, sub.LastError,
+	)
+	if err != nil {
+		return fmt.Errorf("repository: save websub subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetWebSubSubscription(ctx context.Context, feedID int64) (*types.WebSubSubscription, error) {
+	query := `SELECT ` + webSubColumns + ` FROM websub_subscriptions WHERE feed_id = ?`
+	sub, err := scanWebSubSubscription(r.db.QueryRowContext(ctx, query, feedID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("repository: get websub subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// ListWebSubRenewals returns active subscriptions whose lease expires at or before the given time.
+func (r *Repository) ListWebSubRenewals(ctx context.Context, before time.Time) ([]*types.WebSubSubscription, error) {
+	query := `SELECT ` + webSubColumns + `
+		FROM websub_subscriptions
+		WHERE state = 'active' AND lease_expires_at <= ?
+		ORDER BY lease_expires_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list websub renewals: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	subs := []*types.WebSubSubscription{}
+	for rows.Next() {
+		sub, err := scanWebSubSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: scan websub subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return subs, nil
+}
+
+// ============================================================================
+// Magic Link Key Operations
+// ============================================================================
+
+// CreateMagicKey stores a new signing key.
+func (r *Repository) CreateMagicKey(ctx context.Context, key *types.MagicKey) error {
+	query := `INSERT INTO magic_keys (secret, created_at) VALUES (?, ?) RETURNING id`
+	if err := r.db.QueryRowContext(ctx, query, key.Secret, key.CreatedAt).Scan(&key.ID); err != nil {
+		return fmt.Errorf("repository: create magic key: %w", err)
+	}
+	return nil
+}
+
+// ListMagicKeys returns every stored signing key, newest first.
+func (r *Repository) ListMagicKeys(ctx context.Context) ([]*types.MagicKey, error) {
+	query := `SELECT id, secret, created_at, retired_at FROM magic_keys ORDER BY id DESC`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("repository: list magic keys: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	keys := []*types.MagicKey{}
+	for rows.Next() {
+		var key types.MagicKey
+		if err := rows.Scan(&key.ID, &key.Secret, &key.CreatedAt, &key.RetiredAt); err != nil {
+			return nil, fmt.Errorf("repository: scan magic key: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows error: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RetireMagicKeys marks every active key retired as of the given time.
+func (r *Repository) RetireMagicKeys(ctx context.Context, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE magic_keys SET retired_at = ? WHERE retired_at IS NULL`, at)
+	if err != nil {
+		return fmt.Errorf("repository: retire magic keys: %w", err)
+	}
+	return nil
+}
+
+// DeleteMagicKeysRetiredBefore removes keys whose grace period ended before the given time.
+func (r *Repository) DeleteMagicKeysRetiredBefore(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM magic_keys WHERE retired_at IS NOT NULL AND retired_at < ?`, before)
+	if err != nil {
+		return fmt.Errorf("repository: delete magic keys: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// Outbox Queue Operations
+// ============================================================================
+
+func (r *Repository) EnqueueOutboxItem(ctx context.Context, item *types.OutboxItem) error {
+	query := `
+		INSERT INTO outbox (
+			subject, body, status, retry_count, next_attempt_at, 
+			last_attempt_at, last_error, channel_id, list_unsubscribe
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	var lastAttempt *time.Time
 	if item.LastAttemptAt != nil {
@@ -546,7 +1810,7 @@ func (r *Repository) EnqueueOutboxItem(ctx context.Context, item *types.OutboxIt
 	res, err := r.db.ExecContext(
 		ctx, query,
 		item.Subject, item.Body, string(item.Status), item.RetryCount,
-		item.NextAttemptAt, lastAttempt, item.LastError,
+		item.NextAttemptAt, lastAttempt, item.LastError, item.ChannelID, item.ListUnsubscribe,
 	)
 	if err != nil {
 		return fmt.Errorf("repository: enqueue outbox item: %w", err)
@@ -571,7 +1835,7 @@ func (r *Repository) EnqueueOutboxItem(ctx context.Context, item *types.OutboxIt
 
 func (r *Repository) GetOutboxItem(ctx context.Context, id int64) (*types.OutboxItem, error) {
 	query := `
-		SELECT id, subject, body, status, retry_count, next_attempt_at, last_attempt_at, last_error, created_at 
+		SELECT id, subject, body, status, retry_count, next_attempt_at, last_attempt_at, last_error, channel_id, list_unsubscribe, created_at 
 		FROM outbox 
 		WHERE id = ?
 	`
@@ -579,10 +1843,11 @@ func (r *Repository) GetOutboxItem(ctx context.Context, id int64) (*types.Outbox
 	var item types.OutboxItem
 	var statusStr string
 	var lastAttempt sql.NullTime
+	var channelID sql.NullInt64
 
 	err := row.Scan(
 		&item.ID, &item.Subject, &item.Body, &statusStr, &item.RetryCount,
-		&item.NextAttemptAt, &lastAttempt, &item.LastError, &item.CreatedAt,
+		&item.NextAttemptAt, &lastAttempt, &item.LastError, &channelID, &item.ListUnsubscribe, &item.CreatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -594,6 +1859,9 @@ func (r *Repository) GetOutboxItem(ctx context.Context, id int64) (*types.Outbox
 	if lastAttempt.Valid {
 		item.LastAttemptAt = &lastAttempt.Time
 	}
+	if channelID.Valid {
+		item.ChannelID = &channelID.Int64
+	}
 
 	// Fetch recipients
 	recipQuery := `SELECT email FROM outbox_recipients WHERE outbox_id = ? ORDER BY email ASC`
@@ -620,7 +1888,7 @@ func (r *Repository) GetOutboxItem(ctx context.Context, id int64) (*types.Outbox
 
 func (r *Repository) ListPendingOutboxItems(ctx context.Context, now time.Time) ([]*types.OutboxItem, error) {
 	query := `
-		SELECT id, subject, body, status, retry_count, next_attempt_at, last_attempt_at, last_error, created_at 
+		SELECT id, subject, body, status, retry_count, next_attempt_at, last_attempt_at, last_error, channel_id, list_unsubscribe, created_at 
 		FROM outbox 
 		WHERE status IN ('pending', 'failed') AND next_attempt_at <= ? 
 		ORDER BY next_attempt_at ASC
@@ -636,10 +1904,11 @@ func (r *Repository) ListPendingOutboxItems(ctx context.Context, now time.Time)
 		var item types.OutboxItem
 		var statusStr string
 		var lastAttempt sql.NullTime
+		var channelID sql.NullInt64
 
 		err := rows.Scan(
 			&item.ID, &item.Subject, &item.Body, &statusStr, &item.RetryCount,
-			&item.NextAttemptAt, &lastAttempt, &item.LastError, &item.CreatedAt,
+			&item.NextAttemptAt, &lastAttempt, &item.LastError, &channelID, &item.ListUnsubscribe, &item.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("repository: scan outbox row: %w", err)
@@ -648,6 +1917,9 @@ func (r *Repository) ListPendingOutboxItems(ctx context.Context, now time.Time)
 		if lastAttempt.Valid {
 			item.LastAttemptAt = &lastAttempt.Time
 		}
+		if channelID.Valid {
+			item.ChannelID = &channelID.Int64
+		}
 		items = append(items, &item)
 	}
 
@@ -714,7 +1986,7 @@ func (r *Repository) UpdateOutboxItemStatus(ctx context.Context, item *types.Out
 
 func (r *Repository) ListOutboxItems(ctx context.Context, limit int) ([]*types.OutboxItem, error) {
 	query := `
-		SELECT id, subject, body, status, retry_count, next_attempt_at, last_attempt_at, last_error, created_at 
+		SELECT id, subject, body, status, retry_count, next_attempt_at, last_attempt_at, last_error, channel_id, list_unsubscribe, created_at 
 		FROM outbox 
 		ORDER BY id DESC
 		LIMIT ?
@@ -730,10 +2002,11 @@ func (r *Repository) ListOutboxItems(ctx context.Context, limit int) ([]*types.O
 		var item types.OutboxItem
 		var statusStr string
 		var lastAttempt sql.NullTime
+		var channelID sql.NullInt64
 
 		err := rows.Scan(
 			&item.ID, &item.Subject, &item.Body, &statusStr, &item.RetryCount,
-			&item.NextAttemptAt, &lastAttempt, &item.LastError, &item.CreatedAt,
+			&item.NextAttemptAt, &lastAttempt, &item.LastError, &channelID, &item.ListUnsubscribe, &item.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("repository: scan outbox row: %w", err)
@@ -742,6 +2015,9 @@ func (r *Repository) ListOutboxItems(ctx context.Context, limit int) ([]*types.O
 		if lastAttempt.Valid {
 			item.LastAttemptAt = &lastAttempt.Time
 		}
+		if channelID.Valid {
+			item.ChannelID = &channelID.Int64
+		}
 		items = append(items, &item)
 	}
 
@@ -797,6 +2073,17 @@ func (r *Repository) GetStats(ctx context.Context) (*types.DBStats, error) {
 	if err != nil {
 		return nil, fmt.Errorf("repository: get stats outbox delivered: %w", err)
 	}
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(CASE WHEN health = 'healthy' THEN 1 END),
+			COUNT(CASE WHEN health = 'degraded' THEN 1 END),
+			COUNT(CASE WHEN health = 'failing' THEN 1 END),
+			COUNT(CASE WHEN health = 'disabled' THEN 1 END)
+		FROM feeds
+	`).Scan(&stats.FeedsHealthy, &stats.FeedsDegraded, &stats.FeedsFailing, &stats.FeedsDisabled)
+	if err != nil {
+		return nil, fmt.Errorf("repository: get stats feed health: %w", err)
+	}
 	return &stats, nil
 }
 
@@ -808,8 +2095,12 @@ func scanFeed(row *sql.Row) (*types.Feed, error) {
 	var f types.Feed
 	var errTime sql.NullTime
 	var polledTime sql.NullTime
+	var failingSince sql.NullTime
+	var healthChanged sql.NullTime
+	var healthStr string
 	var extractVal int
-	var strategyStr string
+	var adaptiveVal, followVal int
+	var strategyStr, dedupeStr, chainStr, rulesStr string
 
 	err := row.Scan(
 		&f.ID, &f.Title, &f.URL, &f.ETag, &f.LastModified, &f.NextPollAt,
@@ -817,7 +2108,7 @@ func scanFeed(row *sql.Row) (*types.Feed, error) {
 		&errTime, &f.LastErrorSnippet, &polledTime, &extractVal,
 		&strategyStr, &f.CSSSelector,
 		&f.ScraperItemSelector, &f.ScraperTitleSelector, &f.ScraperLinkSelector, &f.ScraperDescriptionSelector,
-		&f.CreatedAt, &f.UpdatedAt,
+		&f.Category, &f.NotFoundCount, &healthStr, &f.ConsecutiveFailures, &failingSince, &healthChanged, &f.DisabledReason, &adaptiveVal, &f.AdaptiveIntervalSecs, &f.SourceFormat, &dedupeStr, &chainStr, &followVal, &f.NextPageSelector, &f.MaxPages, &rulesStr, &f.CreatedAt, &f.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -827,13 +2118,27 @@ func scanFeed(row *sql.Row) (*types.Feed, error) {
 	}
 
 	f.ExtractFullArticle = extractVal == 1
+	f.AdaptivePolling = adaptiveVal == 1
+	f.FollowPages = followVal == 1
 	f.ExtractionStrategy = types.ExtractionStrategy(strategyStr)
+	f.DedupeStrategy = types.DedupeStrategy(dedupeStr)
+	f.ExtractionChain = splitChain(chainStr)
+	if f.ContentRules, err = decodeRules(rulesStr); err != nil {
+		return nil, err
+	}
 	if errTime.Valid {
 		f.LastErrorTime = &errTime.Time
 	}
 	if polledTime.Valid {
 		f.LastPolledAt = &polledTime.Time
 	}
+	f.Health = types.FeedHealth(healthStr)
+	if failingSince.Valid {
+		f.FailingSince = &failingSince.Time
+	}
+	if healthChanged.Valid {
+		f.HealthChangedAt = &healthChanged.Time
+	}
 
 	return &f, nil
 }
@@ -842,8 +2147,12 @@ func scanFeedRow(rows *sql.Rows) (*types.Feed, error) {
 	var f types.Feed
 	var errTime sql.NullTime
 	var polledTime sql.NullTime
+	var failingSince sql.NullTime
+	var healthChanged sql.NullTime
+	var healthStr string
 	var extractVal int
-	var strategyStr string
+	var adaptiveVal, followVal int
+	var strategyStr, dedupeStr, chainStr, rulesStr string
 
 	err := rows.Scan(
 		&f.ID, &f.Title, &f.URL, &f.ETag, &f.LastModified, &f.NextPollAt,
@@ -851,20 +2160,163 @@ func scanFeedRow(rows *sql.Rows) (*types.Feed, error) {
 		&errTime, &f.LastErrorSnippet, &polledTime, &extractVal,
 		&strategyStr, &f.CSSSelector,
 		&f.ScraperItemSelector, &f.ScraperTitleSelector, &f.ScraperLinkSelector, &f.ScraperDescriptionSelector,
-		&f.CreatedAt, &f.UpdatedAt,
+		&f.Category, &f.NotFoundCount, &healthStr, &f.ConsecutiveFailures, &failingSince, &healthChanged, &f.DisabledReason, &adaptiveVal, &f.AdaptiveIntervalSecs, &f.SourceFormat, &dedupeStr, &chainStr, &followVal, &f.NextPageSelector, &f.MaxPages, &rulesStr, &f.CreatedAt, &f.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("repository: scan feed row: %w", err)
 	}
 
 	f.ExtractFullArticle = extractVal == 1
+	f.AdaptivePolling = adaptiveVal == 1
+	f.FollowPages = followVal == 1
 	f.ExtractionStrategy = types.ExtractionStrategy(strategyStr)
+	f.DedupeStrategy = types.DedupeStrategy(dedupeStr)
+	f.ExtractionChain = splitChain(chainStr)
+	if f.ContentRules, err = decodeRules(rulesStr); err != nil {
+		return nil, err
+	}
 	if errTime.Valid {
 		f.LastErrorTime = &errTime.Time
 	}
 	if polledTime.Valid {
 		f.LastPolledAt = &polledTime.Time
 	}
+	f.Health = types.FeedHealth(healthStr)
+	if failingSince.Valid {
+		f.FailingSince = &failingSince.Time
+	}
+	if healthChanged.Valid {
+		f.HealthChangedAt = &healthChanged.Time
+	}
 
 	return &f, nil
 }
+
+// joinChain stores an extraction chain as a comma-separated list.
+func joinChain(chain []types.ExtractionStrategy) string {
+	parts := make([]string, len(chain))
+	for i, strategy := range chain {
+		parts[i] = string(strategy)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitChain(s string) []types.ExtractionStrategy {
+	if s == "" {
+		return nil
+	}
+	var chain []types.ExtractionStrategy
+	for part := range strings.SplitSeq(s, ",") {
+		chain = append(chain, types.ExtractionStrategy(part))
+	}
+	return chain
+}
+
+// encodeRules stores content rules as JSON, or "" for none.
+func encodeRules(rules *types.ContentRules) (string, error) {
+	if rules == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("repository: encode content rules: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeRules reads content rules stored by encodeRules.
+func decodeRules(s string) (*types.ContentRules, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var rules types.ContentRules
+	if err := json.Unmarshal([]byte(s), &rules); err != nil {
+		return nil, fmt.Errorf("repository: decode content rules: %w", err)
+	}
+	return &rules, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row rowScanner) (*types.Subscription, error) {
+	var sub types.Subscription
+	var modeStr string
+	var weekday int
+	var nextDigest sql.NullTime
+
+	err := row.Scan(
+		&sub.UserID, &sub.FeedID, &modeStr, &sub.DigestTime, &weekday, &nextDigest, &sub.UserEmail, &sub.CrossFeedDedupe,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.DeliveryMode = types.DeliveryMode(modeStr)
+	sub.DigestWeekday = time.Weekday(weekday)
+	if nextDigest.Valid {
+		sub.NextDigestAt = &nextDigest.Time
+	}
+	return &sub, nil
+}
+
+func scanWebSubSubscription(row rowScanner) (*types.WebSubSubscription, error) {
+	var sub types.WebSubSubscription
+	var stateStr string
+	var leaseExpires sql.NullTime
+
+	err := row.Scan(
+		&sub.FeedID, &sub.Hub, &sub.Topic, &sub.Secret, &stateStr, &sub.LeaseSeconds,
+		&leaseExpires, &sub.LastError, &sub.CreatedAt, &sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.State = types.WebSubState(stateStr)
+	if leaseExpires.Valid {
+		sub.LeaseExpiresAt = &leaseExpires.Time
+	}
+	return &sub, nil
+}
+
+func scanFilterRule(row rowScanner) (*types.FilterRule, error) {
+	var rule types.FilterRule
+	var action, field, match string
+
+	err := row.Scan(
+		&rule.ID, &rule.UserID, &rule.FeedID, &action, &field, &match, &rule.Pattern,
+		&rule.CreatedAt, &rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Action = types.FilterAction(action)
+	rule.Field = types.FilterField(field)
+	rule.Match = types.FilterMatch(match)
+	return &rule, nil
+}
+
+// scanChannel scans a channel row, returning its sealed token separately for
+// openChannelToken.
+func scanChannel(row rowScanner) (*types.Channel, []byte, error) {
+	var ch types.Channel
+	var kind string
+	var enabled int
+	var sealed []byte
+
+	err := row.Scan(
+		&ch.ID, &ch.UserID, &kind, &ch.Name, &ch.Target, &ch.Server, &ch.Token, &sealed, &enabled,
+		&ch.CreatedAt, &ch.UpdatedAt,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch.Kind = types.ChannelKind(kind)
+	ch.Enabled = enabled == 1
+	return &ch, sealed, nil
+}
@@ -1,13 +1,18 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"rss2go/internal/secrets"
 	"rss2go/internal/types"
 )
 
@@ -103,6 +108,14 @@ func TestFeedOperations(t *testing.T) {
 	fetched.LastErrorTime = &errTimeUpdate
 	fetched.LastErrorStr = "HTTP 500"
 	fetched.LastErrorSnippet = "Internal Server Error"
+	fetched.FollowPages = true
+	fetched.NextPageSelector = "a.next"
+	fetched.MaxPages = 3
+	fetched.ContentRules = &types.ContentRules{
+		Remove:   []string{".newsletter"},
+		Links:    []types.LinkRule{{Action: types.LinkStripTracking}},
+		Rewrites: []types.RewriteRule{{Pattern: "Advertisement", Replacement: ""}},
+	}
 	if err := repo.UpdateFeed(ctx, fetched); err != nil {
 		t.Fatalf("failed to update feed: %v", err)
 	}
@@ -120,6 +133,15 @@ func TestFeedOperations(t *testing.T) {
 	if updated.LastErrorTime == nil || !updated.LastErrorTime.Equal(errTimeUpdate) {
 		t.Errorf("expected LastErrorTime to match: %v, got %v", errTimeUpdate, updated.LastErrorTime)
 	}
+	if !updated.FollowPages || updated.NextPageSelector != "a.next" || updated.MaxPages != 3 {
+		t.Errorf("expected pagination settings to be updated, got %v %q %d", updated.FollowPages, updated.NextPageSelector, updated.MaxPages)
+	}
+	if rules := updated.ContentRules; rules == nil || rules.Remove[0] != ".newsletter" || rules.Links[0].Action != types.LinkStripTracking || rules.Rewrites[0].Pattern != "Advertisement" {
+		t.Errorf("expected content rules to be updated, got %+v", rules)
+	}
+	if fetchedByURL.ContentRules != nil {
+		t.Errorf("expected no content rules by default, got %+v", fetchedByURL.ContentRules)
+	}
 
 	// Negative update check
 	err = repo.UpdateFeed(ctx, &types.Feed{ID: 9999, Title: "Ghost"})
@@ -336,6 +358,523 @@ func TestSeenItems(t *testing.T) {
 	}
 }
 
+func TestItemDedupe(t *testing.T) {
+	_, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	feed := &types.Feed{Title: "Feed", URL: "http://url", NextPollAt: time.Now(), DedupeStrategy: types.DedupeLink}
+	other := &types.Feed{Title: "Other", URL: "http://other", NextPollAt: time.Now()}
+	for _, f := range []*types.Feed{feed, other} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+	}
+	got, _ := repo.GetFeed(ctx, feed.ID)
+	if got.DedupeStrategy != types.DedupeLink {
+		t.Errorf("expected dedupe strategy to be stored, got %q", got.DedupeStrategy)
+	}
+
+	// Items are found by their dedupe key whatever their GUID
+	if err := repo.MarkItemSeenWithKey(ctx, feed.ID, "guid-1", "example.com/a"); err != nil {
+		t.Fatalf("failed to mark seen: %v", err)
+	}
+	if seen, _ := repo.IsDedupeKeySeen(ctx, feed.ID, "example.com/a"); !seen {
+		t.Error("expected the dedupe key to be seen")
+	}
+	if seen, _ := repo.IsDedupeKeySeen(ctx, other.ID, "example.com/a"); seen {
+		t.Error("expected dedupe keys to be kept per feed")
+	}
+	if seen, _ := repo.IsDedupeKeySeen(ctx, feed.ID, ""); seen {
+		t.Error("expected an empty key never to be seen")
+	}
+
+	// Catching up a GUID that is already seen adds its key
+	if err := repo.MarkItemSeen(ctx, feed.ID, "guid-2"); err != nil {
+		t.Fatalf("failed to mark seen: %v", err)
+	}
+	if err := repo.MarkItemSeenWithKey(ctx, feed.ID, "guid-2", "example.com/b"); err != nil {
+		t.Fatalf("failed to mark seen: %v", err)
+	}
+	if err := repo.MarkItemSeen(ctx, feed.ID, "guid-2"); err != nil {
+		t.Fatalf("failed to mark seen: %v", err)
+	}
+	if seen, _ := repo.IsDedupeKeySeen(ctx, feed.ID, "example.com/b"); !seen {
+		t.Error("expected the key of an already seen GUID to be kept")
+	}
+
+	// Cross-feed deliveries are recorded once per user and fingerprint
+	user := &types.User{Email: "reader@test.com", CrossFeedDedupe: true}
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if u, _ := repo.GetUser(ctx, user.ID); !u.CrossFeedDedupe {
+		t.Error("expected cross-feed dedupe to be stored")
+	}
+	_ = repo.Subscribe(ctx, user.ID, other.ID)
+	subs, _ := repo.ListSubscriptionSettingsForFeed(ctx, other.ID)
+	if len(subs) != 1 || !subs[0].CrossFeedDedupe {
+		t.Errorf("expected subscription settings to carry the user's setting, got %+v", subs)
+	}
+
+	first, err := repo.RecordDelivery(ctx, user.ID, feed.ID, "example.com/a")
+	if err != nil || !first {
+		t.Fatalf("expected the first delivery to be recorded, got %v, %v", first, err)
+	}
+	if first, _ := repo.RecordDelivery(ctx, user.ID, other.ID, "example.com/a"); first {
+		t.Error("expected a second delivery of the same article to be refused")
+	}
+	// A rewound feed delivers its own items again
+	if first, _ := repo.RecordDelivery(ctx, user.ID, feed.ID, "example.com/a"); !first {
+		t.Error("expected a redelivery from the same feed to be allowed")
+	}
+
+	// Old deliveries are forgotten
+	if n, err := repo.PruneDeliveries(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("expected recent deliveries to be kept, pruned %d (err %v)", n, err)
+	}
+	if n, err := repo.PruneDeliveries(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("expected the delivery to be pruned, pruned %d (err %v)", n, err)
+	}
+	if first, _ := repo.RecordDelivery(ctx, user.ID, other.ID, "example.com/a"); !first {
+		t.Error("expected a pruned delivery to be recorded afresh")
+	}
+
+	if err := repo.SetCrossFeedDedupe(ctx, user.ID, false); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	if u, _ := repo.GetUser(ctx, user.ID); u.CrossFeedDedupe {
+		t.Error("expected cross-feed dedupe to be turned off")
+	}
+	if err := repo.SetCrossFeedDedupe(ctx, 9999, true); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a missing user, got %v", err)
+	}
+}
+
+func TestItemHistory(t *testing.T) {
+	_, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	feed := &types.Feed{Title: "History Feed", URL: "https://history.com/feed", NextPollAt: time.Now()}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		published := base.Add(time.Duration(i) * time.Hour)
+		item := &types.Item{
+			FeedID:      feed.ID,
+			GUID:        fmt.Sprintf("guid-%d", i),
+			Title:       fmt.Sprintf("Item %d", i),
+			Link:        fmt.Sprintf("https://history.com/%d", i),
+			Author:      "Jane",
+			PublishedAt: &published,
+			Content:     "<p>summary</p>",
+			ContentHash: "hash",
+		}
+		if err := repo.SaveItem(ctx, item); err != nil {
+			t.Fatalf("failed to save item: %v", err)
+		}
+		if item.ID == 0 {
+			t.Fatalf("expected item ID to be populated")
+		}
+	}
+
+	// Re-saving the same GUID updates in place rather than duplicating.
+	updated := &types.Item{FeedID: feed.ID, GUID: "guid-4", Title: "Item 4 (edited)", ExtractedContent: "<p>full</p>"}
+	if err := repo.SaveItem(ctx, updated); err != nil {
+		t.Fatalf("failed to re-save item: %v", err)
+	}
+
+	total, err := repo.CountItemsForFeed(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to count items: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("expected 5 items, got %d", total)
+	}
+
+	if err := repo.MarkItemSeen(ctx, feed.ID, "guid-3"); err != nil {
+		t.Fatalf("failed to mark seen: %v", err)
+	}
+
+	page, err := repo.ListItemsForFeed(ctx, feed.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("failed to list items: %v", err)
+	}
+	if len(page) != 2 {
+		t.Fatalf("expected page of 2, got %d", len(page))
+	}
+	// The edited item lost its published time so it sorts by created_at, which is newest.
+	if page[0].Title != "Item 4 (edited)" || page[0].ExtractedContent != "<p>full</p>" {
+		t.Errorf("expected edited item first, got %+v", page[0])
+	}
+	if page[1].GUID != "guid-3" || !page[1].Seen || page[1].Author != "Jane" {
+		t.Errorf("expected seen guid-3 second, got %+v", page[1])
+	}
+	if page[1].PublishedAt == nil || !page[1].PublishedAt.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("published time not round-tripped: %v", page[1].PublishedAt)
+	}
+
+	page, err = repo.ListItemsForFeed(ctx, feed.ID, 10, 4)
+	if err != nil {
+		t.Fatalf("failed to list items: %v", err)
+	}
+	if len(page) != 1 || page[0].GUID != "guid-0" || page[0].Seen {
+		t.Errorf("expected last page to hold unseen guid-0, got %+v", page)
+	}
+
+	// Items are removed with their feed.
+	if err := repo.DeleteFeed(ctx, feed.ID); err != nil {
+		t.Fatalf("failed to delete feed: %v", err)
+	}
+	total, _ = repo.CountItemsForFeed(ctx, feed.ID)
+	if total != 0 {
+		t.Errorf("expected items to cascade on feed delete, got %d", total)
+	}
+}
+
+func TestWebSubSubscriptions(t *testing.T) {
+	_, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	feed := &types.Feed{Title: "Push Feed", URL: "https://push.com/feed", NextPollAt: time.Now()}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	_, err := repo.GetWebSubSubscription(ctx, feed.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	sub := &types.WebSubSubscription{
+		FeedID: feed.ID, Hub: "https://hub.com", Topic: feed.URL, Secret: "s3cret",
+		State: types.WebSubPending, LeaseSeconds: 3600,
+	}
+	if err := repo.SaveWebSubSubscription(ctx, sub); err != nil {
+		t.Fatalf("failed to save subscription: %v", err)
+	}
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	sub.State = types.WebSubActive
+	sub.LeaseExpiresAt = &expires
+	if err := repo.SaveWebSubSubscription(ctx, sub); err != nil {
+		t.Fatalf("failed to update subscription: %v", err)
+	}
+
+	got, err := repo.GetWebSubSubscription(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to get subscription: %v", err)
+	}
+	if got.State != types.WebSubActive || got.Secret != "s3cret" || got.LeaseExpiresAt == nil || !got.LeaseExpiresAt.Equal(expires) {
+		t.Errorf("subscription not persisted correctly: %+v", got)
+	}
+
+	due, err := repo.ListWebSubRenewals(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("failed to list renewals: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("expected no renewals due yet, got %d", len(due))
+	}
+	due, _ = repo.ListWebSubRenewals(ctx, expires.Add(time.Minute))
+	if len(due) != 1 || due[0].FeedID != feed.ID {
+		t.Errorf("expected subscription to be due for renewal, got %+v", due)
+	}
+
+	if err := repo.DeleteFeed(ctx, feed.ID); err != nil {
+		t.Fatalf("failed to delete feed: %v", err)
+	}
+	_, err = repo.GetWebSubSubscription(ctx, feed.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected subscription to cascade on feed delete, got %v", err)
+	}
+}
+
+func TestFilterRules(t *testing.T) {
+	_, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	user := &types.User{Email: "filter@test.com"}
+	feed := &types.Feed{Title: "Filter Feed", URL: "https://filter.com/feed", NextPollAt: time.Now()}
+	_ = repo.CreateUser(ctx, user)
+	_ = repo.CreateFeed(ctx, feed)
+
+	rule := &types.FilterRule{
+		UserID: user.ID, FeedID: feed.ID,
+		Action: types.FilterInclude, Field: types.FilterFieldTitle, Match: types.FilterMatchSubstring, Pattern: "widget",
+	}
+	if err := repo.CreateFilterRule(ctx, rule); err == nil {
+		t.Errorf("expected rule without a subscription to be rejected")
+	}
+
+	if err := repo.Subscribe(ctx, user.ID, feed.ID); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := repo.CreateFilterRule(ctx, rule); err != nil {
+		t.Fatalf("failed to create filter rule: %v", err)
+	}
+	if rule.ID == 0 || rule.CreatedAt.IsZero() {
+		t.Errorf("expected id and timestamps to be populated, got %+v", rule)
+	}
+
+	rule.Action = types.FilterExclude
+	rule.Match = types.FilterMatchRegex
+	rule.Pattern = "^Ad:"
+	if err := repo.UpdateFilterRule(ctx, rule); err != nil {
+		t.Fatalf("failed to update filter rule: %v", err)
+	}
+	got, err := repo.GetFilterRule(ctx, rule.ID)
+	if err != nil {
+		t.Fatalf("failed to get filter rule: %v", err)
+	}
+	if got.Action != types.FilterExclude || got.Match != types.FilterMatchRegex || got.Pattern != "^Ad:" || got.Field != types.FilterFieldTitle {
+		t.Errorf("filter rule not updated correctly: %+v", got)
+	}
+
+	second := &types.FilterRule{
+		UserID: user.ID, FeedID: feed.ID,
+		Action: types.FilterInclude, Field: types.FilterFieldCategory, Match: types.FilterMatchSubstring, Pattern: "go",
+	}
+	_ = repo.CreateFilterRule(ctx, second)
+
+	rules, err := repo.ListFilterRules(ctx, user.ID, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to list filter rules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].ID != rule.ID || rules[1].ID != second.ID {
+		t.Errorf("expected rules in creation order, got %+v", rules)
+	}
+	byFeed, _ := repo.ListFilterRulesForFeed(ctx, feed.ID)
+	if len(byFeed) != 2 {
+		t.Errorf("expected 2 rules for feed, got %d", len(byFeed))
+	}
+
+	if err := repo.DeleteFilterRule(ctx, second.ID); err != nil {
+		t.Fatalf("failed to delete filter rule: %v", err)
+	}
+	if err := repo.DeleteFilterRule(ctx, second.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows deleting a missing rule, got %v", err)
+	}
+	if err := repo.UpdateFilterRule(ctx, second); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows updating a missing rule, got %v", err)
+	}
+
+	// Rules go away with the subscription.
+	if err := repo.Unsubscribe(ctx, user.ID, feed.ID); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	if _, err := repo.GetFilterRule(ctx, rule.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected rule to cascade on unsubscribe, got %v", err)
+	}
+}
+
+func TestFeedRequestOptions(t *testing.T) {
+	db, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	feed := &types.Feed{Title: "Private Feed", URL: "https://gitlab.test/activity.atom", NextPollAt: time.Now()}
+	other := &types.Feed{Title: "Other Feed", URL: "https://other.test/feed", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.CreateFeed(ctx, other)
+
+	opts := &types.RequestOptions{
+		UserAgent:   "Custom/1.0",
+		Headers:     map[string]string{"PRIVATE-TOKEN": "glpat-secret"},
+		Cookies:     map[string]string{"session": "cookie-secret"},
+		Username:    "reader",
+		Password:    "hunter2",
+		BearerToken: "bearer-secret",
+	}
+	if err := repo.SetFeedRequestOptions(ctx, feed.ID, opts); !errors.Is(err, ErrNoSecrets) {
+		t.Fatalf("expected ErrNoSecrets without a secrets box, got %v", err)
+	}
+
+	box, err := secrets.NewBox(make([]byte, secrets.KeySize))
+	if err != nil {
+		t.Fatalf("failed to create secrets box: %v", err)
+	}
+	repo.SetSecrets(box)
+
+	got, err := repo.GetFeedRequestOptions(ctx, feed.ID)
+	if err != nil || got != nil {
+		t.Fatalf("expected no options for a new feed, got %+v, %v", got, err)
+	}
+
+	err = repo.WithTx(ctx, func(txRepo *Repository) error {
+		return txRepo.SetFeedRequestOptions(ctx, feed.ID, opts)
+	})
+	if err != nil {
+		t.Fatalf("failed to set request options: %v", err)
+	}
+	got, err = repo.GetFeedRequestOptions(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to get request options: %v", err)
+	}
+	if !reflect.DeepEqual(got, opts) {
+		t.Errorf("expected %+v, got %+v", opts, got)
+	}
+
+	// Values are encrypted at rest and bound to their feed
+	var sealed []byte
+	if err := db.QueryRowContext(ctx, `SELECT sealed FROM feed_request_options WHERE feed_id = ?`, feed.ID).Scan(&sealed); err != nil {
+		t.Fatalf("failed to read sealed options: %v", err)
+	}
+	for _, secret := range []string{"glpat-secret", "cookie-secret", "hunter2", "bearer-secret"} {
+		if strings.Contains(string(sealed), secret) {
+			t.Errorf("stored options contain plaintext %q", secret)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO feed_request_options (feed_id, sealed) VALUES (?, ?)`, other.ID, sealed); err != nil {
+		t.Fatalf("failed to copy sealed options: %v", err)
+	}
+	if _, err := repo.GetFeedRequestOptions(ctx, other.ID); !errors.Is(err, secrets.ErrDecrypt) {
+		t.Errorf("expected options copied to another feed not to open, got %v", err)
+	}
+
+	if err := repo.SetFeedRequestOptions(ctx, feed.ID, &types.RequestOptions{}); err != nil {
+		t.Fatalf("failed to clear request options: %v", err)
+	}
+	if got, _ := repo.GetFeedRequestOptions(ctx, feed.ID); got != nil {
+		t.Errorf("expected cleared options, got %+v", got)
+	}
+
+	// Options go with their feed
+	if err := repo.DeleteFeed(ctx, other.ID); err != nil {
+		t.Fatalf("failed to delete feed: %v", err)
+	}
+	var count int
+	_ = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM feed_request_options`).Scan(&count)
+	if count != 0 {
+		t.Errorf("expected options to be deleted with their feed, %d rows remain", count)
+	}
+}
+
+func TestChannelOperations(t *testing.T) {
+	db, repo := setupTestDB(t)
+	ctx := context.Background()
+	box, err := secrets.NewBox(make([]byte, secrets.KeySize))
+	if err != nil {
+		t.Fatalf("failed to create secrets box: %v", err)
+	}
+	repo.SetSecrets(box)
+
+	user := &types.User{Email: "channels@test.com"}
+	other := &types.User{Email: "other@test.com"}
+	feed := &types.Feed{Title: "Channel Feed", URL: "https://channel.com/feed", NextPollAt: time.Now()}
+	_ = repo.CreateUser(ctx, user)
+	_ = repo.CreateUser(ctx, other)
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+
+	hook := &types.Channel{UserID: user.ID, Kind: types.ChannelWebhook, Name: "Hook", Target: "https://hooks.test/a", Token: "secret", Enabled: true}
+	if err := repo.CreateChannel(ctx, hook); err != nil {
+		t.Fatalf("failed to create channel: %v", err)
+	}
+	if hook.ID == 0 || !hook.HasToken || hook.CreatedAt.IsZero() {
+		t.Errorf("expected id, has_token and timestamps to be populated, got %+v", hook)
+	}
+	var plaintext string
+	var sealed []byte
+	if err := db.QueryRowContext(ctx, `SELECT token, sealed_token FROM channels WHERE id = ?`, hook.ID).Scan(&plaintext, &sealed); err != nil {
+		t.Fatalf("failed to read channel row: %v", err)
+	}
+	if plaintext != "" || len(sealed) == 0 || bytes.Contains(sealed, []byte("secret")) {
+		t.Errorf("expected the token stored sealed, got %q and %x", plaintext, sealed)
+	}
+	if got, err := repo.GetChannel(ctx, hook.ID); err != nil || got.Token != "secret" || !got.HasToken {
+		t.Errorf("expected the token opened on read, got %+v, %v", got, err)
+	}
+	ntfy := &types.Channel{UserID: user.ID, Kind: types.ChannelNtfy, Target: "https://ntfy.test/topic"}
+	_ = repo.CreateChannel(ctx, ntfy)
+	_ = repo.CreateChannel(ctx, &types.Channel{UserID: other.ID, Kind: types.ChannelEmail, Target: "x@test.com", Enabled: true})
+
+	got, err := repo.GetChannel(ctx, ntfy.ID)
+	if err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	if got.Enabled || got.HasToken || got.Kind != types.ChannelNtfy {
+		t.Errorf("unexpected channel %+v", got)
+	}
+
+	got.Enabled = true
+	got.Token = "tk"
+	got.Name = "Phone"
+	if err := repo.UpdateChannel(ctx, got); err != nil {
+		t.Fatalf("failed to update channel: %v", err)
+	}
+	got, _ = repo.GetChannel(ctx, ntfy.ID)
+	if !got.Enabled || got.Token != "tk" || got.Name != "Phone" {
+		t.Errorf("channel not updated correctly: %+v", got)
+	}
+
+	// Tokens stored in plaintext before tokens were sealed are sealed on request
+	if _, err := db.ExecContext(ctx, `UPDATE channels SET token = 'legacy', sealed_token = NULL WHERE id = ?`, ntfy.ID); err != nil {
+		t.Fatalf("failed to store a plaintext token: %v", err)
+	}
+	if got, _ = repo.GetChannel(ctx, ntfy.ID); got.Token != "legacy" {
+		t.Errorf("expected a plaintext token to be read as is, got %q", got.Token)
+	}
+	if n, err := repo.SealChannelTokens(ctx); err != nil || n != 1 {
+		t.Fatalf("expected 1 token sealed, got %d, %v", n, err)
+	}
+	if got, _ = repo.GetChannel(ctx, ntfy.ID); got.Token != "legacy" {
+		t.Errorf("expected the sealed token to read back, got %q", got.Token)
+	}
+	if n, _ := repo.SealChannelTokens(ctx); n != 0 {
+		t.Errorf("expected nothing left to seal, got %d", n)
+	}
+	repo.SetSecrets(nil)
+	if _, err := repo.GetChannel(ctx, ntfy.ID); !errors.Is(err, ErrNoSecrets) {
+		t.Errorf("expected ErrNoSecrets reading a sealed token without a box, got %v", err)
+	}
+	repo.SetSecrets(box)
+
+	channels, err := repo.ListChannelsForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list channels: %v", err)
+	}
+	if len(channels) != 2 || channels[0].ID != hook.ID {
+		t.Errorf("expected the user's 2 channels in creation order, got %+v", channels)
+	}
+	byFeed, _ := repo.ListChannelsForFeed(ctx, feed.ID)
+	if len(byFeed) != 2 {
+		t.Errorf("expected only subscribers' channels for the feed, got %d", len(byFeed))
+	}
+
+	// Queued deliveries go away with their channel.
+	item := &types.OutboxItem{Subject: "s", Body: "{}", Status: types.OutboxPending, NextAttemptAt: time.Now(), ChannelID: &hook.ID}
+	if err := repo.EnqueueOutboxItem(ctx, item); err != nil {
+		t.Fatalf("failed to enqueue channel item: %v", err)
+	}
+	fetched, _ := repo.GetOutboxItem(ctx, item.ID)
+	if fetched.ChannelID == nil || *fetched.ChannelID != hook.ID {
+		t.Errorf("expected channel_id %d, got %v", hook.ID, fetched.ChannelID)
+	}
+
+	if err := repo.DeleteChannel(ctx, hook.ID); err != nil {
+		t.Fatalf("failed to delete channel: %v", err)
+	}
+	if _, err := repo.GetOutboxItem(ctx, item.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected outbox item to cascade with its channel, got %v", err)
+	}
+	if err := repo.DeleteChannel(ctx, hook.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows deleting a missing channel, got %v", err)
+	}
+	if err := repo.UpdateChannel(ctx, hook); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows updating a missing channel, got %v", err)
+	}
+
+	// Channels go away with their user.
+	if err := repo.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if _, err := repo.GetChannel(ctx, ntfy.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected channel to cascade with its user, got %v", err)
+	}
+}
+
 func TestOutboxOperations(t *testing.T) {
 	_, repo := setupTestDB(t)
 	ctx := context.Background()
@@ -417,6 +956,113 @@ func TestOutboxOperations(t *testing.T) {
 	}
 }
 
+func TestDigestOperations(t *testing.T) {
+	_, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	feed := &types.Feed{Title: "Digest Feed", URL: "https://digest.com/feed", NextPollAt: time.Now()}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+	user := &types.User{Email: "digest@example.com"}
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := repo.Subscribe(ctx, user.ID, feed.ID); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	// New subscriptions default to immediate delivery.
+	sub, err := repo.GetSubscription(ctx, user.ID, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to get subscription: %v", err)
+	}
+	if sub.DeliveryMode != types.DeliveryImmediate || sub.NextDigestAt != nil || sub.UserEmail != user.Email {
+		t.Errorf("unexpected default subscription: %+v", sub)
+	}
+
+	digestSubs, err := repo.ListDigestSubscriptions(ctx)
+	if err != nil {
+		t.Fatalf("failed to list digest subscriptions: %v", err)
+	}
+	if len(digestSubs) != 0 {
+		t.Errorf("expected no digest subscriptions, got %d", len(digestSubs))
+	}
+
+	next := time.Now().Add(time.Hour).Truncate(time.Second)
+	sub.DeliveryMode = types.DeliveryWeekly
+	sub.DigestTime = "07:30"
+	sub.DigestWeekday = time.Saturday
+	sub.NextDigestAt = &next
+	if err := repo.UpdateSubscriptionDelivery(ctx, sub); err != nil {
+		t.Fatalf("failed to update delivery: %v", err)
+	}
+
+	settings, err := repo.ListSubscriptionSettingsForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list subscription settings: %v", err)
+	}
+	if len(settings) != 1 {
+		t.Fatalf("expected 1 subscription, got %d", len(settings))
+	}
+	got := settings[0]
+	if got.DeliveryMode != types.DeliveryWeekly || got.DigestTime != "07:30" || got.DigestWeekday != time.Saturday {
+		t.Errorf("delivery settings not persisted: %+v", got)
+	}
+	if got.NextDigestAt == nil || !got.NextDigestAt.Equal(next) {
+		t.Errorf("expected next digest at %v, got %v", next, got.NextDigestAt)
+	}
+
+	// Updating a missing subscription reports ErrNoRows.
+	err = repo.UpdateSubscriptionDelivery(ctx, &types.Subscription{UserID: user.ID, FeedID: 9999, DeliveryMode: types.DeliveryDaily})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	// Pending items
+	for _, title := range []string{"First", "Second"} {
+		if err := repo.EnqueueDigestItem(ctx, &types.DigestItem{UserID: user.ID, FeedID: feed.ID, Title: title, Body: "<p>" + title + "</p>"}); err != nil {
+			t.Fatalf("failed to enqueue digest item: %v", err)
+		}
+	}
+	items, err := repo.ListDigestItems(ctx, user.ID, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to list digest items: %v", err)
+	}
+	if len(items) != 2 || items[0].Title != "First" || items[1].FeedTitle != "Digest Feed" {
+		t.Fatalf("unexpected digest items: %+v", items)
+	}
+
+	// Switching back to immediate still surfaces the subscription while items are pending.
+	sub.DeliveryMode = types.DeliveryImmediate
+	sub.NextDigestAt = nil
+	if err := repo.UpdateSubscriptionDelivery(ctx, sub); err != nil {
+		t.Fatalf("failed to update delivery: %v", err)
+	}
+	digestSubs, _ = repo.ListDigestSubscriptions(ctx)
+	if len(digestSubs) != 1 {
+		t.Errorf("expected subscription with pending items to be listed, got %d", len(digestSubs))
+	}
+
+	if err := repo.DeleteDigestItems(ctx, []int64{items[0].ID, items[1].ID}); err != nil {
+		t.Fatalf("failed to delete digest items: %v", err)
+	}
+	digestSubs, _ = repo.ListDigestSubscriptions(ctx)
+	if len(digestSubs) != 0 {
+		t.Errorf("expected no digest subscriptions after flush, got %d", len(digestSubs))
+	}
+
+	// Unsubscribing drops any pending items with the subscription.
+	_ = repo.EnqueueDigestItem(ctx, &types.DigestItem{UserID: user.ID, FeedID: feed.ID, Title: "Orphan"})
+	if err := repo.Unsubscribe(ctx, user.ID, feed.ID); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	items, _ = repo.ListDigestItems(ctx, user.ID, feed.ID)
+	if len(items) != 0 {
+		t.Errorf("expected pending items to be removed on unsubscribe, got %d", len(items))
+	}
+}
+
 func TestTransactionRollback(t *testing.T) {
 	_, repo := setupTestDB(t)
 	ctx := context.Background()
@@ -442,3 +1088,57 @@ func TestTransactionRollback(t *testing.T) {
 		t.Errorf("expected user to be missing (rolled back), got err: %v", err)
 	}
 }
+
+func TestExtractionCache(t *testing.T) {
+	_, repo := setupTestDB(t)
+	ctx := context.Background()
+
+	if ex, err := repo.GetExtraction(ctx, "example.com/missing", types.StrategySelector, "article"); err != nil || ex != nil {
+		t.Fatalf("expected no extraction, got %v, %v", ex, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	put := func(key, content, etag string, fetched time.Time) {
+		t.Helper()
+		err := repo.PutExtraction(ctx, &types.Extraction{
+			Key: key, URL: "https://" + key, Strategy: types.StrategySelector, Selector: "article",
+			Content: content, ETag: etag, FetchedAt: fetched,
+		})
+		if err != nil {
+			t.Fatalf("failed to put extraction: %v", err)
+		}
+	}
+
+	put("example.com/a", "<p>old</p>", "", now)
+	put("example.com/a", "<p>new</p>", `"v2"`, now)
+	got, err := repo.GetExtraction(ctx, "example.com/a", types.StrategySelector, "article")
+	if err != nil || got == nil {
+		t.Fatalf("failed to get extraction: %v", err)
+	}
+	if got.Content != "<p>new</p>" || got.ETag != `"v2"` || got.Strategy != types.StrategySelector || got.Selector != "article" {
+		t.Errorf("unexpected extraction: %+v", got)
+	}
+	if !got.FetchedAt.Equal(now) {
+		t.Errorf("expected fetched_at %v, got %v", now, got.FetchedAt)
+	}
+
+	// Stale entries without validators go; the rest are trimmed oldest first
+	put("example.com/stale", "<p>stale</p>", "", now.Add(-48*time.Hour))
+	put("example.com/old", "<p>0123456789</p>", `"old"`, now.Add(-48*time.Hour))
+	put("example.com/b", "<p>0123456789</p>", "", now.Add(-time.Minute))
+
+	n, err := repo.PruneExtractions(ctx, now.Add(-24*time.Hour), 40)
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 extractions pruned, got %d", n)
+	}
+	for key, want := range map[string]bool{
+		"example.com/a": true, "example.com/b": true, "example.com/stale": false, "example.com/old": false,
+	} {
+		if ex, _ := repo.GetExtraction(ctx, key, types.StrategySelector, "article"); (ex != nil) != want {
+			t.Errorf("%s kept = %v, want %v", key, ex != nil, want)
+		}
+	}
+}
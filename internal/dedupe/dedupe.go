@@ -0,0 +1,137 @@
+// Package dedupe derives the keys rss2go uses to recognize items it has
+// already delivered, both within one feed and across a subscriber's feeds.
+package dedupe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"rss2go/internal/filter"
+	"rss2go/internal/types"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// trackingParams are query parameters that only identify a campaign or click,
+// and so are dropped from links before comparing them. Any utm_ parameter is
+// dropped as well.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"mkt_tok": true,
+	"ref_src": true,
+	"cmpid":   true,
+}
+
+// GUID returns an item's identity within its feed: its GUID, falling back to
+// its link and then its title. It is empty for items with none of these.
+func GUID(item *gofeed.Item, link string) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	if link != "" {
+		return link
+	}
+	return item.Title
+}
+
+// Key returns the key strategy uses to recognize an item that reappears
+// under a new GUID. It is empty for the GUID strategy, and for items that
+// lack what the strategy needs.
+func Key(strategy types.DedupeStrategy, item *gofeed.Item, link string) string {
+	switch strategy {
+	case types.DedupeLink:
+		return LinkKey(link)
+	case types.DedupeContent:
+		return ContentKey(item.Title, itemContent(item))
+	default:
+		return ""
+	}
+}
+
+// Fingerprint identifies an article across feeds: by its link, or by its
+// title and text when it has no link.
+func Fingerprint(item *gofeed.Item, link string) string {
+	if key := LinkKey(link); key != "" {
+		return key
+	}
+	return ContentKey(item.Title, itemContent(item))
+}
+
+// LinkKey normalizes a link for comparison. The scheme, a leading "www.",
+// default ports, the fragment, a trailing slash and tracking parameters are
+// dropped, and the remaining query parameters are sorted. It returns "" for
+// links without a host.
+func LinkKey(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for name := range query {
+		if TrackingParam(name) {
+			query.Del(name)
+		}
+	}
+
+	key := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+	return key
+}
+
+// TrackingParam reports whether a query parameter only identifies a
+// campaign or click, such as utm_source or fbclid.
+func TrackingParam(name string) bool {
+	lower := strings.ToLower(name)
+	return trackingParams[lower] || strings.HasPrefix(lower, "utm_")
+}
+
+// ContentKey hashes an item's title and the visible text of its content, so
+// markup and whitespace changes do not make it look new. It returns "" when
+// both are empty.
+func ContentKey(title, content string) string {
+	title = strings.ToLower(strings.Join(strings.Fields(title), " "))
+	text := strings.ToLower(filter.PlainText(content))
+	if title == "" && text == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(title + "\n" + text))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func itemContent(item *gofeed.Item) string {
+	if item.Content != "" {
+		return item.Content
+	}
+	return item.Description
+}
+
+// Valid reports whether strategy is empty, meaning GUID, or a known strategy.
+func Valid(strategy types.DedupeStrategy) bool {
+	switch strategy {
+	case "", types.DedupeGUID, types.DedupeLink, types.DedupeContent:
+		return true
+	default:
+		return false
+	}
+}
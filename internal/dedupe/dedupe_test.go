@@ -0,0 +1,95 @@
+package dedupe
+
+import (
+	"testing"
+
+	"rss2go/internal/types"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestLinkKey(t *testing.T) {
+	same := []string{
+		"https://www.example.com/posts/42/?utm_source=rss&utm_medium=feed",
+		"http://example.com/posts/42",
+		"https://EXAMPLE.com:443/posts/42#comments",
+		"https://example.com/posts/42?fbclid=abc&UTM_Campaign=x",
+	}
+	want := "example.com/posts/42"
+	for _, link := range same {
+		if got := LinkKey(link); got != want {
+			t.Errorf("LinkKey(%q) = %q, want %q", link, got, want)
+		}
+	}
+
+	// Meaningful parameters are kept, in a stable order
+	if got := LinkKey("https://example.com/read?p=2&id=7&gclid=x"); got != "example.com/read?id=7&p=2" {
+		t.Errorf("LinkKey kept the wrong parameters: %q", got)
+	}
+	if got := LinkKey("https://example.com:8443/a"); got != "example.com:8443/a" {
+		t.Errorf("LinkKey dropped a non-default port: %q", got)
+	}
+	if LinkKey("https://example.com/a") == LinkKey("https://example.com/b") {
+		t.Error("different paths share a key")
+	}
+	for _, link := range []string{"", "/relative/path", "not a url"} {
+		if got := LinkKey(link); got != "" {
+			t.Errorf("LinkKey(%q) = %q, want empty", link, got)
+		}
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	a := ContentKey("Release  Notes", "<p>Version <b>2.0</b> is out.</p>")
+	b := ContentKey("release notes", "<div>Version 2.0\n is out.</div>")
+	if a == "" || a != b {
+		t.Errorf("expected markup and whitespace changes to keep the key, got %q and %q", a, b)
+	}
+	if a == ContentKey("Release Notes", "<p>Version 2.1 is out.</p>") {
+		t.Error("expected a content change to change the key")
+	}
+	if got := ContentKey("", "<p> </p>"); got != "" {
+		t.Errorf("expected no key for an empty item, got %q", got)
+	}
+}
+
+func TestKeyAndGUID(t *testing.T) {
+	item := &gofeed.Item{Title: "Hello", Description: "<p>World</p>"}
+	link := "https://example.com/hello?utm_source=rss"
+
+	if got := GUID(item, link); got != link {
+		t.Errorf("GUID without a GUID = %q, want the link", got)
+	}
+	if got := GUID(item, ""); got != "Hello" {
+		t.Errorf("GUID without a GUID or link = %q, want the title", got)
+	}
+	item.GUID = "tag:example.com,2026:1"
+	if got := GUID(item, link); got != item.GUID {
+		t.Errorf("GUID = %q, want %q", got, item.GUID)
+	}
+
+	if got := Key(types.DedupeGUID, item, link); got != "" {
+		t.Errorf("Key(guid) = %q, want empty", got)
+	}
+	if got := Key("", item, link); got != "" {
+		t.Errorf("Key(\"\") = %q, want empty", got)
+	}
+	if got := Key(types.DedupeLink, item, link); got != "example.com/hello" {
+		t.Errorf("Key(link) = %q", got)
+	}
+	if got := Key(types.DedupeContent, item, link); got != ContentKey("Hello", "<p>World</p>") {
+		t.Errorf("Key(content) = %q", got)
+	}
+
+	// Items without a link are fingerprinted by their content
+	if got := Fingerprint(item, ""); got != ContentKey("Hello", "<p>World</p>") {
+		t.Errorf("Fingerprint without a link = %q", got)
+	}
+	if got := Fingerprint(item, link); got != "example.com/hello" {
+		t.Errorf("Fingerprint = %q", got)
+	}
+
+	if !Valid("") || !Valid(types.DedupeContent) || Valid("fuzzy") {
+		t.Error("Valid accepted or rejected the wrong strategies")
+	}
+}
@@ -0,0 +1,298 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"rss2go/internal/database"
+	"rss2go/internal/magiclink"
+	"rss2go/internal/notifier"
+	"rss2go/internal/outbox"
+	"rss2go/internal/types"
+)
+
+// Config configures the digest worker.
+type Config struct {
+	PollInterval time.Duration
+	// Location is the time zone daily and weekly digest times are interpreted in.
+	Location *time.Location
+	// Now returns the current time. Tests substitute a fake clock.
+	Now func() time.Time
+	// Links, when set, adds a link that unsubscribes from every feed in the digest.
+	Links *magiclink.Links
+}
+
+// Worker periodically batches pending digest items into one email per user and
+// hands them to the durable outbox.
+type Worker struct {
+	repo         *database.Repository
+	cfg          Config
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+	log          *slog.Logger
+}
+
+// NewWorker creates a new digest Worker.
+func NewWorker(repo *database.Repository, cfg Config, log *slog.Logger) *Worker {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Minute
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if log == nil {
+		log = slog.Default().With("component", "digest")
+	}
+
+	return &Worker{
+		repo:       repo,
+		cfg:        cfg,
+		shutdownCh: make(chan struct{}),
+		log:        log,
+	}
+}
+
+// Start runs the digest loop. It blocks until context is cancelled or Stop is called.
+func (w *Worker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			w.log.Error("Digest processing error", "err", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			w.Stop()
+			return ctx.Err()
+		case <-w.shutdownCh:
+			return nil
+		}
+	}
+}
+
+// Stop signals the digest loop to exit.
+func (w *Worker) Stop() {
+	w.shutdownOnce.Do(func() {
+		close(w.shutdownCh)
+	})
+}
+
+// RunOnce schedules newly configured digest subscriptions and sends every digest
+// that is due at the worker's current clock time.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	now := w.cfg.Now()
+
+	subs, err := w.repo.ListDigestSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("digest: list subscriptions: %w", err)
+	}
+
+	// Group due subscriptions by user so each user receives a single email.
+	due := make(map[int64][]*types.Subscription)
+	var userOrder []int64
+	for _, sub := range subs {
+		if sub.DeliveryMode != types.DeliveryImmediate && sub.NextDigestAt == nil {
+			next := NextRun(sub, now, w.cfg.Location)
+			sub.NextDigestAt = &next
+			if err := w.repo.UpdateSubscriptionDelivery(ctx, sub); err != nil {
+				return fmt.Errorf("digest: schedule subscription: %w", err)
+			}
+			w.log.Debug("Scheduled digest subscription", "user_id", sub.UserID, "feed_id", sub.FeedID, "next_digest_at", next)
+			continue
+		}
+
+		// Items left behind by a subscription switched back to immediate are flushed right away.
+		if sub.DeliveryMode != types.DeliveryImmediate && sub.NextDigestAt.After(now) {
+			continue
+		}
+
+		if _, ok := due[sub.UserID]; !ok {
+			userOrder = append(userOrder, sub.UserID)
+		}
+		due[sub.UserID] = append(due[sub.UserID], sub)
+	}
+
+	for _, userID := range userOrder {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.sendDigest(ctx, due[userID], now); err != nil {
+			w.log.Error("Failed to send digest", "user_id", userID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+// sendDigest renders and enqueues one digest email for a single user's due
+// subscriptions, then clears the sent items and advances each schedule.
+func (w *Worker) sendDigest(ctx context.Context, subs []*types.Subscription, now time.Time) error {
+	var items []*types.DigestItem
+	for _, sub := range subs {
+		subItems, err := w.repo.ListDigestItems(ctx, sub.UserID, sub.FeedID)
+		if err != nil {
+			return fmt.Errorf("digest: list items: %w", err)
+		}
+		items = append(items, subItems...)
+	}
+
+	channels, err := w.repo.ListChannelsForUser(ctx, subs[0].UserID)
+	if err != nil {
+		return fmt.Errorf("digest: list channels: %w", err)
+	}
+
+	return w.repo.WithTx(ctx, func(txRepo *database.Repository) error {
+		if len(items) > 0 {
+			ids := make([]int64, 0, len(items))
+			var feedIDs []int64
+			for _, item := range items {
+				ids = append(ids, item.ID)
+				if !slices.Contains(feedIDs, item.FeedID) {
+					feedIDs = append(feedIDs, item.FeedID)
+				}
+			}
+
+			mode := subs[0].DeliveryMode
+			for _, sub := range subs[1:] {
+				if sub.DeliveryMode != mode {
+					mode = ""
+				}
+			}
+
+			msg := &notifier.Message{
+				Subject:        Subject(mode, len(items)),
+				HTML:           Render(items),
+				UnsubscribeURL: w.cfg.Links.Unsubscribe(subs[0].UserEmail, feedIDs...),
+			}
+			if err := outbox.Enqueue(ctx, txRepo, subs[0].UserEmail, channels, msg, now); err != nil {
+				return err
+			}
+			if err := txRepo.DeleteDigestItems(ctx, ids); err != nil {
+				return err
+			}
+			w.log.Info("Queued digest email", "user_id", subs[0].UserID, "items", len(items))
+		}
+
+		for _, sub := range subs {
+			if sub.DeliveryMode == types.DeliveryImmediate {
+				continue
+			}
+			next := NextRun(sub, now, w.cfg.Location)
+			sub.NextDigestAt = &next
+			if err := txRepo.UpdateSubscriptionDelivery(ctx, sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ParseDigestTime parses a local "HH:MM" digest time.
+func ParseDigestTime(val string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(val))
+	if err != nil {
+		return 0, 0, fmt.Errorf("digest: invalid digest time %q (expected HH:MM)", val)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// NextRun returns the first digest time for sub strictly after the given instant.
+func NextRun(sub *types.Subscription, after time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.Local
+	}
+	local := after.In(loc)
+
+	switch sub.DeliveryMode {
+	case types.DeliveryHourly:
+		// Truncate works on absolute time, so it would land on the half hour
+		// in zones such as Asia/Kolkata; round on the wall clock instead.
+		return time.Date(local.Year(), local.Month(), local.Day(), local.Hour()+1, 0, 0, 0, loc)
+
+	case types.DeliveryWeekly:
+		hour, minute, err := ParseDigestTime(sub.DigestTime)
+		if err != nil {
+			hour, minute = 8, 0
+		}
+		days := (int(sub.DigestWeekday) - int(local.Weekday()) + 7) % 7
+		next := time.Date(local.Year(), local.Month(), local.Day()+days, hour, minute, 0, 0, loc)
+		if !next.After(local) {
+			next = next.AddDate(0, 0, 7)
+		}
+		return next
+
+	default: // types.DeliveryDaily
+		hour, minute, err := ParseDigestTime(sub.DigestTime)
+		if err != nil {
+			hour, minute = 8, 0
+		}
+		next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+		if !next.After(local) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+}
+
+// Subject builds the digest email subject line.
+func Subject(mode types.DeliveryMode, count int) string {
+	label := "Digest"
+	switch mode {
+	case types.DeliveryHourly:
+		label = "Hourly digest"
+	case types.DeliveryDaily:
+		label = "Daily digest"
+	case types.DeliveryWeekly:
+		label = "Weekly digest"
+	}
+	noun := "items"
+	if count == 1 {
+		noun = "item"
+	}
+	return fmt.Sprintf("[rss2go] %s: %d new %s", label, count, noun)
+}
+
+// Render builds the digest HTML body: a table of contents grouped by feed
+// followed by each item's pre-rendered body.
+func Render(items []*types.DigestItem) string {
+	// Preserve first-seen feed order while grouping.
+	var feedOrder []int64
+	groups := make(map[int64][]*types.DigestItem)
+	for _, item := range items {
+		if _, ok := groups[item.FeedID]; !ok {
+			feedOrder = append(feedOrder, item.FeedID)
+		}
+		groups[item.FeedID] = append(groups[item.FeedID], item)
+	}
+
+	var toc, body strings.Builder
+	toc.WriteString("<h1>Table of Contents</h1>")
+	for _, feedID := range feedOrder {
+		group := groups[feedID]
+		feedTitle := html.EscapeString(group[0].FeedTitle)
+
+		_, _ = fmt.Fprintf(&toc, "<h3>%s</h3><ul>", feedTitle)
+		_, _ = fmt.Fprintf(&body, "<hr><h2>%s</h2>", feedTitle)
+		for _, item := range group {
+			anchor := fmt.Sprintf("item-%d", item.ID)
+			_, _ = fmt.Fprintf(&toc, "<li><a href=\"#%s\">%s</a></li>", anchor, html.EscapeString(item.Title))
+			_, _ = fmt.Fprintf(&body, "<div id=\"%s\">%s</div>", anchor, item.Body)
+		}
+		toc.WriteString("</ul>")
+	}
+
+	return toc.String() + body.String()
+}
@@ -0,0 +1,255 @@
+package digest
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // the tests use named zones
+
+	"rss2go/internal/database"
+	"rss2go/internal/magiclink"
+	"rss2go/internal/types"
+)
+
+// fakeClock is a manually advanced clock for deterministic digest scheduling.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func setupTestDB(t *testing.T) *database.Repository {
+	t.Helper()
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return database.NewRepository(db)
+}
+
+func TestNextRun(t *testing.T) {
+	loc := time.UTC
+	// Wednesday 2026-06-10 09:30 UTC
+	base := time.Date(2026, 6, 10, 9, 30, 0, 0, loc)
+
+	tests := []struct {
+		name string
+		sub  types.Subscription
+		want time.Time
+	}{
+		{"hourly", types.Subscription{DeliveryMode: types.DeliveryHourly}, time.Date(2026, 6, 10, 10, 0, 0, 0, loc)},
+		{"daily later today", types.Subscription{DeliveryMode: types.DeliveryDaily, DigestTime: "18:00"}, time.Date(2026, 6, 10, 18, 0, 0, 0, loc)},
+		{"daily tomorrow", types.Subscription{DeliveryMode: types.DeliveryDaily, DigestTime: "08:00"}, time.Date(2026, 6, 11, 8, 0, 0, 0, loc)},
+		{"daily exact time rolls over", types.Subscription{DeliveryMode: types.DeliveryDaily, DigestTime: "09:30"}, time.Date(2026, 6, 11, 9, 30, 0, 0, loc)},
+		{"weekly later this week", types.Subscription{DeliveryMode: types.DeliveryWeekly, DigestTime: "07:00", DigestWeekday: time.Friday}, time.Date(2026, 6, 12, 7, 0, 0, 0, loc)},
+		{"weekly same day passed", types.Subscription{DeliveryMode: types.DeliveryWeekly, DigestTime: "07:00", DigestWeekday: time.Wednesday}, time.Date(2026, 6, 17, 7, 0, 0, 0, loc)},
+		{"weekly invalid time falls back", types.Subscription{DeliveryMode: types.DeliveryWeekly, DigestTime: "bogus", DigestWeekday: time.Monday}, time.Date(2026, 6, 15, 8, 0, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NextRun(&tt.sub, base, loc)
+			if !got.Equal(tt.want) {
+				t.Errorf("NextRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextRunHourlyHalfHourZone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	sub := &types.Subscription{DeliveryMode: types.DeliveryHourly}
+
+	// 09:40 IST is 04:10 UTC; the next run is 10:00 IST, not 10:30.
+	got := NextRun(sub, time.Date(2026, 6, 10, 9, 40, 0, 0, loc), loc)
+	if want := time.Date(2026, 6, 10, 10, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("NextRun() = %v, want %v", got, want)
+	}
+	// 09:10 IST is 03:40 UTC; the next run is still 10:00 IST.
+	got = NextRun(sub, time.Date(2026, 6, 10, 9, 10, 0, 0, loc), loc)
+	if want := time.Date(2026, 6, 10, 10, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("NextRun() = %v, want %v", got, want)
+	}
+}
+
+func TestParseDigestTime(t *testing.T) {
+	h, m, err := ParseDigestTime("07:45")
+	if err != nil || h != 7 || m != 45 {
+		t.Errorf("ParseDigestTime(07:45) = %d, %d, %v", h, m, err)
+	}
+	for _, bad := range []string{"", "25:00", "7pm", "12:60"} {
+		if _, _, err := ParseDigestTime(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestWorkerDailyDigest(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	clock := &fakeClock{now: time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC)}
+	keys := magiclink.NewKeyring(nil, magiclink.Config{Now: clock.Now}, slog.New(slog.DiscardHandler))
+	_ = keys.Load(ctx)
+	links := magiclink.NewLinks("https://rss.example.com", keys)
+	w := NewWorker(repo, Config{Location: time.UTC, Now: clock.Now, Links: links}, slog.New(slog.DiscardHandler))
+
+	user := &types.User{Email: "digest@test.com"}
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	feedA := &types.Feed{Title: "Feed A", URL: "http://a.url/rss", NextPollAt: clock.now}
+	feedB := &types.Feed{Title: "Feed B", URL: "http://b.url/rss", NextPollAt: clock.now}
+	for _, f := range []*types.Feed{feedA, feedB} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+		if err := repo.Subscribe(ctx, user.ID, f.ID); err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		if err := repo.UpdateSubscriptionDelivery(ctx, &types.Subscription{
+			UserID: user.ID, FeedID: f.ID, DeliveryMode: types.DeliveryDaily, DigestTime: "08:00",
+		}); err != nil {
+			t.Fatalf("failed to set delivery mode: %v", err)
+		}
+	}
+
+	// First run only schedules the subscriptions.
+	if err := w.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	sub, err := repo.GetSubscription(ctx, user.ID, feedA.ID)
+	if err != nil {
+		t.Fatalf("failed to get subscription: %v", err)
+	}
+	wantNext := time.Date(2026, 6, 10, 8, 0, 0, 0, time.UTC)
+	if sub.NextDigestAt == nil || !sub.NextDigestAt.Equal(wantNext) {
+		t.Fatalf("expected next digest at %v, got %v", wantNext, sub.NextDigestAt)
+	}
+
+	for i, f := range []*types.Feed{feedA, feedA, feedB} {
+		item := &types.DigestItem{UserID: user.ID, FeedID: f.ID, Title: f.Title + " item", Link: "http://x", Body: "<p>body</p>"}
+		if i == 1 {
+			item.Title = "Second <A> item"
+		}
+		if err := repo.EnqueueDigestItem(ctx, item); err != nil {
+			t.Fatalf("failed to enqueue digest item: %v", err)
+		}
+	}
+
+	// Before the digest time nothing is sent.
+	clock.Advance(time.Hour)
+	if err := w.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if items, _ := repo.ListOutboxItems(ctx, 10); len(items) != 0 {
+		t.Fatalf("expected no outbox items before digest time, got %d", len(items))
+	}
+
+	// At the digest time a single email with every item goes out.
+	clock.Advance(time.Hour)
+	if err := w.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	outbox, err := repo.ListOutboxItems(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to list outbox: %v", err)
+	}
+	if len(outbox) != 1 {
+		t.Fatalf("expected exactly 1 digest email, got %d", len(outbox))
+	}
+	msg := outbox[0]
+	if msg.Subject != "[rss2go] Daily digest: 3 new items" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if len(msg.Recipients) != 1 || msg.Recipients[0] != "digest@test.com" {
+		t.Errorf("unexpected recipients %v", msg.Recipients)
+	}
+	if !strings.Contains(msg.Body, "Table of Contents") || !strings.Contains(msg.Body, "<h3>Feed A</h3>") || !strings.Contains(msg.Body, "<h3>Feed B</h3>") {
+		t.Errorf("expected table of contents grouped by feed, got %q", msg.Body)
+	}
+	if !strings.Contains(msg.Body, "Second &lt;A&gt; item") {
+		t.Errorf("expected escaped item titles in table of contents, got %q", msg.Body)
+	}
+	if want := links.Unsubscribe("digest@test.com", feedA.ID, feedB.ID); msg.ListUnsubscribe != want {
+		t.Errorf("expected digest to unsubscribe from every feed it covers, got %q", msg.ListUnsubscribe)
+	}
+
+	remaining, _ := repo.ListDigestItems(ctx, user.ID, feedA.ID)
+	if len(remaining) != 0 {
+		t.Errorf("expected digest items to be cleared, got %d", len(remaining))
+	}
+
+	sub, _ = repo.GetSubscription(ctx, user.ID, feedA.ID)
+	wantNext = time.Date(2026, 6, 11, 8, 0, 0, 0, time.UTC)
+	if sub.NextDigestAt == nil || !sub.NextDigestAt.Equal(wantNext) {
+		t.Errorf("expected schedule to advance to %v, got %v", wantNext, sub.NextDigestAt)
+	}
+
+	// A due digest with no pending items only advances the schedule.
+	clock.Advance(24 * time.Hour)
+	if err := w.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if items, _ := repo.ListOutboxItems(ctx, 10); len(items) != 1 {
+		t.Errorf("expected no additional email for an empty digest, got %d total", len(items))
+	}
+}
+
+func TestWorkerFlushesItemsAfterSwitchToImmediate(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	clock := &fakeClock{now: time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC)}
+	w := NewWorker(repo, Config{Location: time.UTC, Now: clock.Now}, slog.New(slog.DiscardHandler))
+
+	user := &types.User{Email: "switch@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	feed := &types.Feed{Title: "Feed", URL: "http://feed.url/rss", NextPollAt: clock.now}
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+
+	if err := repo.EnqueueDigestItem(ctx, &types.DigestItem{UserID: user.ID, FeedID: feed.ID, Title: "Held", Body: "<p>held</p>"}); err != nil {
+		t.Fatalf("failed to enqueue digest item: %v", err)
+	}
+
+	if err := w.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	outbox, _ := repo.ListOutboxItems(ctx, 10)
+	if len(outbox) != 1 || outbox[0].Subject != "[rss2go] Digest: 1 new item" {
+		t.Fatalf("expected leftover items to be flushed in one email, got %+v", outbox)
+	}
+}
+
+func TestWorkerStartStop(t *testing.T) {
+	repo := setupTestDB(t)
+	w := NewWorker(repo, Config{PollInterval: time.Millisecond}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	// Stop after Start returned must be a no-op.
+	w.Stop()
+}
@@ -3,24 +3,63 @@ package extractor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"rss2go/internal/politeness"
+	"rss2go/internal/requestopts"
 	"rss2go/internal/types"
 
 	readability "codeberg.org/readeck/go-readability/v2"
 	"github.com/PuerkitoBio/goquery"
 )
 
+// ErrDisallowed is returned for articles that robots.txt asks crawlers not to fetch.
+var ErrDisallowed = errors.New("extractor: disallowed by robots.txt")
+
+const (
+	// pruneInterval is how often a cached extractor trims its cache.
+	pruneInterval = 10 * time.Minute
+	// maxPageBytes caps how much of an article page is read.
+	maxPageBytes = 10 << 20
+)
+
+// Cache persists extractions so that retries, rewinds and test crawls reuse
+// them instead of downloading and extracting an article again.
+type Cache interface {
+	// GetExtraction returns the extraction cached under key for strategy
+	// and selector, or nil.
+	GetExtraction(ctx context.Context, key string, strategy types.ExtractionStrategy, selector string) (*types.Extraction, error)
+	// PutExtraction stores an extraction under its Key, Strategy and Selector.
+	PutExtraction(ctx context.Context, ex *types.Extraction) error
+	// PruneExtractions drops stale extractions that cannot be revalidated,
+	// then the oldest until the rest fit in maxBytes.
+	PruneExtractions(ctx context.Context, staleBefore time.Time, maxBytes int64) (int64, error)
+}
+
+// CacheConfig bounds an extraction cache.
+type CacheConfig struct {
+	TTL      time.Duration // how long an extraction is reused without fetching the article
+	MaxBytes int64         // total extracted HTML kept
+}
+
 // Extractor manages fetching remote destination articles and extracting their primary content.
 type Extractor struct {
 	client *http.Client
 	log    *slog.Logger
+	robots *politeness.Robots
+
+	cache     Cache
+	cacheCfg  CacheConfig
+	lastPrune atomic.Int64 // UnixNano of the last prune
 }
 
 // NewExtractor creates a new Extractor instance.
@@ -40,6 +79,20 @@ func NewExtractor(client *http.Client, log ...*slog.Logger) *Extractor {
 	return &Extractor{client: client, log: l}
 }
 
+// SetRobots makes the extractor skip articles that robots disallows. With
+// none set, robots.txt is not consulted.
+func (e *Extractor) SetRobots(robots *politeness.Robots) {
+	e.robots = robots
+}
+
+// SetCache makes the extractor reuse extractions stored in cache. An
+// extraction younger than cfg.TTL is returned without a fetch; an older one
+// is revalidated with a conditional GET when the article sent validators.
+func (e *Extractor) SetCache(cache Cache, cfg CacheConfig) {
+	e.cache = cache
+	e.cacheCfg = cfg
+}
+
 // SanitizeURL strips Basic Auth credentials (user:pass) and query/fragment parameters from raw URLs for safe logging.
 func SanitizeURL(rawURL string) string {
 	parsed, err := url.Parse(rawURL)
@@ -53,44 +106,271 @@ func SanitizeURL(rawURL string) string {
 }
 
 // Extract fetches the page at targetURL and extracts the content based on strategy.
-func (e *Extractor) Extract(ctx context.Context, targetURL string, strategy types.ExtractionStrategy, selector string) (string, error) {
+// The request carries opts, if any; see requestopts.ForLink for choosing them.
+func (e *Extractor) Extract(ctx context.Context, targetURL string, strategy types.ExtractionStrategy, selector string, opts *types.RequestOptions) (string, error) {
+	results, err := e.extractAll(ctx, targetURL, []types.ExtractionStrategy{strategy}, selector, nil, opts)
+	if err != nil {
+		return "", err
+	}
+	return results[0].content, results[0].err
+}
+
+// ExtractChain extracts the article at targetURL with every strategy in
+// chain, fetching the page at most once, and returns the candidate Pick
+// prefers. summary is the item's own content, which competes wherever the
+// chain lists StrategySummary. With paging set, the article's later pages
+// are fetched and stitched onto the first. Failed strategies are reported in
+// the error, which may accompany a result; the result is nil if nothing was
+// extracted.
+func (e *Extractor) ExtractChain(ctx context.Context, targetURL string, chain []types.ExtractionStrategy, selector, summary string, paging *Paging, opts *types.RequestOptions) (*Candidate, error) {
+	log := e.log
+	if log == nil {
+		log = slog.Default().With("component", "extractor")
+	}
+
+	var pageStrategies []types.ExtractionStrategy
+	for _, strategy := range chain {
+		if strategy != types.StrategySummary && !slices.Contains(pageStrategies, strategy) {
+			pageStrategies = append(pageStrategies, strategy)
+		}
+	}
+
+	var errs []error
+	extracted := make(map[types.ExtractionStrategy]string)
+	if len(pageStrategies) > 0 {
+		results, err := e.extractAll(ctx, targetURL, pageStrategies, selector, paging, opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for i, res := range results {
+			if res.err != nil {
+				errs = append(errs, res.err)
+			} else if res.content != "" {
+				extracted[pageStrategies[i]] = res.content
+			}
+		}
+	}
+
+	var candidates []Candidate
+	for _, strategy := range chain {
+		content := extracted[strategy]
+		if strategy == types.StrategySummary {
+			content = summary
+		}
+		if content == "" || slices.ContainsFunc(candidates, func(c Candidate) bool { return c.Strategy == strategy }) {
+			continue
+		}
+		q := Score(content)
+		log.Debug("Scored extraction candidate", "url", SanitizeURL(targetURL), "strategy", strategy, "score", q.Score,
+			"text_length", q.TextLength, "link_density", q.LinkDensity, "images", q.Images)
+		candidates = append(candidates, Candidate{Strategy: strategy, Content: content, Quality: q})
+	}
+
+	best := Pick(candidates, MinScore)
+	if best != nil {
+		log.Debug("Picked extraction strategy", "url", SanitizeURL(targetURL), "strategy", best.Strategy, "score", best.Quality.Score)
+	}
+	return best, errors.Join(errs...)
+}
+
+// extraction is the outcome of one strategy in extractAll.
+type extraction struct {
+	content string
+	err     error
+}
+
+// extractAll extracts the page at targetURL with each strategy, reusing
+// cached extractions and fetching the page at most once for the rest. The
+// error reports a failure to fetch the page; failures of single strategies
+// are returned with their results.
+func (e *Extractor) extractAll(ctx context.Context, targetURL string, strategies []types.ExtractionStrategy, selector string, paging *Paging, opts *types.RequestOptions) ([]extraction, error) {
 	log := e.log
 	if log == nil {
 		log = slog.Default().With("component", "extractor")
 	}
 
 	safeURL := SanitizeURL(targetURL)
-	log.Debug("Starting article extraction", "url", safeURL, "strategy", strategy, "selector", selector)
+	log.Debug("Starting article extraction", "url", safeURL, "strategies", strategies, "selector", selector)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	results := make([]extraction, len(strategies))
+	var key string
+	if e.cache != nil {
+		key = cacheKey(targetURL, paging, opts)
+	}
+	var pending []int
+	stale := make(map[int]*types.Extraction)
+	for i, strategy := range strategies {
+		if key != "" {
+			ex, err := e.cache.GetExtraction(ctx, key, strategy, selector)
+			if err != nil {
+				log.Warn("Failed to read extraction cache", "url", safeURL, "err", err)
+			} else if ex != nil {
+				if time.Since(ex.FetchedAt) < e.cacheCfg.TTL {
+					log.Debug("Reusing cached extraction", "url", safeURL, "strategy", strategy, "fetched_at", ex.FetchedAt)
+					results[i].content = ex.Content
+					continue
+				}
+				stale[i] = ex
+			}
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	validators := sharedValidators(pending, stale)
+	first, err := e.fetch(ctx, log, targetURL, opts, validators)
+	if err != nil {
+		return nil, err
+	}
+
+	if first.notModified {
+		log.Debug("Cached extraction still current", "url", safeURL)
+		for _, i := range pending {
+			ex := stale[i]
+			ex.FetchedAt = time.Now()
+			e.store(ctx, log, ex)
+			results[i].content = ex.Content
+		}
+		return results, nil
+	}
+
+	pages := []*page{first}
+	if paging != nil {
+		pages = append(pages, e.followPages(ctx, log, first, paging, opts)...)
+	}
+
+	for _, i := range pending {
+		extracted, err := e.extractPages(log, pages, strategies[i], selector)
+		results[i] = extraction{content: extracted, err: err}
+		if err != nil || key == "" {
+			continue
+		}
+		e.store(ctx, log, &types.Extraction{
+			Key:          key,
+			URL:          targetURL,
+			Strategy:     strategies[i],
+			Selector:     selector,
+			Content:      extracted,
+			ETag:         first.header.Get("ETag"),
+			LastModified: first.header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+		})
+	}
+	return results, nil
+}
+
+// page is a fetched article page.
+type page struct {
+	url         string
+	body        []byte
+	header      http.Header
+	notModified bool // the page still matches validators; body is empty
+}
+
+// fetch downloads the HTML page at pageURL, sending validators' ETag and
+// Last-Modified as conditions when validators is not nil.
+func (e *Extractor) fetch(ctx context.Context, log *slog.Logger, pageURL string, opts *types.RequestOptions, validators *types.Extraction) (*page, error) {
+	safeURL := SanitizeURL(pageURL)
+	if e.robots != nil && !e.robots.Allowed(ctx, pageURL) {
+		log.Debug("Article fetch disallowed by robots.txt", "url", safeURL)
+		return nil, ErrDisallowed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
 	if err != nil {
 		log.Debug("Failed creating HTTP request for article extraction", "url", safeURL, "err", err)
-		return "", fmt.Errorf("extractor: create request: %w", err)
+		return nil, fmt.Errorf("extractor: create request: %w", err)
 	}
 
 	req.Header.Set("User-Agent", "rss2go/1.0 (Full Article Extractor)")
+	requestopts.Apply(req, opts)
+	if validators != nil {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
 
 	start := time.Now()
-	resp, err := e.client.Do(req)
+	resp, err := requestopts.Client(e.client, opts).Do(req)
 	duration := time.Since(start)
 	if err != nil {
 		log.Debug("Article fetch failed", "url", safeURL, "duration", duration, "err", err)
-		return "", fmt.Errorf("extractor: fetch failed: %w", err)
+		return nil, fmt.Errorf("extractor: fetch failed: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusNotModified && validators != nil {
+		return &page{url: pageURL, header: resp.Header, notModified: true}, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		log.Debug("Article fetch non-200 HTTP status", "url", safeURL, "status", resp.StatusCode)
-		return "", fmt.Errorf("extractor: fetch returned HTTP status %d", resp.StatusCode)
+		return nil, fmt.Errorf("extractor: fetch returned HTTP status %d", resp.StatusCode)
 	}
 
 	contentType := resp.Header.Get("Content-Type")
 	if !strings.Contains(contentType, "text/html") && !strings.Contains(contentType, "application/xhtml+xml") {
 		log.Debug("Article content-type unsupported for extraction", "url", safeURL, "content_type", contentType)
-		return "", fmt.Errorf("extractor: unsupported content type %q", contentType)
+		return nil, fmt.Errorf("extractor: unsupported content type %q", contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		log.Debug("Article read failed", "url", safeURL, "err", err)
+		return nil, fmt.Errorf("extractor: read page: %w", err)
 	}
+	log.Debug("Fetched article page", "url", safeURL, "duration", duration, "bytes", len(body))
+	return &page{url: pageURL, body: body, header: resp.Header}, nil
+}
 
-	return e.ExtractFromReader(resp.Body, targetURL, strategy, selector)
+// sharedValidators returns the stale extraction whose validators can
+// revalidate every pending strategy at once, or nil if any pending strategy
+// has nothing cached or they were cached from different responses.
+func sharedValidators(pending []int, stale map[int]*types.Extraction) *types.Extraction {
+	var first *types.Extraction
+	for _, i := range pending {
+		ex := stale[i]
+		if ex == nil || (ex.ETag == "" && ex.LastModified == "") {
+			return nil
+		}
+		if first == nil {
+			first = ex
+		} else if ex.ETag != first.ETag || ex.LastModified != first.LastModified {
+			return nil
+		}
+	}
+	return first
+}
+
+// store writes an extraction to the cache and, every pruneInterval, trims
+// the cache to its limits. Failures are logged, since the extraction itself
+// succeeded.
+func (e *Extractor) store(ctx context.Context, log *slog.Logger, ex *types.Extraction) {
+	if err := e.cache.PutExtraction(ctx, ex); err != nil {
+		log.Warn("Failed to cache extraction", "url", SanitizeURL(ex.URL), "err", err)
+		return
+	}
+
+	// Claiming the prune with a compare-and-swap lets exactly one caller
+	// prune per interval, without any extraction waiting on its DELETE.
+	now := time.Now()
+	last := e.lastPrune.Load()
+	if now.Sub(time.Unix(0, last)) < pruneInterval || !e.lastPrune.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	n, err := e.cache.PruneExtractions(ctx, time.Now().Add(-e.cacheCfg.TTL), e.cacheCfg.MaxBytes)
+	if err != nil {
+		log.Warn("Failed to prune extraction cache", "err", err)
+		return
+	}
+	if n > 0 {
+		log.Debug("Pruned extraction cache", "removed", n)
+	}
 }
 
 // ExtractFromReader extracts content from an HTML reader.
@@ -2,12 +2,18 @@ package extractor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"rss2go/internal/dedupe"
+	"rss2go/internal/politeness"
 	"rss2go/internal/types"
 )
 
@@ -99,7 +105,7 @@ func TestExtractNetworkCalls(t *testing.T) {
 	e := NewExtractor(nil, slog.New(slog.DiscardHandler))
 
 	// Happy path
-	res, err := e.Extract(context.Background(), server.URL, types.StrategySelector, "article h1")
+	res, err := e.Extract(context.Background(), server.URL, types.StrategySelector, "article h1", nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -115,7 +121,7 @@ func TestExtractNetworkCalls(t *testing.T) {
 	}))
 	defer serverBadType.Close()
 
-	_, err = e.Extract(context.Background(), serverBadType.URL, types.StrategyHeuristic, "")
+	_, err = e.Extract(context.Background(), serverBadType.URL, types.StrategyHeuristic, "", nil)
 	if err == nil {
 		t.Fatal("expected error on non-HTML content type, got nil")
 	}
@@ -126,14 +132,219 @@ func TestExtractNetworkCalls(t *testing.T) {
 	}))
 	defer server500.Close()
 
-	_, err = e.Extract(context.Background(), server500.URL, types.StrategyHeuristic, "")
+	_, err = e.Extract(context.Background(), server500.URL, types.StrategyHeuristic, "", nil)
 	if err == nil {
 		t.Fatal("expected error on HTTP 500 status, got nil")
 	}
 
 	// Network failure path
-	_, err = e.Extract(context.Background(), "http://non-existent-local-server.local/page", types.StrategyHeuristic, "")
+	_, err = e.Extract(context.Background(), "http://non-existent-local-server.local/page", types.StrategyHeuristic, "", nil)
 	if err == nil {
 		t.Fatal("expected network failure error, got nil")
 	}
 }
+
+func TestExtractRespectsRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /members/\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(sampleArticleHTML))
+	}))
+	defer server.Close()
+
+	e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+	e.SetRobots(politeness.NewRobots(server.Client(), "rss2go", nil))
+
+	if _, err := e.Extract(context.Background(), server.URL+"/members/story", types.StrategyHeuristic, "", nil); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("expected ErrDisallowed, got %v", err)
+	}
+	if _, err := e.Extract(context.Background(), server.URL+"/news/story", types.StrategySelector, "article h1", nil); err != nil {
+		t.Errorf("unexpected error for an allowed path: %v", err)
+	}
+}
+
+// memCache is an in-memory Cache for tests.
+type memCache struct {
+	entries map[string]*types.Extraction
+	pruned  int
+}
+
+func (c *memCache) GetExtraction(_ context.Context, key string, strategy types.ExtractionStrategy, selector string) (*types.Extraction, error) {
+	if ex, ok := c.entries[key+" "+string(strategy)+" "+selector]; ok {
+		cp := *ex
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (c *memCache) PutExtraction(_ context.Context, ex *types.Extraction) error {
+	cp := *ex
+	c.entries[ex.Key+" "+string(ex.Strategy)+" "+ex.Selector] = &cp
+	return nil
+}
+
+func (c *memCache) PruneExtractions(context.Context, time.Time, int64) (int64, error) {
+	c.pruned++
+	return 0, nil
+}
+
+// blockingCache holds every prune until release is closed.
+type blockingCache struct {
+	memCache
+	mu      sync.Mutex
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCache) PutExtraction(ctx context.Context, ex *types.Extraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.memCache.PutExtraction(ctx, ex)
+}
+
+func (c *blockingCache) PruneExtractions(ctx context.Context, staleBefore time.Time, maxBytes int64) (int64, error) {
+	c.mu.Lock()
+	c.pruned++
+	c.mu.Unlock()
+	c.started <- struct{}{}
+	<-c.release
+	return 0, nil
+}
+
+func TestStoreDoesNotWaitForPrune(t *testing.T) {
+	cache := &blockingCache{
+		memCache: memCache{entries: make(map[string]*types.Extraction)},
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+	e.SetCache(cache, CacheConfig{TTL: time.Hour, MaxBytes: 1 << 20})
+	ctx := context.Background()
+	log := slog.New(slog.DiscardHandler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.store(ctx, log, &types.Extraction{Key: "a", URL: "https://example.com/a"})
+	}()
+	<-cache.started
+
+	// The first store is still pruning; the next one stores and returns.
+	e.store(ctx, log, &types.Extraction{Key: "b", URL: "https://example.com/b"})
+	close(cache.release)
+	<-done
+
+	if cache.pruned != 1 || len(cache.entries) != 2 {
+		t.Errorf("expected 2 stored extractions and 1 prune, got %d and %d", len(cache.entries), cache.pruned)
+	}
+}
+
+func TestExtractCache(t *testing.T) {
+	var fetches, revalidated int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(sampleArticleHTML))
+	}))
+	defer server.Close()
+
+	cache := &memCache{entries: make(map[string]*types.Extraction)}
+	e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+	e.SetCache(cache, CacheConfig{TTL: time.Hour, MaxBytes: 1 << 20})
+
+	ctx := context.Background()
+	link := server.URL + "/story?utm_source=rss"
+	first, err := e.Extract(ctx, link, types.StrategySelector, "article h1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A retry of the same article, even with other tracking parameters, is
+	// served from the cache
+	again, err := e.Extract(ctx, server.URL+"/story?utm_medium=email", types.StrategySelector, "article h1", nil)
+	if err != nil || again != first {
+		t.Fatalf("expected the cached extraction, got %q, %v", again, err)
+	}
+	if fetches != 1 {
+		t.Errorf("expected 1 fetch, got %d", fetches)
+	}
+	if cache.pruned != 1 {
+		t.Errorf("expected the first store to prune the cache, got %d prunes", cache.pruned)
+	}
+
+	// A different selector is extracted afresh
+	if _, err := e.Extract(ctx, link, types.StrategySelector, "article .content", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("expected a new selector to fetch, got %d fetches", fetches)
+	}
+
+	// A stale entry is revalidated, and a 304 keeps its content
+	for _, ex := range cache.entries {
+		ex.FetchedAt = time.Now().Add(-2 * time.Hour)
+	}
+	res, err := e.Extract(ctx, link, types.StrategySelector, "article .content", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revalidated != 1 || !strings.Contains(res, "main readable content") {
+		t.Errorf("expected a conditional fetch reusing the cached content, got %d revalidations and %q", revalidated, res)
+	}
+	if ex, _ := cache.GetExtraction(ctx, dedupe.LinkKey(link), types.StrategySelector, "article .content"); ex == nil || time.Since(ex.FetchedAt) > time.Minute {
+		t.Errorf("expected revalidation to refresh the entry, got %+v", ex)
+	}
+}
+
+func TestExtractCacheKeepsRequestOptionsApart(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		who := r.Header.Get("Authorization")
+		if who == "" {
+			who = "anonymous"
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprintf(w, "<html><body><article><h1>Story for %s</h1></article></body></html>", who)
+	}))
+	defer server.Close()
+
+	cache := &memCache{entries: make(map[string]*types.Extraction)}
+	e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+	e.SetCache(cache, CacheConfig{TTL: time.Hour, MaxBytes: 1 << 20})
+
+	// Two feeds link to the same article with different credentials
+	ctx := context.Background()
+	link := server.URL + "/story"
+	alice := &types.RequestOptions{BearerToken: "alice"}
+	bob := &types.RequestOptions{BearerToken: "bob"}
+	for _, tt := range []struct {
+		opts *types.RequestOptions
+		want string
+	}{
+		{alice, "Bearer alice"},
+		{bob, "Bearer bob"},
+		{nil, "anonymous"},
+		{&types.RequestOptions{BearerToken: "alice"}, "Bearer alice"},
+	} {
+		got, err := e.Extract(ctx, link, types.StrategySelector, "article h1", tt.opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(got, "Story for "+tt.want) {
+			t.Errorf("expected the extraction fetched as %s, got %q", tt.want, got)
+		}
+	}
+	if fetches != 3 {
+		t.Errorf("expected one fetch per set of options, got %d", fetches)
+	}
+}
@@ -0,0 +1,137 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"rss2go/internal/dedupe"
+	"rss2go/internal/requestopts"
+	"rss2go/internal/types"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// DefaultMaxPages is how many pages of an article are stitched when a
+	// feed does not set a limit.
+	DefaultMaxPages = 5
+	// MaxPagesLimit is the most pages a feed may stitch.
+	MaxPagesLimit = 20
+)
+
+// nextRelSelector finds the next page of an article by its rel="next" link.
+const nextRelSelector = `link[rel~="next"], a[rel~="next"]`
+
+// Paging configures following an article split across several pages.
+type Paging struct {
+	Selector string // matches the next-page link; empty uses rel="next"
+	MaxPages int    // pages fetched at most, counting the first
+}
+
+// PagingFor returns how feed's articles are followed across pages, or nil
+// if the feed extracts only the linked page.
+func PagingFor(feed *types.Feed) *Paging {
+	if !feed.FollowPages {
+		return nil
+	}
+	limit := feed.MaxPages
+	if limit <= 0 {
+		limit = DefaultMaxPages
+	}
+	return &Paging{Selector: feed.NextPageSelector, MaxPages: min(limit, MaxPagesLimit)}
+}
+
+// cacheKey returns the key extractions of targetURL are cached under.
+// Stitched extractions and those fetched with request options get their own
+// keys, with the paging settings and a digest of the options in fragments,
+// which dedupe.LinkKey never leaves in a real link's key.
+func cacheKey(targetURL string, paging *Paging, opts *types.RequestOptions) string {
+	key := dedupe.LinkKey(targetURL)
+	if paging != nil {
+		key += fmt.Sprintf("#pages=%d,%s", paging.MaxPages, paging.Selector)
+	}
+	if digest := requestopts.Key(opts); digest != "" {
+		key += "#opts=" + digest
+	}
+	return key
+}
+
+// followPages fetches the pages that follow first, up to paging.MaxPages in
+// all. It stops at the first page without a next link, a link that leaves
+// the article's host or returns to a page already seen, or a page that
+// cannot be fetched, keeping the pages fetched so far.
+func (e *Extractor) followPages(ctx context.Context, log *slog.Logger, first *page, paging *Paging, opts *types.RequestOptions) []*page {
+	seen := map[string]bool{dedupe.LinkKey(first.url): true}
+	var pages []*page
+	for cur := first; len(pages)+1 < paging.MaxPages; {
+		next := nextPageURL(cur, paging.Selector)
+		if next == "" {
+			break
+		}
+		if seen[dedupe.LinkKey(next)] {
+			log.Debug("Article page links back to an earlier page", "url", SanitizeURL(cur.url), "next", SanitizeURL(next))
+			break
+		}
+		seen[dedupe.LinkKey(next)] = true
+
+		p, err := e.fetch(ctx, log, next, opts, nil)
+		if err != nil {
+			log.Debug("Stopped following article pages", "url", SanitizeURL(next), "pages", len(pages)+1, "err", err)
+			break
+		}
+		pages = append(pages, p)
+		cur = p
+	}
+	if len(pages) > 0 {
+		log.Debug("Followed article pages", "url", SanitizeURL(first.url), "pages", len(pages)+1)
+	}
+	return pages
+}
+
+// nextPageURL returns the absolute URL of the page after p, or "" if p has
+// no next-page link on the same host.
+func nextPageURL(p *page, selector string) string {
+	if selector == "" {
+		selector = nextRelSelector
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(p.body))
+	if err != nil {
+		return ""
+	}
+	href, ok := doc.Find(selector).First().Attr("href")
+	if !ok || strings.TrimSpace(href) == "" {
+		return ""
+	}
+	base, err := url.Parse(p.url)
+	if err != nil {
+		return ""
+	}
+	next, err := base.Parse(strings.TrimSpace(href))
+	if err != nil || (next.Scheme != "http" && next.Scheme != "https") || next.Host != base.Host {
+		return ""
+	}
+	next.Fragment = ""
+	return next.String()
+}
+
+// extractPages extracts each page with strategy and joins the results. The
+// first page must extract; later pages that do not are skipped.
+func (e *Extractor) extractPages(log *slog.Logger, pages []*page, strategy types.ExtractionStrategy, selector string) (string, error) {
+	var parts []string
+	for i, p := range pages {
+		extracted, err := e.ExtractFromReader(bytes.NewReader(p.body), p.url, strategy, selector)
+		if err != nil {
+			if i == 0 {
+				return "", err
+			}
+			log.Debug("Skipping article page that did not extract", "url", SanitizeURL(p.url), "strategy", strategy, "err", err)
+			continue
+		}
+		parts = append(parts, extracted)
+	}
+	return strings.Join(parts, "\n"), nil
+}
@@ -0,0 +1,171 @@
+package extractor
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rss2go/internal/types"
+)
+
+// pagedServer serves /story from fixtures keyed by its page query
+// parameter, "" for the first page, and counts the requests.
+func pagedServer(t *testing.T, fixtures map[string]string) (*httptest.Server, *int) {
+	t.Helper()
+	fetches := new(int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*fetches++
+		name, ok := fixtures[r.URL.Query().Get("page")]
+		if r.URL.Path != "/story" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(readFixture(t, name))
+	}))
+	t.Cleanup(server.Close)
+	return server, fetches
+}
+
+func TestExtractPages(t *testing.T) {
+	threePages := map[string]string{"": "paged-1.html", "2": "paged-2.html", "3": "paged-3.html"}
+
+	tests := []struct {
+		name     string
+		fixtures map[string]string
+		paging   *Paging
+		want     []string
+		fetches  int
+	}{
+		{
+			name:     "follows rel=next to the last page",
+			fixtures: threePages,
+			paging:   &Paging{MaxPages: 5},
+			want:     []string{"Part one", "Part two", "Part three"},
+			fetches:  3,
+		},
+		{
+			name:     "stops at the page cap",
+			fixtures: threePages,
+			paging:   &Paging{MaxPages: 2},
+			want:     []string{"Part one", "Part two"},
+			fetches:  2,
+		},
+		{
+			name:     "follows a configured next-page selector",
+			fixtures: threePages,
+			paging:   &Paging{Selector: "nav.pager a.more", MaxPages: 5},
+			want:     []string{"Part one", "Part two", "Part three"},
+			fetches:  3,
+		},
+		{
+			name:     "a selector replaces rel=next",
+			fixtures: threePages,
+			paging:   &Paging{Selector: "a.continue", MaxPages: 5},
+			want:     []string{"Part one"},
+			fetches:  1,
+		},
+		{
+			name:     "stops at a link back to an earlier page",
+			fixtures: map[string]string{"": "paged-1.html", "2": "paged-loop.html"},
+			paging:   &Paging{MaxPages: 5},
+			want:     []string{"Part one", "links back"},
+			fetches:  2,
+		},
+		{
+			name:     "does not follow links to another host",
+			fixtures: map[string]string{"": "paged-1.html", "2": "paged-offsite.html"},
+			paging:   &Paging{MaxPages: 5},
+			want:     []string{"Part one", "leaves the site"},
+			fetches:  2,
+		},
+		{
+			name:     "keeps the pages fetched before a failure",
+			fixtures: map[string]string{"": "paged-1.html", "2": "paged-2.html"},
+			paging:   &Paging{MaxPages: 5},
+			want:     []string{"Part one", "Part two"},
+			fetches:  3,
+		},
+		{
+			name:     "extracts only the linked page without paging",
+			fixtures: threePages,
+			want:     []string{"Part one"},
+			fetches:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, fetches := pagedServer(t, tt.fixtures)
+			e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+			chain := []types.ExtractionStrategy{types.StrategySelector}
+
+			best, err := e.ExtractChain(context.Background(), server.URL+"/story", chain, ".article-body", "", tt.paging, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if best == nil {
+				t.Fatal("expected an extraction")
+			}
+
+			// Pages are stitched in order, each exactly once
+			rest := best.Content
+			for _, part := range tt.want {
+				i := strings.Index(rest, part)
+				if i < 0 {
+					t.Fatalf("expected %q in order in %q", part, best.Content)
+				}
+				rest = rest[i+len(part):]
+			}
+			if got := strings.Count(best.Content, "<p>"); got != len(tt.want) {
+				t.Errorf("expected %d stitched pages, got %d in %q", len(tt.want), got, best.Content)
+			}
+			if *fetches != tt.fetches {
+				t.Errorf("expected %d fetches, got %d", tt.fetches, *fetches)
+			}
+		})
+	}
+}
+
+func TestExtractPagesCache(t *testing.T) {
+	server, fetches := pagedServer(t, map[string]string{"": "paged-1.html", "2": "paged-2.html", "3": "paged-3.html"})
+	e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+	e.SetCache(&memCache{entries: make(map[string]*types.Extraction)}, CacheConfig{TTL: time.Hour, MaxBytes: 1 << 20})
+	ctx := context.Background()
+	link := server.URL + "/story"
+	chain := []types.ExtractionStrategy{types.StrategySelector}
+
+	single, err := e.ExtractChain(ctx, link, chain, ".article-body", "", nil, nil)
+	if err != nil || strings.Contains(single.Content, "Part two") {
+		t.Fatalf("expected only the first page, got %+v, %v", single, err)
+	}
+
+	// A stitched extraction is cached apart from the single page
+	stitched, err := e.ExtractChain(ctx, link, chain, ".article-body", "", &Paging{MaxPages: 5}, nil)
+	if err != nil || !strings.Contains(stitched.Content, "Part three") {
+		t.Fatalf("expected all pages, got %+v, %v", stitched, err)
+	}
+	again, err := e.ExtractChain(ctx, link, chain, ".article-body", "", &Paging{MaxPages: 5}, nil)
+	if err != nil || again.Content != stitched.Content {
+		t.Fatalf("expected the cached stitched extraction, got %+v, %v", again, err)
+	}
+	if *fetches != 4 {
+		t.Errorf("expected 4 fetches, got %d", *fetches)
+	}
+}
+
+func TestPagingFor(t *testing.T) {
+	if p := PagingFor(&types.Feed{}); p != nil {
+		t.Errorf("expected no paging unless enabled, got %+v", p)
+	}
+	if p := PagingFor(&types.Feed{FollowPages: true}); p.MaxPages != DefaultMaxPages || p.Selector != "" {
+		t.Errorf("expected default paging, got %+v", p)
+	}
+	if p := PagingFor(&types.Feed{FollowPages: true, NextPageSelector: "a.next", MaxPages: 100}); p.MaxPages != MaxPagesLimit || p.Selector != "a.next" {
+		t.Errorf("expected the page cap to be limited, got %+v", p)
+	}
+}
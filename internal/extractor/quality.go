@@ -0,0 +1,103 @@
+package extractor
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"rss2go/internal/types"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// MinScore is the quality score an extraction needs to be preferred over
+// the rest of its chain: roughly a short paragraph of text outside links.
+const MinScore = 250
+
+const (
+	imageScore      = 50 // score each image adds
+	maxScoredImages = 10 // images beyond this add nothing
+)
+
+// Quality describes how much readable content an extraction holds.
+type Quality struct {
+	TextLength  int     // visible characters, with whitespace collapsed
+	LinkDensity float64 // share of the text inside links
+	Images      int
+	Score       float64
+}
+
+// Score rates extracted HTML by its text outside links, plus a bonus for
+// each image. Navigation, link lists and empty page shells score low, and
+// article bodies high.
+func Score(html string) Quality {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return Quality{}
+	}
+	doc.Find("script, style, noscript").Remove()
+
+	q := Quality{
+		TextLength: visibleLength(doc.Text()),
+		Images:     doc.Find("img").Length(),
+	}
+	if q.TextLength > 0 {
+		linked := 0
+		doc.Find("a").Each(func(_ int, a *goquery.Selection) {
+			linked += visibleLength(a.Text())
+		})
+		q.LinkDensity = float64(min(linked, q.TextLength)) / float64(q.TextLength)
+	}
+	q.Score = float64(q.TextLength)*(1-q.LinkDensity) + float64(imageScore*min(q.Images, maxScoredImages))
+	return q
+}
+
+func visibleLength(s string) int {
+	return utf8.RuneCountInString(strings.Join(strings.Fields(s), " "))
+}
+
+// Candidate is one strategy's extraction of an article.
+type Candidate struct {
+	Strategy types.ExtractionStrategy
+	Content  string
+	Quality  Quality
+}
+
+// Pick chooses among candidates given in chain order. The highest scoring
+// candidate that reaches minScore wins, with earlier candidates winning
+// ties. If none reaches it, the first candidate is used, so a chain still
+// falls back in order. It returns nil if there are no candidates.
+func Pick(candidates []Candidate, minScore float64) *Candidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+	var best *Candidate
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Quality.Score >= minScore && (best == nil || c.Quality.Score > best.Quality.Score) {
+			best = c
+		}
+	}
+	if best == nil {
+		best = &candidates[0]
+	}
+	return best
+}
+
+// Chain returns the strategies a feed extracts articles with: its
+// extraction chain, or else its single extraction strategy.
+func Chain(feed *types.Feed) []types.ExtractionStrategy {
+	if len(feed.ExtractionChain) > 0 {
+		return feed.ExtractionChain
+	}
+	return []types.ExtractionStrategy{feed.ExtractionStrategy}
+}
+
+// ValidStrategy reports whether strategy is a known extraction strategy.
+func ValidStrategy(strategy types.ExtractionStrategy) bool {
+	switch strategy {
+	case types.StrategySummary, types.StrategyHeuristic, types.StrategySelector:
+		return true
+	default:
+		return false
+	}
+}
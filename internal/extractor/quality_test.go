@@ -0,0 +1,164 @@
+package extractor
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rss2go/internal/types"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return body
+}
+
+func TestScore(t *testing.T) {
+	q := Score(`<p>Eighteen letters.</p><p><a href="/x">Ten chars.</a></p><script>var ignored = "long script text";</script>`)
+	if q.TextLength != 27 {
+		t.Errorf("TextLength = %d, want 27", q.TextLength)
+	}
+	if q.LinkDensity != 10.0/27 {
+		t.Errorf("LinkDensity = %v, want 10/27", q.LinkDensity)
+	}
+	if q.Score != 17 {
+		t.Errorf("Score = %v, want the 17 characters outside links", q.Score)
+	}
+
+	many := Score(strings.Repeat(`<img src="a.jpg">`, 20))
+	if many.Images != 20 || many.Score != imageScore*maxScoredImages {
+		t.Errorf("expected image scoring to be capped, got %+v", many)
+	}
+	if empty := Score(""); empty.Score != 0 {
+		t.Errorf("expected an empty extraction to score 0, got %+v", empty)
+	}
+}
+
+func TestPick(t *testing.T) {
+	candidates := []Candidate{
+		{Strategy: types.StrategySelector, Quality: Quality{Score: 100}},
+		{Strategy: types.StrategyHeuristic, Quality: Quality{Score: 400}},
+		{Strategy: types.StrategySummary, Quality: Quality{Score: 400}},
+	}
+	if got := Pick(candidates, 250); got.Strategy != types.StrategyHeuristic {
+		t.Errorf("expected the best candidate, earliest on ties, got %s", got.Strategy)
+	}
+	if got := Pick(candidates, 1000); got.Strategy != types.StrategySelector {
+		t.Errorf("expected the first candidate when none is good enough, got %s", got.Strategy)
+	}
+	if got := Pick(nil, 250); got != nil {
+		t.Errorf("expected no pick without candidates, got %+v", got)
+	}
+}
+
+func TestExtractChainFixtures(t *testing.T) {
+	fullSummary := "<p>" + strings.Repeat("Revenue rose by a tenth in the quarter on strong demand. ", 8) + "</p>"
+
+	tests := []struct {
+		name     string
+		fixture  string
+		chain    []types.ExtractionStrategy
+		selector string
+		summary  string
+		want     types.ExtractionStrategy
+		contains string
+	}{
+		{
+			name:     "heuristic beats a selector that only matches a teaser",
+			fixture:  "teaser-selector.html",
+			chain:    []types.ExtractionStrategy{types.StrategySelector, types.StrategyHeuristic, types.StrategySummary},
+			selector: ".article-body",
+			summary:  "<p>The council voted on Tuesday night.</p>",
+			want:     types.StrategyHeuristic,
+			contains: "twelve kilometre route",
+		},
+		{
+			name:     "selector beats a heuristic that keeps navigation",
+			fixture:  "recipe-selector.html",
+			chain:    []types.ExtractionStrategy{types.StrategyHeuristic, types.StrategySelector},
+			selector: ".recipe",
+			want:     types.StrategySelector,
+			contains: "Weeknight lentil soup",
+		},
+		{
+			name:     "full-text summary beats a paywalled page",
+			fixture:  "paywall.html",
+			chain:    []types.ExtractionStrategy{types.StrategySelector, types.StrategyHeuristic, types.StrategySummary},
+			selector: ".article-body",
+			summary:  fullSummary,
+			want:     types.StrategySummary,
+			contains: "Revenue rose",
+		},
+		{
+			name:     "chain order decides when nothing is good enough",
+			fixture:  "paywall.html",
+			chain:    []types.ExtractionStrategy{types.StrategySelector, types.StrategySummary},
+			selector: ".article-body",
+			summary:  "<p>Results are in.</p>",
+			want:     types.StrategySelector,
+			contains: "subscribers only",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := readFixture(t, tt.fixture)
+			fetches := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetches++
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				_, _ = w.Write(page)
+			}))
+			defer server.Close()
+
+			e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+			best, err := e.ExtractChain(context.Background(), server.URL+"/story", tt.chain, tt.selector, tt.summary, nil, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if best == nil || best.Strategy != tt.want {
+				t.Fatalf("expected %s to win, got %+v", tt.want, best)
+			}
+			if !strings.Contains(best.Content, tt.contains) {
+				t.Errorf("expected the winning content to contain %q, got %q", tt.contains, best.Content)
+			}
+			if fetches != 1 {
+				t.Errorf("expected the page to be fetched once, got %d", fetches)
+			}
+		})
+	}
+}
+
+func TestExtractChainFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	e := NewExtractor(nil, slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+	chain := []types.ExtractionStrategy{types.StrategyHeuristic, types.StrategySummary}
+
+	// The summary still competes when the page cannot be fetched
+	best, err := e.ExtractChain(ctx, server.URL, chain, "", "<p>Feed summary</p>", nil, nil)
+	if err == nil {
+		t.Error("expected the fetch failure to be reported")
+	}
+	if best == nil || best.Strategy != types.StrategySummary {
+		t.Errorf("expected the summary to be picked, got %+v", best)
+	}
+
+	best, err = e.ExtractChain(ctx, server.URL, chain[:1], "", "<p>Feed summary</p>", nil, nil)
+	if err == nil || best != nil {
+		t.Errorf("expected no result and an error without a summary in the chain, got %+v, %v", best, err)
+	}
+}
@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rss2go/internal/types"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxPatternLen bounds rule patterns so a single rule cannot dominate crawl time.
+const maxPatternLen = 1024
+
+// Candidate is the subset of an item that filter rules inspect.
+type Candidate struct {
+	Title      string
+	Content    string // Plain text; see PlainText
+	Author     string
+	Categories []string
+}
+
+// Set is a compiled group of one subscriber's filter rules. An item is
+// delivered when it matches no exclude rule and, if any include rules exist,
+// at least one of them.
+type Set struct {
+	rules    []*types.FilterRule
+	matchers []func(string) bool
+}
+
+// Validate checks a rule's action, field and match type, and that its pattern compiles.
+func Validate(rule *types.FilterRule) error {
+	_, err := compileRule(rule)
+	return err
+}
+
+// Compile validates and compiles rules into a Set. A nil or empty Set delivers everything.
+func Compile(rules []*types.FilterRule) (*Set, error) {
+	s := &Set{}
+	for _, rule := range rules {
+		m, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		s.rules = append(s.rules, rule)
+		s.matchers = append(s.matchers, m)
+	}
+	return s, nil
+}
+
+// Allows reports whether an item should be delivered.
+func (s *Set) Allows(c *Candidate) bool {
+	allowed, _ := s.Evaluate(c)
+	return allowed
+}
+
+// Evaluate reports whether an item should be delivered together with every rule it matched.
+func (s *Set) Evaluate(c *Candidate) (bool, []*types.FilterRule) {
+	if s == nil || len(s.rules) == 0 {
+		return true, nil
+	}
+
+	var matched []*types.FilterRule
+	hasInclude, included, excluded := false, false, false
+	for i, rule := range s.rules {
+		if rule.Action == types.FilterInclude {
+			hasInclude = true
+		}
+		if !matchField(c, rule.Field, s.matchers[i]) {
+			continue
+		}
+		matched = append(matched, rule)
+		if rule.Action == types.FilterInclude {
+			included = true
+		} else {
+			excluded = true
+		}
+	}
+
+	return !excluded && (!hasInclude || included), matched
+}
+
+// PlainText strips markup from an HTML fragment so content rules only match visible text.
+func PlainText(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return html
+	}
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+func compileRule(rule *types.FilterRule) (func(string) bool, error) {
+	switch rule.Action {
+	case types.FilterInclude, types.FilterExclude:
+	default:
+		return nil, fmt.Errorf("filter: invalid action %q (expected include or exclude)", rule.Action)
+	}
+
+	switch rule.Field {
+	case types.FilterFieldTitle, types.FilterFieldContent, types.FilterFieldAuthor, types.FilterFieldCategory:
+	default:
+		return nil, fmt.Errorf("filter: invalid field %q (expected title, content, author or category)", rule.Field)
+	}
+
+	if rule.Pattern == "" {
+		return nil, fmt.Errorf("filter: pattern cannot be empty")
+	}
+	if len(rule.Pattern) > maxPatternLen {
+		return nil, fmt.Errorf("filter: pattern exceeds %d characters", maxPatternLen)
+	}
+
+	switch rule.Match {
+	case types.FilterMatchSubstring:
+		needle := strings.ToLower(rule.Pattern)
+		return func(val string) bool {
+			return strings.Contains(strings.ToLower(val), needle)
+		}, nil
+	case types.FilterMatchRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("filter: invalid regex %q: %w", rule.Pattern, err)
+		}
+		return re.MatchString, nil
+	default:
+		return nil, fmt.Errorf("filter: invalid match type %q (expected substring or regex)", rule.Match)
+	}
+}
+
+func matchField(c *Candidate, field types.FilterField, match func(string) bool) bool {
+	switch field {
+	case types.FilterFieldTitle:
+		return match(c.Title)
+	case types.FilterFieldContent:
+		return match(c.Content)
+	case types.FilterFieldAuthor:
+		return match(c.Author)
+	case types.FilterFieldCategory:
+		for _, cat := range c.Categories {
+			if match(cat) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"testing"
+
+	"rss2go/internal/types"
+)
+
+func rule(id int64, action types.FilterAction, field types.FilterField, match types.FilterMatch, pattern string) *types.FilterRule {
+	return &types.FilterRule{ID: id, Action: action, Field: field, Match: match, Pattern: pattern}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    *types.FilterRule
+		wantErr bool
+	}{
+		{"substring", rule(0, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchSubstring, "go"), false},
+		{"regex", rule(0, types.FilterExclude, types.FilterFieldContent, types.FilterMatchRegex, `(?i)sponsored\b`), false},
+		{"bad regex", rule(0, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchRegex, "(unclosed"), true},
+		{"bad action", rule(0, "drop", types.FilterFieldTitle, types.FilterMatchSubstring, "go"), true},
+		{"bad field", rule(0, types.FilterInclude, "body", types.FilterMatchSubstring, "go"), true},
+		{"bad match", rule(0, types.FilterInclude, types.FilterFieldTitle, "glob", "go*"), true},
+		{"empty pattern", rule(0, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchSubstring, ""), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.rule); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	item := &Candidate{
+		Title:      "Announcing Widget Pro 2",
+		Content:    "Widget Pro now ships with a sponsored upgrade.",
+		Author:     "Jane Doe",
+		Categories: []string{"Releases", "Hardware"},
+	}
+
+	tests := []struct {
+		name        string
+		rules       []*types.FilterRule
+		wantAllowed bool
+		wantMatched []int64
+	}{
+		{"no rules", nil, true, nil},
+		{"include title substring is case-insensitive", []*types.FilterRule{
+			rule(1, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchSubstring, "widget pro"),
+		}, true, []int64{1}},
+		{"include without match", []*types.FilterRule{
+			rule(1, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchSubstring, "gadget"),
+		}, false, nil},
+		{"any include suffices", []*types.FilterRule{
+			rule(1, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchSubstring, "gadget"),
+			rule(2, types.FilterInclude, types.FilterFieldCategory, types.FilterMatchSubstring, "hardware"),
+		}, true, []int64{2}},
+		{"exclude wins over include", []*types.FilterRule{
+			rule(1, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchSubstring, "widget"),
+			rule(2, types.FilterExclude, types.FilterFieldContent, types.FilterMatchRegex, `\bsponsored\b`),
+		}, false, []int64{1, 2}},
+		{"exclude only", []*types.FilterRule{
+			rule(1, types.FilterExclude, types.FilterFieldAuthor, types.FilterMatchRegex, "^John"),
+		}, true, nil},
+		{"regex is case-sensitive by default", []*types.FilterRule{
+			rule(1, types.FilterInclude, types.FilterFieldCategory, types.FilterMatchRegex, "^releases$"),
+		}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := Compile(tt.rules)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			allowed, matched := set.Evaluate(item)
+			if allowed != tt.wantAllowed {
+				t.Errorf("Evaluate() allowed = %v, want %v", allowed, tt.wantAllowed)
+			}
+			if len(matched) != len(tt.wantMatched) {
+				t.Fatalf("Evaluate() matched %d rules, want %d", len(matched), len(tt.wantMatched))
+			}
+			for i, r := range matched {
+				if r.ID != tt.wantMatched[i] {
+					t.Errorf("matched[%d] = rule %d, want %d", i, r.ID, tt.wantMatched[i])
+				}
+			}
+		})
+	}
+
+	var nilSet *Set
+	if !nilSet.Allows(item) {
+		t.Errorf("expected a nil set to allow every item")
+	}
+	if _, err := Compile([]*types.FilterRule{rule(1, types.FilterInclude, types.FilterFieldTitle, types.FilterMatchRegex, "[")}); err == nil {
+		t.Errorf("expected Compile to reject an invalid regex")
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	got := PlainText(`<p>Hello <a href="https://widget.example">world</a></p>
+<p>again</p>`)
+	if got != "Hello world again" {
+		t.Errorf("PlainText() = %q", got)
+	}
+}
@@ -0,0 +1,195 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"rss2go/internal/database"
+	"rss2go/internal/types"
+)
+
+// AlertConfig configures the operator alert digest.
+type AlertConfig struct {
+	// Interval is how often pending health changes are summarized.
+	Interval time.Duration
+	// Recipients receive the alert email. Without any, changes are only
+	// marked as reported.
+	Recipients []string
+	// Now returns the current time. Tests substitute a fake clock.
+	Now func() time.Time
+}
+
+// Alerter periodically emails operators a summary of feeds that broke or
+// recovered since the previous summary, through the durable outbox.
+type Alerter struct {
+	repo         *database.Repository
+	cfg          AlertConfig
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+	log          *slog.Logger
+}
+
+// NewAlerter creates a new Alerter.
+func NewAlerter(repo *database.Repository, cfg AlertConfig, log *slog.Logger) *Alerter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if log == nil {
+		log = slog.Default().With("component", "health")
+	}
+
+	return &Alerter{
+		repo:       repo,
+		cfg:        cfg,
+		shutdownCh: make(chan struct{}),
+		log:        log,
+	}
+}
+
+// Start runs the alert loop. It blocks until context is cancelled or Stop is called.
+func (a *Alerter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				a.log.Error("Feed health alert error", "err", err)
+			}
+		case <-ctx.Done():
+			a.Stop()
+			return ctx.Err()
+		case <-a.shutdownCh:
+			return nil
+		}
+	}
+}
+
+// Stop signals the alert loop to exit.
+func (a *Alerter) Stop() {
+	a.shutdownOnce.Do(func() {
+		close(a.shutdownCh)
+	})
+}
+
+// RunOnce queues one alert email covering every unreported health change, if
+// any feed broke or recovered, and marks the changes reported.
+func (a *Alerter) RunOnce(ctx context.Context) error {
+	events, err := a.repo.ListUnalertedFeedHealthEvents(ctx)
+	if err != nil {
+		return fmt.Errorf("health: list events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	broken, recovered := Summarize(events)
+	now := a.cfg.Now()
+
+	return a.repo.WithTx(ctx, func(txRepo *database.Repository) error {
+		if len(a.cfg.Recipients) > 0 && len(broken)+len(recovered) > 0 {
+			err := txRepo.EnqueueOutboxItem(ctx, &types.OutboxItem{
+				Subject:       Subject(len(broken), len(recovered)),
+				Body:          Render(broken, recovered),
+				Status:        types.OutboxPending,
+				NextAttemptAt: now,
+				Recipients:    a.cfg.Recipients,
+			})
+			if err != nil {
+				return err
+			}
+			a.log.Info("Queued feed health alert", "broken", len(broken), "recovered", len(recovered))
+		}
+
+		ids := make([]int64, len(events))
+		for i, ev := range events {
+			ids[i] = ev.ID
+		}
+		return txRepo.MarkFeedHealthEventsAlerted(ctx, ids, now)
+	})
+}
+
+// Summarize collapses each feed's health changes, oldest first, into its net
+// change and sorts the feeds into those that newly need attention and those
+// that recovered. Feeds that broke and recovered in between are left out.
+func Summarize(events []*types.FeedHealthEvent) (broken, recovered []*types.FeedHealthEvent) {
+	net := make(map[int64]*types.FeedHealthEvent)
+	var order []int64
+	for _, ev := range events {
+		prev, ok := net[ev.FeedID]
+		if !ok {
+			order = append(order, ev.FeedID)
+			net[ev.FeedID] = ev
+			continue
+		}
+		merged := *ev
+		merged.From = prev.From
+		net[ev.FeedID] = &merged
+	}
+
+	for _, id := range order {
+		ev := net[id]
+		switch {
+		case Broken(ev.To) && ev.To != ev.From:
+			broken = append(broken, ev)
+		case !Broken(ev.To) && Broken(ev.From):
+			recovered = append(recovered, ev)
+		}
+	}
+	return broken, recovered
+}
+
+// Subject returns the alert email subject line.
+func Subject(broken, recovered int) string {
+	var parts []string
+	if broken > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s attention", broken, plural(broken, "feed needs", "feeds need")))
+	}
+	if recovered > 0 {
+		parts = append(parts, fmt.Sprintf("%d recovered", recovered))
+	}
+	return "[rss2go] Feed health: " + strings.Join(parts, ", ")
+}
+
+// Render returns the HTML body of an alert email.
+func Render(broken, recovered []*types.FeedHealthEvent) string {
+	var b strings.Builder
+	if len(broken) > 0 {
+		b.WriteString("<h2>Feeds needing attention</h2>\n<ul>\n")
+		for _, ev := range broken {
+			fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a> is now <strong>%s</strong>",
+				html.EscapeString(ev.FeedURL), html.EscapeString(ev.FeedTitle), ev.To)
+			if ev.Reason != "" {
+				fmt.Fprintf(&b, ": %s", html.EscapeString(ev.Reason))
+			}
+			b.WriteString("</li>\n")
+		}
+		b.WriteString("</ul>\n")
+	}
+	if len(recovered) > 0 {
+		b.WriteString("<h2>Recovered feeds</h2>\n<ul>\n")
+		for _, ev := range recovered {
+			fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a> is now %s (was %s)</li>\n",
+				html.EscapeString(ev.FeedURL), html.EscapeString(ev.FeedTitle), ev.To, ev.From)
+		}
+		b.WriteString("</ul>\n")
+	}
+	return b.String()
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,104 @@
+package health
+
+import (
+	"fmt"
+	"time"
+
+	"rss2go/internal/types"
+)
+
+// Policy decides a feed's health from its run of consecutive crawl failures.
+type Policy struct {
+	// FailingAfter is how many consecutive failures mark a feed failing; fewer
+	// mark it degraded. It defaults to 3.
+	FailingAfter int
+	// DisableAfter is how long a failing feed keeps being polled before it is
+	// disabled. Zero never disables feeds for failing.
+	DisableAfter time.Duration
+}
+
+func (p Policy) failingAfter() int {
+	if p.FailingAfter <= 0 {
+		return 3
+	}
+	return p.FailingAfter
+}
+
+// Failure records a failed crawl on feed, whose LastErrorStr must already
+// describe the failure. A non-empty disableReason disables the feed at once,
+// for feeds that will not come back. It returns the resulting health change,
+// or nil if the feed's health is unchanged.
+func (p Policy) Failure(feed *types.Feed, now time.Time, disableReason string) *types.FeedHealthEvent {
+	feed.ConsecutiveFailures++
+	if feed.FailingSince == nil {
+		feed.FailingSince = &now
+	}
+
+	to, reason := types.FeedDegraded, feed.LastErrorStr
+	switch {
+	case feed.Health == types.FeedDisabled:
+		// A manual scan of a disabled feed failed again
+		to = types.FeedDisabled
+	case disableReason != "":
+		to, reason = types.FeedDisabled, disableReason
+	case feed.ConsecutiveFailures >= p.failingAfter() && p.DisableAfter > 0 && now.Sub(*feed.FailingSince) >= p.DisableAfter:
+		to = types.FeedDisabled
+		reason = fmt.Sprintf("failed %d times in a row since %s: %s",
+			feed.ConsecutiveFailures, feed.FailingSince.Format(time.DateOnly), feed.LastErrorStr)
+	case feed.ConsecutiveFailures >= p.failingAfter():
+		to = types.FeedFailing
+	}
+
+	if to == types.FeedDisabled && feed.Health != types.FeedDisabled {
+		feed.DisabledReason = reason
+	}
+	return transition(feed, to, reason, now)
+}
+
+// Success records a successful crawl on feed, returning it to healthy. It
+// returns the resulting health change, or nil if the feed was already healthy.
+func (p Policy) Success(feed *types.Feed, now time.Time) *types.FeedHealthEvent {
+	feed.ConsecutiveFailures = 0
+	feed.FailingSince = nil
+	feed.DisabledReason = ""
+	return transition(feed, types.FeedHealthy, "", now)
+}
+
+// Enable returns a disabled feed to healthy with its failure history cleared,
+// as when an operator edits it. It returns the resulting health change, or nil
+// if the feed was already healthy.
+func Enable(feed *types.Feed, now time.Time) *types.FeedHealthEvent {
+	feed.ConsecutiveFailures = 0
+	feed.NotFoundCount = 0
+	feed.FailingSince = nil
+	feed.DisabledReason = ""
+	return transition(feed, types.FeedHealthy, "re-enabled by an operator", now)
+}
+
+// transition moves feed to the given health and describes the change.
+func transition(feed *types.Feed, to types.FeedHealth, reason string, now time.Time) *types.FeedHealthEvent {
+	from := feed.Health
+	if from == "" {
+		from = types.FeedHealthy
+	}
+	feed.Health = to
+	if from == to {
+		return nil
+	}
+
+	feed.HealthChangedAt = &now
+	return &types.FeedHealthEvent{
+		FeedID:    feed.ID,
+		FeedTitle: feed.Title,
+		FeedURL:   feed.URL,
+		From:      from,
+		To:        to,
+		Reason:    reason,
+		CreatedAt: now,
+	}
+}
+
+// Broken reports whether a feed in state h needs an operator's attention.
+func Broken(h types.FeedHealth) bool {
+	return h == types.FeedFailing || h == types.FeedDisabled
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rss2go/internal/database"
+	"rss2go/internal/types"
+)
+
+func setupTestDB(t *testing.T) *database.Repository {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return database.NewRepository(db)
+}
+
+func TestPolicyTransitions(t *testing.T) {
+	p := Policy{FailingAfter: 3, DisableAfter: 48 * time.Hour}
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+	feed := &types.Feed{ID: 1, Title: "Blog", LastErrorStr: "timeout"}
+
+	change := p.Failure(feed, now, "")
+	if change == nil || change.From != types.FeedHealthy || change.To != types.FeedDegraded {
+		t.Fatalf("expected first failure to degrade the feed, got %+v", change)
+	}
+	if change := p.Failure(feed, now.Add(time.Hour), ""); change != nil {
+		t.Errorf("expected no change while still degraded, got %+v", change)
+	}
+	change = p.Failure(feed, now.Add(2*time.Hour), "")
+	if change == nil || change.To != types.FeedFailing || feed.ConsecutiveFailures != 3 {
+		t.Fatalf("expected third failure to mark the feed failing, got %+v", change)
+	}
+	if !feed.FailingSince.Equal(now) {
+		t.Errorf("expected failing_since to stay at the first failure, got %v", feed.FailingSince)
+	}
+
+	change = p.Failure(feed, now.Add(48*time.Hour), "")
+	if change == nil || change.To != types.FeedDisabled || !strings.Contains(feed.DisabledReason, "timeout") {
+		t.Fatalf("expected a feed failing past DisableAfter to be disabled, got %+v (reason %q)", change, feed.DisabledReason)
+	}
+	if change := p.Failure(feed, now.Add(49*time.Hour), ""); change != nil || feed.Health != types.FeedDisabled {
+		t.Errorf("expected a disabled feed to stay disabled, got %+v", change)
+	}
+
+	change = p.Success(feed, now.Add(50*time.Hour))
+	if change == nil || change.From != types.FeedDisabled || change.To != types.FeedHealthy {
+		t.Fatalf("expected success to restore the feed, got %+v", change)
+	}
+	if feed.ConsecutiveFailures != 0 || feed.FailingSince != nil || feed.DisabledReason != "" {
+		t.Errorf("expected success to clear the failure history, got %+v", feed)
+	}
+	if change := p.Success(feed, now.Add(51*time.Hour)); change != nil {
+		t.Errorf("expected no change for a healthy feed, got %+v", change)
+	}
+
+	// Gone feeds are disabled at once; without DisableAfter nothing else disables.
+	change = Policy{}.Failure(feed, now, "server returned 410 Gone")
+	if change == nil || change.To != types.FeedDisabled || feed.DisabledReason != "server returned 410 Gone" {
+		t.Errorf("expected a disable reason to disable the feed, got %+v", change)
+	}
+	other := &types.Feed{FailingSince: &now, ConsecutiveFailures: 100}
+	Policy{}.Failure(other, now.AddDate(1, 0, 0), "")
+	if other.Health != types.FeedFailing {
+		t.Errorf("expected no auto-disable without DisableAfter, got %s", other.Health)
+	}
+
+	if change := Enable(feed, now); change == nil || change.To != types.FeedHealthy || feed.NotFoundCount != 0 {
+		t.Errorf("expected Enable to restore a disabled feed, got %+v", change)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ev := func(feedID int64, from, to types.FeedHealth) *types.FeedHealthEvent {
+		return &types.FeedHealthEvent{FeedID: feedID, From: from, To: to}
+	}
+	broken, recovered := Summarize([]*types.FeedHealthEvent{
+		ev(1, types.FeedHealthy, types.FeedDegraded),
+		ev(1, types.FeedDegraded, types.FeedFailing),
+		ev(2, types.FeedFailing, types.FeedHealthy),
+		ev(3, types.FeedDegraded, types.FeedHealthy), // Never broke
+		ev(4, types.FeedHealthy, types.FeedFailing),
+		ev(4, types.FeedFailing, types.FeedHealthy), // Broke and recovered in between
+		ev(5, types.FeedFailing, types.FeedDisabled),
+	})
+
+	if len(broken) != 2 || broken[0].FeedID != 1 || broken[0].From != types.FeedHealthy || broken[1].FeedID != 5 {
+		t.Errorf("unexpected broken feeds %+v", broken)
+	}
+	if len(recovered) != 1 || recovered[0].FeedID != 2 {
+		t.Errorf("unexpected recovered feeds %+v", recovered)
+	}
+}
+
+func TestAlerterRunOnce(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	feed := &types.Feed{Title: "Broken <Blog>", URL: "https://example.com/feed", NextPollAt: now}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	alerter := NewAlerter(repo, AlertConfig{
+		Recipients: []string{"ops@example.com"},
+		Now:        func() time.Time { return now },
+	}, slog.New(slog.DiscardHandler))
+
+	if err := alerter.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if items, _ := repo.ListOutboxItems(ctx, 10); len(items) != 0 {
+		t.Fatalf("expected no alert without health changes, got %d", len(items))
+	}
+
+	for _, change := range []*types.FeedHealthEvent{
+		{FeedID: feed.ID, From: types.FeedHealthy, To: types.FeedDegraded, Reason: "timeout", CreatedAt: now},
+		{FeedID: feed.ID, From: types.FeedDegraded, To: types.FeedFailing, Reason: "timeout", CreatedAt: now},
+	} {
+		if err := repo.RecordFeedHealthEvent(ctx, change); err != nil {
+			t.Fatalf("failed to record event: %v", err)
+		}
+	}
+
+	if err := alerter.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	items, _ := repo.ListOutboxItems(ctx, 10)
+	if len(items) != 1 {
+		t.Fatalf("expected one alert email, got %d", len(items))
+	}
+	item := items[0]
+	if item.Subject != "[rss2go] Feed health: 1 feed needs attention" || item.Recipients[0] != "ops@example.com" {
+		t.Errorf("unexpected alert email %q to %v", item.Subject, item.Recipients)
+	}
+	if !strings.Contains(item.Body, "Broken &lt;Blog&gt;") || !strings.Contains(item.Body, "<strong>failing</strong>: timeout") {
+		t.Errorf("unexpected alert body %s", item.Body)
+	}
+
+	// Reported changes are not sent again.
+	if pending, _ := repo.ListUnalertedFeedHealthEvents(ctx); len(pending) != 0 {
+		t.Errorf("expected events to be marked alerted, got %d pending", len(pending))
+	}
+	if err := alerter.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if items, _ := repo.ListOutboxItems(ctx, 10); len(items) != 1 {
+		t.Errorf("expected no repeat alert, got %d emails", len(items))
+	}
+}
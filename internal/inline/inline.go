@@ -0,0 +1,349 @@
+// Package inline embeds the remote images of an email in the message itself,
+// so it renders without the reader's mail client fetching anything from the
+// publisher. Images are downloaded when the email is sent, downscaled to the
+// email width and referenced from the HTML by Content-ID.
+package inline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"rss2go/internal/crawler"
+	"rss2go/internal/notifier"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// DefaultMaxImages is how many distinct images one email embeds by default.
+	DefaultMaxImages = 20
+	// DefaultMaxBytes bounds the embedded images of one email by default.
+	DefaultMaxBytes = 10 << 20
+	// DefaultMaxWidth matches the width the sanitizer lays images out at.
+	DefaultMaxWidth = 800
+
+	// maxPixels rejects images whose decoded size would dwarf the download,
+	// before they are decoded.
+	maxPixels = 25_000_000
+	// jpegQuality is used when re-encoding downscaled JPEGs.
+	jpegQuality = 85
+	// cacheBytes and cacheTTL bound the processed images kept between sends,
+	// so an item fanned out to many subscribers downloads each image once.
+	cacheBytes = 32 << 20
+	cacheTTL   = time.Hour
+	// cidDomain is the right-hand side of every Content-ID.
+	cidDomain = "rss2go"
+)
+
+// ErrNotPublic is returned for images served from a loopback, link-local or
+// private address.
+var ErrNotPublic = errors.New("inline: address is not public")
+
+// Config limits what one email embeds.
+type Config struct {
+	MaxImages int   // distinct images embedded per email
+	MaxBytes  int64 // total embedded bytes per email, and the largest download
+	MaxWidth  int   // wider images are downscaled to this width
+}
+
+// Inliner implements notifier.Inliner by downloading images over HTTP.
+type Inliner struct {
+	client *http.Client
+	cfg    Config
+	log    *slog.Logger
+
+	mu     sync.Mutex
+	cache  map[string]*cached
+	order  []string // cache keys, oldest first
+	cached int      // bytes held in cache
+}
+
+type cached struct {
+	img     notifier.InlineImage
+	expires time.Time
+}
+
+// New creates an Inliner. A nil client uses a client with a short timeout
+// over PublicTransport; zero config fields take their defaults.
+func New(client *http.Client, cfg Config, log *slog.Logger) *Inliner {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second, Transport: PublicTransport()}
+	}
+	if cfg.MaxImages <= 0 {
+		cfg.MaxImages = DefaultMaxImages
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.MaxWidth <= 0 {
+		cfg.MaxWidth = DefaultMaxWidth
+	}
+	if log == nil {
+		log = slog.Default().With("component", "inline")
+	}
+	return &Inliner{client: client, cfg: cfg, log: log, cache: map[string]*cached{}}
+}
+
+// Inline points the http(s) images of body at embedded copies, up to the
+// configured count and size, and drops their srcset so clients cannot fall
+// back to the remote files. Images that cannot be fetched, decoded or fitted
+// in the budget keep their remote URLs. Each image's Content-ID derives from
+// its URL, so sending the same body again yields the same message.
+func (in *Inliner) Inline(ctx context.Context, body string) (string, []notifier.InlineImage) {
+	if !strings.Contains(body, "<img") {
+		return body, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return body, nil
+	}
+
+	var images []notifier.InlineImage
+	embedded := map[string]string{} // src to Content-ID
+	remaining := in.cfg.MaxBytes
+	doc.Find("img[src]").Each(func(_ int, sel *goquery.Selection) {
+		src, _ := sel.Attr("src")
+		cid, ok := embedded[src]
+		if !ok {
+			if len(images) >= in.cfg.MaxImages || !isHTTPURL(src) {
+				return
+			}
+			img, err := in.load(ctx, src)
+			if err != nil {
+				in.log.Debug("Leaving image remote", "url", crawler.SanitizeURL(src), "err", err)
+				return
+			}
+			if int64(len(img.Data)) > remaining {
+				in.log.Debug("Leaving image remote, email image budget spent", "url", crawler.SanitizeURL(src), "bytes", len(img.Data))
+				return
+			}
+			remaining -= int64(len(img.Data))
+			images = append(images, img)
+			cid = img.CID
+			embedded[src] = cid
+		}
+		sel.SetAttr("src", "cid:"+cid)
+		sel.RemoveAttr("srcset")
+	})
+	if len(images) == 0 {
+		return body, nil
+	}
+
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return body, nil
+	}
+	return out, images
+}
+
+// load returns the processed image at src, from the cache when it holds a
+// fresh copy.
+func (in *Inliner) load(ctx context.Context, src string) (notifier.InlineImage, error) {
+	now := time.Now()
+	in.mu.Lock()
+	if c, ok := in.cache[src]; ok && now.Before(c.expires) {
+		in.mu.Unlock()
+		return c.img, nil
+	}
+	in.mu.Unlock()
+
+	data, err := in.fetch(ctx, src)
+	if err != nil {
+		return notifier.InlineImage{}, err
+	}
+	data, contentType, err := fit(data, in.cfg.MaxWidth)
+	if err != nil {
+		return notifier.InlineImage{}, err
+	}
+	img := notifier.InlineImage{CID: contentID(src), ContentType: contentType, Data: data}
+	in.store(src, img, now.Add(cacheTTL))
+	return img, nil
+}
+
+// fetch downloads the image at src, refusing anything larger than the
+// per-email budget.
+func (in *Inliner) fetch(ctx context.Context, src string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("inline: create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "rss2go/1.0 (Email Image Inliner)")
+	req.Header.Set("Accept", "image/jpeg, image/png, image/gif")
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("inline: fetch failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("inline: fetch returned HTTP status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		return nil, fmt.Errorf("inline: unsupported content type %q", ct)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, in.cfg.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("inline: read image: %w", err)
+	}
+	if int64(len(data)) > in.cfg.MaxBytes {
+		return nil, fmt.Errorf("inline: image exceeds %d bytes", in.cfg.MaxBytes)
+	}
+	return data, nil
+}
+
+// store caches img under src, evicting the oldest entries to stay within
+// cacheBytes.
+func (in *Inliner) store(src string, img notifier.InlineImage, expires time.Time) {
+	size := len(img.Data)
+	if size > cacheBytes {
+		return
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if old, ok := in.cache[src]; ok {
+		in.cached -= len(old.img.Data)
+		delete(in.cache, src)
+		in.order = slices.DeleteFunc(in.order, func(k string) bool { return k == src })
+	}
+	for in.cached+size > cacheBytes && len(in.order) > 0 {
+		oldest := in.order[0]
+		in.order = in.order[1:]
+		in.cached -= len(in.cache[oldest].img.Data)
+		delete(in.cache, oldest)
+	}
+	in.cache[src] = &cached{img: img, expires: expires}
+	in.order = append(in.order, src)
+	in.cached += size
+}
+
+// fit returns data and its media type when the image is at most maxWidth
+// wide. Wider images are downscaled to maxWidth and re-encoded: JPEGs as
+// JPEG, PNGs and GIFs as PNG, keeping only the first frame of an animation.
+func fit(data []byte, maxWidth int) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("inline: decode image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, "", fmt.Errorf("inline: image is %dx%d pixels", cfg.Width, cfg.Height)
+	}
+	if cfg.Width <= maxWidth {
+		return data, "image/" + format, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("inline: decode image: %w", err)
+	}
+	height := max(1, cfg.Height*maxWidth/cfg.Width)
+	dst := downscale(src, maxWidth, height)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		format = "png"
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("inline: encode image: %w", err)
+	}
+	return buf.Bytes(), "image/" + format, nil
+}
+
+// downscale shrinks src to width by height, averaging the source pixels
+// each destination pixel covers.
+func downscale(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/width)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// PublicTransport returns a transport that only connects to public
+// addresses. Image URLs come from feed content, so without it a feed could
+// have the server fetch from itself, its network or a cloud metadata
+// endpoint. The check runs on every dial, after DNS resolution and on each
+// redirect. Proxies from the environment are not used, as they would dial on
+// the transport's behalf.
+func PublicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnly,
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+// publicOnly is a net.Dialer Control hook that refuses to connect to
+// loopback, link-local, private and unspecified addresses.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("inline: dial %s: %w", address, err)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("inline: dial %s: %w", address, err)
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrNotPublic, ip)
+	}
+	return nil
+}
+
+// contentID derives a stable Content-ID from an image URL.
+func contentID(src string) string {
+	sum := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(sum[:12]) + "@" + cidDomain
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
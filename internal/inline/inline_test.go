@@ -0,0 +1,273 @@
+package inline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testImage draws a w by h gradient in the given format.
+func testImage(t *testing.T, format string, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+type imageServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+// newImageServer serves files by path, with their content types, counting
+// requests per path. Unknown paths are 404s.
+func newImageServer(t *testing.T, files map[string]string, bodies map[string][]byte) *imageServer {
+	t.Helper()
+	s := &imageServer{hits: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.mu.Unlock()
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", files[r.URL.Path])
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *imageServer) hitsFor(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+func decodeWidth(t *testing.T, data []byte) (int, string) {
+	t.Helper()
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode inlined image: %v", err)
+	}
+	return cfg.Width, format
+}
+
+func TestInline(t *testing.T) {
+	small := testImage(t, "jpeg", 200, 100)
+	srv := newImageServer(t,
+		map[string]string{"/wide.png": "image/png", "/small.jpg": "image/jpeg", "/page": "text/html"},
+		map[string][]byte{"/wide.png": testImage(t, "png", 1600, 400), "/small.jpg": small, "/page": []byte("<html></html>")},
+	)
+	in := New(srv.Client(), Config{MaxWidth: 400}, slog.New(slog.DiscardHandler))
+
+	body := `<p><img src="` + srv.URL + `/wide.png" alt="Wide"></p>` +
+		`<p><img src="` + srv.URL + `/small.jpg" srcset="` + srv.URL + `/small@2x.jpg 2x"></p>` +
+		`<p><img src="` + srv.URL + `/small.jpg"></p>` +
+		`<p><img src="` + srv.URL + `/missing.png"><img src="` + srv.URL + `/page"></p>` +
+		`<p><img src="data:image/gif;base64,R0lGODlhAQABAAAAACw="><img src="/relative.png"></p>`
+
+	got, images := in.Inline(context.Background(), body)
+	if len(images) != 2 {
+		t.Fatalf("expected 2 inlined images, got %d: %s", len(images), got)
+	}
+
+	wide, jpg := images[0], images[1]
+	if wide.CID != contentID(srv.URL+"/wide.png") || wide.ContentType != "image/png" {
+		t.Errorf("unexpected wide image: cid %q, type %q", wide.CID, wide.ContentType)
+	}
+	if width, format := decodeWidth(t, wide.Data); width != 400 || format != "png" {
+		t.Errorf("expected the wide image downscaled to a 400px PNG, got %dpx %s", width, format)
+	}
+	if jpg.ContentType != "image/jpeg" || !bytes.Equal(jpg.Data, small) {
+		t.Errorf("expected the narrow JPEG embedded unchanged, got %s of %d bytes", jpg.ContentType, len(jpg.Data))
+	}
+
+	if strings.Count(got, `src="cid:`+jpg.CID+`"`) != 2 {
+		t.Errorf("expected both references to the same image to share its Content-ID: %s", got)
+	}
+	if !strings.Contains(got, `src="cid:`+wide.CID+`" alt="Wide"`) {
+		t.Errorf("expected the wide image to reference its Content-ID: %s", got)
+	}
+	if strings.Contains(got, "srcset") {
+		t.Errorf("expected srcset dropped from inlined images: %s", got)
+	}
+	for _, remote := range []string{srv.URL + "/missing.png", srv.URL + "/page", "data:image/gif", "/relative.png"} {
+		if !strings.Contains(got, `src="`+remote) {
+			t.Errorf("expected %s left as it was: %s", remote, got)
+		}
+	}
+
+	// Content-IDs depend only on the URLs, so a resend is identical.
+	again, _ := in.Inline(context.Background(), body)
+	if again != got {
+		t.Errorf("expected the same body on a second send:\n%s\n%s", got, again)
+	}
+}
+
+func TestInlineWithoutImages(t *testing.T) {
+	in := New(nil, Config{}, slog.New(slog.DiscardHandler))
+	body := `<p>No pictures here.</p>`
+	got, images := in.Inline(context.Background(), body)
+	if got != body || images != nil {
+		t.Errorf("expected the body untouched, got %q with %d images", got, len(images))
+	}
+}
+
+func TestInlineLimits(t *testing.T) {
+	a := testImage(t, "png", 100, 100)
+	b := testImage(t, "png", 120, 100)
+	big := testImage(t, "png", 300, 300)
+	srv := newImageServer(t,
+		map[string]string{"/a.png": "image/png", "/b.png": "image/png", "/big.png": "image/png"},
+		map[string][]byte{"/a.png": a, "/b.png": b, "/big.png": big},
+	)
+	body := `<img src="` + srv.URL + `/a.png"><img src="` + srv.URL + `/b.png">`
+
+	t.Run("count", func(t *testing.T) {
+		in := New(srv.Client(), Config{MaxImages: 1}, slog.New(slog.DiscardHandler))
+		got, images := in.Inline(context.Background(), body)
+		if len(images) != 1 || !strings.Contains(got, `src="`+srv.URL+`/b.png"`) {
+			t.Errorf("expected only the first image inlined, got %d: %s", len(images), got)
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		in := New(srv.Client(), Config{MaxBytes: int64(len(a) + len(b) - 1)}, slog.New(slog.DiscardHandler))
+		got, images := in.Inline(context.Background(), body)
+		if len(images) != 1 || !strings.Contains(got, `src="`+srv.URL+`/b.png"`) {
+			t.Errorf("expected the second image left remote once the budget is spent, got %d: %s", len(images), got)
+		}
+	})
+
+	t.Run("download size", func(t *testing.T) {
+		in := New(srv.Client(), Config{MaxBytes: int64(len(big) - 1)}, slog.New(slog.DiscardHandler))
+		_, images := in.Inline(context.Background(), `<img src="`+srv.URL+`/big.png">`)
+		if len(images) != 0 {
+			t.Errorf("expected an image larger than the budget left remote, got %d inlined", len(images))
+		}
+	})
+}
+
+func TestInlineCache(t *testing.T) {
+	srv := newImageServer(t,
+		map[string]string{"/a.png": "image/png"},
+		map[string][]byte{"/a.png": testImage(t, "png", 50, 50)},
+	)
+	in := New(srv.Client(), Config{}, slog.New(slog.DiscardHandler))
+	body := `<img src="` + srv.URL + `/a.png">`
+
+	// One item fanned out to several subscribers fetches its images once.
+	for range 3 {
+		if _, images := in.Inline(context.Background(), body); len(images) != 1 {
+			t.Fatalf("expected 1 inlined image, got %d", len(images))
+		}
+	}
+	if hits := srv.hitsFor("/a.png"); hits != 1 {
+		t.Errorf("expected 1 download, got %d", hits)
+	}
+}
+
+func TestPublicTransport(t *testing.T) {
+	srv := newImageServer(t,
+		map[string]string{"/a.png": "image/png"},
+		map[string][]byte{"/a.png": testImage(t, "png", 50, 50)},
+	)
+	in := New(&http.Client{Transport: PublicTransport()}, Config{}, slog.New(slog.DiscardHandler))
+
+	// The test server listens on loopback, like an internal service would
+	body := `<img src="` + srv.URL + `/a.png">`
+	if got, images := in.Inline(context.Background(), body); got != body || len(images) != 0 {
+		t.Errorf("expected an image on a loopback address left remote, got %d inlined: %s", len(images), got)
+	}
+	if _, err := in.fetch(context.Background(), srv.URL+"/a.png"); !errors.Is(err, ErrNotPublic) {
+		t.Errorf("expected ErrNotPublic, got %v", err)
+	}
+	if hits := srv.hitsFor("/a.png"); hits != 0 {
+		t.Errorf("expected no request to reach the server, got %d", hits)
+	}
+
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"127.0.0.1:80", false},
+		{"[::1]:443", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.10:8080", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"93.184.215.14:443", true},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", true},
+	}
+	for _, tt := range tests {
+		if err := publicOnly("tcp", tt.address, nil); (err == nil) != tt.public {
+			t.Errorf("publicOnly(%s) = %v, want public %v", tt.address, err, tt.public)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		wantType  string
+		wantWidth int
+	}{
+		{"narrow png", testImage(t, "png", 300, 10), "image/png", 300},
+		{"wide png", testImage(t, "png", 900, 10), "image/png", 400},
+		{"wide jpeg", testImage(t, "jpeg", 900, 10), "image/jpeg", 400},
+		{"narrow gif", testImage(t, "gif", 100, 10), "image/gif", 100},
+		{"wide gif", testImage(t, "gif", 900, 10), "image/png", 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := fit(tt.data, 400)
+			if err != nil {
+				t.Fatalf("fit failed: %v", err)
+			}
+			if width, _ := decodeWidth(t, data); contentType != tt.wantType || width != tt.wantWidth {
+				t.Errorf("fit() = %s %dpx, want %s %dpx", contentType, width, tt.wantType, tt.wantWidth)
+			}
+		})
+	}
+
+	if _, _, err := fit([]byte("not an image"), 400); err == nil {
+		t.Error("expected an error for undecodable data")
+	}
+}
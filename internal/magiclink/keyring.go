@@ -0,0 +1,392 @@
+package magiclink
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rss2go/internal/database"
+	"rss2go/internal/types"
+)
+
+// tokenVersion prefixes every token so the format can change without
+// misreading older links.
+const tokenVersion = "v1"
+
+// Purpose scopes a token to one kind of action.
+type Purpose string
+
+const (
+	// PurposeManage authorizes viewing and changing all of a subscriber's subscriptions.
+	PurposeManage Purpose = "manage"
+	// PurposeUnsubscribe authorizes removing only the feeds named in the token.
+	PurposeUnsubscribe Purpose = "unsubscribe"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed, tampered or unknown-key tokens.
+	ErrInvalidToken = errors.New("magiclink: invalid token")
+	// ErrExpiredToken is returned for correctly signed tokens past their expiry.
+	ErrExpiredToken = errors.New("magiclink: token expired")
+	// ErrWrongPurpose is returned when a token is used for an action it was not issued for.
+	ErrWrongPurpose = errors.New("magiclink: token not valid for this action")
+)
+
+// Claims are the verified contents of a token.
+type Claims struct {
+	Email     string
+	Purpose   Purpose
+	FeedIDs   []int64
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// tokenPayload is the signed JSON body of a token. The email is bound by the
+// signature but carried separately in the link, keeping tokens short.
+type tokenPayload struct {
+	Purpose   Purpose `json:"p"`
+	FeedIDs   []int64 `json:"f,omitempty"`
+	IssuedAt  int64   `json:"iat"`
+	ExpiresAt int64   `json:"exp"`
+}
+
+// Config configures token lifetimes and key rotation.
+type Config struct {
+	// ManageTTL is how long subscriber management tokens stay valid.
+	ManageTTL time.Duration
+	// UnsubscribeTTL is how long emailed unsubscribe links stay valid.
+	UnsubscribeTTL time.Duration
+	// RotateEvery is the age at which the signing key is replaced.
+	RotateEvery time.Duration
+	// Grace is how long a retired key still verifies tokens. It defaults to
+	// the longest token lifetime so rotation never cuts a link short.
+	Grace time.Duration
+	// CheckInterval is how often the rotation check runs.
+	CheckInterval time.Duration
+	// Now returns the current time. Tests substitute a fake clock.
+	Now func() time.Time
+}
+
+// Keyring issues and verifies magic-link tokens. Keys are persisted in the
+// database when a repository is given, so links survive restarts; without one
+// the keyring lives in memory only.
+type Keyring struct {
+	repo         *database.Repository
+	cfg          Config
+	mu           sync.RWMutex
+	keys         []*types.MagicKey // Newest first
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+	log          *slog.Logger
+}
+
+// NewKeyring creates a Keyring. Call Load before issuing tokens.
+func NewKeyring(repo *database.Repository, cfg Config, log *slog.Logger) *Keyring {
+	if cfg.ManageTTL <= 0 {
+		cfg.ManageTTL = 7 * 24 * time.Hour
+	}
+	if cfg.UnsubscribeTTL <= 0 {
+		cfg.UnsubscribeTTL = 90 * 24 * time.Hour
+	}
+	if cfg.RotateEvery <= 0 {
+		cfg.RotateEvery = 30 * 24 * time.Hour
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = max(cfg.ManageTTL, cfg.UnsubscribeTTL)
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Hour
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if log == nil {
+		log = slog.Default().With("component", "magiclink")
+	}
+
+	return &Keyring{
+		repo:       repo,
+		cfg:        cfg,
+		shutdownCh: make(chan struct{}),
+		log:        log,
+	}
+}
+
+// Load reads the persisted keys, generating the first one on a fresh
+// database, and rotates the signing key if it is due.
+func (k *Keyring) Load(ctx context.Context) error {
+	if k.repo != nil {
+		keys, err := k.repo.ListMagicKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("magiclink: load keys: %w", err)
+		}
+		k.mu.Lock()
+		k.keys = keys
+		k.mu.Unlock()
+	}
+	return k.RotateIfDue(ctx)
+}
+
+// Start runs the rotation check loop. It blocks until context is cancelled or Stop is called.
+func (k *Keyring) Start(ctx context.Context) error {
+	ticker := time.NewTicker(k.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := k.RotateIfDue(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				k.log.Error("Magic link key rotation error", "err", err)
+			}
+		case <-ctx.Done():
+			k.Stop()
+			return ctx.Err()
+		case <-k.shutdownCh:
+			return nil
+		}
+	}
+}
+
+// Stop signals the rotation loop to exit.
+func (k *Keyring) Stop() {
+	k.shutdownOnce.Do(func() {
+		close(k.shutdownCh)
+	})
+}
+
+// RotateIfDue replaces the signing key once it is older than RotateEvery, or
+// creates one if there is none, and drops retired keys past their grace period.
+func (k *Keyring) RotateIfDue(ctx context.Context) error {
+	k.mu.RLock()
+	current := k.current()
+	k.mu.RUnlock()
+
+	if current == nil || k.cfg.Now().Sub(current.CreatedAt) >= k.cfg.RotateEvery {
+		return k.Rotate(ctx)
+	}
+	return k.prune(ctx)
+}
+
+// Rotate retires the current signing key and starts signing with a new one.
+// Tokens signed with the retired key stay valid for the grace period.
+func (k *Keyring) Rotate(ctx context.Context) error {
+	now := k.cfg.Now()
+	key := &types.MagicKey{Secret: NewSecret(), CreatedAt: now}
+
+	if k.repo == nil {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		key.ID = 1
+		if len(k.keys) > 0 {
+			key.ID = k.keys[0].ID + 1
+		}
+		keys := []*types.MagicKey{key}
+		for _, old := range k.keys {
+			if old.RetiredAt == nil {
+				retired := *old
+				retired.RetiredAt = &now
+				old = &retired
+			}
+			keys = append(keys, old)
+		}
+		k.keys = unexpired(keys, k.cutoff())
+		k.log.Info("Rotated magic link signing key", "key_id", key.ID)
+		return nil
+	}
+
+	err := k.repo.WithTx(ctx, func(txRepo *database.Repository) error {
+		if err := txRepo.RetireMagicKeys(ctx, now); err != nil {
+			return err
+		}
+		return txRepo.CreateMagicKey(ctx, key)
+	})
+	if err != nil {
+		return fmt.Errorf("magiclink: rotate key: %w", err)
+	}
+	k.log.Info("Rotated magic link signing key", "key_id", key.ID)
+	return k.prune(ctx)
+}
+
+// prune drops retired keys past their grace period. With a repository, the
+// keys are deleted there and the keyring is reloaded from it; the database
+// work happens before k.mu is taken, so signing and verifying never wait on it.
+func (k *Keyring) prune(ctx context.Context) error {
+	cutoff := k.cutoff()
+	if k.repo == nil {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		k.keys = unexpired(k.keys, cutoff)
+		return nil
+	}
+
+	if err := k.repo.DeleteMagicKeysRetiredBefore(ctx, cutoff); err != nil {
+		return fmt.Errorf("magiclink: prune keys: %w", err)
+	}
+	keys, err := k.repo.ListMagicKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("magiclink: load keys: %w", err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = unexpired(keys, cutoff)
+	return nil
+}
+
+// cutoff returns the retirement time before which keys no longer verify.
+func (k *Keyring) cutoff() time.Time {
+	return k.cfg.Now().Add(-k.cfg.Grace)
+}
+
+// unexpired returns a new slice of the keys not retired before cutoff.
+func unexpired(keys []*types.MagicKey, cutoff time.Time) []*types.MagicKey {
+	kept := make([]*types.MagicKey, 0, len(keys))
+	for _, key := range keys {
+		if key.RetiredAt == nil || !key.RetiredAt.Before(cutoff) {
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
+
+// current returns the signing key. Callers must hold k.mu.
+func (k *Keyring) current() *types.MagicKey {
+	for _, key := range k.keys {
+		if key.RetiredAt == nil {
+			return key
+		}
+	}
+	return nil
+}
+
+// DeriveKey returns a key for another purpose derived from the current
+// signing key, so rotating it also rotates anything derived from it.
+func (k *Keyring) DeriveKey(label string) []byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var secret string
+	if key := k.current(); key != nil {
+		secret = key.Secret
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// Issue returns a token authorizing purpose for email, limited to feedIDs
+// for unsubscribe tokens. It returns "" if the keyring holds no signing key.
+func (k *Keyring) Issue(email string, purpose Purpose, feedIDs ...int64) string {
+	k.mu.RLock()
+	key := k.current()
+	k.mu.RUnlock()
+	if key == nil {
+		return ""
+	}
+
+	ttl := k.cfg.ManageTTL
+	if purpose == PurposeUnsubscribe {
+		ttl = k.cfg.UnsubscribeTTL
+	}
+	now := k.cfg.Now()
+	raw, _ := json.Marshal(tokenPayload{
+		Purpose:   purpose,
+		FeedIDs:   feedIDs,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+
+	signed := tokenVersion + "." + strconv.FormatInt(key.ID, 10) + "." + base64.RawURLEncoding.EncodeToString(raw)
+	return signed + "." + sign(key.Secret, signed, email)
+}
+
+// Verify checks that token was issued by this keyring for email and purpose
+// and has not expired.
+func (k *Keyring) Verify(token, email string, purpose Purpose) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 || parts[0] != tokenVersion {
+		return nil, ErrInvalidToken
+	}
+	keyID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := k.cfg.Now()
+	key := k.verificationKey(keyID, now)
+	if key == nil {
+		return nil, ErrInvalidToken
+	}
+	signed := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(sign(key.Secret, signed, email))) {
+		return nil, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var payload tokenPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{
+		Email:     email,
+		Purpose:   payload.Purpose,
+		FeedIDs:   payload.FeedIDs,
+		IssuedAt:  time.Unix(payload.IssuedAt, 0),
+		ExpiresAt: time.Unix(payload.ExpiresAt, 0),
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrWrongPurpose
+	}
+	return claims, nil
+}
+
+// verificationKey returns the key with the given ID if it may still verify
+// tokens at now.
+func (k *Keyring) verificationKey(id int64, now time.Time) *types.MagicKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID != id {
+			continue
+		}
+		if key.RetiredAt != nil && now.After(key.RetiredAt.Add(k.cfg.Grace)) {
+			return nil
+		}
+		return key
+	}
+	return nil
+}
+
+// sign returns the base64url HMAC binding the signed token prefix to email.
+func sign(secret, signed, email string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed + "\n" + email))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
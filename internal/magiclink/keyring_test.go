@@ -0,0 +1,224 @@
+package magiclink
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rss2go/internal/database"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func setupTestDB(t *testing.T) *database.Repository {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return database.NewRepository(db)
+}
+
+func newTestKeyring(t *testing.T, repo *database.Repository, clock *fakeClock) *Keyring {
+	t.Helper()
+	keys := NewKeyring(repo, Config{
+		ManageTTL:      time.Hour,
+		UnsubscribeTTL: 24 * time.Hour,
+		RotateEvery:    48 * time.Hour,
+		Now:            clock.Now,
+	}, slog.New(slog.DiscardHandler))
+	if err := keys.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return keys
+}
+
+func TestKeyringVerify(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)}
+	keys := newTestKeyring(t, nil, clock)
+
+	token := keys.Issue("reader@example.com", PurposeManage)
+	claims, err := keys.Verify(token, "reader@example.com", PurposeManage)
+	if err != nil {
+		t.Fatalf("expected token to verify: %v", err)
+	}
+	if !claims.IssuedAt.Equal(clock.now) || !claims.ExpiresAt.Equal(clock.now.Add(time.Hour)) {
+		t.Errorf("unexpected token lifetime %v - %v", claims.IssuedAt, claims.ExpiresAt)
+	}
+
+	if _, err := keys.Verify(token, "other@example.com", PurposeManage); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token for another address to be rejected, got %v", err)
+	}
+	if _, err := keys.Verify(token, "reader@example.com", PurposeUnsubscribe); !errors.Is(err, ErrWrongPurpose) {
+		t.Errorf("expected manage token to be rejected for unsubscribe, got %v", err)
+	}
+
+	other := newTestKeyring(t, nil, clock)
+	if _, err := other.Verify(token, "reader@example.com", PurposeManage); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token from another keyring to be rejected, got %v", err)
+	}
+}
+
+func TestKeyringRejectsTamperedTokens(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)}
+	keys := newTestKeyring(t, nil, clock)
+	token := keys.Issue("reader@example.com", PurposeUnsubscribe, 3)
+	parts := strings.Split(token, ".")
+
+	// Widen the feed scope and push the expiry out, keeping the signature.
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"p":"unsubscribe","f":[3,4],"iat":0,"exp":9999999999}`))
+	flipped := []byte(parts[3])
+	flipped[0] ^= 1
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"legacy hex token", "7f3a9c"},
+		{"wrong version", "v0." + strings.Join(parts[1:], ".")},
+		{"unknown key", strings.Join([]string{parts[0], "99", parts[2], parts[3]}, ".")},
+		{"forged payload", strings.Join([]string{parts[0], parts[1], forged, parts[3]}, ".")},
+		{"flipped signature", strings.Join([]string{parts[0], parts[1], parts[2], string(flipped)}, ".")},
+		{"truncated", strings.Join(parts[:3], ".")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keys.Verify(tt.token, "reader@example.com", PurposeUnsubscribe); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestKeyringExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)}
+	keys := newTestKeyring(t, nil, clock)
+
+	manage := keys.Issue("reader@example.com", PurposeManage)
+	unsubscribe := keys.Issue("reader@example.com", PurposeUnsubscribe, 1)
+
+	clock.Advance(time.Hour - time.Second)
+	if _, err := keys.Verify(manage, "reader@example.com", PurposeManage); err != nil {
+		t.Errorf("expected manage token to verify before expiry: %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if _, err := keys.Verify(manage, "reader@example.com", PurposeManage); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expected manage token to expire after its TTL, got %v", err)
+	}
+	if _, err := keys.Verify(unsubscribe, "reader@example.com", PurposeUnsubscribe); err != nil {
+		t.Errorf("expected longer-lived unsubscribe token to still verify: %v", err)
+	}
+
+	clock.Advance(23 * time.Hour)
+	if _, err := keys.Verify(unsubscribe, "reader@example.com", PurposeUnsubscribe); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expected unsubscribe token to expire after its TTL, got %v", err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)}
+	keys := newTestKeyring(t, repo, clock)
+
+	stored, err := repo.ListMagicKeys(ctx)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("expected first start to persist one key, got %d (err %v)", len(stored), err)
+	}
+
+	// A restart loads the same key, so earlier links keep working.
+	old := keys.Issue("reader@example.com", PurposeUnsubscribe, 1)
+	restarted := newTestKeyring(t, repo, clock)
+	if _, err := restarted.Verify(old, "reader@example.com", PurposeUnsubscribe); err != nil {
+		t.Fatalf("expected token to survive a restart: %v", err)
+	}
+
+	// Not yet due: nothing changes.
+	clock.Advance(47 * time.Hour)
+	if err := keys.RotateIfDue(ctx); err != nil {
+		t.Fatalf("RotateIfDue failed: %v", err)
+	}
+	if stored, _ := repo.ListMagicKeys(ctx); len(stored) != 1 {
+		t.Fatalf("expected no rotation before RotateEvery, got %d keys", len(stored))
+	}
+
+	old = keys.Issue("reader@example.com", PurposeUnsubscribe, 1)
+	clock.Advance(time.Hour)
+	if err := keys.RotateIfDue(ctx); err != nil {
+		t.Fatalf("RotateIfDue failed: %v", err)
+	}
+	stored, _ = repo.ListMagicKeys(ctx)
+	if len(stored) != 2 || stored[0].RetiredAt != nil || stored[1].RetiredAt == nil {
+		t.Fatalf("expected a new active key and a retired one, got %+v", stored)
+	}
+
+	fresh := keys.Issue("reader@example.com", PurposeUnsubscribe, 1)
+	if strings.Split(fresh, ".")[1] == strings.Split(old, ".")[1] {
+		t.Errorf("expected new tokens to be signed with the new key")
+	}
+	if _, err := keys.Verify(old, "reader@example.com", PurposeUnsubscribe); err != nil {
+		t.Errorf("expected token signed before rotation to verify during the grace period: %v", err)
+	}
+
+	// Once the grace period ends the retired key is dropped.
+	clock.Advance(24*time.Hour + time.Second)
+	if err := keys.RotateIfDue(ctx); err != nil {
+		t.Fatalf("RotateIfDue failed: %v", err)
+	}
+	if _, err := keys.Verify(old, "reader@example.com", PurposeUnsubscribe); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token from a pruned key to be rejected, got %v", err)
+	}
+	if stored, _ := repo.ListMagicKeys(ctx); len(stored) != 1 {
+		t.Errorf("expected retired key to be pruned, got %d keys", len(stored))
+	}
+}
+
+func TestKeyringVerifyDuringRotation(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)}
+	for _, repo := range []*database.Repository{nil, setupTestDB(t)} {
+		keys := newTestKeyring(t, repo, clock)
+		token := keys.Issue("reader@example.com", PurposeUnsubscribe, 1)
+
+		// Rotation swaps in a new key list rather than editing the one
+		// readers see, so concurrent verification stays race-free.
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					if _, err := keys.Verify(token, "reader@example.com", PurposeUnsubscribe); err != nil {
+						t.Errorf("expected token to verify during rotation: %v", err)
+						return
+					}
+				}
+			})
+		}
+		for range 5 {
+			if err := keys.Rotate(context.Background()); err != nil {
+				t.Errorf("Rotate failed: %v", err)
+			}
+		}
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,40 @@
+package magiclink
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UnsubscribePath is the public endpoint behind unsubscribe links. GET shows a
+// confirmation page; POST, including RFC 8058 one-click requests from mail
+// clients, performs the unsubscribe.
+const UnsubscribePath = "/unsubscribe"
+
+// Links builds signed subscriber links against the server's public base URL.
+// A nil Links, or one without a base URL, builds no links.
+type Links struct {
+	baseURL string
+	keys    *Keyring
+}
+
+// NewLinks creates a Links for the given public base URL and keyring.
+func NewLinks(baseURL string, keys *Keyring) *Links {
+	return &Links{baseURL: strings.TrimSuffix(baseURL, "/"), keys: keys}
+}
+
+// Unsubscribe returns the URL that unsubscribes email from the given feeds, or
+// "" when no public base URL is configured.
+func (l *Links) Unsubscribe(email string, feedIDs ...int64) string {
+	if l == nil || l.baseURL == "" || len(feedIDs) == 0 {
+		return ""
+	}
+	token := l.keys.Issue(email, PurposeUnsubscribe, feedIDs...)
+	if token == "" {
+		return ""
+	}
+
+	q := url.Values{}
+	q.Set("email", email)
+	q.Set("token", token)
+	return l.baseURL + UnsubscribePath + "?" + q.Encode()
+}
@@ -0,0 +1,40 @@
+package magiclink
+
+import (
+	"context"
+	"net/url"
+	"testing"
+)
+
+func TestUnsubscribeLink(t *testing.T) {
+	keys := NewKeyring(nil, Config{}, nil)
+	if err := keys.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	links := NewLinks("https://rss.example.com/", keys)
+	raw := links.Unsubscribe("reader+news@example.com", 3, 7)
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("invalid link %q: %v", raw, err)
+	}
+	if u.Scheme != "https" || u.Host != "rss.example.com" || u.Path != UnsubscribePath {
+		t.Errorf("unexpected link %q", raw)
+	}
+	q := u.Query()
+	claims, err := keys.Verify(q.Get("token"), q.Get("email"), PurposeUnsubscribe)
+	if err != nil {
+		t.Fatalf("expected link token to verify: %v", err)
+	}
+	if claims.Email != "reader+news@example.com" || len(claims.FeedIDs) != 2 || claims.FeedIDs[1] != 7 {
+		t.Errorf("unexpected link claims %+v", claims)
+	}
+
+	var nilLinks *Links
+	if nilLinks.Unsubscribe("reader@example.com", 1) != "" || NewLinks("", keys).Unsubscribe("reader@example.com", 1) != "" {
+		t.Errorf("expected no link without a base URL")
+	}
+	if links.Unsubscribe("reader@example.com") != "" {
+		t.Errorf("expected no link without feeds")
+	}
+}
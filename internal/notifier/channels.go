@@ -0,0 +1,293 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"rss2go/internal/types"
+)
+
+// Message is a channel-neutral notification. Render turns it into the payload
+// stored in the outbox for one kind of channel.
+type Message struct {
+	Subject   string // Email subject and push notification title
+	FeedTitle string
+	Title     string // Item title; empty for digests
+	Link      string
+	HTML      string // Complete HTML body as emailed
+	// UnsubscribeURL, when set, adds an unsubscribe footer to emails and is
+	// advertised through List-Unsubscribe headers.
+	UnsubscribeURL string
+}
+
+// webhookPayload is the JSON body POSTed to generic webhook channels.
+type webhookPayload struct {
+	Subject   string `json:"subject"`
+	FeedTitle string `json:"feed_title,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Link      string `json:"link,omitempty"`
+	HTML      string `json:"html"`
+
+	UnsubscribeURL string `json:"unsubscribe_url,omitempty"`
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+type matrixPayload struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// ntfyPayload is the stored form of an ntfy notification; Deliver maps it onto
+// ntfy's header-based publish API.
+type ntfyPayload struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Click   string `json:"click,omitempty"`
+}
+
+// Render produces the outbox body for a channel kind. Email bodies are HTML;
+// every other kind is a JSON document understood by Dispatcher.Deliver.
+func Render(kind types.ChannelKind, msg *Message) (string, error) {
+	var payload any
+	switch kind {
+	case types.ChannelEmail:
+		return msg.HTML + unsubscribeFooter(msg.UnsubscribeURL), nil
+	case types.ChannelWebhook:
+		payload = webhookPayload{
+			Subject: msg.Subject, FeedTitle: msg.FeedTitle, Title: msg.Title, Link: msg.Link, HTML: msg.HTML,
+			UnsubscribeURL: msg.UnsubscribeURL,
+		}
+	case types.ChannelSlack:
+		payload = slackPayload{Text: slackText(msg)}
+	case types.ChannelMatrix:
+		payload = matrixPayload{MsgType: "m.text", Body: plainSummary(msg), Format: "org.matrix.custom.html", FormattedBody: msg.HTML}
+	case types.ChannelNtfy:
+		payload = ntfyPayload{Title: msg.Subject, Message: plainSummary(msg), Click: msg.Link}
+	default:
+		return "", fmt.Errorf("notifier: unknown channel kind %q", kind)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("notifier: render %s payload: %w", kind, err)
+	}
+	return string(b), nil
+}
+
+// unsubscribeFooter is the small print appended to emails that carry an unsubscribe link.
+func unsubscribeFooter(link string) string {
+	if link == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<hr><p style="font-size:12px;color:#666">Don't want these emails any more? <a href="%s">Unsubscribe</a>.</p>`, html.EscapeString(link))
+}
+
+// slackText renders a message in Slack mrkdwn, which Mattermost also accepts.
+func slackText(msg *Message) string {
+	if msg.Title == "" {
+		return slackEscape(msg.Subject)
+	}
+	text := slackEscape(msg.Title)
+	if msg.Link != "" {
+		text = fmt.Sprintf("<%s|%s>", msg.Link, text)
+	}
+	if msg.FeedTitle != "" {
+		text = fmt.Sprintf("*%s*\n%s", slackEscape(msg.FeedTitle), text)
+	}
+	return text
+}
+
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// plainSummary is the short plain-text form used by push-style channels.
+func plainSummary(msg *Message) string {
+	if msg.Title == "" {
+		return msg.Subject
+	}
+	lines := []string{msg.Title}
+	if msg.FeedTitle != "" {
+		lines[0] = msg.FeedTitle + ": " + msg.Title
+	}
+	if msg.Link != "" {
+		lines = append(lines, msg.Link)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateChannel checks that a channel has the fields its kind requires.
+func ValidateChannel(ch *types.Channel) error {
+	switch ch.Kind {
+	case types.ChannelEmail:
+		if _, err := mail.ParseAddress(ch.Target); err != nil {
+			return fmt.Errorf("notifier: invalid email address %q", ch.Target)
+		}
+	case types.ChannelWebhook, types.ChannelSlack, types.ChannelNtfy:
+		if !isHTTPURL(ch.Target) {
+			return fmt.Errorf("notifier: %s target must be an http(s) URL", ch.Kind)
+		}
+	case types.ChannelMatrix:
+		if !isHTTPURL(ch.Server) {
+			return fmt.Errorf("notifier: matrix server must be an http(s) URL")
+		}
+		if !strings.HasPrefix(ch.Target, "!") || !strings.Contains(ch.Target, ":") {
+			return fmt.Errorf("notifier: matrix target must be a room ID such as !abc123:example.org")
+		}
+		if ch.Token == "" {
+			return fmt.Errorf("notifier: matrix channels require an access token")
+		}
+	default:
+		return fmt.Errorf("notifier: unknown channel kind %q (expected email, webhook, slack, matrix or ntfy)", ch.Kind)
+	}
+	return nil
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Inliner replaces the remote images of an email body with inline images,
+// returning the rewritten body and the images it now references. Images it
+// cannot fetch keep their remote URLs.
+type Inliner interface {
+	Inline(ctx context.Context, body string) (string, []InlineImage)
+}
+
+// Dispatcher delivers rendered outbox items to their channels. Email goes
+// through the daemon's configured Sender; every other kind is an HTTP request.
+type Dispatcher struct {
+	email   Sender
+	client  *http.Client
+	inliner Inliner
+}
+
+// NewDispatcher creates a Dispatcher. email may be nil when no mailer is configured.
+func NewDispatcher(email Sender, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+	return &Dispatcher{email: email, client: client}
+}
+
+// SetInliner embeds the images of emails in the messages at delivery time,
+// when the email sender supports it. Outbox items keep their remote image
+// URLs, so retries fetch the images again.
+func (d *Dispatcher) SetInliner(in Inliner) {
+	d.inliner = in
+}
+
+// Deliver sends one rendered outbox item to ch. A nil channel means a plain
+// email to the item's recipients.
+func (d *Dispatcher) Deliver(ctx context.Context, ch *types.Channel, item *types.OutboxItem) error {
+	if ch == nil || ch.Kind == types.ChannelEmail {
+		if d.email == nil {
+			return fmt.Errorf("notifier: no email sender configured")
+		}
+		recipients := item.Recipients
+		if ch != nil && len(recipients) == 0 {
+			recipients = []string{ch.Target}
+		}
+		var headers map[string]string
+		if item.ListUnsubscribe != "" {
+			// RFC 8058: mail clients POST "List-Unsubscribe=One-Click" to the URL.
+			headers = map[string]string{
+				"List-Unsubscribe":      "<" + item.ListUnsubscribe + ">",
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			}
+		}
+		if is, ok := d.email.(InlineSender); ok && d.inliner != nil {
+			body, images := d.inliner.Inline(ctx, item.Body)
+			if len(images) > 0 {
+				return is.SendInline(ctx, item.Subject, body, recipients, headers, images)
+			}
+		}
+		return d.email.Send(ctx, item.Subject, item.Body, recipients, headers)
+	}
+
+	switch ch.Kind {
+	case types.ChannelWebhook:
+		header := http.Header{"Content-Type": {"application/json"}}
+		if ch.Token != "" {
+			mac := hmac.New(sha256.New, []byte(ch.Token))
+			_, _ = mac.Write([]byte(item.Body))
+			header.Set("X-Rss2go-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		return d.do(ctx, http.MethodPost, ch.Target, header, []byte(item.Body))
+
+	case types.ChannelSlack:
+		return d.do(ctx, http.MethodPost, ch.Target, http.Header{"Content-Type": {"application/json"}}, []byte(item.Body))
+
+	case types.ChannelMatrix:
+		// The transaction ID makes retries of the same outbox item idempotent.
+		endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/rss2go-%d",
+			strings.TrimSuffix(ch.Server, "/"), url.PathEscape(ch.Target), item.ID)
+		header := http.Header{
+			"Content-Type":  {"application/json"},
+			"Authorization": {"Bearer " + ch.Token},
+		}
+		return d.do(ctx, http.MethodPut, endpoint, header, []byte(item.Body))
+
+	case types.ChannelNtfy:
+		var p ntfyPayload
+		if err := json.Unmarshal([]byte(item.Body), &p); err != nil {
+			return fmt.Errorf("notifier: decode ntfy payload: %w", err)
+		}
+		header := http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+		// ntfy headers must be ASCII; RFC 2047 encoding is understood by the server.
+		header.Set("Title", mime.QEncoding.Encode("utf-8", p.Title))
+		if p.Click != "" {
+			header.Set("Click", p.Click)
+		}
+		if ch.Token != "" {
+			header.Set("Authorization", "Bearer "+ch.Token)
+		}
+		return d.do(ctx, http.MethodPost, ch.Target, header, []byte(p.Message))
+
+	default:
+		return fmt.Errorf("notifier: unknown channel kind %q", ch.Kind)
+	}
+}
+
+// do performs one HTTP delivery request and expects a 2xx reply.
+func (d *Dispatcher) do(ctx context.Context, method, target string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("notifier: create request: %w", err)
+	}
+	req.Header = header
+	req.Header.Set("User-Agent", "rss2go/1.0 (Syndication Aggregator Daemon)")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notifier: request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notifier: %s returned status %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"rss2go/internal/types"
+)
+
+type capturedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// captureServer records the requests it receives and answers with status.
+func captureServer(t *testing.T, status int) (*httptest.Server, *[]capturedRequest) {
+	t.Helper()
+	var reqs []capturedRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, capturedRequest{Method: r.Method, Path: r.URL.EscapedPath(), Header: r.Header.Clone(), Body: string(body)})
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("reply"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &reqs
+}
+
+type recordingSender struct {
+	subject    string
+	body       string
+	recipients []string
+	headers    map[string]string
+}
+
+func (s *recordingSender) Send(ctx context.Context, subject, body string, recipients []string, headers map[string]string) error {
+	s.subject, s.body, s.recipients, s.headers = subject, body, recipients, headers
+	return nil
+}
+
+// inlineSender is a recordingSender that also accepts inline images.
+type inlineSender struct {
+	recordingSender
+	images []InlineImage
+}
+
+func (s *inlineSender) SendInline(ctx context.Context, subject, body string, recipients []string, headers map[string]string, images []InlineImage) error {
+	s.images = images
+	return s.Send(ctx, subject, body, recipients, headers)
+}
+
+// stubInliner embeds every body as a single image, unless it has no images.
+type stubInliner struct{}
+
+func (stubInliner) Inline(ctx context.Context, body string) (string, []InlineImage) {
+	if !strings.Contains(body, "<img") {
+		return body, nil
+	}
+	return `<img src="cid:1@rss2go">`, []InlineImage{{CID: "1@rss2go", ContentType: "image/png", Data: []byte("png")}}
+}
+
+var testMessage = &Message{
+	Subject:   "[Go Blog] Go 1.99 <released>",
+	FeedTitle: "Go Blog",
+	Title:     "Go 1.99 <released>",
+	Link:      "https://go.dev/blog/go1.99",
+	HTML:      "<h2>Go 1.99</h2><p>It is out.</p>",
+}
+
+func renderItem(t *testing.T, kind types.ChannelKind) *types.OutboxItem {
+	t.Helper()
+	body, err := Render(kind, testMessage)
+	if err != nil {
+		t.Fatalf("Render(%s) failed: %v", kind, err)
+	}
+	return &types.OutboxItem{ID: 42, Subject: testMessage.Subject, Body: body}
+}
+
+func TestValidateChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		ch      types.Channel
+		wantErr bool
+	}{
+		{"email", types.Channel{Kind: types.ChannelEmail, Target: "me@example.com"}, false},
+		{"bad email", types.Channel{Kind: types.ChannelEmail, Target: "not-an-address"}, true},
+		{"webhook", types.Channel{Kind: types.ChannelWebhook, Target: "https://hooks.example.com/x"}, false},
+		{"webhook without scheme", types.Channel{Kind: types.ChannelWebhook, Target: "hooks.example.com/x"}, true},
+		{"slack", types.Channel{Kind: types.ChannelSlack, Target: "https://hooks.slack.com/services/T/B/X"}, false},
+		{"ntfy", types.Channel{Kind: types.ChannelNtfy, Target: "https://ntfy.sh/rss2go"}, false},
+		{"matrix", types.Channel{Kind: types.ChannelMatrix, Server: "https://matrix.example.org", Target: "!abc:example.org", Token: "t"}, false},
+		{"matrix alias", types.Channel{Kind: types.ChannelMatrix, Server: "https://matrix.example.org", Target: "#news:example.org", Token: "t"}, true},
+		{"matrix without token", types.Channel{Kind: types.ChannelMatrix, Server: "https://matrix.example.org", Target: "!abc:example.org"}, true},
+		{"unknown kind", types.Channel{Kind: "pager", Target: "https://example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateChannel(&tt.ch); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateChannel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDispatcherEmail(t *testing.T) {
+	sender := &recordingSender{}
+	d := NewDispatcher(sender, nil)
+	ctx := context.Background()
+
+	item := &types.OutboxItem{Subject: "s", Body: "<p>b</p>", Recipients: []string{"legacy@example.com"}}
+	if err := d.Deliver(ctx, nil, item); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if sender.recipients[0] != "legacy@example.com" || sender.body != "<p>b</p>" {
+		t.Errorf("unexpected email send: %+v", sender)
+	}
+
+	ch := &types.Channel{Kind: types.ChannelEmail, Target: "channel@example.com"}
+	if err := d.Deliver(ctx, ch, &types.OutboxItem{Subject: "s", Body: "b"}); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if sender.recipients[0] != "channel@example.com" {
+		t.Errorf("expected channel target as recipient, got %v", sender.recipients)
+	}
+
+	if sender.headers != nil {
+		t.Errorf("expected no extra headers without an unsubscribe URL, got %v", sender.headers)
+	}
+
+	item.ListUnsubscribe = "https://rss.example.com/unsubscribe?feed=1"
+	if err := d.Deliver(ctx, nil, item); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if sender.headers["List-Unsubscribe"] != "<https://rss.example.com/unsubscribe?feed=1>" ||
+		sender.headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Errorf("unexpected unsubscribe headers %v", sender.headers)
+	}
+
+	if err := NewDispatcher(nil, nil).Deliver(ctx, nil, item); err == nil {
+		t.Errorf("expected an error without an email sender")
+	}
+}
+
+func TestDispatcherInlinesImages(t *testing.T) {
+	ctx := context.Background()
+	item := &types.OutboxItem{Subject: "s", Body: `<img src="https://img.example.com/a.png">`, Recipients: []string{"me@example.com"}}
+
+	sender := &inlineSender{}
+	d := NewDispatcher(sender, nil)
+	if err := d.Deliver(ctx, nil, item); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if sender.images != nil || sender.body != item.Body {
+		t.Errorf("expected remote images without an inliner, got body %q and %d images", sender.body, len(sender.images))
+	}
+
+	d.SetInliner(stubInliner{})
+	if err := d.Deliver(ctx, nil, item); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if len(sender.images) != 1 || sender.body != `<img src="cid:1@rss2go">` {
+		t.Errorf("expected an inlined image, got body %q and %d images", sender.body, len(sender.images))
+	}
+	if item.Body != `<img src="https://img.example.com/a.png">` {
+		t.Errorf("inlining must not change the stored outbox body, got %q", item.Body)
+	}
+
+	// Senders that cannot embed images get the remote body.
+	plain := &recordingSender{}
+	d = NewDispatcher(plain, nil)
+	d.SetInliner(stubInliner{})
+	if err := d.Deliver(ctx, nil, item); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if plain.body != item.Body {
+		t.Errorf("expected the remote body for a plain sender, got %q", plain.body)
+	}
+}
+
+func TestRenderUnsubscribeFooter(t *testing.T) {
+	msg := *testMessage
+	if body, _ := Render(types.ChannelEmail, &msg); body != msg.HTML {
+		t.Errorf("expected no footer without an unsubscribe URL, got %s", body)
+	}
+
+	msg.UnsubscribeURL = "https://rss.example.com/unsubscribe?email=a%40b.c&feed=1&token=t"
+	body, _ := Render(types.ChannelEmail, &msg)
+	if !strings.HasPrefix(body, msg.HTML) || !strings.Contains(body, `href="https://rss.example.com/unsubscribe?email=a%40b.c&amp;feed=1&amp;token=t"`) {
+		t.Errorf("expected an escaped unsubscribe footer, got %s", body)
+	}
+	hook, _ := Render(types.ChannelWebhook, &msg)
+	if !strings.Contains(hook, `"unsubscribe_url":`) {
+		t.Errorf("expected webhook payload to carry the unsubscribe URL, got %s", hook)
+	}
+}
+
+func TestDispatcherWebhook(t *testing.T) {
+	ts, reqs := captureServer(t, http.StatusNoContent)
+	d := NewDispatcher(nil, ts.Client())
+
+	item := renderItem(t, types.ChannelWebhook)
+	ch := &types.Channel{Kind: types.ChannelWebhook, Target: ts.URL + "/hook", Token: "s3cret"}
+	if err := d.Deliver(context.Background(), ch, item); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	req := (*reqs)[0]
+	if req.Method != http.MethodPost || req.Path != "/hook" {
+		t.Errorf("unexpected request %s %s", req.Method, req.Path)
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal([]byte(req.Body), &payload); err != nil {
+		t.Fatalf("invalid webhook JSON: %v", err)
+	}
+	if payload.Title != testMessage.Title || payload.Link != testMessage.Link || payload.HTML != testMessage.HTML {
+		t.Errorf("unexpected webhook payload %+v", payload)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(req.Body))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get("X-Rss2go-Signature") != want {
+		t.Errorf("signature = %q, want %q", req.Header.Get("X-Rss2go-Signature"), want)
+	}
+}
+
+func TestDispatcherSlack(t *testing.T) {
+	ts, reqs := captureServer(t, http.StatusOK)
+	d := NewDispatcher(nil, ts.Client())
+
+	ch := &types.Channel{Kind: types.ChannelSlack, Target: ts.URL}
+	if err := d.Deliver(context.Background(), ch, renderItem(t, types.ChannelSlack)); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	var payload slackPayload
+	_ = json.Unmarshal([]byte((*reqs)[0].Body), &payload)
+	want := "*Go Blog*\n<https://go.dev/blog/go1.99|Go 1.99 &lt;released&gt;>"
+	if payload.Text != want {
+		t.Errorf("slack text = %q, want %q", payload.Text, want)
+	}
+}
+
+func TestDispatcherMatrix(t *testing.T) {
+	ts, reqs := captureServer(t, http.StatusOK)
+	d := NewDispatcher(nil, ts.Client())
+
+	ch := &types.Channel{Kind: types.ChannelMatrix, Server: ts.URL + "/", Target: "!room:example.org", Token: "syt_abc"}
+	if err := d.Deliver(context.Background(), ch, renderItem(t, types.ChannelMatrix)); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	req := (*reqs)[0]
+	if req.Method != http.MethodPut {
+		t.Errorf("expected PUT, got %s", req.Method)
+	}
+	if want := "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/rss2go-42"; req.Path != want {
+		t.Errorf("path = %q, want %q", req.Path, want)
+	}
+	if req.Header.Get("Authorization") != "Bearer syt_abc" {
+		t.Errorf("unexpected Authorization header %q", req.Header.Get("Authorization"))
+	}
+	var payload matrixPayload
+	_ = json.Unmarshal([]byte(req.Body), &payload)
+	if payload.MsgType != "m.text" || payload.FormattedBody != testMessage.HTML || !strings.HasPrefix(payload.Body, "Go Blog: Go 1.99") {
+		t.Errorf("unexpected matrix payload %+v", payload)
+	}
+}
+
+func TestDispatcherNtfy(t *testing.T) {
+	ts, reqs := captureServer(t, http.StatusOK)
+	d := NewDispatcher(nil, ts.Client())
+
+	msg := *testMessage
+	msg.Subject = "[Blog] Café"
+	body, _ := Render(types.ChannelNtfy, &msg)
+	ch := &types.Channel{Kind: types.ChannelNtfy, Target: ts.URL + "/rss2go", Token: "tk"}
+	if err := d.Deliver(context.Background(), ch, &types.OutboxItem{Body: body}); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	req := (*reqs)[0]
+	if req.Path != "/rss2go" || req.Header.Get("Authorization") != "Bearer tk" {
+		t.Errorf("unexpected request %s with headers %v", req.Path, req.Header)
+	}
+	if req.Header.Get("Title") != "=?utf-8?q?[Blog]_Caf=C3=A9?=" {
+		t.Errorf("unexpected Title header %q", req.Header.Get("Title"))
+	}
+	if req.Header.Get("Click") != testMessage.Link {
+		t.Errorf("unexpected Click header %q", req.Header.Get("Click"))
+	}
+	if req.Body != "Go Blog: Go 1.99 <released>\nhttps://go.dev/blog/go1.99" {
+		t.Errorf("unexpected ntfy message %q", req.Body)
+	}
+}
+
+func TestDispatcherHTTPError(t *testing.T) {
+	ts, _ := captureServer(t, http.StatusForbidden)
+	d := NewDispatcher(nil, ts.Client())
+
+	ch := &types.Channel{Kind: types.ChannelSlack, Target: ts.URL}
+	err := d.Deliver(context.Background(), ch, renderItem(t, types.ChannelSlack))
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "reply") {
+		t.Errorf("expected status error with response snippet, got %v", err)
+	}
+}
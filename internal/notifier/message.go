@@ -0,0 +1,155 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxHeaderLine is the RFC 5322 recommended line length that folded headers aim for.
+const maxHeaderLine = 78
+
+// base64LineLen is the longest encoded line RFC 2045 allows in a base64 part.
+const base64LineLen = 76
+
+// InlineImage is an image carried inside an email and referenced from its
+// HTML as "cid:" followed by CID.
+type InlineImage struct {
+	CID         string
+	ContentType string
+	Data        []byte
+}
+
+// buildMessage formats a multipart/alternative email carrying a plain-text
+// rendering of body followed by the HTML itself. Both parts are
+// quoted-printable encoded so no line exceeds 76 characters, and headers are
+// RFC 2047 encoded and folded. extra headers follow the standard ones in
+// name order. When images are given, the HTML part is wrapped in a
+// multipart/related part that carries them after it.
+func buildMessage(from string, to []string, subject string, body string, extra map[string]string, images []InlineImage) []byte {
+	return composeMessage(from, to, subject, body, extra, images, "")
+}
+
+// composeMessage is buildMessage with a fixed multipart boundary, letting
+// tests produce byte-identical output. An empty boundary picks a random one.
+// The multipart/related part, if any, uses the same boundary with a prefix,
+// so neither delimiter line begins with the other.
+func composeMessage(from string, to []string, subject, body string, extra map[string]string, images []InlineImage, boundary string) []byte {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if boundary != "" {
+		// Only fails for invalid boundaries, which tests never pass.
+		_ = mw.SetBoundary(boundary)
+	}
+
+	recipients := make([]string, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, formatAddress(addr))
+	}
+
+	writeHeader(&buf, "From", formatAddress(from))
+	writeHeader(&buf, "To", strings.Join(recipients, ", "))
+	writeHeader(&buf, "Subject", encodeHeader(CleanHeader(subject)))
+	for _, name := range slices.Sorted(maps.Keys(extra)) {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(CleanHeader(name)), encodeHeader(CleanHeader(extra[name])))
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+	writeHeader(&buf, "Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+	buf.WriteString("\r\n")
+
+	writePart(mw, "text/plain", htmlToText(body))
+	if len(images) == 0 {
+		writePart(mw, "text/html", body)
+	} else {
+		writeRelated(mw, body, images, "related-"+mw.Boundary())
+	}
+	_ = mw.Close()
+
+	return buf.Bytes()
+}
+
+// writeRelated adds a multipart/related part holding the HTML followed by
+// the images it references, each base64 encoded with its Content-ID.
+func writeRelated(mw *multipart.Writer, body string, images []InlineImage, boundary string) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/related", map[string]string{"boundary": boundary, "type": "text/html"}))
+	pw, _ := mw.CreatePart(header)
+
+	related := multipart.NewWriter(pw)
+	_ = related.SetBoundary(boundary)
+	writePart(related, "text/html", body)
+	for _, img := range images {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", img.ContentType)
+		header.Set("Content-Transfer-Encoding", "base64")
+		header.Set("Content-ID", "<"+img.CID+">")
+		header.Set("Content-Disposition", "inline")
+		iw, _ := related.CreatePart(header)
+
+		encoded := base64.StdEncoding.EncodeToString(img.Data)
+		for len(encoded) > base64LineLen {
+			_, _ = fmt.Fprintf(iw, "%s\r\n", encoded[:base64LineLen])
+			encoded = encoded[base64LineLen:]
+		}
+		_, _ = fmt.Fprintf(iw, "%s\r\n", encoded)
+	}
+	_ = related.Close()
+}
+
+// writePart adds one quoted-printable UTF-8 part. Writes go to a bytes.Buffer
+// and cannot fail.
+func writePart(mw *multipart.Writer, mediaType, content string) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mediaType+"; charset=UTF-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	pw, _ := mw.CreatePart(header)
+
+	qp := quotedprintable.NewWriter(pw)
+	_, _ = qp.Write([]byte(content))
+	_ = qp.Close()
+}
+
+// formatAddress encodes the display name of an address for a header, leaving
+// bare addresses as they were given.
+func formatAddress(raw string) string {
+	raw = CleanHeader(raw)
+	addr, err := mail.ParseAddress(raw)
+	if err != nil || addr.Name == "" {
+		return raw
+	}
+	return addr.String()
+}
+
+// encodeHeader RFC 2047 encodes a header value when it is not plain ASCII.
+func encodeHeader(val string) string {
+	for i := 0; i < len(val); i++ {
+		if val[i] >= utf8.RuneSelf {
+			return mime.QEncoding.Encode("utf-8", val)
+		}
+	}
+	return val
+}
+
+// writeHeader writes "Name: value", folding at spaces so lines stay within
+// maxHeaderLine where the value allows it. Encoded words are separated by
+// spaces, so long encoded subjects fold cleanly too.
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	line := name + ":"
+	for i, word := range strings.Split(value, " ") {
+		if i > 0 && len(line)+1+len(word) > maxHeaderLine {
+			_, _ = fmt.Fprintf(buf, "%s\r\n", line)
+			line = ""
+		}
+		line += " " + word
+	}
+	_, _ = fmt.Fprintf(buf, "%s\r\n", line)
+}
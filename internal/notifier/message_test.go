@@ -0,0 +1,243 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+const testBoundary = "rss2go-test-boundary"
+
+var messageCases = []struct {
+	name    string
+	from    string
+	to      []string
+	subject string
+	body    string
+	headers map[string]string
+}{
+	{
+		name:    "basic",
+		from:    "sender@test.com",
+		to:      []string{"recipient1@test.com", "recipient2@test.com"},
+		subject: "[Go Blog] Go 1.30 is released",
+		body: `<h2><a href="https://go.dev/blog/go1.30">Go 1.30 is released</a></h2>` +
+			`<p>Today the Go team is <b>happy</b> to announce:</p>` +
+			`<ul><li>Faster builds</li><li>Smaller binaries</li></ul>` +
+			`<blockquote><p>Upgrade at your leisure.</p></blockquote>`,
+		headers: map[string]string{
+			"List-Unsubscribe":      "<https://rss.example.com/unsubscribe?email=recipient1%40test.com&feed=7&token=abc123>",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	},
+	{
+		name:    "unicode",
+		from:    `"Flux Café" <rss@example.com>`,
+		to:      []string{"reader@example.com"},
+		subject: "[Café Blog] Ünïcødé ✓ — 日本語のタイトル is long enough that the encoded subject must be folded",
+		body:    `<p>Crème brûlée, naïve façade, 日本語のテキスト and emoji 🎉 = fun.</p>`,
+	},
+	{
+		name:    "long_lines",
+		from:    "sender@test.com",
+		to:      []string{"recipient@test.com"},
+		subject: "[Long Feed] A remarkably verbose item title that keeps going well past the seventy-eight column mark",
+		body: `<p>` + strings.Repeat("All work and no play makes Jack a dull boy. ", 20) + `</p>` +
+			`<p><a href="https://example.com/` + strings.Repeat("very-long-path-segment/", 8) + `">link</a></p>` +
+			`<pre>` + strings.Repeat("x", 200) + `</pre>`,
+	},
+}
+
+func TestBuildMessageGolden(t *testing.T) {
+	for _, tc := range messageCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := composeMessage(tc.from, tc.to, tc.subject, tc.body, tc.headers, nil, testBoundary)
+
+			golden := filepath.Join("testdata", tc.name+".eml")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("failed to update golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("message differs from %s (run with -update to accept):\n%s", golden, got)
+			}
+
+			// Folded headers and quoted-printable bodies stay within 78 columns;
+			// only an unbreakable header value such as a URL may run longer.
+			header, body, _ := strings.Cut(string(got), "\r\n\r\n")
+			for i, line := range strings.Split(header, "\r\n") {
+				if len(line) > maxHeaderLine && (strings.Count(strings.TrimSpace(line), " ") > 1 || len(line) > 998) {
+					t.Errorf("header line %d is %d characters long: %q", i+1, len(line), line)
+				}
+			}
+			for i, line := range strings.Split(body, "\r\n") {
+				if len(line) > 76 {
+					t.Errorf("body line %d is %d characters long: %q", i+1, len(line), line)
+				}
+			}
+			checkMessageRoundTrip(t, got, tc.subject, tc.body)
+			if msg, _ := mail.ReadMessage(bytes.NewReader(got)); msg != nil {
+				for name, val := range tc.headers {
+					if msg.Header.Get(name) != val {
+						t.Errorf("header %s = %q, want %q", name, msg.Header.Get(name), val)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMessageInlineImages(t *testing.T) {
+	body := `<p>Chart:</p><p><img src="cid:chart@rss2go" alt="Chart"></p>`
+	images := []InlineImage{
+		{CID: "chart@rss2go", ContentType: "image/png", Data: bytes.Repeat([]byte{0x89, 'P', 'N', 'G', 0}, 40)},
+	}
+	got := composeMessage("sender@test.com", []string{"reader@test.com"}, "[Charts] Weekly", body, nil, images, testBoundary)
+
+	golden := filepath.Join("testdata", "inline_images.eml")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("message differs from %s (run with -update to accept):\n%s", golden, got)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	if part, err := mr.NextPart(); err != nil || !strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected a text/plain first part, got err %v", err)
+	}
+
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("missing related part: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" || params["type"] != "text/html" {
+		t.Fatalf("unexpected related Content-Type %q: %v", part.Header.Get("Content-Type"), err)
+	}
+	related := multipart.NewReader(part, params["boundary"])
+
+	html, err := related.NextPart()
+	if err != nil || !strings.HasPrefix(html.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("expected the HTML first in the related part, got err %v", err)
+	}
+	if content, _ := io.ReadAll(html); string(content) != body {
+		t.Errorf("decoded HTML = %q, want %q", content, body)
+	}
+
+	img, err := related.NextPart()
+	if err != nil {
+		t.Fatalf("missing image part: %v", err)
+	}
+	if img.Header.Get("Content-Id") != "<chart@rss2go>" || img.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("unexpected image headers: %v", img.Header)
+	}
+	encoded, _ := io.ReadAll(img)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("base64 line is %d characters long", len(line))
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || !bytes.Equal(data, images[0].Data) {
+		t.Errorf("image data did not round-trip (err %v)", err)
+	}
+	if _, err := related.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly two related parts, got err %v", err)
+	}
+}
+
+// checkMessageRoundTrip parses raw with the standard library and checks the
+// decoded subject and parts match what was encoded.
+func checkMessageRoundTrip(t *testing.T, raw []byte, subject, body string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	dec := new(mime.WordDecoder)
+	gotSubject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || gotSubject != subject {
+		t.Errorf("decoded subject = %q (err %v), want %q", gotSubject, err, subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected Content-Type %q: %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct{ mediaType, content string }{
+		{"text/plain", htmlToText(body)},
+		{"text/html", body},
+	}
+	for _, w := range want {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", w.mediaType, err)
+		}
+		if ct := part.Header.Get("Content-Type"); !strings.HasPrefix(ct, w.mediaType) {
+			t.Errorf("part Content-Type = %q, want %s", ct, w.mediaType)
+		}
+		// Reading decodes quoted-printable, which carries line breaks as CRLF.
+		content, _ := io.ReadAll(part)
+		if got := strings.ReplaceAll(string(content), "\r\n", "\n"); got != w.content {
+			t.Errorf("decoded %s part = %q, want %q", w.mediaType, content, w.content)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly two parts, got err %v", err)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"paragraphs", "<p>One\n  two</p><p>Three</p>", "One two\n\nThree\n"},
+		{"inline whitespace", "<p>Hello <b>bold</b> <i>world</i></p>", "Hello bold world\n"},
+		{"links", `<a href="https://a.example">A</a> and <a href="https://b.example">https://b.example</a>`, "A <https://a.example> and https://b.example\n"},
+		{"lists", "<ul><li>a</li><li>b</li></ul><ol><li>x</li><li>y</li></ol>", "- a\n- b\n\n1. x\n2. y\n"},
+		{"blockquote", "<blockquote><p>q1</p><p>q2</p></blockquote>", "> q1\n>\n> q2\n"},
+		{"line breaks", "a<br>b<br/>c", "a\nb\nc\n"},
+		{"pre", "<pre>  keep\n    this</pre>", "  keep\n    this\n"},
+		{"images and scripts", `<img src="x.png" alt="Chart"><script>alert(1)</script>`, "[Chart]\n"},
+		{"table", "<table><tr><th>k</th><th>v</th></tr><tr><td>a</td><td>1</td></tr></table>", "k | v\na | 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.html); got != tt.want {
+				t.Errorf("htmlToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -33,9 +33,17 @@ type SMTPConfig struct {
 	Security SecurityType
 }
 
-// Sender defines the interface for dispatching emails.
+// Sender defines the interface for dispatching emails. headers carries extra
+// message headers such as List-Unsubscribe and may be nil.
 type Sender interface {
-	Send(ctx context.Context, subject string, body string, recipients []string) error
+	Send(ctx context.Context, subject string, body string, recipients []string, headers map[string]string) error
+}
+
+// InlineSender is a Sender that can also embed images in the HTML part,
+// where body references them by "cid:" URLs.
+type InlineSender interface {
+	Sender
+	SendInline(ctx context.Context, subject string, body string, recipients []string, headers map[string]string, images []InlineImage) error
 }
 
 // defaultSMTPOpTimeout bounds every SMTP command (including the initial
@@ -183,7 +191,12 @@ func (s *SMTPSender) getClient(ctx context.Context) (*smtp.Client, error) {
 
 // Send dispatches an HTML email to recipients via SMTP, reusing a cached
 // connection across calls when possible.
-func (s *SMTPSender) Send(ctx context.Context, subject string, body string, recipients []string) error {
+func (s *SMTPSender) Send(ctx context.Context, subject string, body string, recipients []string, headers map[string]string) error {
+	return s.SendInline(ctx, subject, body, recipients, headers, nil)
+}
+
+// SendInline is Send with images embedded in the message.
+func (s *SMTPSender) SendInline(ctx context.Context, subject string, body string, recipients []string, headers map[string]string, images []InlineImage) error {
 	if len(recipients) == 0 {
 		return fmt.Errorf("notifier: smtp: no recipients specified")
 	}
@@ -195,7 +208,7 @@ func (s *SMTPSender) Send(ctx context.Context, subject string, body string, reci
 	cleanedSubject := CleanHeader(subject)
 	log.Debug("Starting SMTP email delivery", "host", s.cfg.Host, "port", s.cfg.Port, "recipients_count", len(recipients), "subject", cleanedSubject)
 
-	msg := buildMessage(s.cfg.From, recipients, cleanedSubject, body)
+	msg := buildMessage(s.cfg.From, recipients, cleanedSubject, body, headers, images)
 
 	// Holding s.mu across blocking network I/O below is a deliberate
 	// exception to this project's "never hold a mutex during I/O" rule
@@ -284,7 +297,12 @@ func NewSendmailSender(path string, from string, log ...*slog.Logger) *SendmailS
 }
 
 // Send dispatches an HTML email via the local sendmail command.
-func (s *SendmailSender) Send(ctx context.Context, subject string, body string, recipients []string) error {
+func (s *SendmailSender) Send(ctx context.Context, subject string, body string, recipients []string, headers map[string]string) error {
+	return s.SendInline(ctx, subject, body, recipients, headers, nil)
+}
+
+// SendInline is Send with images embedded in the message.
+func (s *SendmailSender) SendInline(ctx context.Context, subject string, body string, recipients []string, headers map[string]string, images []InlineImage) error {
 	if len(recipients) == 0 {
 		return fmt.Errorf("notifier: sendmail: no recipients specified")
 	}
@@ -297,7 +315,7 @@ func (s *SendmailSender) Send(ctx context.Context, subject string, body string,
 	cleanedSubject := CleanHeader(subject)
 	log.Debug("Starting sendmail binary delivery", "path", s.path, "recipients_count", len(recipients), "subject", cleanedSubject)
 
-	msg := buildMessage(s.from, recipients, cleanedSubject, body)
+	msg := buildMessage(s.from, recipients, cleanedSubject, body, headers, images)
 
 	// Invoke local sendmail binary: sendmail -t
 	cmd := exec.CommandContext(ctx, s.path, "-t")
@@ -321,16 +339,3 @@ func CleanHeader(val string) string {
 	val = strings.ReplaceAll(val, "\r", "")
 	return val
 }
-
-// buildMessage formats the MIME HTML email raw bytes.
-func buildMessage(from string, to []string, subject string, body string) []byte {
-	var buf bytes.Buffer
-	_, _ = fmt.Fprintf(&buf, "From: %s\r\n", from)
-	_, _ = fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
-	_, _ = fmt.Fprintf(&buf, "Subject: %s\r\n", subject)
-	buf.WriteString("MIME-Version: 1.0\r\n")
-	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
-	buf.WriteString("\r\n")
-	buf.WriteString(body)
-	return buf.Bytes()
-}
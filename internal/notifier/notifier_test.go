@@ -50,7 +50,7 @@ func TestSendmailSender(t *testing.T) {
 	subject := "Test Subject\nWith Injection" // Injection should be stripped
 	body := "<h1>HTML Body</h1>"
 
-	if err := sender.Send(ctx, subject, body, recipients); err != nil {
+	if err := sender.Send(ctx, subject, body, recipients, nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -335,7 +335,7 @@ func TestSMTPSenderHappyPath(t *testing.T) {
 	srv := startMockSMTPServer(t)
 
 	sender := newTestSMTPSender(t, srv.addr)
-	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"})
+	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 	if err != nil {
 		t.Fatalf("SMTP send failed: %v", err)
 	}
@@ -367,7 +367,7 @@ func TestNilLoggerGuards(t *testing.T) {
 		opTimeout: defaultSMTPOpTimeout,
 	}
 
-	if err := smtpSender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := smtpSender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("SMTPSender.Send with nil logger failed: %v", err)
 	}
 
@@ -385,7 +385,7 @@ func TestNilLoggerGuards(t *testing.T) {
 		log:  nil,
 	}
 
-	if err := sendmailSender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sendmailSender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("SendmailSender.Send with nil logger failed: %v", err)
 	}
 }
@@ -459,7 +459,7 @@ func TestSMTPSenderDataCloseError(t *testing.T) {
 	}
 
 	sender := NewSMTPSender(cfg)
-	err = sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"})
+	err = sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 	if err == nil {
 		t.Fatalf("expected error on SMTP DATA termination failure, got nil")
 	}
@@ -475,7 +475,7 @@ func TestSMTPSenderConnectionReuse(t *testing.T) {
 	sender := newTestSMTPSender(t, srv.addr)
 
 	for i := range 3 {
-		if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+		if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 			t.Fatalf("send %d failed: %v", i, err)
 		}
 	}
@@ -498,7 +498,7 @@ func TestSMTPSenderConcurrentSendsSerialize(t *testing.T) {
 
 	for i := range n {
 		wg.Go(func() {
-			errs[i] = sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"})
+			errs[i] = sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 		})
 	}
 	wg.Wait()
@@ -520,7 +520,7 @@ func TestSMTPSenderReconnectAfterStaleConnection(t *testing.T) {
 	srv := startMockSMTPServer(t)
 	sender := newTestSMTPSender(t, srv.addr)
 
-	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("first send failed: %v", err)
 	}
 
@@ -530,7 +530,7 @@ func TestSMTPSenderReconnectAfterStaleConnection(t *testing.T) {
 	}
 	_ = conn.Close()
 
-	if err := sender.Send(context.Background(), "Hello again", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello again", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("second send failed after forced disconnect: %v", err)
 	}
 
@@ -548,7 +548,7 @@ func TestSMTPSenderHungConnectBound(t *testing.T) {
 	sender.opTimeout = 100 * time.Millisecond
 
 	start := time.Now()
-	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"})
+	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 	elapsed := time.Since(start)
 
 	if err == nil {
@@ -561,7 +561,7 @@ func TestSMTPSenderHungConnectBound(t *testing.T) {
 	// A subsequent send against a healthy server must still succeed.
 	srv := startMockSMTPServer(t)
 	sender2 := newTestSMTPSender(t, srv.addr)
-	if err := sender2.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender2.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("send against healthy server failed: %v", err)
 	}
 }
@@ -581,7 +581,7 @@ func TestSMTPSenderHungReuseBound(t *testing.T) {
 	sender := newTestSMTPSender(t, srv.addr)
 	sender.opTimeout = 100 * time.Millisecond
 
-	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("first send failed: %v", err)
 	}
 
@@ -590,7 +590,7 @@ func TestSMTPSenderHungReuseBound(t *testing.T) {
 	srv.silenceConnAt(0)
 
 	start := time.Now()
-	err := sender.Send(context.Background(), "Hello again", "<p>Test</p>", []string{"recipient@test.com"})
+	err := sender.Send(context.Background(), "Hello again", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -617,7 +617,7 @@ func TestSMTPSenderRcptRejected(t *testing.T) {
 	srv.setRejectRcpt("bad@test.com")
 	sender := newTestSMTPSender(t, srv.addr)
 
-	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"bad@test.com"})
+	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"bad@test.com"}, nil)
 	if err == nil {
 		t.Fatal("expected an error for a rejected recipient, got nil")
 	}
@@ -625,7 +625,7 @@ func TestSMTPSenderRcptRejected(t *testing.T) {
 	// A later send to a valid recipient must redial cleanly rather than
 	// reuse the connection the rejection left mid-transaction.
 	srv.setRejectRcpt("")
-	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"good@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"good@test.com"}, nil); err != nil {
 		t.Fatalf("send after rejection failed: %v", err)
 	}
 
@@ -643,13 +643,13 @@ func TestSMTPSenderMailFromRejected(t *testing.T) {
 	srv.rejectMailFrom.Store(true)
 	sender := newTestSMTPSender(t, srv.addr)
 
-	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"})
+	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 	if err == nil {
 		t.Fatal("expected an error for a rejected MAIL FROM, got nil")
 	}
 
 	srv.rejectMailFrom.Store(false)
-	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("send after rejection failed: %v", err)
 	}
 
@@ -666,7 +666,7 @@ func TestSMTPSenderAuthRejected(t *testing.T) {
 	srv.rejectAuth.Store(true)
 	sender := newTestSMTPSender(t, srv.addr)
 
-	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"})
+	err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 	if err == nil {
 		t.Fatal("expected an error for rejected authentication, got nil")
 	}
@@ -675,7 +675,7 @@ func TestSMTPSenderAuthRejected(t *testing.T) {
 	}
 
 	srv.rejectAuth.Store(false)
-	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("send after auth rejection cleared failed: %v", err)
 	}
 }
@@ -689,14 +689,14 @@ func TestSMTPSenderCanceledContext(t *testing.T) {
 	sender := newTestSMTPSender(t, srv.addr)
 
 	// Warm the cache with one successful send first.
-	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("warm-up send failed: %v", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	if err := sender.Send(ctx, "Hello again", "<p>Test</p>", []string{"recipient@test.com"}); err == nil {
+	if err := sender.Send(ctx, "Hello again", "<p>Test</p>", []string{"recipient@test.com"}, nil); err == nil {
 		t.Fatal("expected an error for an already-canceled context, got nil")
 	}
 
@@ -706,7 +706,7 @@ func TestSMTPSenderCanceledContext(t *testing.T) {
 	if got := srv.authCount.Load(); got != 1 {
 		t.Errorf("expected AUTH count to stay at 1 (canceled send should not touch the connection), got %d", got)
 	}
-	if err := sender.Send(context.Background(), "Hello once more", "<p>Test</p>", []string{"recipient@test.com"}); err != nil {
+	if err := sender.Send(context.Background(), "Hello once more", "<p>Test</p>", []string{"recipient@test.com"}, nil); err != nil {
 		t.Fatalf("send after canceled-context send failed: %v", err)
 	}
 	if got := srv.authCount.Load(); got != 1 {
@@ -738,7 +738,7 @@ func TestSMTPSenderCanceledContextWhileWaitingForLock(t *testing.T) {
 		close(unlocked)
 	}()
 
-	err := sender.Send(ctx, "Hello", "<p>Test</p>", []string{"recipient@test.com"})
+	err := sender.Send(ctx, "Hello", "<p>Test</p>", []string{"recipient@test.com"}, nil)
 	<-unlocked
 
 	if err == nil {
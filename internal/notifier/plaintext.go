@@ -0,0 +1,225 @@
+package notifier
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlToText renders sanitized item HTML as readable plain text for the
+// text/plain alternative of an email: paragraphs become blank-line separated
+// blocks, list items get bullets, quotes get "> " prefixes and links keep
+// their target in angle brackets.
+func htmlToText(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, DataAtom: atom.Body, Data: "body"})
+	if err != nil {
+		return fragment
+	}
+
+	tw := &textWriter{}
+	for _, n := range nodes {
+		tw.walk(n)
+	}
+	return tw.String()
+}
+
+// textWriter accumulates rendered text, collapsing whitespace the way a
+// browser would and tracking how many line breaks end the output so blocks
+// never stack more than one blank line.
+type textWriter struct {
+	buf      strings.Builder
+	newlines int  // Line breaks at the end of buf
+	space    bool // A collapsed space is pending before the next word
+	pre      int  // Depth of enclosing <pre> elements
+}
+
+func (t *textWriter) String() string {
+	// Leading spaces are kept for a document that opens with <pre>.
+	return strings.TrimRight(strings.TrimLeft(t.buf.String(), "\n"), " \n") + "\n"
+}
+
+// breakLines ensures the output ends with at least n line breaks.
+func (t *textWriter) breakLines(n int) {
+	if n == 0 || t.buf.Len() == 0 {
+		return
+	}
+	for t.newlines < n {
+		t.buf.WriteByte('\n')
+		t.newlines++
+	}
+	t.space = false
+}
+
+func (t *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if t.space && t.newlines == 0 && t.buf.Len() > 0 {
+		t.buf.WriteByte(' ')
+	}
+	t.space = false
+	t.buf.WriteString(s)
+	t.newlines = len(s) - len(strings.TrimRight(s, "\n"))
+}
+
+func (t *textWriter) text(s string) {
+	if t.pre > 0 {
+		t.write(s)
+		return
+	}
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		t.space = t.space || s != ""
+		return
+	}
+	if strings.TrimLeft(s, " \t\r\n\f") != s {
+		t.space = true
+	}
+	t.write(strings.Join(words, " "))
+	t.space = strings.TrimRight(s, " \t\r\n\f") != s
+}
+
+// render walks n's children into a fresh writer, for content that is
+// indented or prefixed as a whole.
+func render(n *html.Node) string {
+	sub := &textWriter{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sub.walk(c)
+	}
+	return strings.TrimSpace(sub.buf.String())
+}
+
+func (t *textWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		t.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			t.walk(c)
+		}
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Template:
+		return
+
+	case atom.Br:
+		t.write("\n")
+		return
+
+	case atom.Hr:
+		t.breakLines(2)
+		t.write("----")
+		t.breakLines(2)
+		return
+
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			t.text("[" + alt + "]")
+		}
+		return
+
+	case atom.A:
+		label := render(n)
+		href := attr(n, "href")
+		switch {
+		case href == "" || strings.HasPrefix(href, "#") || href == label:
+			t.text(label)
+		case label == "":
+			t.text(href)
+		default:
+			t.text(label + " <" + href + ">")
+		}
+		return
+
+	case atom.Blockquote:
+		t.breakLines(2)
+		t.write(prefixLines(render(n), "> ", "> "))
+		t.breakLines(2)
+		return
+
+	case atom.Li:
+		bullet := "- "
+		if n.Parent != nil && n.Parent.DataAtom == atom.Ol {
+			bullet = strconv.Itoa(listIndex(n)) + ". "
+		}
+		t.breakLines(1)
+		t.write(prefixLines(render(n), bullet, strings.Repeat(" ", len(bullet))))
+		t.breakLines(1)
+		return
+
+	case atom.Pre:
+		t.breakLines(2)
+		t.pre++
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			t.walk(c)
+		}
+		t.pre--
+		t.breakLines(2)
+		return
+
+	case atom.Td, atom.Th:
+		for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+			if s.Type == html.ElementNode {
+				t.write(" | ")
+				break
+			}
+		}
+	}
+
+	block := blockBreaks(n.DataAtom)
+	t.breakLines(block)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t.walk(c)
+	}
+	t.breakLines(block)
+}
+
+// blockBreaks returns how many line breaks separate an element from its
+// surroundings: two for paragraphs and headings, one for row-like containers.
+func blockBreaks(a atom.Atom) int {
+	switch a {
+	case atom.P, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Dl, atom.Table, atom.Figure, atom.Section, atom.Article:
+		return 2
+	case atom.Div, atom.Tr, atom.Dt, atom.Dd, atom.Header, atom.Footer, atom.Figcaption:
+		return 1
+	}
+	return 0
+}
+
+func listIndex(li *html.Node) int {
+	i := 1
+	for s := li.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode && s.DataAtom == atom.Li {
+			i++
+		}
+	}
+	return i
+}
+
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,379 @@
+package opml
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss2go/internal/crawler"
+	"rss2go/internal/database"
+	"rss2go/internal/dedupe"
+	"rss2go/internal/extractor"
+	"rss2go/internal/rewrite"
+	"rss2go/internal/types"
+)
+
+// Namespace is the XML namespace of the rss2go-specific outline attributes.
+const Namespace = "https://github.com/hobeone/rss2go/opml"
+
+// defaultPollIntervalSecs is used for imported feeds without an rss2go poll interval.
+const defaultPollIntervalSecs = 3600
+
+// Document is an OPML 2.0 document.
+type Document struct {
+	XMLName xml.Name   `xml:"opml"`
+	Version string     `xml:"version,attr"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Head    Head       `xml:"head"`
+	Body    Body       `xml:"body"`
+}
+
+// Head is the OPML document header.
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// Body holds the top level outlines.
+type Body struct {
+	Outlines []*Outline `xml:"outline"`
+}
+
+// Outline is either a feed subscription (XMLURL set) or a category folder
+// containing nested outlines. Attrs carries any attribute not modelled below,
+// including the rss2go namespaced extraction settings.
+type Outline struct {
+	Text     string     `xml:"text,attr"`
+	Title    string     `xml:"title,attr,omitempty"`
+	Type     string     `xml:"type,attr,omitempty"`
+	XMLURL   string     `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string     `xml:"htmlUrl,attr,omitempty"`
+	Category string     `xml:"category,attr,omitempty"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Outlines []*Outline `xml:"outline"`
+}
+
+// Import statuses reported per outline.
+const (
+	StatusCreated  = "created"
+	StatusExisting = "existing"
+	StatusFailed   = "failed"
+)
+
+// OutlineResult reports what happened to a single feed outline during import.
+type OutlineResult struct {
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	FeedID int64  `json:"feed_id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport summarises an OPML import.
+type ImportReport struct {
+	Created  int              `json:"created"`
+	Existing int              `json:"existing"`
+	Failed   int              `json:"failed"`
+	Outlines []*OutlineResult `json:"outlines"`
+}
+
+// Export writes every feed as an OPML 2.0 document. Feeds are grouped into one
+// folder outline per category; uncategorised feeds sit at the top level.
+func Export(w io.Writer, feeds []*types.Feed, now time.Time) error {
+	doc := Document{
+		Version: "2.0",
+		Attrs:   []xml.Attr{{Name: xml.Name{Local: "xmlns:rss2go"}, Value: Namespace}},
+		Head: Head{
+			Title:       "rss2go feeds",
+			DateCreated: now.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]*Outline)
+	var names []string
+	for _, f := range feeds {
+		o := feedOutline(f)
+		if f.Category == "" {
+			doc.Body.Outlines = append(doc.Body.Outlines, o)
+			continue
+		}
+		folder, ok := folders[f.Category]
+		if !ok {
+			folder = &Outline{Text: f.Category, Title: f.Category}
+			folders[f.Category] = folder
+			names = append(names, f.Category)
+		}
+		folder.Outlines = append(folder.Outlines, o)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.Body.Outlines = append(doc.Body.Outlines, folders[name])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("opml: write header: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("opml: encode: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("opml: write: %w", err)
+	}
+	return nil
+}
+
+// feedOutline converts a feed into an outline carrying its rss2go settings.
+func feedOutline(f *types.Feed) *Outline {
+	o := &Outline{
+		Text:   f.Title,
+		Title:  f.Title,
+		Type:   "rss",
+		XMLURL: f.URL,
+	}
+	if f.Category != "" {
+		o.Category = "/" + f.Category
+	}
+
+	attr := func(name, val string) {
+		if val != "" {
+			o.Attrs = append(o.Attrs, xml.Attr{Name: xml.Name{Local: "rss2go:" + name}, Value: val})
+		}
+	}
+	attr("pollIntervalSecs", strconv.Itoa(f.PollIntervalSecs))
+	attr("adaptivePolling", strconv.FormatBool(f.AdaptivePolling))
+	attr("extractFullArticle", strconv.FormatBool(f.ExtractFullArticle))
+	attr("extractionStrategy", string(f.ExtractionStrategy))
+	chain := make([]string, len(f.ExtractionChain))
+	for i, strategy := range f.ExtractionChain {
+		chain[i] = string(strategy)
+	}
+	attr("extractionChain", strings.Join(chain, ","))
+	attr("cssSelector", f.CSSSelector)
+	if f.FollowPages {
+		attr("followPages", "true")
+		attr("nextPageSelector", f.NextPageSelector)
+		if f.MaxPages > 0 {
+			attr("maxPages", strconv.Itoa(f.MaxPages))
+		}
+	}
+	attr("scraperItemSelector", f.ScraperItemSelector)
+	attr("scraperTitleSelector", f.ScraperTitleSelector)
+	attr("scraperLinkSelector", f.ScraperLinkSelector)
+	attr("scraperDescriptionSelector", f.ScraperDescriptionSelector)
+	attr("sourceFormat", f.SourceFormat)
+	attr("dedupeStrategy", string(f.DedupeStrategy))
+	if f.ContentRules != nil {
+		if rules, err := json.Marshal(f.ContentRules); err == nil {
+			attr("contentRules", string(rules))
+		}
+	}
+	return o
+}
+
+// Parse decodes an OPML document.
+func Parse(r io.Reader) (*Document, error) {
+	var doc Document
+	dec := xml.NewDecoder(r)
+	// Feed URLs and titles are overwhelmingly ASCII; read other declared charsets as-is.
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("opml: parse: %w", err)
+	}
+	return &doc, nil
+}
+
+// Feeds flattens a document into feeds, deriving each feed's category from its
+// enclosing folder outlines, or its category attribute at the top level.
+func (d *Document) Feeds() []*types.Feed {
+	var feeds []*types.Feed
+	var walk func(outlines []*Outline, folder []string)
+	walk = func(outlines []*Outline, folder []string) {
+		for _, o := range outlines {
+			if o.XMLURL == "" {
+				name := strings.TrimSpace(o.Text)
+				if name == "" {
+					name = strings.TrimSpace(o.Title)
+				}
+				next := folder[:len(folder):len(folder)]
+				if name != "" {
+					next = append(next, name)
+				}
+				walk(o.Outlines, next)
+				continue
+			}
+			feeds = append(feeds, outlineFeed(o, strings.Join(folder, "/")))
+		}
+	}
+	walk(d.Body.Outlines, nil)
+	return feeds
+}
+
+// outlineFeed converts a feed outline into an unsaved feed.
+func outlineFeed(o *Outline, category string) *types.Feed {
+	f := &types.Feed{
+		Title:              strings.TrimSpace(o.Title),
+		URL:                strings.TrimSpace(o.XMLURL),
+		Category:           category,
+		PollIntervalSecs:   defaultPollIntervalSecs,
+		ExtractionStrategy: types.StrategyHeuristic,
+	}
+	if f.Title == "" {
+		f.Title = strings.TrimSpace(o.Text)
+	}
+	if f.Title == "" {
+		f.Title = f.URL
+	}
+	if f.Category == "" && o.Category != "" {
+		// The OPML category attribute is a comma separated list of slash delimited paths.
+		first, _, _ := strings.Cut(o.Category, ",")
+		f.Category = strings.Trim(strings.TrimSpace(first), "/")
+	}
+
+	for _, a := range o.Attrs {
+		if a.Name.Space != Namespace && a.Name.Space != "rss2go" {
+			continue
+		}
+		switch a.Name.Local {
+		case "pollIntervalSecs":
+			if val, err := strconv.Atoi(a.Value); err == nil && val > 0 {
+				f.PollIntervalSecs = val
+			}
+		case "adaptivePolling":
+			f.AdaptivePolling, _ = strconv.ParseBool(a.Value)
+		case "extractFullArticle":
+			f.ExtractFullArticle, _ = strconv.ParseBool(a.Value)
+		case "extractionStrategy":
+			f.ExtractionStrategy = types.ExtractionStrategy(a.Value)
+		case "extractionChain":
+			f.ExtractionChain = nil
+			for _, strategy := range strings.Split(a.Value, ",") {
+				if strategy = strings.TrimSpace(strategy); strategy != "" {
+					f.ExtractionChain = append(f.ExtractionChain, types.ExtractionStrategy(strategy))
+				}
+			}
+		case "cssSelector":
+			f.CSSSelector = a.Value
+		case "followPages":
+			f.FollowPages, _ = strconv.ParseBool(a.Value)
+		case "nextPageSelector":
+			f.NextPageSelector = a.Value
+		case "maxPages":
+			if val, err := strconv.Atoi(a.Value); err == nil && val > 0 {
+				f.MaxPages = val
+			}
+		case "scraperItemSelector":
+			f.ScraperItemSelector = a.Value
+		case "scraperTitleSelector":
+			f.ScraperTitleSelector = a.Value
+		case "scraperLinkSelector":
+			f.ScraperLinkSelector = a.Value
+		case "scraperDescriptionSelector":
+			f.ScraperDescriptionSelector = a.Value
+		case "sourceFormat":
+			f.SourceFormat = a.Value
+		case "dedupeStrategy":
+			f.DedupeStrategy = types.DedupeStrategy(a.Value)
+		case "contentRules":
+			var rules types.ContentRules
+			if err := json.Unmarshal([]byte(a.Value), &rules); err == nil {
+				f.ContentRules = &rules
+			}
+		}
+	}
+	return f
+}
+
+// Import creates every feed in the document that does not already exist,
+// matching on URL, and subscribes userID to each feed when non-zero. Source
+// formats are checked against formats. Failures are reported per outline; an
+// error is only returned for an unreadable document.
+func Import(ctx context.Context, repo *database.Repository, formats *crawler.Registry, r io.Reader, userID int64) (*ImportReport, error) {
+	doc, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Outlines: []*OutlineResult{}}
+	for _, feed := range doc.Feeds() {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		res := &OutlineResult{Title: feed.Title, URL: feed.URL}
+		if err := importFeed(ctx, repo, formats, feed, userID, res); err != nil {
+			res.Status = StatusFailed
+			res.Error = err.Error()
+			report.Failed++
+		} else if res.Status == StatusCreated {
+			report.Created++
+		} else {
+			report.Existing++
+		}
+		report.Outlines = append(report.Outlines, res)
+	}
+	return report, nil
+}
+
+// importFeed creates or finds a single feed and optionally subscribes userID.
+func importFeed(ctx context.Context, repo *database.Repository, formats *crawler.Registry, feed *types.Feed, userID int64, res *OutlineResult) error {
+	u, err := url.Parse(feed.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid feed URL %q", feed.URL)
+	}
+	if feed.SourceFormat != "" {
+		if _, ok := formats.Lookup(feed.SourceFormat); !ok {
+			return fmt.Errorf("unknown source format %q", feed.SourceFormat)
+		}
+	}
+	if feed.ExtractionStrategy != "" && !extractor.ValidStrategy(feed.ExtractionStrategy) {
+		return fmt.Errorf("unknown extraction strategy %q", feed.ExtractionStrategy)
+	}
+	for _, strategy := range feed.ExtractionChain {
+		if !extractor.ValidStrategy(strategy) {
+			return fmt.Errorf("unknown strategy %q in extraction chain", strategy)
+		}
+	}
+	if !dedupe.Valid(feed.DedupeStrategy) {
+		return fmt.Errorf("unknown dedupe strategy %q", feed.DedupeStrategy)
+	}
+	if err := rewrite.Validate(feed.ContentRules); err != nil {
+		return fmt.Errorf("invalid content rules: %w", err)
+	}
+
+	return repo.WithTx(ctx, func(txRepo *database.Repository) error {
+		existing, err := txRepo.GetFeedByURL(ctx, feed.URL)
+		switch {
+		case err == nil:
+			res.FeedID = existing.ID
+			res.Status = StatusExisting
+		case errors.Is(err, sql.ErrNoRows):
+			feed.NextPollAt = time.Now()
+			feed.BackoffFactor = 1.0
+			if err := txRepo.CreateFeed(ctx, feed); err != nil {
+				return err
+			}
+			res.FeedID = feed.ID
+			res.Status = StatusCreated
+		default:
+			return err
+		}
+
+		if userID != 0 {
+			if err := txRepo.Subscribe(ctx, userID, res.FeedID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
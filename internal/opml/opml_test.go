@@ -0,0 +1,230 @@
+package opml
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rss2go/internal/crawler"
+	"rss2go/internal/database"
+	"rss2go/internal/types"
+)
+
+func setupTestDB(t *testing.T) *database.Repository {
+	t.Helper()
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return database.NewRepository(db)
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	feeds := []*types.Feed{
+		{
+			Title:              "Tech News",
+			URL:                "https://tech.example.com/rss",
+			Category:           "Tech",
+			PollIntervalSecs:   900,
+			AdaptivePolling:    true,
+			ExtractFullArticle: true,
+			ExtractionStrategy: types.StrategySelector,
+			ExtractionChain:    []types.ExtractionStrategy{types.StrategySelector, types.StrategyHeuristic, types.StrategySummary},
+			CSSSelector:        "article .body",
+			FollowPages:        true,
+			MaxPages:           3,
+		},
+		{
+			Title:                "Scraped Blog",
+			URL:                  "https://blog.example.com/",
+			PollIntervalSecs:     7200,
+			ExtractionStrategy:   types.StrategyHeuristic,
+			ScraperItemSelector:  ".post",
+			ScraperTitleSelector: "h2",
+			ScraperLinkSelector:  "a.permalink",
+			SourceFormat:         "scraper",
+			DedupeStrategy:       types.DedupeContent,
+			ContentRules:         &types.ContentRules{Remove: []string{".share"}, Links: []types.LinkRule{{Action: types.LinkCanonicalAMP}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, feeds, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<opml version="2.0" xmlns:rss2go="` + Namespace + `">`,
+		`<outline text="Tech" title="Tech">`,
+		`category="/Tech"`,
+		`rss2go:cssSelector="article .body"`,
+		`rss2go:scraperItemSelector=".post"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected export to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	doc, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := doc.Feeds()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 feeds, got %d", len(got))
+	}
+
+	// Uncategorised feeds are exported before category folders.
+	blog, tech := got[0], got[1]
+	if tech.Title != "Tech News" || tech.URL != feeds[0].URL || tech.Category != "Tech" {
+		t.Errorf("unexpected feed %+v", tech)
+	}
+	if tech.PollIntervalSecs != 900 || !tech.AdaptivePolling || blog.AdaptivePolling {
+		t.Errorf("expected polling settings to round trip, got %+v", tech)
+	}
+	if tech.PollIntervalSecs != 900 || !tech.ExtractFullArticle || tech.ExtractionStrategy != types.StrategySelector || tech.CSSSelector != "article .body" {
+		t.Errorf("expected extraction settings to round trip, got %+v", tech)
+	}
+	if len(tech.ExtractionChain) != 3 || tech.ExtractionChain[2] != types.StrategySummary {
+		t.Errorf("expected the extraction chain to round trip, got %v", tech.ExtractionChain)
+	}
+	if !tech.FollowPages || tech.MaxPages != 3 || blog.FollowPages {
+		t.Errorf("expected pagination settings to round trip, got %v %d", tech.FollowPages, tech.MaxPages)
+	}
+	if blog.Category != "" || blog.SourceFormat != "scraper" || blog.DedupeStrategy != types.DedupeContent || blog.ScraperItemSelector != ".post" || blog.ScraperTitleSelector != "h2" || blog.ScraperLinkSelector != "a.permalink" {
+		t.Errorf("expected scraper settings to round trip, got %+v", blog)
+	}
+	if blog.ContentRules == nil || blog.ContentRules.Remove[0] != ".share" || blog.ContentRules.Links[0].Action != types.LinkCanonicalAMP || tech.ContentRules != nil {
+		t.Errorf("expected content rules to round trip, got %+v", blog.ContentRules)
+	}
+}
+
+func TestParseForeignOPML(t *testing.T) {
+	src := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<opml version="1.0">
+  <head><title>Reader export</title></head>
+  <body>
+    <outline text="News">
+      <outline text="World">
+        <outline text="Daily" xmlUrl="https://daily.example.com/feed"/>
+      </outline>
+    </outline>
+    <outline text="Loose" type="rss" xmlUrl="https://loose.example.com/feed" category="/Misc/Stuff,/Other"/>
+    <outline text="Prefixed" xmlUrl="https://prefixed.example.com/feed" rss2go:pollIntervalSecs="60"/>
+  </body>
+</opml>`
+
+	doc, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	feeds := doc.Feeds()
+	if len(feeds) != 3 {
+		t.Fatalf("expected 3 feeds, got %d", len(feeds))
+	}
+	if feeds[0].Title != "Daily" || feeds[0].Category != "News/World" {
+		t.Errorf("expected nested folders to form the category, got %+v", feeds[0])
+	}
+	if feeds[0].PollIntervalSecs != defaultPollIntervalSecs || feeds[0].ExtractionStrategy != types.StrategyHeuristic {
+		t.Errorf("expected defaults for plain outlines, got %+v", feeds[0])
+	}
+	if feeds[1].Category != "Misc/Stuff" {
+		t.Errorf("expected first category attribute path, got %q", feeds[1].Category)
+	}
+	if feeds[2].PollIntervalSecs != 60 {
+		t.Errorf("expected undeclared rss2go prefix to be honoured, got %d", feeds[2].PollIntervalSecs)
+	}
+
+	if _, err := Parse(strings.NewReader("not xml")); err == nil {
+		t.Errorf("expected error for invalid document")
+	}
+}
+
+func TestImport(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	user := &types.User{Email: "opml@test.com"}
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	existing := &types.Feed{Title: "Existing", URL: "https://existing.example.com/rss", NextPollAt: time.Now()}
+	if err := repo.CreateFeed(ctx, existing); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	src := `<opml version="2.0" xmlns:rss2go="` + Namespace + `"><body>
+  <outline text="Tech">
+    <outline text="New" xmlUrl="https://new.example.com/rss" rss2go:extractFullArticle="true" rss2go:extractionStrategy="selector" rss2go:cssSelector="main" rss2go:sourceFormat="jsonfeed"/>
+  </outline>
+  <outline text="Existing" xmlUrl="https://existing.example.com/rss"/>
+  <outline text="Broken" xmlUrl="ftp://broken.example.com/rss"/>
+  <outline text="Bad rules" xmlUrl="https://rules.example.com/rss" rss2go:contentRules='{"remove": ["div[["]}'/>
+  <outline text="Bad strategy" xmlUrl="https://strategy.example.com/rss" rss2go:extractionStrategy="magic"/>
+  <outline text="Bad chain" xmlUrl="https://chain.example.com/rss" rss2go:extractionChain="heuristic, magic"/>
+  <outline text="Bad dedupe" xmlUrl="https://dedupe.example.com/rss" rss2go:dedupeStrategy="title"/>
+  <outline text="Bad format" xmlUrl="https://format.example.com/rss" rss2go:sourceFormat="gopher"/>
+</body></opml>`
+
+	report, err := Import(ctx, repo, crawler.DefaultRegistry(), strings.NewReader(src), user.ID)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if report.Created != 1 || report.Existing != 1 || report.Failed != 6 || len(report.Outlines) != 8 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Outlines[2].Status != StatusFailed || report.Outlines[2].Error == "" {
+		t.Errorf("expected invalid URL to be reported, got %+v", report.Outlines[2])
+	}
+	if report.Outlines[3].Status != StatusFailed || !strings.Contains(report.Outlines[3].Error, "content rules") {
+		t.Errorf("expected invalid content rules to be reported, got %+v", report.Outlines[3])
+	}
+	for i, want := range map[int]string{4: "extraction strategy", 5: "extraction chain", 6: "dedupe strategy", 7: "source format"} {
+		if res := report.Outlines[i]; res.Status != StatusFailed || !strings.Contains(res.Error, want) {
+			t.Errorf("expected an unknown %s to be reported, got %+v", want, res)
+		}
+	}
+
+	created, err := repo.GetFeedByURL(ctx, "https://new.example.com/rss")
+	if err != nil {
+		t.Fatalf("expected imported feed: %v", err)
+	}
+	if created.Category != "Tech" || !created.ExtractFullArticle || created.ExtractionStrategy != types.StrategySelector || created.CSSSelector != "main" || created.SourceFormat != crawler.FormatJSONFeed {
+		t.Errorf("unexpected imported feed %+v", created)
+	}
+	if created.BackoffFactor != 1.0 || created.PollIntervalSecs != defaultPollIntervalSecs {
+		t.Errorf("expected scheduling defaults, got %+v", created)
+	}
+
+	subs, err := repo.ListSubscriptionsForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list subscriptions: %v", err)
+	}
+	if len(subs) != 2 {
+		t.Errorf("expected user to be subscribed to both feeds, got %d", len(subs))
+	}
+
+	// Re-importing the same document is a no-op.
+	report, err = Import(ctx, repo, crawler.DefaultRegistry(), strings.NewReader(src), user.ID)
+	if err != nil {
+		t.Fatalf("second Import failed: %v", err)
+	}
+	if report.Created != 0 || report.Existing != 2 || report.Failed != 6 {
+		t.Errorf("expected idempotent re-import, got %+v", report)
+	}
+	feeds, _ := repo.ListFeeds(ctx)
+	if len(feeds) != 2 {
+		t.Errorf("expected 2 feeds after re-import, got %d", len(feeds))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -29,9 +30,9 @@ type Config struct {
 // property of how Queue drains its own batch, not a requirement
 // notifier.Sender itself imposes.
 type Queue struct {
-	repo   *database.Repository
-	sender notifier.Sender
-	cfg    Config
+	repo       *database.Repository
+	dispatcher *notifier.Dispatcher
+	cfg        Config
 
 	// mu guards stopped, checked atomically alongside wg.Add in
 	// tryBeginCycle so a Stop() call can never race a new cycle starting:
@@ -69,13 +70,18 @@ func NewQueue(repo *database.Repository, sender notifier.Sender, cfg Config, log
 
 	return &Queue{
 		repo:       repo,
-		sender:     sender,
+		dispatcher: notifier.NewDispatcher(sender, nil),
 		cfg:        cfg,
 		shutdownCh: make(chan struct{}),
 		log:        log,
 	}
 }
 
+// SetInliner embeds email images in the messages as they are sent.
+func (q *Queue) SetInliner(in notifier.Inliner) {
+	q.dispatcher.SetInliner(in)
+}
+
 // tryBeginCycle atomically checks whether shutdown has been requested and,
 // if not, registers one in-flight processPending cycle with wg. Returns
 // false if Stop has already been called, in which case the caller must not
@@ -166,7 +172,8 @@ func (q *Queue) processPending(ctx context.Context) error {
 	return nil
 }
 
-// deliverItem attempts sending an email and updates its database state based on results.
+// deliverItem attempts delivery to the item's channel (plain email when it has
+// none) and updates its database state based on results.
 func (q *Queue) deliverItem(ctx context.Context, item *types.OutboxItem) {
 	// Set status to delivering in DB first to protect against double delivery on restart
 	item.Status = types.OutboxDelivering
@@ -180,7 +187,7 @@ func (q *Queue) deliverItem(ctx context.Context, item *types.OutboxItem) {
 	}
 
 	// Attempt delivery
-	err := q.sender.Send(ctx, item.Subject, item.Body, item.Recipients)
+	err := q.deliver(ctx, item)
 	now = time.Now()
 	item.LastAttemptAt = &now
 
@@ -212,6 +219,72 @@ func (q *Queue) deliverItem(ctx context.Context, item *types.OutboxItem) {
 	}
 }
 
+// deliver resolves the item's channel, if any, and hands it to the dispatcher.
+// The channel is looked up at delivery time so edits to its target or token
+// apply to retries.
+func (q *Queue) deliver(ctx context.Context, item *types.OutboxItem) error {
+	if item.ChannelID == nil {
+		return q.dispatcher.Deliver(ctx, nil, item)
+	}
+
+	ch, err := q.repo.GetChannel(ctx, *item.ChannelID)
+	if err != nil {
+		return fmt.Errorf("outbox: load channel %d: %w", *item.ChannelID, err)
+	}
+	if !ch.Enabled {
+		return fmt.Errorf("outbox: channel %d is disabled", ch.ID)
+	}
+	return q.dispatcher.Deliver(ctx, ch, item)
+}
+
+// Enqueue renders msg for each of a user's enabled channels and adds one
+// outbox item per channel. A user without any enabled channel receives a
+// plain email at their account address, as before channels existed, so no
+// item is lost to disabled channels.
+func Enqueue(ctx context.Context, repo *database.Repository, email string, channels []*types.Channel, msg *notifier.Message, now time.Time) error {
+	if !slices.ContainsFunc(channels, func(ch *types.Channel) bool { return ch.Enabled }) {
+		body, err := notifier.Render(types.ChannelEmail, msg)
+		if err != nil {
+			return err
+		}
+		return repo.EnqueueOutboxItem(ctx, &types.OutboxItem{
+			Subject:         msg.Subject,
+			Body:            body,
+			Status:          types.OutboxPending,
+			NextAttemptAt:   now,
+			Recipients:      []string{email},
+			ListUnsubscribe: msg.UnsubscribeURL,
+		})
+	}
+
+	for _, ch := range channels {
+		if !ch.Enabled {
+			continue
+		}
+		body, err := notifier.Render(ch.Kind, msg)
+		if err != nil {
+			return err
+		}
+
+		item := &types.OutboxItem{
+			Subject:       msg.Subject,
+			Body:          body,
+			Status:        types.OutboxPending,
+			NextAttemptAt: now,
+			Recipients:    []string{},
+			ChannelID:     &ch.ID,
+		}
+		if ch.Kind == types.ChannelEmail {
+			item.Recipients = []string{ch.Target}
+			item.ListUnsubscribe = msg.UnsubscribeURL
+		}
+		if err := repo.EnqueueOutboxItem(ctx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func calculateBackoff(retryCount int, initial, max time.Duration) time.Duration {
 	if retryCount <= 0 {
 		return initial
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -12,6 +15,7 @@ import (
 	"time"
 
 	"rss2go/internal/database"
+	"rss2go/internal/notifier"
 	"rss2go/internal/types"
 )
 
@@ -26,9 +30,10 @@ type sentEmail struct {
 	Subject    string
 	Body       string
 	Recipients []string
+	Headers    map[string]string
 }
 
-func (m *MockSender) Send(ctx context.Context, subject string, body string, recipients []string) error {
+func (m *MockSender) Send(ctx context.Context, subject string, body string, recipients []string, headers map[string]string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.called++
@@ -41,6 +46,7 @@ func (m *MockSender) Send(ctx context.Context, subject string, body string, reci
 		Subject:    subject,
 		Body:       body,
 		Recipients: recipients,
+		Headers:    headers,
 	})
 	return nil
 }
@@ -630,7 +636,7 @@ type blockingSender struct {
 	started chan struct{}
 }
 
-func (b *blockingSender) Send(_ context.Context, _ string, _ string, _ []string) error {
+func (b *blockingSender) Send(_ context.Context, _ string, _ string, _ []string, _ map[string]string) error {
 	select {
 	case b.started <- struct{}{}:
 	default:
@@ -714,8 +720,8 @@ type cancelAfterFirstSendSender struct {
 	cancel func()
 }
 
-func (s *cancelAfterFirstSendSender) Send(ctx context.Context, subject, body string, recipients []string) error {
-	err := s.MockSender.Send(ctx, subject, body, recipients)
+func (s *cancelAfterFirstSendSender) Send(ctx context.Context, subject, body string, recipients []string, headers map[string]string) error {
+	err := s.MockSender.Send(ctx, subject, body, recipients, headers)
 	s.cancel()
 	return err
 }
@@ -844,3 +850,89 @@ func TestOutboxQueueNoErrorLogOnContextCancellation(t *testing.T) {
 		t.Error("expected no \"Processing error\" log record for a context-cancellation shutdown")
 	}
 }
+
+func TestOutboxQueueChannelDelivery(t *testing.T) {
+	repo := setupTestDB(t)
+	sender := &MockSender{}
+	ctx := context.Background()
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hook.Close()
+
+	user := &types.User{Email: "user@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	webhook := &types.Channel{UserID: user.ID, Kind: types.ChannelWebhook, Target: hook.URL, Enabled: true}
+	email := &types.Channel{UserID: user.ID, Kind: types.ChannelEmail, Target: "inbox@test.com", Enabled: true}
+	muted := &types.Channel{UserID: user.ID, Kind: types.ChannelSlack, Target: hook.URL + "/slack"}
+	for _, ch := range []*types.Channel{webhook, email, muted} {
+		if err := repo.CreateChannel(ctx, ch); err != nil {
+			t.Fatalf("failed to create channel: %v", err)
+		}
+	}
+
+	msg := &notifier.Message{Subject: "[Feed] Post", FeedTitle: "Feed", Title: "Post", Link: "https://feed.test/post", HTML: "<p>post</p>"}
+	channels, _ := repo.ListChannelsForUser(ctx, user.ID)
+	if err := Enqueue(ctx, repo, user.Email, channels, msg, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	queue := NewQueue(repo, sender, Config{MaxRetries: 3, PollInterval: time.Hour}, slog.New(slog.DiscardHandler))
+	if err := queue.processPending(ctx); err != nil {
+		t.Fatalf("processPending failed: %v", err)
+	}
+
+	// One delivery per enabled channel; the disabled Slack channel is skipped.
+	if len(bodies) != 1 || !strings.Contains(bodies[0], `"link":"https://feed.test/post"`) {
+		t.Errorf("expected one webhook delivery, got %v", bodies)
+	}
+	sent := sender.getSent()
+	if len(sent) != 1 || sent[0].Recipients[0] != "inbox@test.com" || sent[0].Body != "<p>post</p>" {
+		t.Errorf("expected one email to the channel address, got %+v", sent)
+	}
+
+	// Without channels the user's own address gets a plain email.
+	if err := Enqueue(ctx, repo, user.Email, nil, msg, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := queue.processPending(ctx); err != nil {
+		t.Fatalf("processPending failed: %v", err)
+	}
+	if sent = sender.getSent(); len(sent) != 2 || sent[1].Recipients[0] != user.Email {
+		t.Errorf("expected a fallback email to %s, got %+v", user.Email, sent)
+	}
+
+	// So does a user whose channels are all disabled, rather than losing the item.
+	if err := Enqueue(ctx, repo, user.Email, []*types.Channel{muted}, msg, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := queue.processPending(ctx); err != nil {
+		t.Fatalf("processPending failed: %v", err)
+	}
+	if sent = sender.getSent(); len(sent) != 3 || sent[2].Recipients[0] != user.Email {
+		t.Errorf("expected a fallback email to %s with every channel disabled, got %+v", user.Email, sent)
+	}
+
+	// Deliveries to a channel disabled after enqueueing fail and are retried.
+	if err := Enqueue(ctx, repo, user.Email, []*types.Channel{webhook}, msg, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	webhook.Enabled = false
+	_ = repo.UpdateChannel(ctx, webhook)
+	if err := queue.processPending(ctx); err != nil {
+		t.Fatalf("processPending failed: %v", err)
+	}
+	items, _ := repo.ListOutboxItems(ctx, 10)
+	if items[0].Status != types.OutboxPending || items[0].RetryCount != 1 || !strings.Contains(items[0].LastError, "disabled") {
+		t.Errorf("expected disabled channel delivery to be retried, got %+v", items[0])
+	}
+}
@@ -0,0 +1,167 @@
+// Package politeness keeps rss2go from hammering the sites it fetches from:
+// it paces and caps the requests made to each host, and checks robots.txt.
+package politeness
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits, so tests can control both.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Config configures a Limiter.
+type Config struct {
+	// Rate is how many requests per second may start against one host.
+	// Zero means no limit.
+	Rate float64
+	// MaxConns is how many requests to one host may be in flight at once.
+	// Zero means no limit.
+	MaxConns int
+	// Clock defaults to the real clock.
+	Clock Clock
+}
+
+// Limiter paces and caps the requests made to each host. It is shared by
+// every client whose transport it wraps, so the crawler and the article
+// extractor together stay within the limits.
+type Limiter struct {
+	cfg      Config
+	interval time.Duration
+	mu       sync.Mutex
+	hosts    map[string]*hostState
+}
+
+type hostState struct {
+	next  time.Time     // earliest start of the next request
+	slots chan struct{} // in-flight requests; nil without a cap
+}
+
+// NewLimiter creates a Limiter.
+func NewLimiter(cfg Config) *Limiter {
+	if cfg.Clock == nil {
+		cfg.Clock = realClock{}
+	}
+	l := &Limiter{cfg: cfg, hosts: make(map[string]*hostState)}
+	if cfg.Rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / cfg.Rate)
+	}
+	return l
+}
+
+// Wait blocks until a request to host may start, and returns a function to
+// call once the request is done. It fails only if ctx ends first.
+func (l *Limiter) Wait(ctx context.Context, host string) (func(), error) {
+	st := l.host(strings.ToLower(host))
+
+	release := func() {}
+	if st.slots != nil {
+		select {
+		case st.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-st.slots }) }
+	}
+
+	if l.interval > 0 {
+		// Reserve the next start time, so waiters are served in order
+		l.mu.Lock()
+		now := l.cfg.Clock.Now()
+		start := st.next
+		if start.Before(now) {
+			start = now
+		}
+		st.next = start.Add(l.interval)
+		l.mu.Unlock()
+
+		if wait := start.Sub(now); wait > 0 {
+			select {
+			case <-l.cfg.Clock.After(wait):
+			case <-ctx.Done():
+				l.unreserve(st, start)
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return release, nil
+}
+
+// unreserve gives back the start time reserved by a request that gave up
+// waiting, unless a later request has already reserved the one after it.
+func (l *Limiter) unreserve(st *hostState, start time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if st.next.Equal(start.Add(l.interval)) {
+		st.next = start
+	}
+}
+
+func (l *Limiter) host(name string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.hosts[name]
+	if !ok {
+		st = &hostState{}
+		if l.cfg.MaxConns > 0 {
+			st.slots = make(chan struct{}, l.cfg.MaxConns)
+		}
+		l.hosts[name] = st
+	}
+	return st
+}
+
+// Transport wraps next, or http.DefaultTransport if nil, so every request
+// waits its turn with the limiter. A request counts as in flight until its
+// response body is closed.
+func (l *Limiter) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{limiter: l, next: next}
+}
+
+type transport struct {
+	limiter *Limiter
+	next    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.Wait(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees the request's slot with the limiter once closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,243 @@
+package politeness
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when advanced, firing the timers that come due.
+type fakeClock struct {
+	mu      sync.Mutex
+	timers  *sync.Cond // signalled when a timer is added
+	now     time.Time
+	waiters []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)}
+	c.timers = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeTimer{at: c.now.Add(d), ch: ch})
+	c.timers.Broadcast()
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// WaitForTimers blocks until n timers are pending.
+func (c *fakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.timers.Wait()
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	clock := newFakeClock()
+	l := NewLimiter(Config{Rate: 2, Clock: clock})
+	ctx := context.Background()
+
+	// The first request to a host starts straight away
+	if _, err := l.Wait(ctx, "example.com"); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	started := make(chan struct{}, 2)
+	for range 2 {
+		go func() {
+			if _, err := l.Wait(ctx, "example.com"); err == nil {
+				started <- struct{}{}
+			}
+		}()
+	}
+	clock.WaitForTimers(2)
+
+	// Other hosts are not held up
+	if _, err := l.Wait(ctx, "other.example"); err != nil {
+		t.Fatalf("Wait(other host): %v", err)
+	}
+
+	// No timer is due yet, so neither request can have started
+	clock.Advance(400 * time.Millisecond)
+	select {
+	case <-started:
+		t.Fatal("a request started before its turn")
+	default:
+	}
+	clock.Advance(100 * time.Millisecond)
+	<-started
+	select {
+	case <-started:
+		t.Fatal("the third request started with the second")
+	default:
+	}
+	clock.Advance(500 * time.Millisecond)
+	<-started
+}
+
+func TestLimiterRateCancelledWait(t *testing.T) {
+	clock := newFakeClock()
+	l := NewLimiter(Config{Rate: 2, Clock: clock})
+
+	if _, err := l.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	// A request that gives up waiting hands its turn back
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Wait(cancelled, "example.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled request to give up, got %v", err)
+	}
+
+	started := make(chan struct{})
+	go func() {
+		if _, err := l.Wait(context.Background(), "example.com"); err == nil {
+			close(started)
+		}
+	}()
+	clock.WaitForTimers(2) // the abandoned timer is still pending
+
+	// The next request takes the abandoned turn rather than the one after
+	clock.Advance(500 * time.Millisecond)
+	<-started
+}
+
+func TestLimiterMaxConns(t *testing.T) {
+	l := NewLimiter(Config{MaxConns: 1})
+
+	release, err := l.Wait(context.Background(), "Example.com")
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	// With every slot taken, a cancelled context is the only way out of
+	// Wait. Host names are compared without case.
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Wait(cancelled, "example.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the second request to wait for a free slot, got %v", err)
+	}
+
+	release()
+	release() // releasing twice must not free a second slot
+	if _, err := l.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Wait after release: %v", err)
+	}
+	if _, err := l.Wait(cancelled, "example.com"); err == nil {
+		t.Fatal("a double release freed an extra slot")
+	}
+}
+
+func TestTransportCapsConnectionsPerHost(t *testing.T) {
+	// The handler reports each arrival and holds the request until told
+	// to answer, so the test decides when slots free up.
+	var inFlight, peak atomic.Int32
+	arrived := make(chan struct{}, 16)
+	proceed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		arrived <- struct{}{}
+		<-proceed
+		inFlight.Add(-1)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	l := NewLimiter(Config{MaxConns: 2})
+	client := &http.Client{Transport: l.Transport(server.Client().Transport)}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Errorf("GET: %v", err)
+				return
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		})
+	}
+
+	// Two requests fill the cap; each answered one lets the next through
+	<-arrived
+	<-arrived
+	for range 6 {
+		proceed <- struct{}{}
+		<-arrived
+	}
+	close(proceed)
+	wg.Wait()
+
+	if p := peak.Load(); p != 2 {
+		t.Errorf("expected at most 2 requests in flight, and to reach it; peak was %d", p)
+	}
+
+	// A slot is held until the body is closed
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp2, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(cancelled, http.MethodGet, server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a third request to wait while two bodies are open, got %v", err)
+	}
+	_ = resp.Body.Close()
+	_ = resp2.Body.Close()
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET after closing the bodies: %v", err)
+	}
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,234 @@
+package politeness
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsTTL is how long a host's robots.txt is trusted, the most RFC 9309 allows.
+	robotsTTL = 24 * time.Hour
+	// robotsRetry is how long a host whose robots.txt could not be fetched
+	// stays disallowed before trying again.
+	robotsRetry = time.Hour
+	// maxRobotsSize is how much of a robots.txt file is read.
+	maxRobotsSize = 512 << 10
+)
+
+// Robots checks URLs against their host's robots.txt, fetching each file
+// once and caching it per host.
+type Robots struct {
+	client *http.Client
+	agent  string
+	clock  Clock
+	mu     sync.Mutex
+	hosts  map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	ready   chan struct{} // closed once fetched
+	rules   []robotsRule
+	expires time.Time
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int // of the path pattern, for picking the most specific rule
+	pattern *regexp.Regexp
+}
+
+// NewRobots creates a Robots checker that fetches with client and obeys
+// the rules for agent, a product token such as "rss2go". A nil clock means
+// the real clock.
+func NewRobots(client *http.Client, agent string, clock Clock) *Robots {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &Robots{client: client, agent: agent, clock: clock, hosts: make(map[string]*robotsEntry)}
+}
+
+// Allowed reports whether the agent may fetch target. Hosts without a
+// robots.txt allow everything, while hosts whose robots.txt fails to load
+// allow nothing until it can be fetched.
+func (r *Robots) Allowed(ctx context.Context, target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return true
+	}
+	if u.Path == "/robots.txt" {
+		return true
+	}
+	entry := r.entry(ctx, u.Scheme+"://"+strings.ToLower(u.Host))
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return allowedBy(entry.rules, path)
+}
+
+// allowedBy reports whether rules allow path, which includes any query.
+func allowedBy(rules []robotsRule, path string) bool {
+	allowed, longest := true, -1
+	for _, rule := range rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		// The longest match wins, and Allow wins a tie
+		if rule.length > longest || (rule.length == longest && rule.allow) {
+			allowed, longest = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
+
+// entry returns the host's cached rules, fetching them if they are missing
+// or expired. Concurrent callers share one fetch.
+func (r *Robots) entry(ctx context.Context, origin string) *robotsEntry {
+	r.mu.Lock()
+	entry, ok := r.hosts[origin]
+	if ok {
+		select {
+		case <-entry.ready:
+			if r.clock.Now().After(entry.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		r.hosts[origin] = entry
+		r.mu.Unlock()
+
+		// The fetch is shared, so one caller giving up must not fail it for all
+		entry.rules, entry.expires = r.fetch(context.WithoutCancel(ctx), origin)
+		close(entry.ready)
+		return entry
+	}
+	r.mu.Unlock()
+
+	<-entry.ready
+	return entry
+}
+
+// fetch loads and parses a host's robots.txt, returning its rules and when
+// they expire.
+func (r *Robots) fetch(ctx context.Context, origin string) ([]robotsRule, time.Time) {
+	disallowAll := []robotsRule{{allow: false, length: 1, pattern: regexp.MustCompile(`^/`)}}
+	now := r.clock.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll, now.Add(robotsRetry)
+	}
+	req.Header.Set("User-Agent", r.agent+"/1.0")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return disallowAll, now.Add(robotsRetry)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+		if err != nil {
+			return disallowAll, now.Add(robotsRetry)
+		}
+		return parseRobots(body, r.agent), now.Add(robotsTTL)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// No robots.txt means no restrictions
+		return nil, now.Add(robotsTTL)
+	default:
+		return disallowAll, now.Add(robotsRetry)
+	}
+}
+
+// parseRobots returns the rules of the groups that name agent, or of the
+// "*" group if none do.
+func parseRobots(body []byte, agent string) []robotsRule {
+	type group struct {
+		agents []string
+		rules  []robotsRule
+	}
+	var groups []*group
+	var current *group
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: robotsPattern(value),
+			})
+		default:
+			inAgents = false
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	var named, wildcard []robotsRule
+	matched := false
+	for _, g := range groups {
+		switch {
+		case slices.Contains(g.agents, agent):
+			named = append(named, g.rules...)
+			matched = true
+		case slices.Contains(g.agents, "*"):
+			wildcard = append(wildcard, g.rules...)
+		}
+	}
+	if matched {
+		return named
+	}
+	return wildcard
+}
+
+// robotsPattern compiles a path pattern, where * matches any characters and
+// a trailing $ anchors the end.
+func robotsPattern(p string) *regexp.Regexp {
+	anchored := strings.HasSuffix(p, "$")
+	p = strings.TrimSuffix(p, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
@@ -0,0 +1,125 @@
+package politeness
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	body := []byte(`
+# Everyone else
+User-agent: *
+Disallow: /
+
+User-agent: Googlebot
+User-agent: rss2go
+Disallow: /private/
+Allow: /private/public-*
+Disallow: /*.pdf$
+Disallow: /search?
+Allow: /news
+
+User-agent: otherbot
+Disallow:
+`)
+	rules := parseRobots(body, "rss2go")
+	tests := map[string]bool{
+		"/":                         true,
+		"/articles/1":               true,
+		"/private/notes":            false,
+		"/private/public-post":      true,
+		"/docs/report.pdf":          false,
+		"/docs/report.pdf?download": true,
+		"/search?q=go":              false,
+		"/searching":                true,
+		"/news/today":               true,
+	}
+	for path, want := range tests {
+		if got := allowedBy(rules, path); got != want {
+			t.Errorf("%s: allowed = %v, want %v", path, got, want)
+		}
+	}
+
+	// Agents without a group of their own get the "*" rules
+	if allowedBy(parseRobots(body, "someone"), "/articles/1") {
+		t.Error("expected the * group to disallow everything for other agents")
+	}
+	// An empty Disallow allows everything, rather than falling back to "*"
+	if !allowedBy(parseRobots(body, "otherbot"), "/articles/1") {
+		t.Error("expected an empty Disallow to allow everything")
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	var fetches atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	// The first fetch is held until released, so other checks arrive while
+	// it is in flight; closing release lets later fetches through at once.
+	fetching := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			return
+		}
+		if fetches.Add(1) == 1 {
+			fetching <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /members/\n"))
+	}))
+	defer server.Close()
+
+	clock := newFakeClock()
+	robots := NewRobots(server.Client(), "rss2go", clock)
+	ctx := context.Background()
+
+	// Checks made while the fetch is in flight share it
+	var wg sync.WaitGroup
+	check := func() {
+		if !robots.Allowed(ctx, server.URL+"/articles/1") {
+			t.Error("expected /articles/1 to be allowed")
+		}
+	}
+	wg.Go(check)
+	<-fetching
+	for range 4 {
+		wg.Go(check)
+	}
+	close(release)
+	wg.Wait()
+	if robots.Allowed(ctx, server.URL+"/members/secret") {
+		t.Error("expected /members/ to be disallowed")
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected robots.txt to be fetched once, got %d", n)
+	}
+
+	// Once the cached copy expires, the file is fetched again. A server
+	// error disallows everything until the retry.
+	status.Store(http.StatusServiceUnavailable)
+	clock.Advance(robotsTTL + time.Minute)
+	if robots.Allowed(ctx, server.URL+"/articles/1") {
+		t.Error("expected a failing robots.txt to disallow everything")
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("expected robots.txt to be fetched again after expiring, got %d fetches", n)
+	}
+
+	// A missing robots.txt allows everything
+	status.Store(http.StatusNotFound)
+	clock.Advance(robotsRetry + time.Minute)
+	if !robots.Allowed(ctx, server.URL+"/members/secret") {
+		t.Error("expected a missing robots.txt to allow everything")
+	}
+	if n := fetches.Load(); n != 3 {
+		t.Errorf("expected a retry after the failure, got %d fetches", n)
+	}
+}
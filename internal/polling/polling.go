@@ -0,0 +1,110 @@
+package polling
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"rss2go/internal/types"
+)
+
+// pollsPerPost is how many times a feed is polled in its typical gap
+// between posts, so new items wait at most about half that gap.
+const pollsPerPost = 2
+
+// maxSamples caps how many of the newest item timestamps are considered, so
+// the estimate follows recent changes in how often a feed posts.
+const maxSamples = 20
+
+// Policy picks poll intervals for feeds in adaptive mode.
+type Policy struct {
+	// Min and Max bound every learned interval. They default to 5 minutes
+	// and 24 hours.
+	Min time.Duration
+	Max time.Duration
+	// Jitter spreads polls by up to this fraction of the interval either
+	// way, so feeds added together do not stay in lockstep. It defaults to 0.1.
+	Jitter float64
+	// Rand returns a number in [0, 1). Tests substitute a fixed value.
+	Rand func() float64
+}
+
+func (p Policy) bounds() (time.Duration, time.Duration) {
+	lo, hi := p.Min, p.Max
+	if lo <= 0 {
+		lo = 5 * time.Minute
+	}
+	if hi <= 0 {
+		hi = 24 * time.Hour
+	}
+	return lo, max(lo, hi)
+}
+
+// Interval returns the interval for a feed estimated to need polling every
+// estimate, which honors the publisher's hint as a lower bound. The result
+// always lies within the policy's bounds, so Max wins over a longer hint.
+func (p Policy) Interval(estimate, hint time.Duration) time.Duration {
+	lo, hi := p.bounds()
+	return min(max(estimate, hint, lo), hi)
+}
+
+// Spread applies the policy's jitter to d.
+func (p Policy) Spread(d time.Duration) time.Duration {
+	jitter := p.Jitter
+	if jitter <= 0 {
+		jitter = 0.1
+	}
+	random := p.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+	return time.Duration(float64(d) * (1 + jitter*(2*random()-1)))
+}
+
+// FromItems estimates a poll interval from the publication times of a
+// feed's items: the newest few posts are spread over the time from the
+// oldest of them until now. Times that are zero or not before now are
+// ignored, since they say nothing about when the item was posted. It reports
+// false when fewer than two usable times remain.
+func FromItems(published []time.Time, now time.Time) (time.Duration, bool) {
+	var times []time.Time
+	for _, t := range published {
+		if !t.IsZero() && t.Before(now) {
+			times = append(times, t)
+		}
+	}
+	if len(times) < 2 {
+		return 0, false
+	}
+
+	slices.SortFunc(times, func(a, b time.Time) int { return b.Compare(a) })
+	times = times[:min(len(times), maxSamples)]
+	span := now.Sub(times[len(times)-1])
+	return span / time.Duration(len(times)*pollsPerPost), true
+}
+
+// FromRuns estimates a poll interval from a feed's crawl history, newest
+// first, for feeds whose items carry no usable dates: the new items found
+// by successful runs are spread over the time the runs cover. A history
+// without new items suggests polling no more often than the time it covers.
+// It reports false when there is no successful run to go by.
+func FromRuns(runs []*types.CrawlRun, now time.Time) (time.Duration, bool) {
+	var oldest time.Time
+	var newItems int
+	for _, run := range runs {
+		if run.Error != "" {
+			continue
+		}
+		oldest = run.StartedAt
+		newItems += run.NewItemCount
+	}
+	if oldest.IsZero() || !oldest.Before(now) {
+		return 0, false
+	}
+
+	span := now.Sub(oldest)
+	if newItems == 0 {
+		return span, true
+	}
+	return span / time.Duration(newItems*pollsPerPost), true
+}
@@ -0,0 +1,100 @@
+package polling
+
+import (
+	"testing"
+	"time"
+
+	"rss2go/internal/types"
+)
+
+func TestFromItems(t *testing.T) {
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(h ...int) []time.Time {
+		var times []time.Time
+		for _, n := range h {
+			times = append(times, now.Add(-time.Duration(n)*time.Hour))
+		}
+		return times
+	}
+
+	tests := []struct {
+		name      string
+		published []time.Time
+		want      time.Duration
+		ok        bool
+	}{
+		{"no dates", nil, 0, false},
+		{"one date", hoursAgo(5), 0, false},
+		{"hourly posts", hoursAgo(1, 2, 3, 4), 30 * time.Minute, true},
+		{"silence stretches the estimate", hoursAgo(97, 98, 99, 100), 12*time.Hour + 30*time.Minute, true},
+		{"future and zero dates ignored", append(hoursAgo(1, 2), now.Add(time.Hour), time.Time{}), 30 * time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromItems(tt.published, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("FromItems() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	// Only the newest posts count, so a burst long ago does not linger
+	var many []time.Time
+	for i := range 50 {
+		many = append(many, now.Add(-time.Duration(i+1)*time.Hour))
+	}
+	if got, _ := FromItems(many, now); got != 30*time.Minute {
+		t.Errorf("expected the newest %d items to decide, got %v", maxSamples, got)
+	}
+}
+
+func TestFromRuns(t *testing.T) {
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+	run := func(hoursAgo, newItems int, err string) *types.CrawlRun {
+		return &types.CrawlRun{StartedAt: now.Add(-time.Duration(hoursAgo) * time.Hour), NewItemCount: newItems, Error: err}
+	}
+
+	if _, ok := FromRuns(nil, now); ok {
+		t.Errorf("expected no estimate without history")
+	}
+	if _, ok := FromRuns([]*types.CrawlRun{run(1, 0, "timeout")}, now); ok {
+		t.Errorf("expected failed runs to be ignored")
+	}
+	if got, ok := FromRuns([]*types.CrawlRun{run(1, 1, ""), run(2, 0, ""), run(4, 1, ""), run(8, 0, "timeout")}, now); !ok || got != time.Hour {
+		t.Errorf("expected 2 new items over 4h to poll hourly, got %v, %v", got, ok)
+	}
+	if got, _ := FromRuns([]*types.CrawlRun{run(3, 0, ""), run(6, 0, "")}, now); got != 6*time.Hour {
+		t.Errorf("expected a quiet history to poll no more often than it covers, got %v", got)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{Min: 10 * time.Minute, Max: 6 * time.Hour, Jitter: 0.2, Rand: func() float64 { return 1 }}
+
+	tests := []struct {
+		estimate, hint, want time.Duration
+	}{
+		{time.Minute, 0, 10 * time.Minute},
+		{time.Hour, 0, time.Hour},
+		{time.Hour, 2 * time.Hour, 2 * time.Hour},
+		{time.Hour, 24 * time.Hour, 6 * time.Hour},
+		{48 * time.Hour, 0, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := p.Interval(tt.estimate, tt.hint); got != tt.want {
+			t.Errorf("Interval(%v, %v) = %v, want %v", tt.estimate, tt.hint, got, tt.want)
+		}
+	}
+
+	if got := p.Spread(time.Hour); got != 72*time.Minute {
+		t.Errorf("expected jitter to stretch the interval by up to 20%%, got %v", got)
+	}
+	p.Rand = func() float64 { return 0 }
+	if got := p.Spread(time.Hour); got != 48*time.Minute {
+		t.Errorf("expected jitter to shorten the interval by up to 20%%, got %v", got)
+	}
+
+	if got := (Policy{}).Interval(0, 0); got != 5*time.Minute {
+		t.Errorf("expected a 5m default minimum, got %v", got)
+	}
+}
@@ -0,0 +1,226 @@
+// Package requestopts applies a feed's custom HTTP request options, such as
+// credentials and extra headers, and masks them for display.
+package requestopts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"rss2go/internal/types"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Masked stands in for secret values shown to the operator. Sent back in an
+// update, it keeps the stored value.
+const Masked = "********"
+
+// Apply sets the options on req. Values from the options replace headers
+// the caller already set, including the default User-Agent.
+func Apply(req *http.Request, opts *types.RequestOptions) {
+	if opts == nil {
+		return
+	}
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	for _, name := range slices.Sorted(maps.Keys(opts.Cookies)) {
+		req.AddCookie(&http.Cookie{Name: name, Value: opts.Cookies[name]})
+	}
+	switch {
+	case opts.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+opts.BearerToken)
+	case opts.Username != "" || opts.Password != "":
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+}
+
+// Client returns a copy of client that drops the options' custom headers
+// when a redirect leaves the site of the original request. The standard
+// library already drops Authorization and Cookie headers in that case.
+func Client(client *http.Client, opts *types.RequestOptions) *http.Client {
+	if opts == nil || len(opts.Headers) == 0 {
+		return client
+	}
+	c := *client
+	next := client.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > 0 && !SameSite(via[0].URL, req.URL) {
+			for name := range opts.Headers {
+				req.Header.Del(name)
+			}
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &c
+}
+
+// ForLink returns the feed's options if link is on the same site as the
+// feed, and nil otherwise, so credentials for a feed are not sent to every
+// site its items link to. Links must also not downgrade from https.
+func ForLink(feedURL, link string, opts *types.RequestOptions) *types.RequestOptions {
+	if opts == nil {
+		return nil
+	}
+	f, err := url.Parse(feedURL)
+	if err != nil {
+		return nil
+	}
+	l, err := url.Parse(link)
+	if err != nil || (l.Scheme != "https" && l.Scheme != f.Scheme) || !SameSite(f, l) {
+		return nil
+	}
+	return opts
+}
+
+// Key returns a digest that tells requests made with different options
+// apart, so content fetched with one feed's credentials is never cached for
+// another. It is empty for nil options.
+func Key(opts *types.RequestOptions) string {
+	if opts == nil {
+		return ""
+	}
+	// Map keys are marshalled in sorted order, so equal options match
+	data, _ := json.Marshal(opts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// SameSite reports whether a and b share a registrable domain, such as
+// gitlab.example.com and www.example.com.
+func SameSite(a, b *url.URL) bool {
+	if a.Hostname() == "" || b.Hostname() == "" {
+		return false
+	}
+	return site(a.Hostname()) == site(b.Hostname())
+}
+
+// site returns host's registrable domain. IP addresses and hosts without
+// one are their own site.
+func site(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if s, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return s
+	}
+	return host
+}
+
+// Validate checks that the options can be sent as HTTP headers.
+func Validate(opts *types.RequestOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if !httpguts.ValidHeaderFieldValue(opts.UserAgent) {
+		return fmt.Errorf("invalid user agent")
+	}
+	for name, value := range opts.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid value for header %q", name)
+		}
+	}
+	for name, value := range opts.Cookies {
+		if err := (&http.Cookie{Name: name, Value: value}).Valid(); err != nil {
+			return fmt.Errorf("invalid cookie %q", name)
+		}
+	}
+	if !httpguts.ValidHeaderFieldValue(opts.BearerToken) {
+		return fmt.Errorf("invalid bearer token")
+	}
+	return nil
+}
+
+// Mask returns a copy of opts with every secret value replaced by Masked.
+// Header and cookie names, the user agent and the username stay readable.
+func Mask(opts *types.RequestOptions) *types.RequestOptions {
+	if opts == nil {
+		return nil
+	}
+	return &types.RequestOptions{
+		UserAgent:   opts.UserAgent,
+		Headers:     maskValues(opts.Headers),
+		Cookies:     maskValues(opts.Cookies),
+		Username:    opts.Username,
+		Password:    maskValue(opts.Password),
+		BearerToken: maskValue(opts.BearerToken),
+	}
+}
+
+// Merge resolves options sent back by the operator against the stored ones:
+// values left as Masked keep the stored value, or are dropped if there is
+// none to keep.
+func Merge(incoming, stored *types.RequestOptions) *types.RequestOptions {
+	if incoming == nil {
+		return nil
+	}
+	if stored == nil {
+		stored = &types.RequestOptions{}
+	}
+	return &types.RequestOptions{
+		UserAgent:   incoming.UserAgent,
+		Headers:     mergeValues(incoming.Headers, stored.Headers),
+		Cookies:     mergeValues(incoming.Cookies, stored.Cookies),
+		Username:    incoming.Username,
+		Password:    mergeValue(incoming.Password, stored.Password),
+		BearerToken: mergeValue(incoming.BearerToken, stored.BearerToken),
+	}
+}
+
+func maskValue(v string) string {
+	if v == "" {
+		return ""
+	}
+	return Masked
+}
+
+func maskValues(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	masked := make(map[string]string, len(m))
+	for k, v := range m {
+		masked[k] = maskValue(v)
+	}
+	return masked
+}
+
+func mergeValue(incoming, stored string) string {
+	if incoming == Masked {
+		return stored
+	}
+	return incoming
+}
+
+func mergeValues(incoming, stored map[string]string) map[string]string {
+	if len(incoming) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(incoming))
+	for k, v := range incoming {
+		if v = mergeValue(v, stored[k]); v != "" {
+			merged[k] = v
+		}
+	}
+	return merged
+}
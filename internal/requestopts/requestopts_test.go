@@ -0,0 +1,167 @@
+package requestopts
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"rss2go/internal/types"
+)
+
+func TestApply(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://jira.example.com/activity", nil)
+	req.Header.Set("User-Agent", "rss2go/1.0")
+	Apply(req, &types.RequestOptions{
+		Headers:     map[string]string{"X-Api-Key": "k"},
+		Cookies:     map[string]string{"b": "2", "a": "1"},
+		Username:    "reader",
+		Password:    "hunter2",
+		BearerToken: "tok",
+	})
+
+	if got := req.Header.Get("User-Agent"); got != "rss2go/1.0" {
+		t.Errorf("User-Agent = %q, want the default kept", got)
+	}
+	if got := req.Header.Get("X-Api-Key"); got != "k" {
+		t.Errorf("X-Api-Key = %q, want k", got)
+	}
+	if got := req.Header.Get("Cookie"); got != "a=1; b=2" {
+		t.Errorf("Cookie = %q, want a=1; b=2", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("Authorization = %q, want the bearer token to win over Basic auth", got)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "https://jira.example.com/activity", nil)
+	Apply(req, &types.RequestOptions{UserAgent: "Custom/1.0", Username: "reader", Password: "hunter2"})
+	if user, pass, ok := req.BasicAuth(); !ok || user != "reader" || pass != "hunter2" {
+		t.Errorf("BasicAuth = %q, %q, %v", user, pass, ok)
+	}
+	if got := req.Header.Get("User-Agent"); got != "Custom/1.0" {
+		t.Errorf("User-Agent = %q, want Custom/1.0", got)
+	}
+
+	Apply(req, nil)
+}
+
+func TestForLink(t *testing.T) {
+	opts := &types.RequestOptions{BearerToken: "tok"}
+	tests := []struct {
+		feed, link string
+		want       bool
+	}{
+		{"https://gitlab.example.com/feed.atom", "https://gitlab.example.com/group/project", true},
+		{"https://feeds.example.com/rss", "https://www.example.com/article", true},
+		{"https://example.co.uk/rss", "https://news.example.co.uk/a", true},
+		{"https://example.com/rss", "https://example.org/a", false},
+		{"https://one.github.io/feed", "https://two.github.io/post", false},
+		{"https://example.com/rss", "http://example.com/a", false},
+		{"http://example.com/rss", "http://example.com/a", true},
+		{"http://example.com/rss", "https://example.com/a", true},
+		{"http://10.0.0.1/rss", "http://10.0.0.1/a", true},
+		{"http://10.0.0.1/rss", "http://192.168.0.1/a", false},
+		{"https://example.com/rss", "/relative", false},
+	}
+	for _, tt := range tests {
+		if got := ForLink(tt.feed, tt.link, opts) != nil; got != tt.want {
+			t.Errorf("ForLink(%q, %q) = %v, want %v", tt.feed, tt.link, got, tt.want)
+		}
+	}
+	if ForLink("https://example.com/rss", "https://example.com/a", nil) != nil {
+		t.Error("ForLink without options returned options")
+	}
+}
+
+func TestMaskAndMerge(t *testing.T) {
+	stored := &types.RequestOptions{
+		UserAgent:   "Custom/1.0",
+		Headers:     map[string]string{"PRIVATE-TOKEN": "glpat", "X-Old": "old"},
+		Cookies:     map[string]string{"session": "abc"},
+		Username:    "reader",
+		Password:    "hunter2",
+		BearerToken: "tok",
+	}
+
+	masked := Mask(stored)
+	want := &types.RequestOptions{
+		UserAgent:   "Custom/1.0",
+		Headers:     map[string]string{"PRIVATE-TOKEN": Masked, "X-Old": Masked},
+		Cookies:     map[string]string{"session": Masked},
+		Username:    "reader",
+		Password:    Masked,
+		BearerToken: Masked,
+	}
+	if !reflect.DeepEqual(masked, want) {
+		t.Errorf("Mask = %+v, want %+v", masked, want)
+	}
+	if stored.Password != "hunter2" {
+		t.Error("Mask changed the stored options")
+	}
+
+	// Returning the masked form unchanged keeps every stored value
+	if got := Merge(masked, stored); !reflect.DeepEqual(got, stored) {
+		t.Errorf("Merge(Mask(stored)) = %+v, want %+v", got, stored)
+	}
+
+	edited := Mask(stored)
+	edited.Password = "new"
+	edited.BearerToken = ""
+	delete(edited.Headers, "X-Old")
+	edited.Headers["X-New"] = Masked // nothing stored to keep
+	edited.Cookies["theme"] = "dark"
+	got := Merge(edited, stored)
+	want = &types.RequestOptions{
+		UserAgent: "Custom/1.0",
+		Headers:   map[string]string{"PRIVATE-TOKEN": "glpat"},
+		Cookies:   map[string]string{"session": "abc", "theme": "dark"},
+		Username:  "reader",
+		Password:  "new",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+
+	if Mask(nil) != nil || Merge(nil, stored) != nil {
+		t.Error("expected nil options to stay nil")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := &types.RequestOptions{
+		UserAgent: "Custom/1.0",
+		Headers:   map[string]string{"PRIVATE-TOKEN": "glpat"},
+		Cookies:   map[string]string{"session": "abc"},
+	}
+	if err := Validate(valid); err != nil {
+		t.Errorf("Validate(valid) = %v", err)
+	}
+	if err := Validate(nil); err != nil {
+		t.Errorf("Validate(nil) = %v", err)
+	}
+
+	for name, opts := range map[string]*types.RequestOptions{
+		"header name":  {Headers: map[string]string{"Bad Header": "v"}},
+		"header value": {Headers: map[string]string{"X-Token": "a\r\nInjected: yes"}},
+		"cookie name":  {Cookies: map[string]string{"bad;name": "v"}},
+		"user agent":   {UserAgent: "a\nb"},
+		"bearer token": {BearerToken: "a\r\nb"},
+	} {
+		if err := Validate(opts); err == nil {
+			t.Errorf("Validate accepted an invalid %s", name)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	a := &types.RequestOptions{Headers: map[string]string{"X-One": "1", "X-Two": "2"}, Cookies: map[string]string{"session": "s"}}
+	b := &types.RequestOptions{Cookies: map[string]string{"session": "s"}, Headers: map[string]string{"X-Two": "2", "X-One": "1"}}
+	if Key(nil) != "" {
+		t.Error("expected no key without options")
+	}
+	if Key(a) == "" || Key(a) != Key(b) {
+		t.Errorf("expected equal options to share a key, got %q and %q", Key(a), Key(b))
+	}
+	if Key(a) == Key(&types.RequestOptions{Cookies: map[string]string{"session": "other"}}) {
+		t.Error("expected different credentials to get different keys")
+	}
+}
@@ -0,0 +1,264 @@
+// Package rewrite applies a feed's content rules to item HTML: removing
+// elements, rewriting link URLs and replacing text by regular expression.
+package rewrite
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"rss2go/internal/dedupe"
+	"rss2go/internal/types"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// maxPatternLen bounds rule patterns and selectors so a single rule cannot
+// dominate crawl time.
+const maxPatternLen = 1024
+
+// ampParams are query parameters that select or describe an AMP page.
+var ampParams = map[string]bool{
+	"amp":      true,
+	"amp_js_v": true,
+	"usqp":     true,
+}
+
+// Set is a feed's compiled content rules.
+type Set struct {
+	remove   []cascadia.Selector
+	links    []func(*url.URL) *url.URL
+	rewrites []rewriteFunc
+}
+
+type rewriteFunc struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Validate checks that every selector and pattern in rules compiles.
+func Validate(rules *types.ContentRules) error {
+	_, err := Compile(rules)
+	return err
+}
+
+// Compile validates and compiles rules into a Set. A nil Set leaves HTML
+// unchanged.
+func Compile(rules *types.ContentRules) (*Set, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	s := &Set{}
+	for _, selector := range rules.Remove {
+		if len(selector) > maxPatternLen {
+			return nil, fmt.Errorf("rewrite: selector exceeds %d characters", maxPatternLen)
+		}
+		sel, err := cascadia.Compile(selector)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite: invalid selector %q: %w", selector, err)
+		}
+		s.remove = append(s.remove, sel)
+	}
+	for _, rule := range rules.Links {
+		link, err := compileLink(rule)
+		if err != nil {
+			return nil, err
+		}
+		s.links = append(s.links, link)
+	}
+	for _, rule := range rules.Rewrites {
+		re, err := compilePattern(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		s.rewrites = append(s.rewrites, rewriteFunc{re: re, replacement: rule.Replacement})
+	}
+	return s, nil
+}
+
+// Apply runs the rules on an HTML fragment: elements matching the remove
+// selectors are deleted, then links are rewritten, with relative links
+// resolved against baseURL first, then the regex rewrites replace their
+// matches in the resulting HTML, in order. The result has not been
+// sanitized.
+func (s *Set) Apply(html, baseURL string) (string, error) {
+	if s == nil || strings.TrimSpace(html) == "" {
+		return html, nil
+	}
+
+	if len(s.remove) > 0 || len(s.links) > 0 {
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			return "", fmt.Errorf("rewrite: parse base URL: %w", err)
+		}
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			return "", fmt.Errorf("rewrite: parse HTML: %w", err)
+		}
+		for _, sel := range s.remove {
+			doc.FindMatcher(sel).Remove()
+		}
+		if len(s.links) > 0 {
+			doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+				href, _ := a.Attr("href")
+				if rewritten := s.rewriteLink(base, href); rewritten != "" {
+					a.SetAttr("href", rewritten)
+				}
+			})
+		}
+		html, err = doc.Find("body").Html()
+		if err != nil {
+			return "", fmt.Errorf("rewrite: render body: %w", err)
+		}
+	}
+
+	for _, r := range s.rewrites {
+		html = r.re.ReplaceAllString(html, r.replacement)
+	}
+	return html, nil
+}
+
+// rewriteLink applies the link rules to href, returning "" if href is not
+// an absolute http(s) URL once resolved.
+func (s *Set) rewriteLink(base *url.URL, href string) string {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	for _, link := range s.links {
+		if next := link(u); next != nil {
+			u = next
+		}
+	}
+	return u.String()
+}
+
+func compileLink(rule types.LinkRule) (func(*url.URL) *url.URL, error) {
+	switch rule.Action {
+	case types.LinkStripTracking:
+		return stripTracking, nil
+	case types.LinkCanonicalAMP:
+		return canonicalAMP, nil
+	case types.LinkRegex:
+		re, err := compilePattern(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(u *url.URL) *url.URL {
+			next, err := url.Parse(re.ReplaceAllString(u.String(), rule.Replacement))
+			if err != nil || (next.Scheme != "http" && next.Scheme != "https") {
+				return nil
+			}
+			return next
+		}, nil
+	default:
+		return nil, fmt.Errorf("rewrite: invalid link action %q (expected strip_tracking, amp_canonical or regex)", rule.Action)
+	}
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("rewrite: pattern cannot be empty")
+	}
+	if len(pattern) > maxPatternLen {
+		return nil, fmt.Errorf("rewrite: pattern exceeds %d characters", maxPatternLen)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("rewrite: invalid regex %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// stripTracking drops the query parameters dedupe.TrackingParam recognizes.
+func stripTracking(u *url.URL) *url.URL {
+	query := u.Query()
+	changed := false
+	for name := range query {
+		if dedupe.TrackingParam(name) {
+			query.Del(name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	next := *u
+	next.RawQuery = query.Encode()
+	return &next
+}
+
+// canonicalAMP maps an AMP page to its canonical article: AMP cache and
+// Google AMP viewer URLs to the origin URL, then "amp." hosts, "/amp" and
+// ".amp" path suffixes and amp query parameters to the plain page.
+func canonicalAMP(u *url.URL) *url.URL {
+	next := *u
+	changed := false
+
+	// https://www-example-com.cdn.ampproject.org/c/s/www.example.com/story
+	// https://www.google.com/amp/s/www.example.com/story
+	isCache := strings.HasSuffix(next.Host, ".cdn.ampproject.org")
+	isViewer := strings.TrimPrefix(next.Host, "www.") == "google.com" && strings.HasPrefix(next.Path, "/amp/")
+	if isCache || isViewer {
+		rest := next.Path
+		if isViewer {
+			rest = strings.TrimPrefix(rest, "/amp")
+		} else {
+			for _, kind := range []string{"/c/", "/v/", "/i/"} {
+				if after, ok := strings.CutPrefix(rest, kind); ok {
+					rest = "/" + after
+					break
+				}
+			}
+		}
+		scheme := "http"
+		if after, ok := strings.CutPrefix(rest, "/s/"); ok {
+			scheme, rest = "https", "/"+after
+		}
+		host, path, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+		if host != "" {
+			next.Scheme, next.Host, next.Path, next.RawPath = scheme, host, "/"+path, ""
+			changed = true
+		}
+	}
+
+	if host, ok := strings.CutPrefix(next.Host, "amp."); ok && strings.Contains(host, ".") {
+		next.Host = host
+		changed = true
+	}
+	for _, suffix := range []string{"/amp/", "/amp"} {
+		if path, ok := strings.CutSuffix(next.Path, suffix); ok {
+			next.Path, next.RawPath = path, ""
+			if next.Path == "" {
+				next.Path = "/"
+			}
+			changed = true
+			break
+		}
+	}
+	if strings.HasSuffix(next.Path, ".amp.html") || strings.HasSuffix(next.Path, ".amp") {
+		next.Path = strings.Replace(next.Path, ".amp", "", 1)
+		next.RawPath = ""
+		changed = true
+	}
+
+	query := next.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		if ampParams[lower] || (lower == "outputtype" && strings.EqualFold(query.Get(name), "amp")) {
+			query.Del(name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	next.RawQuery = query.Encode()
+	return &next
+}
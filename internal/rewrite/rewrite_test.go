@@ -0,0 +1,122 @@
+package rewrite
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"rss2go/internal/types"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   *types.ContentRules
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"remove", &types.ContentRules{Remove: []string{".newsletter", "aside.related, .ad-slot"}}, false},
+		{"bad selector", &types.ContentRules{Remove: []string{"div[["}}, true},
+		{"links", &types.ContentRules{Links: []types.LinkRule{{Action: types.LinkStripTracking}, {Action: types.LinkCanonicalAMP}}}, false},
+		{"link regex", &types.ContentRules{Links: []types.LinkRule{{Action: types.LinkRegex, Pattern: `^http://`, Replacement: "https://"}}}, false},
+		{"bad link action", &types.ContentRules{Links: []types.LinkRule{{Action: "shorten"}}}, true},
+		{"empty link pattern", &types.ContentRules{Links: []types.LinkRule{{Action: types.LinkRegex}}}, true},
+		{"rewrite", &types.ContentRules{Rewrites: []types.RewriteRule{{Pattern: `(?i)<p>advertisement</p>`}}}, false},
+		{"bad rewrite", &types.ContentRules{Rewrites: []types.RewriteRule{{Pattern: "(unclosed"}}}, true},
+		{"long rewrite", &types.ContentRules{Rewrites: []types.RewriteRule{{Pattern: strings.Repeat("a", maxPatternLen+1)}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	html := `<p>Story text.</p>` +
+		`<div class="newsletter"><p>Sign up for our newsletter!</p></div>` +
+		`<p>Read <a href="/related?utm_source=rss&amp;id=7">more</a> or the <a href="https://example.com/story/amp">AMP version</a>.</p>` +
+		`<div class="share-bar"><a href="https://social.example/share">Share</a></div>` +
+		`<p>Photo: Staff</p>`
+
+	set, err := Compile(&types.ContentRules{
+		Remove:   []string{".newsletter", ".share-bar"},
+		Links:    []types.LinkRule{{Action: types.LinkStripTracking}, {Action: types.LinkCanonicalAMP}},
+		Rewrites: []types.RewriteRule{{Pattern: `<p>Photo: (\w+)</p>`, Replacement: `<p><em>Photo by $1</em></p>`}},
+	})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	got, err := set.Apply(html, "https://example.com/story")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	for _, unwanted := range []string{"newsletter", "Share", "utm_source", "/amp"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("expected %q to be gone, got %s", unwanted, got)
+		}
+	}
+	for _, wanted := range []string{"<p>Story text.</p>", `href="https://example.com/related?id=7"`, `href="https://example.com/story"`, "<em>Photo by Staff</em>"} {
+		if !strings.Contains(got, wanted) {
+			t.Errorf("expected %q, got %s", wanted, got)
+		}
+	}
+
+	// Rewrites run on the HTML left by the earlier rules
+	set, _ = Compile(&types.ContentRules{
+		Remove:   []string{".ad"},
+		Rewrites: []types.RewriteRule{{Pattern: `<div class="ad">`, Replacement: "AD"}},
+	})
+	if got, _ := set.Apply(`<p>Text</p><div class="ad">Buy</div>`, "https://example.com/"); got != "<p>Text</p>" {
+		t.Errorf("expected removal before rewriting, got %s", got)
+	}
+
+	var none *Set
+	if got, _ := none.Apply(html, "https://example.com/"); got != html {
+		t.Errorf("expected no rules to leave the HTML alone, got %s", got)
+	}
+}
+
+func TestCanonicalAMP(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://www.example.com/2026/10/story/amp/", "https://www.example.com/2026/10/story"},
+		{"https://www.example.com/story.amp.html", "https://www.example.com/story.html"},
+		{"https://amp.example.com/story", "https://example.com/story"},
+		{"https://www.example.com/story?amp=1&id=3", "https://www.example.com/story?id=3"},
+		{"https://www.example.com/story?outputType=amp", "https://www.example.com/story"},
+		{"https://www-example-com.cdn.ampproject.org/c/s/www.example.com/story/amp?amp_js_v=0.1", "https://www.example.com/story"},
+		{"https://www.google.com/amp/s/www.example.com/story.amp", "https://www.example.com/story"},
+		{"https://www.example.com/amplifiers", ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.in)
+		got := ""
+		if next := canonicalAMP(u); next != nil {
+			got = next.String()
+		}
+		if got != tt.want {
+			t.Errorf("canonicalAMP(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLinkRules(t *testing.T) {
+	set, err := Compile(&types.ContentRules{Links: []types.LinkRule{
+		{Action: types.LinkRegex, Pattern: `^https?://m\.example\.com/`, Replacement: "https://www.example.com/"},
+		{Action: types.LinkRegex, Pattern: `^https://www\.example\.com/bad`, Replacement: "javascript:alert(1)"},
+	}})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	got, _ := set.Apply(`<a href="http://m.example.com/a">A</a><a href="https://www.example.com/bad">B</a><a href="mailto:x@example.com">C</a>`, "https://example.com/")
+	for _, wanted := range []string{`href="https://www.example.com/a"`, `href="https://www.example.com/bad"`, `href="mailto:x@example.com"`} {
+		if !strings.Contains(got, wanted) {
+			t.Errorf("expected %q, got %s", wanted, got)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"rss2go/internal/types"
+)
+
+// fairScanLimit bounds how many queued feeds a worker looks past to find
+// one on a host that is not already being crawled.
+const fairScanLimit = 64
+
+// crawlQueue holds due feeds for the worker pool. Feeds triggered by an
+// operator come first in the order they were triggered, then the rest in
+// NextPollAt order. A feed is queued or running at most once, and work on a
+// feed from outside the queue, such as pushed content, claims it as running.
+type crawlQueue struct {
+	mu      sync.Mutex
+	items   queueHeap
+	byFeed  map[int64]*queueItem
+	running map[int64]*queueItem
+	hosts   map[string]int // running crawls per host
+	seq     uint64
+	ready   chan struct{}
+
+	started   int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+type queueItem struct {
+	feed     *types.Feed
+	host     string
+	urgent   bool // triggered by an operator
+	queuedAt time.Time
+	seq      uint64
+	index    int
+	done     chan struct{} // closed when the feed stops running
+}
+
+func newCrawlQueue() *crawlQueue {
+	return &crawlQueue{
+		byFeed:  make(map[int64]*queueItem),
+		running: make(map[int64]*queueItem),
+		hosts:   make(map[string]int),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// schedule makes the queue match the feeds that are due: new feeds are
+// added, queued ones pick up the latest copy of their feed, and ones that
+// are no longer due are dropped unless an operator triggered them. It
+// returns how many feeds were added.
+func (q *crawlQueue) schedule(feeds []*types.Feed, now time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	due := make(map[int64]bool, len(feeds))
+	added := 0
+	for _, f := range feeds {
+		due[f.ID] = true
+		if q.running[f.ID] != nil {
+			continue
+		}
+		if it, ok := q.byFeed[f.ID]; ok {
+			it.feed = f
+			heap.Fix(&q.items, it.index)
+			continue
+		}
+		q.add(f, false, now)
+		added++
+	}
+	for id, it := range q.byFeed {
+		if !due[id] && !it.urgent {
+			heap.Remove(&q.items, it.index)
+			delete(q.byFeed, id)
+		}
+	}
+	if added > 0 {
+		q.signal()
+	}
+	return added
+}
+
+// trigger moves a feed to the front of the queue, adding it if needed. It
+// reports false if the feed is already running or already triggered.
+func (q *crawlQueue) trigger(feed *types.Feed, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running[feed.ID] != nil {
+		return false
+	}
+	if it, ok := q.byFeed[feed.ID]; ok {
+		if it.urgent {
+			return false
+		}
+		q.seq++
+		it.feed, it.urgent, it.seq = feed, true, q.seq
+		heap.Fix(&q.items, it.index)
+		q.signal()
+		return true
+	}
+	q.add(feed, true, now)
+	q.signal()
+	return true
+}
+
+func (q *crawlQueue) add(feed *types.Feed, urgent bool, now time.Time) {
+	q.seq++
+	it := &queueItem{feed: feed, host: feedHost(feed.URL), urgent: urgent, queuedAt: now, seq: q.seq}
+	heap.Push(&q.items, it)
+	q.byFeed[feed.ID] = it
+}
+
+// signal wakes one idle worker.
+func (q *crawlQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// next takes the feed a worker should crawl, or nil if the queue is empty.
+// So that one busy host cannot hold every worker while others wait, it
+// prefers the first feed whose host has no crawl running.
+func (q *crawlQueue) next(now time.Time) *queueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil
+	}
+	var picked *queueItem
+	var skipped []*queueItem
+	for len(q.items) > 0 && len(skipped) < fairScanLimit {
+		it := heap.Pop(&q.items).(*queueItem)
+		if q.running[it.feed.ID] == nil && (it.urgent || q.hosts[it.host] == 0) {
+			picked = it
+			break
+		}
+		skipped = append(skipped, it)
+	}
+	if picked == nil {
+		// Every host in reach is busy, so take the first feed not claimed
+		// by other work
+		i := slices.IndexFunc(skipped, func(it *queueItem) bool { return q.running[it.feed.ID] == nil })
+		if i >= 0 {
+			picked = skipped[i]
+			skipped = slices.Delete(skipped, i, i+1)
+		}
+	}
+	for _, it := range skipped {
+		heap.Push(&q.items, it)
+	}
+	if picked == nil {
+		return nil
+	}
+
+	delete(q.byFeed, picked.feed.ID)
+	picked.done = make(chan struct{})
+	q.running[picked.feed.ID] = picked
+	q.hosts[picked.host]++
+
+	wait := now.Sub(picked.queuedAt)
+	q.started++
+	q.totalWait += wait
+	q.maxWait = max(q.maxWait, wait)
+
+	if len(q.items) > 0 {
+		q.signal()
+	}
+	return picked
+}
+
+// claim marks a feed as running for work from outside the queue, waiting
+// while the feed is crawled. A queued crawl of the feed is held back until
+// done releases the claim.
+func (q *crawlQueue) claim(ctx context.Context, feed *types.Feed) (*queueItem, error) {
+	for {
+		it, running := q.tryClaim(feed)
+		if it != nil {
+			return it, nil
+		}
+		select {
+		case <-running:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// tryClaim claims feed if it is not running, or returns the channel closed
+// when it stops.
+func (q *crawlQueue) tryClaim(feed *types.Feed) (*queueItem, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if running := q.running[feed.ID]; running != nil {
+		return nil, running.done
+	}
+	it := &queueItem{feed: feed, host: feedHost(feed.URL), done: make(chan struct{})}
+	q.running[feed.ID] = it
+	q.hosts[it.host]++
+	return it, nil
+}
+
+// done marks a feed taken by next or claim as finished.
+func (q *crawlQueue) done(it *queueItem) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.running, it.feed.ID)
+	if q.hosts[it.host]--; q.hosts[it.host] <= 0 {
+		delete(q.hosts, it.host)
+	}
+	close(it.done)
+	if q.byFeed[it.feed.ID] != nil {
+		// A crawl held back by a claim can now run
+		q.signal()
+	}
+}
+
+// stats reports the queue depth and how long feeds wait to be crawled.
+func (q *crawlQueue) stats(workers int, now time.Time) types.QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	st := types.QueueStats{
+		Workers:   workers,
+		Busy:      len(q.running),
+		Depth:     len(q.items),
+		Started:   q.started,
+		MaxWaitMs: q.maxWait.Milliseconds(),
+	}
+	if q.started > 0 {
+		st.AvgWaitMs = (q.totalWait / time.Duration(q.started)).Milliseconds()
+	}
+	for _, it := range q.items {
+		st.OldestWaitMs = max(st.OldestWaitMs, now.Sub(it.queuedAt).Milliseconds())
+	}
+	return st
+}
+
+// feedHost returns the lowercased host of a feed URL.
+func feedHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// queueHeap implements heap.Interface over queued feeds.
+type queueHeap []*queueItem
+
+func (h queueHeap) Len() int { return len(h) }
+
+func (h queueHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.urgent != b.urgent {
+		return a.urgent
+	}
+	if a.urgent {
+		// Triggered feeds run in the order they were triggered
+		return a.seq < b.seq
+	}
+	if !a.feed.NextPollAt.Equal(b.feed.NextPollAt) {
+		return a.feed.NextPollAt.Before(b.feed.NextPollAt)
+	}
+	return a.seq < b.seq
+}
+
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *queueHeap) Push(x any) {
+	it := x.(*queueItem)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *queueHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return it
+}
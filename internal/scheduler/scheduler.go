@@ -2,22 +2,73 @@ package scheduler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"html"
 	"log/slog"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"rss2go/internal/crawler"
 	"rss2go/internal/database"
+	"rss2go/internal/dedupe"
 	"rss2go/internal/extractor"
+	"rss2go/internal/filter"
+	"rss2go/internal/health"
+	"rss2go/internal/magiclink"
+	"rss2go/internal/notifier"
+	"rss2go/internal/outbox"
+	"rss2go/internal/polling"
+	"rss2go/internal/requestopts"
+	"rss2go/internal/rewrite"
 	"rss2go/internal/sanitizer"
 	"rss2go/internal/types"
+
+	"github.com/mmcdole/gofeed"
 )
 
 // Config configures the feed polling scheduler.
 type Config struct {
 	PollInterval time.Duration
 	MaxWorkers   int
+	// Push, when set, subscribes feeds that advertise a WebSub hub.
+	Push PushSubscriber
+	// PushPollInterval is the fallback poll interval for feeds with an active push subscription.
+	PushPollInterval time.Duration
+	// Links, when set, adds a one-click unsubscribe link to every email.
+	Links *magiclink.Links
+	// NotFoundLimit is how many consecutive 404 responses disable a feed as gone.
+	NotFoundLimit int
+	// AlertRecipients are emailed as soon as a feed is disabled as gone. Other
+	// health changes wait for the alert digest.
+	AlertRecipients []string
+	// Health decides when failing feeds are marked failing or disabled.
+	Health health.Policy
+	// CrawlRunsPerFeed is how many recorded crawl runs are kept per feed.
+	CrawlRunsPerFeed int
+	// Adaptive bounds and spreads the intervals of feeds in adaptive polling mode.
+	Adaptive polling.Policy
+	// DeliveryRetention is how long cross-feed deliveries are remembered for
+	// users who dedupe across feeds.
+	DeliveryRetention time.Duration
+}
+
+// deliveryPruneInterval is how often deliveries older than the retention
+// are deleted.
+const deliveryPruneInterval = time.Hour
+
+// PushSubscriber manages WebSub push subscriptions for feeds that advertise a hub.
+type PushSubscriber interface {
+	// Ensure subscribes the feed to hub for topic unless an equivalent subscription exists.
+	Ensure(ctx context.Context, feed *types.Feed, hub, topic string) error
+	// Active reports whether the feed currently receives pushed updates.
+	Active(ctx context.Context, feedID int64) bool
 }
 
 // Scheduler handles periodic feed crawls and queues email notifications.
@@ -27,9 +78,10 @@ type Scheduler struct {
 	extractor    *extractor.Extractor
 	sanitizer    *sanitizer.Sanitizer
 	cfg          Config
-	inFlight     map[int64]bool
-	inFlightMu   sync.Mutex
+	queue        *crawlQueue
 	wg           sync.WaitGroup
+	runCtx       context.Context // cancelled with the context Start runs under
+	cancelRun    context.CancelFunc
 	shutdownCh   chan struct{}
 	shutdownOnce sync.Once
 	log          *slog.Logger
@@ -50,31 +102,56 @@ func New(
 	if cfg.MaxWorkers <= 0 {
 		cfg.MaxWorkers = 10
 	}
+	if cfg.PushPollInterval <= 0 {
+		cfg.PushPollInterval = 24 * time.Hour
+	}
+	if cfg.NotFoundLimit <= 0 {
+		cfg.NotFoundLimit = 3
+	}
+	if cfg.CrawlRunsPerFeed <= 0 {
+		cfg.CrawlRunsPerFeed = 500
+	}
+	if cfg.DeliveryRetention <= 0 {
+		cfg.DeliveryRetention = 90 * 24 * time.Hour
+	}
 	if log == nil {
 		log = slog.Default().With("component", "scheduler")
 	}
 
+	runCtx, cancelRun := context.WithCancel(context.Background())
 	return &Scheduler{
+		runCtx:     runCtx,
+		cancelRun:  cancelRun,
 		repo:       repo,
 		crawler:    cr,
 		extractor:  ex,
 		sanitizer:  sa,
 		cfg:        cfg,
-		inFlight:   make(map[int64]bool),
+		queue:      newCrawlQueue(),
 		shutdownCh: make(chan struct{}),
 		log:        log,
 	}
 }
 
-// Start runs the scheduler poll loop. It blocks until context is cancelled or Stop is called.
+// Start runs the scheduler poll loop and its worker pool. It blocks until
+// context is cancelled or Stop is called.
 func (s *Scheduler) Start(ctx context.Context) error {
 	ticker := time.NewTicker(s.cfg.PollInterval)
 	defer ticker.Stop()
+	pruneTicker := time.NewTicker(deliveryPruneInterval)
+	defer pruneTicker.Stop()
+	// Pushed content is processed outside this call, under the same context
+	defer context.AfterFunc(ctx, s.cancelRun)()
+
+	for range s.cfg.MaxWorkers {
+		s.wg.Go(func() { s.work(ctx) })
+	}
 
 	// Initial poll on startup
 	if err := s.pollFeeds(ctx); err != nil {
 		s.log.Error("Initial poll error", "err", err)
 	}
+	s.pruneDeliveries(ctx)
 
 	for {
 		select {
@@ -82,6 +159,8 @@ func (s *Scheduler) Start(ctx context.Context) error {
 			if err := s.pollFeeds(ctx); err != nil {
 				s.log.Error("Poll error", "err", err)
 			}
+		case <-pruneTicker.C:
+			s.pruneDeliveries(ctx)
 		case <-ctx.Done():
 			s.Stop()
 			return ctx.Err()
@@ -92,6 +171,7 @@ func (s *Scheduler) Start(ctx context.Context) error {
 }
 
 // Stop gracefully stops the scheduler, waiting for active crawl tasks to complete.
+// Feeds still queued are picked up again by the next poll after a restart.
 func (s *Scheduler) Stop() {
 	s.shutdownOnce.Do(func() {
 		close(s.shutdownCh)
@@ -99,88 +179,92 @@ func (s *Scheduler) Stop() {
 	})
 }
 
-// pollFeeds queries the database for feeds due and starts worker tasks.
+// pollFeeds queries the database for feeds due and queues them for the workers.
 func (s *Scheduler) pollFeeds(ctx context.Context) error {
 	feeds, err := s.repo.ListFeedsDue(ctx, time.Now())
 	if err != nil {
 		return fmt.Errorf("scheduler: list due feeds: %w", err)
 	}
 
-	if len(feeds) > 0 {
-		s.log.Debug("Polled due feeds", "count", len(feeds))
+	if added := s.queue.schedule(feeds, time.Now()); added > 0 {
+		s.log.Debug("Queued due feeds", "added", added, "due", len(feeds))
 	}
+	return nil
+}
 
-	// Semaphore to bound concurrency
-	sem := make(chan struct{}, s.cfg.MaxWorkers)
+// pruneDeliveries forgets cross-feed deliveries older than the retention.
+func (s *Scheduler) pruneDeliveries(ctx context.Context) {
+	n, err := s.repo.PruneDeliveries(ctx, time.Now().Add(-s.cfg.DeliveryRetention))
+	if err != nil {
+		s.log.Error("Failed to prune cross-feed deliveries", "err", err)
+		return
+	}
+	if n > 0 {
+		s.log.Debug("Pruned cross-feed deliveries", "count", n)
+	}
+}
 
-	for _, f := range feeds {
+// work runs one worker of the pool, crawling queued feeds until shutdown.
+func (s *Scheduler) work(ctx context.Context) {
+	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return
 		case <-s.shutdownCh:
-			return nil
+			return
 		default:
 		}
 
-		s.inFlightMu.Lock()
-		if s.inFlight[f.ID] {
-			s.inFlightMu.Unlock()
-			s.log.Debug("Feed crawl already in flight, skipping poll", "feed_id", f.ID, "title", f.Title)
+		if it := s.queue.next(time.Now()); it != nil {
+			s.processFeed(ctx, it.feed)
+			s.queue.done(it)
 			continue
 		}
-		s.inFlight[f.ID] = true
-		s.inFlightMu.Unlock() // --- no lock held below this line ---
 
-		// Attempt to acquire worker slot
 		select {
-		case sem <- struct{}{}:
-			s.wg.Add(1)
-			go func(feed *types.Feed) {
-				defer s.wg.Done()
-				defer func() {
-					<-sem
-					s.inFlightMu.Lock()
-					delete(s.inFlight, feed.ID)
-					s.inFlightMu.Unlock() // --- no lock held below this line ---
-				}()
-				s.processFeed(ctx, feed)
-			}(f)
-		default:
-			// Worker pool is full; release in-flight state and skip for this poll interval
-			s.inFlightMu.Lock()
-			delete(s.inFlight, f.ID)
-			s.inFlightMu.Unlock() // --- no lock held below this line ---
-			s.log.Warn("Worker pool full, postponing feed crawl", "feed_id", f.ID, "title", f.Title)
+		case <-s.queue.ready:
+		case <-ctx.Done():
+			return
+		case <-s.shutdownCh:
+			return
 		}
 	}
-
-	return nil
 }
 
-// TriggerCrawl forces the scheduler to process a feed immediately in a separate goroutine.
-func (s *Scheduler) TriggerCrawl(ctx context.Context, feed *types.Feed) bool {
-	s.inFlightMu.Lock()
-	if s.inFlight[feed.ID] {
-		s.inFlightMu.Unlock()
-		return false
-	}
-	s.inFlight[feed.ID] = true
-	s.inFlightMu.Unlock()
+// TriggerCrawl puts a feed at the front of the crawl queue. It returns false
+// if the feed is already being crawled or was already triggered.
+func (s *Scheduler) TriggerCrawl(feed *types.Feed) bool {
+	return s.queue.trigger(feed, time.Now())
+}
 
-	s.wg.Go(func() {
-		defer func() {
-			s.inFlightMu.Lock()
-			delete(s.inFlight, feed.ID)
-			s.inFlightMu.Unlock()
-		}()
-		s.processFeed(ctx, feed)
-	})
-	return true
+// QueueStats reports the crawl queue depth and wait times.
+func (s *Scheduler) QueueStats() types.QueueStats {
+	return s.queue.stats(s.cfg.MaxWorkers, time.Now())
 }
 
 // processFeed coordinates the lifecycle of crawling a single feed source.
 func (s *Scheduler) processFeed(ctx context.Context, feed *types.Feed) {
-	res, crawlErr := s.crawler.Crawl(ctx, feed)
+	started := time.Now()
+	res, crawlErr := s.crawl(ctx, feed)
+
+	// The run is filled in as processing goes and recorded however it ends
+	run := &types.CrawlRun{
+		FeedID:     feed.ID,
+		StartedAt:  started,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if res != nil {
+		run.StatusCode = res.StatusCode
+		run.Bytes = res.Bytes
+		run.NotModified = res.NotModified
+		if res.Feed != nil {
+			run.ItemCount = len(res.Feed.Items)
+		}
+	}
+	if crawlErr != nil {
+		run.Error = crawlErr.Error()
+	}
+	defer s.recordCrawl(ctx, run)
 
 	now := time.Now().Round(0)
 
@@ -188,10 +272,21 @@ func (s *Scheduler) processFeed(ctx context.Context, feed *types.Feed) {
 		// Log crawl error
 		s.log.Error("Crawl failed", "feed_id", feed.ID, "url", crawler.SanitizeURL(feed.URL), "err", crawlErr)
 
+		// A 410, or a 404 that persists, means the feed will not come back
+		if errors.Is(crawlErr, crawler.ErrNotFound) {
+			feed.NotFoundCount++
+		} else {
+			feed.NotFoundCount = 0
+		}
+		var disableReason string
+		if errors.Is(crawlErr, crawler.ErrGone) || feed.NotFoundCount >= s.cfg.NotFoundLimit {
+			disableReason = crawlErr.Error()
+		}
+
 		// Implement exponential backoff
 		feed.BackoffFactor = min(feed.BackoffFactor*1.5, 24.0)
 
-		expBackoff := time.Duration(float64(feed.PollIntervalSecs)*feed.BackoffFactor) * time.Second
+		expBackoff := time.Duration(float64(baseInterval(feed)) * feed.BackoffFactor)
 		var backoff time.Duration
 		if res != nil && res.RetryAfter != nil {
 			backoff = max(*res.RetryAfter, expBackoff)
@@ -203,10 +298,12 @@ func (s *Scheduler) processFeed(ctx context.Context, feed *types.Feed) {
 		feed.LastErrorStr = crawlErr.Error()
 		feed.LastErrorTime = &now
 		feed.LastErrorSnippet = ""
+		change := s.cfg.Health.Failure(feed, now, disableReason)
 
 		if err := s.repo.UpdateFeed(ctx, feed); err != nil {
 			s.log.Error("Failed to update feed status on crawl failure", "url", feed.URL, "err", err)
 		}
+		s.recordHealth(ctx, feed, change, disableReason != "")
 		return
 	}
 
@@ -216,7 +313,19 @@ func (s *Scheduler) processFeed(ctx context.Context, feed *types.Feed) {
 	feed.LastErrorTime = nil
 	feed.LastErrorSnippet = ""
 	feed.LastPolledAt = &now
-	feed.NextPollAt = now.Add(time.Duration(feed.PollIntervalSecs) * time.Second)
+	feed.NotFoundCount = 0
+	s.recordHealth(ctx, feed, s.cfg.Health.Success(feed, now), false)
+
+	// Hubs push updates as they happen, so polling only acts as a safety net
+	interval := s.pollInterval(ctx, feed, res, started)
+	if s.cfg.Push != nil && s.cfg.Push.Active(ctx, feed.ID) {
+		interval = max(interval, s.cfg.PushPollInterval)
+	}
+	feed.NextPollAt = now.Add(interval)
+
+	if res.MovedTo != "" {
+		s.followMove(ctx, feed, res.MovedTo)
+	}
 
 	if res.NotModified {
 		if err := s.repo.UpdateFeed(ctx, feed); err != nil {
@@ -234,41 +343,322 @@ func (s *Scheduler) processFeed(ctx context.Context, feed *types.Feed) {
 		s.log.Error("Failed to update feed cache markers", "url", feed.URL, "err", err)
 	}
 
-	// Load subscribers
-	subscribers, err := s.repo.ListSubscriptionsForFeed(ctx, feed.ID)
+	if s.cfg.Push != nil && res.Hub != "" {
+		if err := s.cfg.Push.Ensure(ctx, feed, res.Hub, res.Self); err != nil {
+			s.log.Warn("Failed to subscribe to WebSub hub", "feed_id", feed.ID, "hub", res.Hub, "err", err)
+		}
+	}
+
+	run.NewItemCount = s.processItems(ctx, feed, res.Feed.Items)
+}
+
+// crawl fetches the feed with its request options. Options that cannot be
+// read fail the crawl, rather than fetching without credentials.
+func (s *Scheduler) crawl(ctx context.Context, feed *types.Feed) (*crawler.Result, error) {
+	opts, err := s.repo.GetFeedRequestOptions(ctx, feed.ID)
 	if err != nil {
-		s.log.Error("Failed to load subscriptions", "title", feed.Title, "err", err)
+		return nil, fmt.Errorf("scheduler: load request options: %w", err)
+	}
+	feed.RequestOptions = opts
+	return s.crawler.Crawl(ctx, feed)
+}
+
+// pollInterval returns how long to wait after a successful crawl before
+// polling feed again. Feeds in adaptive mode learn the interval from how
+// often they post, falling back to their crawl history for undated items;
+// other feeds keep their fixed interval.
+func (s *Scheduler) pollInterval(ctx context.Context, feed *types.Feed, res *crawler.Result, started time.Time) time.Duration {
+	if !feed.AdaptivePolling {
+		return time.Duration(feed.PollIntervalSecs) * time.Second
+	}
+
+	var estimate time.Duration
+	var ok bool
+	if res.Feed != nil {
+		var published []time.Time
+		for _, item := range res.Feed.Items {
+			if t := itemPublishedAt(item); t != nil {
+				published = append(published, *t)
+			}
+		}
+		estimate, ok = polling.FromItems(published, started)
+	}
+	if !ok {
+		runs, err := s.repo.ListCrawlRuns(ctx, feed.ID, 100)
+		if err != nil {
+			s.log.Warn("Failed to load crawl history for adaptive polling", "feed_id", feed.ID, "err", err)
+		} else {
+			estimate, ok = polling.FromRuns(runs, started)
+		}
+	}
+	if !ok {
+		estimate = baseInterval(feed)
+	}
+
+	learned := s.cfg.Adaptive.Interval(estimate, res.PollHint)
+	if secs := int(learned / time.Second); secs != feed.AdaptiveIntervalSecs {
+		s.log.Debug("Adaptive poll interval changed", "feed_id", feed.ID, "from_secs", feed.AdaptiveIntervalSecs, "to_secs", secs, "hint", res.PollHint)
+		feed.AdaptiveIntervalSecs = secs
+	}
+	return s.cfg.Adaptive.Spread(learned)
+}
+
+// baseInterval returns the interval feed is normally polled at, before
+// jitter and backoff.
+func baseInterval(feed *types.Feed) time.Duration {
+	if feed.AdaptivePolling && feed.AdaptiveIntervalSecs > 0 {
+		return time.Duration(feed.AdaptiveIntervalSecs) * time.Second
+	}
+	return time.Duration(feed.PollIntervalSecs) * time.Second
+}
+
+// recordCrawl stores a crawl run and prunes the feed's oldest runs.
+func (s *Scheduler) recordCrawl(ctx context.Context, run *types.CrawlRun) {
+	if err := s.repo.RecordCrawlRun(ctx, run); err != nil {
+		s.log.Error("Failed to record crawl run", "feed_id", run.FeedID, "err", err)
 		return
 	}
+	if err := s.repo.PruneCrawlRuns(ctx, run.FeedID, s.cfg.CrawlRunsPerFeed); err != nil {
+		s.log.Error("Failed to prune crawl runs", "feed_id", run.FeedID, "err", err)
+	}
+}
+
+// recordHealth logs a feed health change and stores it for the operator alert
+// digest. A gone feed will not come back on its own, so operators are emailed
+// about it at once instead.
+func (s *Scheduler) recordHealth(ctx context.Context, feed *types.Feed, change *types.FeedHealthEvent, gone bool) {
+	if change == nil {
+		return
+	}
+
+	switch {
+	case change.To == types.FeedDisabled:
+		s.log.Warn("Feed disabled; polling stopped until an operator edits it",
+			"feed_id", feed.ID, "title", feed.Title, "url", crawler.SanitizeURL(feed.URL), "reason", change.Reason)
+	case health.Broken(change.To):
+		s.log.Warn("Feed is failing", "feed_id", feed.ID, "title", feed.Title, "failures", feed.ConsecutiveFailures)
+	default:
+		s.log.Info("Feed health changed", "feed_id", feed.ID, "title", feed.Title, "from", change.From, "to", change.To)
+	}
+
+	if gone && change.To == types.FeedDisabled && len(s.cfg.AlertRecipients) > 0 {
+		err := s.alertGone(ctx, feed, change)
+		if err == nil {
+			return
+		}
+		// The next digest reports the feed instead
+		change.AlertedAt = nil
+		s.log.Error("Failed to queue gone feed alert", "feed_id", feed.ID, "err", err)
+	}
+
+	if err := s.repo.RecordFeedHealthEvent(ctx, change); err != nil {
+		s.log.Error("Failed to record feed health change", "feed_id", feed.ID, "err", err)
+	}
+}
+
+// alertGone queues an operator email about a feed disabled as gone and
+// records the change as already reported.
+func (s *Scheduler) alertGone(ctx context.Context, feed *types.Feed, change *types.FeedHealthEvent) error {
+	return s.repo.WithTx(ctx, func(txRepo *database.Repository) error {
+		err := txRepo.EnqueueOutboxItem(ctx, &types.OutboxItem{
+			Subject:       "[rss2go] Feed gone: " + feed.Title,
+			Body:          health.Render([]*types.FeedHealthEvent{change}, nil),
+			Status:        types.OutboxPending,
+			NextAttemptAt: change.CreatedAt,
+			Recipients:    s.cfg.AlertRecipients,
+		})
+		if err != nil {
+			return err
+		}
+		change.AlertedAt = &change.CreatedAt
+		return txRepo.RecordFeedHealthEvent(ctx, change)
+	})
+}
+
+// followMove points a feed at the URL it permanently redirected to and
+// records the change, unless another feed already uses that URL. A feed with
+// request options keeps its URL when the move leaves its site, so its
+// credentials are never sent to the new host; the move is reported as the
+// feed's last error for an operator to confirm.
+func (s *Scheduler) followMove(ctx context.Context, feed *types.Feed, newURL string) {
+	if feed.RequestOptions != nil && !sameSite(feed.URL, newURL) {
+		s.log.Warn("Feed with request options moved to another site; keeping the old URL",
+			"feed_id", feed.ID, "from", crawler.SanitizeURL(feed.URL), "to", crawler.SanitizeURL(newURL))
+		now := time.Now().Round(0)
+		feed.LastErrorStr = fmt.Sprintf("feed permanently moved to %s on another site; not followed because the feed has request options, update the URL to accept the move",
+			crawler.SanitizeURL(newURL))
+		feed.LastErrorTime = &now
+		return
+	}
+	if other, err := s.repo.GetFeedByURL(ctx, newURL); err == nil && other.ID != feed.ID {
+		s.log.Warn("Feed moved to a URL another feed already uses; keeping the old URL",
+			"feed_id", feed.ID, "url", crawler.SanitizeURL(newURL), "other_feed_id", other.ID)
+		return
+	}
+
+	oldURL := feed.URL
+	feed.URL = newURL
+	err := s.repo.WithTx(ctx, func(txRepo *database.Repository) error {
+		if err := txRepo.UpdateFeed(ctx, feed); err != nil {
+			return err
+		}
+		return txRepo.RecordFeedURLChange(ctx, &types.FeedURLChange{
+			FeedID: feed.ID,
+			OldURL: oldURL,
+			NewURL: newURL,
+			Reason: types.URLChangeRedirect,
+		})
+	})
+	if err != nil {
+		feed.URL = oldURL
+		s.log.Error("Failed to follow permanent redirect", "feed_id", feed.ID, "url", crawler.SanitizeURL(newURL), "err", err)
+		return
+	}
+
+	s.log.Info("Feed permanently moved; URL updated",
+		"feed_id", feed.ID, "from", crawler.SanitizeURL(oldURL), "to", crawler.SanitizeURL(newURL))
+}
+
+// sameSite reports whether two URLs are on the same site.
+func sameSite(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return requestopts.SameSite(ua, ub)
+}
+
+// IngestPush runs items delivered by a WebSub hub through the same pipeline
+// as crawled items, in the background. The push waits for any crawl of the
+// feed to finish, and the feed is not crawled while the push is processed.
+// Pushes for disabled feeds are dropped. Cancelling the context Start runs
+// under cancels pushes in progress.
+func (s *Scheduler) IngestPush(feed *types.Feed, parsed *gofeed.Feed) {
+	select {
+	case <-s.shutdownCh:
+		return
+	default:
+	}
+
+	s.wg.Go(func() {
+		ctx := s.runCtx
+		it, err := s.queue.claim(ctx, feed)
+		if err != nil {
+			return
+		}
+		defer s.queue.done(it)
+
+		// A crawl that ran meanwhile may have moved or disabled the feed
+		feed, err := s.repo.GetFeed(ctx, feed.ID)
+		if err != nil {
+			s.log.Error("Failed to load feed for pushed content", "feed_id", it.feed.ID, "err", err)
+			return
+		}
+		if feed.Health == types.FeedDisabled {
+			s.log.Debug("Dropping pushed content for disabled feed", "feed_id", feed.ID)
+			return
+		}
+
+		s.log.Debug("Processing pushed feed content", "feed_id", feed.ID, "items", len(parsed.Items))
+		if feed.ExtractFullArticle {
+			opts, err := s.repo.GetFeedRequestOptions(ctx, feed.ID)
+			if err != nil {
+				s.log.Error("Failed to load request options for pushed content", "feed_id", feed.ID, "err", err)
+			}
+			feed.RequestOptions = opts
+		}
+		s.processItems(ctx, feed, parsed.Items)
+	})
+}
+
+// applyRules runs a feed's content rules on item HTML, with relative links
+// resolved against baseURL. The HTML is returned unchanged if they fail.
+func (s *Scheduler) applyRules(rules *rewrite.Set, html, baseURL string, feed *types.Feed) string {
+	cleaned, err := rules.Apply(html, baseURL)
+	if err != nil {
+		s.log.Warn("Failed to apply content rules", "feed", feed.Title, "err", err)
+		return html
+	}
+	return cleaned
+}
+
+// processItems persists new items and queues them for every subscriber. It
+// returns how many of the items were new.
+func (s *Scheduler) processItems(ctx context.Context, feed *types.Feed, items []*gofeed.Item) int {
+	// Load subscribers along with their delivery settings
+	subscribers, err := s.repo.ListSubscriptionSettingsForFeed(ctx, feed.ID)
+	if err != nil {
+		s.log.Error("Failed to load subscriptions", "title", feed.Title, "err", err)
+		return 0
+	}
+
+	// Compile each subscriber's filter rules once for the whole batch
+	filters, err := s.loadFilters(ctx, feed)
+	if err != nil {
+		s.log.Error("Failed to load filter rules", "title", feed.Title, "err", err)
+		return 0
+	}
+
+	// Content rules are validated when saved, so a failure here means the
+	// stored rules were edited by hand; deliver the items uncleaned
+	rules, err := rewrite.Compile(feed.ContentRules)
+	if err != nil {
+		s.log.Error("Ignoring invalid content rules", "title", feed.Title, "err", err)
+	}
+
+	// Subscribers' notification channels, keyed by user ID
+	channels := make(map[int64][]*types.Channel)
+	feedChannels, err := s.repo.ListChannelsForFeed(ctx, feed.ID)
+	if err != nil {
+		s.log.Error("Failed to load notification channels", "title", feed.Title, "err", err)
+		return 0
+	}
+	for _, ch := range feedChannels {
+		channels[ch.UserID] = append(channels[ch.UserID], ch)
+	}
+
+	// Fingerprints are only needed for subscribers who dedupe across feeds
+	crossFeed := slices.ContainsFunc(subscribers, func(sub *types.Subscription) bool { return sub.CrossFeedDedupe })
 
 	// Parse items
-	for _, item := range res.Feed.Items {
+	var newItems int
+	for _, item := range items {
 		select {
 		case <-ctx.Done():
-			return
+			return newItems
 		case <-s.shutdownCh:
-			return
+			return newItems
 		default:
 		}
 
 		link := crawler.ResolveItemLink(item)
 
-		guid := item.GUID
-		if guid == "" {
-			guid = link
-		}
-		if guid == "" {
-			guid = item.Title
-		}
+		guid := dedupe.GUID(item, link)
 		if guid == "" {
 			continue // Unidentifiable item
 		}
+		key := dedupe.Key(feed.DedupeStrategy, item, link)
 
 		seen, err := s.repo.IsItemSeen(ctx, feed.ID, guid)
 		if err != nil {
 			s.log.Error("Failed to check seen state for item", "guid", guid, "feed", feed.Title, "err", err)
 			continue
 		}
+		if !seen && key != "" {
+			// The feed may have given an item it already sent a new GUID
+			seen, err = s.repo.IsDedupeKeySeen(ctx, feed.ID, key)
+			if err != nil {
+				s.log.Error("Failed to check dedupe key for item", "guid", guid, "feed", feed.Title, "err", err)
+				continue
+			}
+			if seen {
+				s.log.Debug("Skipping item seen under another GUID", "feed", feed.Title, "guid", guid, "strategy", feed.DedupeStrategy)
+			}
+		}
 		if seen {
 			continue
 		}
@@ -278,63 +668,226 @@ func (s *Scheduler) processFeed(ctx context.Context, feed *types.Feed) {
 		if content == "" {
 			content = item.Description
 		}
+		content += attachmentsHTML(item.Enclosures)
+
+		// Apply the feed's content rules, then sanitize HTML
+		sanitized, err := s.sanitizer.Sanitize(s.applyRules(rules, content, feed.URL, feed), feed.URL)
+		if err != nil {
+			s.log.Error("Failed to sanitize content", "guid", guid, "err", err)
+			continue
+		}
 
-		// Extract full article if requested and we have a valid link
+		// Extract full article if requested and we have a valid link, keeping
+		// the best result of the feed's extraction chain
+		var extractedSanitized string
+		var strategy types.ExtractionStrategy
 		if feed.ExtractFullArticle && link != "" {
-			extracted, err := s.extractor.Extract(ctx, link, feed.ExtractionStrategy, feed.CSSSelector)
-			if err != nil {
+			opts := requestopts.ForLink(feed.URL, link, feed.RequestOptions)
+			best, err := s.extractor.ExtractChain(ctx, link, extractor.Chain(feed), feed.CSSSelector, content, extractor.PagingFor(feed), opts)
+			if best == nil {
 				// Log and fallback to standard feed content
 				s.log.Warn("Extraction failed (falling back to summary)", "feed", feed.Title, "link", link, "err", err)
-			} else if extracted != "" {
-				content = extracted
+				strategy = types.StrategySummary
+			} else {
+				if err != nil {
+					s.log.Debug("Some extraction strategies failed", "feed", feed.Title, "link", link, "picked", best.Strategy, "err", err)
+				}
+				strategy = best.Strategy
+				if strategy != types.StrategySummary {
+					extractedSanitized, err = s.sanitizer.Sanitize(s.applyRules(rules, best.Content, link, feed), feed.URL)
+					if err != nil {
+						s.log.Error("Failed to sanitize content", "guid", guid, "err", err)
+						continue
+					}
+				}
 			}
 		}
 
-		// Sanitize HTML
-		sanitized, err := s.sanitizer.Sanitize(content, feed.URL)
-		if err != nil {
-			s.log.Error("Failed to sanitize content", "guid", guid, "err", err)
+		body := sanitized
+		if extractedSanitized != "" {
+			body = extractedSanitized
+		}
+
+		// Persist the item for history before fanning out deliveries
+		stored := &types.Item{
+			FeedID:             feed.ID,
+			GUID:               guid,
+			Title:              item.Title,
+			Link:               link,
+			Author:             itemAuthor(item),
+			Categories:         item.Categories,
+			PublishedAt:        itemPublishedAt(item),
+			Content:            sanitized,
+			ExtractedContent:   extractedSanitized,
+			ExtractionStrategy: strategy,
+			ContentHash:        contentHash(body),
+		}
+		if err := s.repo.SaveItem(ctx, stored); err != nil {
+			// Left unseen, the item is retried on the next crawl rather than
+			// delivered with a gap in the feed's history
+			s.log.Error("Failed to persist item", "guid", guid, "feed", feed.Title, "err", err)
 			continue
 		}
+		newItems++
 
 		// Construct HTML email body containing the title, link, and sanitized content.
-		emailBody := fmt.Sprintf("<h2><a href=\"%s\">%s</a></h2>%s", link, item.Title, sanitized)
+		emailBody := fmt.Sprintf("<h2><a href=\"%s\">%s</a></h2>%s", link, item.Title, body)
 
-		// Queue email notifications atomically per subscriber for privacy
 		if len(subscribers) == 0 {
-			if err := s.repo.MarkItemSeen(ctx, feed.ID, guid); err != nil {
+			if err := s.repo.MarkItemSeenWithKey(ctx, feed.ID, guid, key); err != nil {
 				s.log.Error("Failed to mark item seen with 0 subscribers", "err", err)
 			}
-		} else {
+			continue
+		}
+
+		candidate := &filter.Candidate{Title: item.Title, Author: stored.Author, Categories: item.Categories}
+		if len(filters) > 0 {
+			candidate.Content = filter.PlainText(body)
+		}
+
+		var fingerprint string
+		if crossFeed {
+			fingerprint = dedupe.Fingerprint(item, link)
+		}
+
+		msg := &notifier.Message{
+			Subject:   fmt.Sprintf("[%s] %s", feed.Title, item.Title),
+			FeedTitle: feed.Title,
+			Title:     item.Title,
+			Link:      link,
+			HTML:      emailBody,
+		}
+
+		// Queue one notification per subscriber channel for privacy, and mark the item
+		// seen in the same transaction so a crash cannot deliver it twice
+		txErr := s.repo.WithTx(ctx, func(txRepo *database.Repository) error {
+			// Double-check inside txn
+			txSeen, err := txRepo.IsItemSeen(ctx, feed.ID, guid)
+			if err != nil {
+				return err
+			}
+			if txSeen {
+				return nil
+			}
+
 			for _, sub := range subscribers {
-				txErr := s.repo.WithTx(ctx, func(txRepo *database.Repository) error {
-					// Double-check inside txn
-					txSeen, err := txRepo.IsItemSeen(ctx, feed.ID, guid)
+				if !filters[sub.UserID].Allows(candidate) {
+					s.log.Debug("Item filtered out for subscriber", "feed", feed.Title, "guid", guid, "user_id", sub.UserID)
+					continue
+				}
+
+				if sub.CrossFeedDedupe && fingerprint != "" {
+					first, err := txRepo.RecordDelivery(ctx, sub.UserID, feed.ID, fingerprint)
 					if err != nil {
 						return err
 					}
-					if txSeen {
-						return nil
+					if !first {
+						s.log.Debug("Item already delivered to subscriber by another feed", "feed", feed.Title, "guid", guid, "user_id", sub.UserID)
+						continue
 					}
+				}
 
-					outboxItem := &types.OutboxItem{
-						Subject:       fmt.Sprintf("[%s] %s", feed.Title, item.Title),
-						Body:          emailBody,
-						Status:        types.OutboxPending,
-						NextAttemptAt: time.Now(),
-						Recipients:    []string{sub.Email},
+				if sub.DeliveryMode != "" && sub.DeliveryMode != types.DeliveryImmediate {
+					// Hold the item back for the subscriber's next digest
+					digestItem := &types.DigestItem{
+						UserID: sub.UserID,
+						FeedID: feed.ID,
+						Title:  item.Title,
+						Link:   link,
+						Body:   emailBody,
 					}
-
-					if err := txRepo.EnqueueOutboxItem(ctx, outboxItem); err != nil {
+					if err := txRepo.EnqueueDigestItem(ctx, digestItem); err != nil {
 						return err
 					}
+					continue
+				}
 
-					return txRepo.MarkItemSeen(ctx, feed.ID, guid)
-				})
-				if txErr != nil {
-					s.log.Error("Failed to queue notification and mark seen", "err", txErr)
+				subMsg := *msg
+				subMsg.UnsubscribeURL = s.cfg.Links.Unsubscribe(sub.UserEmail, feed.ID)
+				if err := outbox.Enqueue(ctx, txRepo, sub.UserEmail, channels[sub.UserID], &subMsg, time.Now()); err != nil {
+					return err
 				}
 			}
+
+			return txRepo.MarkItemSeenWithKey(ctx, feed.ID, guid, key)
+		})
+		if txErr != nil {
+			s.log.Error("Failed to queue notification and mark seen", "err", txErr)
+		}
+	}
+
+	return newItems
+}
+
+// loadFilters compiles the filter rules of every subscriber of feed, keyed by user ID.
+// Subscribers without rules have no entry and receive every item.
+func (s *Scheduler) loadFilters(ctx context.Context, feed *types.Feed) (map[int64]*filter.Set, error) {
+	rules, err := s.repo.ListFilterRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[int64][]*types.FilterRule)
+	for _, rule := range rules {
+		byUser[rule.UserID] = append(byUser[rule.UserID], rule)
+	}
+
+	filters := make(map[int64]*filter.Set, len(byUser))
+	for userID, userRules := range byUser {
+		set, err := filter.Compile(userRules)
+		if err != nil {
+			// Rules are validated on save, so this only happens with hand-edited data
+			s.log.Warn("Ignoring invalid filter rules", "feed", feed.Title, "user_id", userID, "err", err)
+			continue
+		}
+		filters[userID] = set
+	}
+	return filters, nil
+}
+
+func itemAuthor(item *gofeed.Item) string {
+	if len(item.Authors) > 0 && item.Authors[0] != nil {
+		return item.Authors[0].Name
+	}
+	if item.Author != nil {
+		return item.Author.Name
+	}
+	return ""
+}
+
+// itemPublishedAt returns the item's published time, falling back to its updated time.
+func itemPublishedAt(item *gofeed.Item) *time.Time {
+	if item.PublishedParsed != nil {
+		return item.PublishedParsed
+	}
+	return item.UpdatedParsed
+}
+
+// attachmentsHTML lists an item's enclosures, such as podcast audio or
+// JSON Feed attachments, as links to append to its content.
+func attachmentsHTML(enclosures []*gofeed.Enclosure) string {
+	var b strings.Builder
+	for _, enc := range enclosures {
+		if enc == nil || enc.URL == "" {
+			continue
+		}
+		label := enc.URL
+		if u, err := url.Parse(enc.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+			label = path.Base(u.Path)
+		}
+		if enc.Type != "" {
+			label += " (" + enc.Type + ")"
 		}
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>", html.EscapeString(enc.URL), html.EscapeString(label))
 	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<p>Attachments:</p><ul>" + b.String() + "</ul>"
+}
+
+// contentHash returns the hex SHA-256 digest of an item body.
+func contentHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
 }
@@ -6,22 +6,32 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"rss2go/internal/crawler"
 	"rss2go/internal/database"
 	"rss2go/internal/extractor"
+	"rss2go/internal/health"
+	"rss2go/internal/magiclink"
+	"rss2go/internal/polling"
 	"rss2go/internal/sanitizer"
+	"rss2go/internal/secrets"
 	"rss2go/internal/types"
+
+	"github.com/mmcdole/gofeed"
 )
 
 const mockFeedXML = `<?xml version="1.0" encoding="utf-8"?>
@@ -199,6 +209,27 @@ func TestSchedulerHappyPath(t *testing.T) {
 	if !seen {
 		t.Errorf("expected item to be marked seen")
 	}
+
+	// Verify the item was persisted for history
+	stored, err := repo.ListItemsForFeed(ctx, feed.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list stored items: %v", err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("expected 1 stored item, got %d", len(stored))
+	}
+	if stored[0].GUID != "guid-1" || stored[0].Title != "Article 1" || !stored[0].Seen {
+		t.Errorf("unexpected stored item: %+v", stored[0])
+	}
+	if !strings.Contains(stored[0].Content, "Summary content of Article 1") {
+		t.Errorf("expected sanitized feed content to be stored, got %q", stored[0].Content)
+	}
+	if !strings.Contains(stored[0].ExtractedContent, "Full body text extracted") {
+		t.Errorf("expected extracted content to be stored, got %q", stored[0].ExtractedContent)
+	}
+	if stored[0].ContentHash != contentHash(stored[0].ExtractedContent) {
+		t.Errorf("expected content hash of the delivered body, got %q", stored[0].ContentHash)
+	}
 }
 
 func TestSchedulerExtractionStrategiesAndFailures(t *testing.T) {
@@ -448,6 +479,531 @@ func TestSchedulerCrawlErrorsAndBackoff(t *testing.T) {
 	}
 }
 
+func TestSchedulerPermanentRedirect(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.Handle("/old.xml", http.RedirectHandler("/new.xml", http.StatusMovedPermanently))
+	mux.Handle("/taken.xml", http.RedirectHandler("/other.xml", http.StatusMovedPermanently))
+	for _, path := range []string{"/new.xml", "/other.xml"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Moved</title></channel></rss>`))
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, cr, nil, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	moved := &types.Feed{Title: "Moved", URL: server.URL + "/old.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	blocked := &types.Feed{Title: "Blocked", URL: server.URL + "/taken.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	other := &types.Feed{Title: "Other", URL: server.URL + "/other.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	for _, f := range []*types.Feed{moved, blocked, other} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+	}
+
+	s.processFeed(ctx, moved)
+	updated, _ := repo.GetFeed(ctx, moved.ID)
+	if updated.URL != server.URL+"/new.xml" {
+		t.Errorf("expected feed URL to follow the permanent redirect, got %q", updated.URL)
+	}
+	history, err := repo.ListFeedURLHistory(ctx, moved.ID)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected one URL history entry, got %d (err %v)", len(history), err)
+	}
+	if h := history[0]; h.OldURL != server.URL+"/old.xml" || h.NewURL != server.URL+"/new.xml" || h.Reason != types.URLChangeRedirect {
+		t.Errorf("unexpected URL history entry %+v", h)
+	}
+
+	// Another feed already owns the redirect target, so the URL is left alone.
+	s.processFeed(ctx, blocked)
+	updated, _ = repo.GetFeed(ctx, blocked.ID)
+	if updated.URL != server.URL+"/taken.xml" {
+		t.Errorf("expected conflicting move to keep the old URL, got %q", updated.URL)
+	}
+	if history, _ := repo.ListFeedURLHistory(ctx, blocked.ID); len(history) != 0 {
+		t.Errorf("expected no URL history for a conflicting move, got %+v", history)
+	}
+}
+
+func TestSchedulerCrossSiteMoveKeepsCredentials(t *testing.T) {
+	repo := setupTestDB(t)
+	box, _ := secrets.NewBox(make([]byte, secrets.KeySize))
+	repo.SetSecrets(box)
+	ctx := context.Background()
+
+	// Both hosts are served by one test server; the client dials it for any name.
+	var leaked atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "feeds.example.com":
+			switch r.URL.Path {
+			case "/feed.xml":
+				http.Redirect(w, r, "http://evil.example.net/feed.xml", http.StatusMovedPermanently)
+				return
+			case "/moved.xml":
+				http.Redirect(w, r, "http://feeds.example.com/new.xml", http.StatusMovedPermanently)
+				return
+			}
+		case "evil.example.net":
+			if r.Header.Get("Authorization") != "" {
+				leaked.Store(true)
+			}
+		}
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Moved</title></channel></rss>`))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+
+	cr := crawler.NewCrawler(client, slog.New(slog.DiscardHandler))
+	s := New(repo, cr, nil, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	feed := &types.Feed{Title: "Private", URL: "http://feeds.example.com/feed.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	sameSite := &types.Feed{Title: "Renamed", URL: "http://feeds.example.com/moved.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	for _, f := range []*types.Feed{feed, sameSite} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+		if err := repo.SetFeedRequestOptions(ctx, f.ID, &types.RequestOptions{BearerToken: "secret-token"}); err != nil {
+			t.Fatalf("failed to set request options: %v", err)
+		}
+	}
+
+	s.processFeed(ctx, feed)
+	updated, _ := repo.GetFeed(ctx, feed.ID)
+	if updated.URL != "http://feeds.example.com/feed.xml" {
+		t.Errorf("expected a cross-site move to keep the old URL, got %q", updated.URL)
+	}
+	if !strings.Contains(updated.LastErrorStr, "evil.example.net") {
+		t.Errorf("expected the refused move to be flagged on the feed, got %q", updated.LastErrorStr)
+	}
+	if history, _ := repo.ListFeedURLHistory(ctx, feed.ID); len(history) != 0 {
+		t.Errorf("expected no URL history for a refused move, got %+v", history)
+	}
+
+	// Later polls still go to the old host, so the token never leaves it.
+	s.processFeed(ctx, updated)
+	if leaked.Load() {
+		t.Error("expected the bearer token never to be sent to the other site")
+	}
+
+	// Moves within the site are still followed.
+	s.processFeed(ctx, sameSite)
+	if got, _ := repo.GetFeed(ctx, sameSite.ID); got.URL != "http://feeds.example.com/new.xml" {
+		t.Errorf("expected a same-site move to be followed, got %q", got.URL)
+	}
+}
+
+func TestSchedulerGoneFeeds(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	var missing atomic.Bool
+	missing.Store(true)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gone.xml", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusGone) })
+	mux.HandleFunc("/flaky.xml", func(w http.ResponseWriter, r *http.Request) {
+		if missing.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Back</title></channel></rss>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, cr, nil, sanitizer.NewSanitizer(600), Config{NotFoundLimit: 2, AlertRecipients: []string{"ops@example.com"}}, slog.New(slog.DiscardHandler))
+
+	gone := &types.Feed{Title: "Gone", URL: server.URL + "/gone.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	flaky := &types.Feed{Title: "Flaky", URL: server.URL + "/flaky.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	for _, f := range []*types.Feed{gone, flaky} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+	}
+
+	// A single 410 disables the feed and takes it out of the poll rotation.
+	s.processFeed(ctx, gone)
+	updated, _ := repo.GetFeed(ctx, gone.ID)
+	if updated.Health != types.FeedDisabled || updated.DisabledReason == "" {
+		t.Errorf("expected a 410 to disable the feed, got %+v", updated)
+	}
+	due, _ := repo.ListFeedsDue(ctx, time.Now().Add(24*time.Hour))
+	for _, f := range due {
+		if f.ID == gone.ID {
+			t.Errorf("expected a gone feed to no longer be polled")
+		}
+	}
+
+	// Operators are emailed at once, and the digest does not repeat it.
+	items, err := repo.ListOutboxItems(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to list outbox: %v", err)
+	}
+	if len(items) != 1 || items[0].Subject != "[rss2go] Feed gone: Gone" ||
+		!slices.Equal(items[0].Recipients, []string{"ops@example.com"}) || !strings.Contains(items[0].Body, "410") {
+		t.Errorf("expected one alert about the gone feed, got %+v", items)
+	}
+	if events, _ := repo.ListUnalertedFeedHealthEvents(ctx); len(events) != 0 {
+		t.Errorf("expected the gone feed to be left out of the digest, got %d events", len(events))
+	}
+
+	// 404s only count once they repeat.
+	s.processFeed(ctx, flaky)
+	updated, _ = repo.GetFeed(ctx, flaky.ID)
+	if updated.Health == types.FeedDisabled || updated.NotFoundCount != 1 {
+		t.Errorf("expected one 404 to be counted without disabling the feed, got %+v", updated)
+	}
+	s.processFeed(ctx, updated)
+	updated, _ = repo.GetFeed(ctx, flaky.ID)
+	if updated.Health != types.FeedDisabled || updated.NotFoundCount != 2 {
+		t.Errorf("expected repeated 404s to disable the feed, got %+v", updated)
+	}
+	if items, _ := repo.ListOutboxItems(ctx, 10); len(items) != 2 {
+		t.Errorf("expected an alert for each gone feed, got %d", len(items))
+	}
+
+	// A manual crawl that succeeds brings the feed back.
+	missing.Store(false)
+	s.processFeed(ctx, updated)
+	updated, _ = repo.GetFeed(ctx, flaky.ID)
+	if updated.Health != types.FeedHealthy || updated.NotFoundCount != 0 {
+		t.Errorf("expected a successful crawl to re-enable the feed, got %+v", updated)
+	}
+}
+
+func TestSchedulerFeedHealth(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	var broken atomic.Bool
+	broken.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Fixed</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, cr, nil, sanitizer.NewSanitizer(600), Config{Health: health.Policy{FailingAfter: 2}}, slog.New(slog.DiscardHandler))
+
+	feed := &types.Feed{Title: "Flaky", URL: server.URL, PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	s.processFeed(ctx, feed)
+	updated, _ := repo.GetFeed(ctx, feed.ID)
+	if updated.Health != types.FeedDegraded || updated.ConsecutiveFailures != 1 || updated.FailingSince == nil {
+		t.Errorf("expected one failure to degrade the feed, got %+v", updated)
+	}
+	s.processFeed(ctx, updated)
+	updated, _ = repo.GetFeed(ctx, feed.ID)
+	if updated.Health != types.FeedFailing || updated.ConsecutiveFailures != 2 {
+		t.Errorf("expected repeated failures to mark the feed failing, got %+v", updated)
+	}
+	if failing, _ := repo.ListFeedsByHealth(ctx, types.FeedFailing); len(failing) != 1 {
+		t.Errorf("expected the feed to be listed as failing, got %d feeds", len(failing))
+	}
+
+	broken.Store(false)
+	s.processFeed(ctx, updated)
+	updated, _ = repo.GetFeed(ctx, feed.ID)
+	if updated.Health != types.FeedHealthy || updated.ConsecutiveFailures != 0 || updated.FailingSince != nil {
+		t.Errorf("expected a successful crawl to restore the feed, got %+v", updated)
+	}
+
+	events, err := repo.ListUnalertedFeedHealthEvents(ctx)
+	if err != nil {
+		t.Fatalf("failed to list health events: %v", err)
+	}
+	var got []types.FeedHealth
+	for _, ev := range events {
+		got = append(got, ev.To)
+	}
+	if want := []types.FeedHealth{types.FeedDegraded, types.FeedFailing, types.FeedHealthy}; !slices.Equal(got, want) {
+		t.Errorf("recorded health changes = %v, want %v", got, want)
+	}
+}
+
+func TestSchedulerRecordsCrawlRuns(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	const body = `<rss version="2.0"><channel><title>Runs</title>
+		<item><guid>1</guid><title>One</title></item>
+		<item><guid>2</guid><title>Two</title></item>
+	</channel></rss>`
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, cr, nil, sanitizer.NewSanitizer(600), Config{CrawlRunsPerFeed: 2}, slog.New(slog.DiscardHandler))
+
+	feed := &types.Feed{Title: "Runs", URL: server.URL, PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	s.processFeed(ctx, feed)
+	runs, err := repo.ListCrawlRuns(ctx, feed.ID, 10)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected one crawl run, got %d (err %v)", len(runs), err)
+	}
+	if r := runs[0]; r.StatusCode != http.StatusOK || r.Bytes != int64(len(body)) || r.ItemCount != 2 || r.NewItemCount != 2 || r.NotModified || r.Error != "" {
+		t.Errorf("unexpected first crawl run %+v", r)
+	}
+
+	s.processFeed(ctx, feed)
+	runs, _ = repo.ListCrawlRuns(ctx, feed.ID, 10)
+	if r := runs[0]; r.StatusCode != http.StatusNotModified || !r.NotModified || r.NewItemCount != 0 {
+		t.Errorf("expected a not-modified crawl run, got %+v", r)
+	}
+
+	failing.Store(true)
+	s.processFeed(ctx, feed)
+	runs, _ = repo.ListCrawlRuns(ctx, feed.ID, 10)
+	if len(runs) != 2 {
+		t.Fatalf("expected pruning to keep 2 runs, got %d", len(runs))
+	}
+	if r := runs[0]; r.StatusCode != http.StatusBadGateway || !strings.Contains(r.Error, "502") {
+		t.Errorf("expected a failed crawl run, got %+v", r)
+	}
+	if !runs[1].NotModified {
+		t.Errorf("expected pruning to drop the oldest run, got %+v", runs[1])
+	}
+}
+
+func TestSchedulerAdaptivePolling(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	// Two posts in the last two hours suggest polling every half hour
+	now := time.Now()
+	body := fmt.Sprintf(`<rss version="2.0"><channel><title>Busy</title>
+		<item><guid>1</guid><title>One</title><pubDate>%s</pubDate></item>
+		<item><guid>2</guid><title>Two</title><pubDate>%s</pubDate></item>
+	</channel></rss>`, now.Add(-time.Hour).Format(time.RFC1123Z), now.Add(-2*time.Hour).Format(time.RFC1123Z))
+	var maxAge atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if secs := maxAge.Load(); secs > 0 {
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", secs))
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, cr, nil, sanitizer.NewSanitizer(600), Config{
+		Adaptive: polling.Policy{Min: time.Minute, Max: 6 * time.Hour, Rand: func() float64 { return 0.5 }},
+	}, slog.New(slog.DiscardHandler))
+
+	adaptive := &types.Feed{Title: "Busy", URL: server.URL, PollIntervalSecs: 3600, AdaptivePolling: true, BackoffFactor: 1.0, NextPollAt: now}
+	pinned := &types.Feed{Title: "Pinned", URL: server.URL + "/pinned", PollIntervalSecs: 3600, BackoffFactor: 1.0, NextPollAt: now}
+	for _, f := range []*types.Feed{adaptive, pinned} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+	}
+
+	s.processFeed(ctx, adaptive)
+	s.processFeed(ctx, pinned)
+
+	got, _ := repo.GetFeed(ctx, adaptive.ID)
+	if got.AdaptiveIntervalSecs < 29*60 || got.AdaptiveIntervalSecs > 31*60 {
+		t.Errorf("expected a learned interval of about 30m, got %ds", got.AdaptiveIntervalSecs)
+	}
+	if wait := time.Until(got.NextPollAt); wait < 28*time.Minute || wait > 31*time.Minute {
+		t.Errorf("expected the next poll in about 30m, got %v", wait)
+	}
+	if got, _ := repo.GetFeed(ctx, pinned.ID); got.AdaptiveIntervalSecs != 0 || time.Until(got.NextPollAt) < 59*time.Minute {
+		t.Errorf("expected a pinned feed to keep its fixed interval, got next poll in %v", time.Until(got.NextPollAt))
+	}
+
+	// The publisher's cache lifetime is a lower bound
+	maxAge.Store(int64((2 * time.Hour).Seconds()))
+	s.processFeed(ctx, got)
+	if got, _ := repo.GetFeed(ctx, adaptive.ID); got.AdaptiveIntervalSecs != 7200 {
+		t.Errorf("expected max-age to stretch the interval to 2h, got %ds", got.AdaptiveIntervalSecs)
+	}
+}
+
+func TestSchedulerRequestOptions(t *testing.T) {
+	repo := setupTestDB(t)
+	box, _ := secrets.NewBox(make([]byte, secrets.KeySize))
+	repo.SetSecrets(box)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer jira-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/browse/PROJ-1" {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><div id="issue">Issue description</div></body></html>`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<rss version="2.0"><channel><title>Jira</title>
+			<item><guid>PROJ-1</guid><title>PROJ-1 updated</title><link>http://%s/browse/PROJ-1</link></item>
+		</channel></rss>`, r.Host)
+	}))
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, cr, ex, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	user := &types.User{Email: "ops@test.com"}
+	feed := &types.Feed{
+		Title: "Jira", URL: server.URL + "/activity", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now(),
+		ExtractFullArticle: true, ExtractionStrategy: types.StrategySelector, CSSSelector: "#issue",
+	}
+	_ = repo.CreateUser(ctx, user)
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+	if err := repo.SetFeedRequestOptions(ctx, feed.ID, &types.RequestOptions{BearerToken: "jira-token"}); err != nil {
+		t.Fatalf("failed to set request options: %v", err)
+	}
+
+	// The feed passed in has no options; the scheduler loads them itself
+	s.processFeed(ctx, &types.Feed{ID: feed.ID, Title: feed.Title, URL: feed.URL, PollIntervalSecs: 60, BackoffFactor: 1.0,
+		ExtractFullArticle: true, ExtractionStrategy: types.StrategySelector, CSSSelector: "#issue"})
+
+	runs, _ := repo.ListCrawlRuns(ctx, feed.ID, 1)
+	if len(runs) != 1 || runs[0].Error != "" {
+		t.Fatalf("expected an authenticated crawl, got %+v", runs)
+	}
+	items, _ := repo.ListItemsForFeed(ctx, feed.ID, 10, 0)
+	if len(items) != 1 || !strings.Contains(items[0].ExtractedContent, "Issue description") {
+		t.Errorf("expected the article to be extracted with the feed's credentials, got %+v", items)
+	}
+}
+
+func TestSchedulerRewindReusesExtraction(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	var articleFetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/article-1" {
+			articleFetches.Add(1)
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><div class="content">Full body text extracted.</div></body></html>`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, mockFeedXML, "http://"+r.Host)
+	}))
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(server.Client(), slog.New(slog.DiscardHandler))
+	ex.SetCache(repo, extractor.CacheConfig{TTL: time.Hour, MaxBytes: 1 << 20})
+	s := New(repo, cr, ex, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	user := &types.User{Email: "reader@test.com"}
+	feed := &types.Feed{
+		Title: "Mock Feed", URL: server.URL + "/feed.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now(),
+		ExtractFullArticle: true, ExtractionStrategy: types.StrategySelector, CSSSelector: ".content",
+	}
+	_ = repo.CreateUser(ctx, user)
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+
+	s.processFeed(ctx, feed)
+	if err := repo.UnmarkSeenItems(ctx, feed.ID, 10); err != nil {
+		t.Fatalf("failed to rewind: %v", err)
+	}
+	s.processFeed(ctx, feed)
+
+	items, _ := repo.ListPendingOutboxItems(ctx, time.Now().Add(time.Second))
+	if len(items) != 2 {
+		t.Fatalf("expected the rewound item to be delivered again, got %d outbox items", len(items))
+	}
+	if !strings.Contains(items[1].Body, "Full body text extracted") {
+		t.Errorf("expected the redelivery to carry the extracted content, got %q", items[1].Body)
+	}
+	if n := articleFetches.Load(); n != 1 {
+		t.Errorf("expected the article to be fetched once, got %d", n)
+	}
+}
+
+func TestSchedulerContentRules(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/article-1" {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><div class="content"><p>Full body text.</p>` +
+				`<aside class="newsletter">Subscribe to our newsletter</aside>` +
+				`<p><a href="/next-story?utm_source=rss&amp;id=2">Next story</a></p>` +
+				`<p>ADVERTISEMENT</p></div></body></html>`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, mockFeedXML, "http://"+r.Host)
+	}))
+	defer server.Close()
+
+	cr := crawler.NewCrawler(server.Client(), slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, cr, ex, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	user := &types.User{Email: "reader@test.com"}
+	feed := &types.Feed{
+		Title: "Mock Feed", URL: server.URL + "/feed.xml", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now(),
+		ExtractFullArticle: true, ExtractionStrategy: types.StrategySelector, CSSSelector: ".content",
+		ContentRules: &types.ContentRules{
+			Remove:   []string{".newsletter"},
+			Links:    []types.LinkRule{{Action: types.LinkStripTracking}},
+			Rewrites: []types.RewriteRule{{Pattern: `<p>ADVERTISEMENT</p>`}},
+		},
+	}
+	_ = repo.CreateUser(ctx, user)
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+
+	s.processFeed(ctx, feed)
+
+	items, _ := repo.ListPendingOutboxItems(ctx, time.Now().Add(time.Second))
+	if len(items) != 1 {
+		t.Fatalf("expected 1 outbox item, got %d", len(items))
+	}
+	body := items[0].Body
+	if !strings.Contains(body, "Full body text.") || !strings.Contains(body, server.URL+"/next-story?id=2") {
+		t.Errorf("expected the cleaned extraction, got %q", body)
+	}
+	for _, unwanted := range []string{"newsletter", "utm_source", "ADVERTISEMENT"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("expected %q to be removed, got %q", unwanted, body)
+		}
+	}
+}
+
 func TestSchedulerNoSubscribers(t *testing.T) {
 	repo := setupTestDB(t)
 	ctx := context.Background()
@@ -490,6 +1046,385 @@ func TestSchedulerNoSubscribers(t *testing.T) {
 	}
 }
 
+func TestSchedulerDigestSubscription(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	ctrl := makeMockServer(t)
+	defer ctrl.server.Close()
+
+	cr := crawler.NewCrawler(ctrl.server.Client(), slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(ctrl.server.Client(), slog.New(slog.DiscardHandler))
+	sa := sanitizer.NewSanitizer(600)
+	s := New(repo, cr, ex, sa, Config{}, nil)
+
+	u := &types.User{Email: "digest@test.com"}
+	if err := repo.CreateUser(ctx, u); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	feed := &types.Feed{
+		Title:            "Mock Feed",
+		URL:              ctrl.server.URL + "/feed.xml",
+		PollIntervalSecs: 60,
+		BackoffFactor:    1.0,
+		NextPollAt:       time.Now().Add(-time.Hour),
+	}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+	if err := repo.Subscribe(ctx, u.ID, feed.ID); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := repo.UpdateSubscriptionDelivery(ctx, &types.Subscription{
+		UserID: u.ID, FeedID: feed.ID, DeliveryMode: types.DeliveryDaily, DigestTime: "08:00",
+	}); err != nil {
+		t.Fatalf("failed to set delivery mode: %v", err)
+	}
+
+	s.processFeed(ctx, feed)
+
+	items, err := repo.ListPendingOutboxItems(ctx, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("failed to list outbox items: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected digest subscriber to get no immediate email, got %d", len(items))
+	}
+
+	pending, err := repo.ListDigestItems(ctx, u.ID, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to list digest items: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Title != "Article 1" {
+		t.Fatalf("expected 1 pending digest item for Article 1, got %+v", pending)
+	}
+	if pending[0].FeedTitle != "Mock Feed" {
+		t.Errorf("expected feed title to be joined, got %q", pending[0].FeedTitle)
+	}
+
+	seen, err := repo.IsItemSeen(ctx, feed.ID, "guid-1")
+	if err != nil {
+		t.Fatalf("failed to check seen: %v", err)
+	}
+	if !seen {
+		t.Errorf("expected item to be marked seen")
+	}
+}
+
+type fakePushSubscriber struct {
+	mu     sync.Mutex
+	active bool
+	hubs   []string
+	topics []string
+}
+
+func (f *fakePushSubscriber) Ensure(ctx context.Context, feed *types.Feed, hub, topic string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hubs = append(f.hubs, hub)
+	f.topics = append(f.topics, topic)
+	return nil
+}
+
+func (f *fakePushSubscriber) Active(ctx context.Context, feedID int64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
+
+func TestSchedulerFilterRules(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	s := New(repo, nil, nil, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	feed := &types.Feed{Title: "Filtered Feed", URL: "http://filtered.invalid/rss", NextPollAt: time.Now()}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+	everything := &types.User{Email: "everything@test.com"}
+	picky := &types.User{Email: "picky@test.com"}
+	for _, u := range []*types.User{everything, picky} {
+		if err := repo.CreateUser(ctx, u); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		if err := repo.Subscribe(ctx, u.ID, feed.ID); err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+	}
+	for _, rule := range []*types.FilterRule{
+		{UserID: picky.ID, FeedID: feed.ID, Action: types.FilterInclude, Field: types.FilterFieldTitle, Match: types.FilterMatchRegex, Pattern: `^Go\b`},
+		{UserID: picky.ID, FeedID: feed.ID, Action: types.FilterExclude, Field: types.FilterFieldCategory, Match: types.FilterMatchSubstring, Pattern: "sponsored"},
+	} {
+		if err := repo.CreateFilterRule(ctx, rule); err != nil {
+			t.Fatalf("failed to create filter rule: %v", err)
+		}
+	}
+
+	s.processItems(ctx, feed, []*gofeed.Item{
+		{GUID: "release", Title: "Go 1.30 released", Content: "<p>Release notes</p>", Categories: []string{"Releases"}},
+		{GUID: "sponsored", Title: "Go faster with Widgets", Content: "<p>Ad</p>", Categories: []string{"Sponsored"}},
+		{GUID: "other", Title: "Rust news", Content: "<p>Other</p>"},
+	})
+
+	outbox, err := repo.ListOutboxItems(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to list outbox: %v", err)
+	}
+	got := make(map[string][]string)
+	for _, item := range outbox {
+		got[item.Recipients[0]] = append(got[item.Recipients[0]], item.Subject)
+	}
+	if len(got[everything.Email]) != 3 {
+		t.Errorf("expected unfiltered subscriber to get all 3 items, got %v", got[everything.Email])
+	}
+	if len(got[picky.Email]) != 1 || got[picky.Email][0] != "[Filtered Feed] Go 1.30 released" {
+		t.Errorf("expected filtered subscriber to get only the release, got %v", got[picky.Email])
+	}
+
+	for _, guid := range []string{"release", "sponsored", "other"} {
+		if seen, _ := repo.IsItemSeen(ctx, feed.ID, guid); !seen {
+			t.Errorf("expected %s to be marked seen", guid)
+		}
+	}
+
+	stored, _ := repo.ListItemsForFeed(ctx, feed.ID, 10, 0)
+	for _, item := range stored {
+		if item.GUID == "sponsored" && (len(item.Categories) != 1 || item.Categories[0] != "Sponsored") {
+			t.Errorf("expected item categories to be stored, got %v", item.Categories)
+		}
+	}
+}
+
+func TestSchedulerItemSaveFailure(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	repo := database.NewRepository(db)
+	ctx := context.Background()
+	s := New(repo, nil, nil, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	feed := &types.Feed{Title: "History Feed", URL: "http://history.invalid/rss", NextPollAt: time.Now()}
+	user := &types.User{Email: "history@test.com"}
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.CreateUser(ctx, user)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+
+	if _, err := db.ExecContext(ctx, `CREATE TRIGGER fail_items BEFORE INSERT ON items BEGIN SELECT RAISE(ABORT, 'disk full'); END`); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	items := []*gofeed.Item{{GUID: "post", Title: "Post", Content: "<p>Body</p>"}}
+
+	// An item that cannot be stored is neither counted, delivered nor seen
+	if n := s.processItems(ctx, feed, items); n != 0 {
+		t.Errorf("expected a failed save not to count, got %d new items", n)
+	}
+	if outbox, _ := repo.ListOutboxItems(ctx, 10); len(outbox) != 0 {
+		t.Errorf("expected nothing delivered, got %d outbox items", len(outbox))
+	}
+	if seen, _ := repo.IsItemSeen(ctx, feed.ID, "post"); seen {
+		t.Error("expected the item to stay unseen")
+	}
+
+	// The next crawl stores and delivers it
+	if _, err := db.ExecContext(ctx, `DROP TRIGGER fail_items`); err != nil {
+		t.Fatalf("failed to drop trigger: %v", err)
+	}
+	if n := s.processItems(ctx, feed, items); n != 1 {
+		t.Errorf("expected the item to be processed on retry, got %d", n)
+	}
+	stored, _ := repo.ListItemsForFeed(ctx, feed.ID, 10, 0)
+	outbox, _ := repo.ListOutboxItems(ctx, 10)
+	if len(stored) != 1 || len(outbox) != 1 {
+		t.Errorf("expected the item stored and delivered once, got %d stored and %d queued", len(stored), len(outbox))
+	}
+}
+
+func TestSchedulerNotificationChannels(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	// A fixed clock keeps issued tokens, and so the expected links, stable.
+	issued := time.Now()
+	keys := magiclink.NewKeyring(nil, magiclink.Config{Now: func() time.Time { return issued }}, slog.New(slog.DiscardHandler))
+	_ = keys.Load(ctx)
+	links := magiclink.NewLinks("https://rss.example.com", keys)
+	s := New(repo, nil, nil, sanitizer.NewSanitizer(600), Config{Links: links}, slog.New(slog.DiscardHandler))
+
+	feed := &types.Feed{Title: "Channel Feed", URL: "http://channels.invalid/rss", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+	plain := &types.User{Email: "plain@test.com"}
+	pushed := &types.User{Email: "pushed@test.com"}
+	for _, u := range []*types.User{plain, pushed} {
+		_ = repo.CreateUser(ctx, u)
+		_ = repo.Subscribe(ctx, u.ID, feed.ID)
+	}
+	ntfy := &types.Channel{UserID: pushed.ID, Kind: types.ChannelNtfy, Target: "https://ntfy.test/news", Enabled: true}
+	if err := repo.CreateChannel(ctx, ntfy); err != nil {
+		t.Fatalf("failed to create channel: %v", err)
+	}
+
+	s.processItems(ctx, feed, []*gofeed.Item{
+		{GUID: "one", Title: "Item One", Link: "https://channels.invalid/one", Content: "<p>One</p>"},
+	})
+
+	items, err := repo.ListOutboxItems(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to list outbox: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 outbox items, got %d", len(items))
+	}
+	for _, item := range items {
+		switch {
+		case item.ChannelID == nil:
+			if item.Recipients[0] != plain.Email {
+				t.Errorf("expected plain email to %s, got %v", plain.Email, item.Recipients)
+			}
+			want := links.Unsubscribe(plain.Email, feed.ID)
+			if item.ListUnsubscribe != want || !strings.Contains(item.Body, html.EscapeString(want)) {
+				t.Errorf("expected unsubscribe header URL and footer link %q, got %q / %s", want, item.ListUnsubscribe, item.Body)
+			}
+		case *item.ChannelID == ntfy.ID:
+			if !strings.Contains(item.Body, `"click":"https://channels.invalid/one"`) || !strings.Contains(item.Body, "Channel Feed: Item One") {
+				t.Errorf("expected rendered ntfy payload, got %s", item.Body)
+			}
+			if item.ListUnsubscribe != "" {
+				t.Errorf("expected no List-Unsubscribe for a push channel, got %q", item.ListUnsubscribe)
+			}
+		default:
+			t.Errorf("unexpected outbox item %+v", item)
+		}
+	}
+}
+
+func TestSchedulerDedupe(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	s := New(repo, nil, nil, sanitizer.NewSanitizer(600), Config{}, slog.New(slog.DiscardHandler))
+
+	byGUID := &types.Feed{Title: "By GUID", URL: "http://guid.invalid/rss", NextPollAt: time.Now()}
+	byLink := &types.Feed{Title: "By Link", URL: "http://link.invalid/rss", NextPollAt: time.Now(), DedupeStrategy: types.DedupeLink}
+	byContent := &types.Feed{Title: "By Content", URL: "http://content.invalid/rss", NextPollAt: time.Now(), DedupeStrategy: types.DedupeContent}
+	user := &types.User{Email: "reader@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	for _, f := range []*types.Feed{byGUID, byLink, byContent} {
+		_ = repo.CreateFeed(ctx, f)
+		_ = repo.Subscribe(ctx, user.ID, f.ID)
+	}
+	delivered := func() int {
+		items, _ := repo.ListOutboxItems(ctx, 100)
+		return len(items)
+	}
+
+	// Regenerated GUIDs repeat items under the GUID strategy only
+	for _, guid := range []string{"a-1", "a-2"} {
+		s.processItems(ctx, byGUID, []*gofeed.Item{{GUID: guid, Title: "Post", Link: "https://example.com/post?utm_source=rss", Content: "<p>Body</p>"}})
+		s.processItems(ctx, byLink, []*gofeed.Item{{GUID: guid, Title: "Post", Link: "https://example.com/post?utm_source=" + guid, Content: "<p>Body</p>"}})
+		s.processItems(ctx, byContent, []*gofeed.Item{{GUID: guid, Title: "Post", Link: "https://example.com/" + guid, Content: "<p>Body</p>"}})
+	}
+	if n := delivered(); n != 4 {
+		t.Fatalf("expected 2 deliveries from the GUID feed and 1 from each other feed, got %d", n)
+	}
+
+	// An edited body is a new item to the content strategy
+	s.processItems(ctx, byContent, []*gofeed.Item{{GUID: "a-3", Title: "Post", Link: "https://example.com/a-3", Content: "<p>Body, updated</p>"}})
+	if n := delivered(); n != 5 {
+		t.Fatalf("expected the edited item to be delivered, got %d deliveries", n)
+	}
+
+	// With cross-feed dedupe the same article reaches a user once
+	if err := repo.SetCrossFeedDedupe(ctx, user.ID, true); err != nil {
+		t.Fatalf("failed to enable cross-feed dedupe: %v", err)
+	}
+	other := &types.User{Email: "everything@test.com"}
+	_ = repo.CreateUser(ctx, other)
+	_ = repo.Subscribe(ctx, other.ID, byGUID.ID)
+	_ = repo.Subscribe(ctx, other.ID, byLink.ID)
+
+	syndicated := "https://news.example.com/story?utm_medium=feed"
+	s.processItems(ctx, byGUID, []*gofeed.Item{{GUID: "story-guid", Title: "Story", Link: syndicated}})
+	s.processItems(ctx, byLink, []*gofeed.Item{{GUID: "story-link", Title: "Story (via aggregator)", Link: "https://www.news.example.com/story"}})
+
+	stories := func() map[string]int {
+		items, _ := repo.ListOutboxItems(ctx, 100)
+		perUser := map[string]int{}
+		for _, item := range items {
+			if strings.Contains(item.Subject, "Story") {
+				perUser[item.Recipients[0]]++
+			}
+		}
+		return perUser
+	}
+	if perUser := stories(); perUser[user.Email] != 1 || perUser[other.Email] != 2 {
+		t.Errorf("expected one story for the deduping reader and two for the other, got %v", perUser)
+	}
+
+	// Rewinding the feed that delivered the story delivers it again
+	if err := repo.UnmarkSeenItems(ctx, byGUID.ID, 1); err != nil {
+		t.Fatalf("failed to rewind: %v", err)
+	}
+	s.processItems(ctx, byGUID, []*gofeed.Item{{GUID: "story-guid", Title: "Story", Link: syndicated}})
+	if perUser := stories(); perUser[user.Email] != 2 || perUser[other.Email] != 3 {
+		t.Errorf("expected the rewound story to reach both readers again, got %v", perUser)
+	}
+}
+
+func TestSchedulerWebSubHub(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	hubFeed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Hub Feed</title>
+    <atom:link rel="hub" href="https://hub.example.com/" />
+    <atom:link rel="self" href="https://example.com/canonical.xml" />
+    <item><title>Pushed</title><guid>push-1</guid></item>
+  </channel>
+</rss>`)
+	}))
+	defer hubFeed.Close()
+
+	push := &fakePushSubscriber{}
+	cr := crawler.NewCrawler(hubFeed.Client(), slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(hubFeed.Client(), slog.New(slog.DiscardHandler))
+	sa := sanitizer.NewSanitizer(600)
+	s := New(repo, cr, ex, sa, Config{Push: push, PushPollInterval: 12 * time.Hour}, nil)
+
+	feed := &types.Feed{
+		Title:            "Hub Feed",
+		URL:              hubFeed.URL + "/feed.xml",
+		PollIntervalSecs: 600,
+		BackoffFactor:    1.0,
+		NextPollAt:       time.Now().Add(-time.Hour),
+	}
+	if err := repo.CreateFeed(ctx, feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	// Without an active push subscription the regular interval applies.
+	s.processFeed(ctx, feed)
+	if len(push.hubs) != 1 || push.hubs[0] != "https://hub.example.com/" || push.topics[0] != "https://example.com/canonical.xml" {
+		t.Fatalf("expected hub subscription to be ensured, got hubs=%v topics=%v", push.hubs, push.topics)
+	}
+	updated, _ := repo.GetFeed(ctx, feed.ID)
+	if diff := time.Until(updated.NextPollAt); diff > 11*time.Minute {
+		t.Errorf("expected regular poll interval, got %v", diff)
+	}
+
+	// With an active push subscription polling falls back to the long interval.
+	push.active = true
+	s.processFeed(ctx, updated)
+	updated, _ = repo.GetFeed(ctx, feed.ID)
+	if diff := time.Until(updated.NextPollAt); diff < 11*time.Hour || diff > 13*time.Hour {
+		t.Errorf("expected push fallback poll interval around 12h, got %v", diff)
+	}
+}
+
 func TestSchedulerStartStop(t *testing.T) {
 	repo := setupTestDB(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -673,7 +1608,11 @@ func TestSchedulerDBErrorsExtended(t *testing.T) {
 		mockTX := &erroringDBTX{DBTX: db, failUpdate: true}
 		repo := database.NewRepository(mockTX)
 		s := New(repo, cr, ex, sa, Config{}, testStdoutLogger())
-		feed := &types.Feed{ID: 4, URL: ctrl.server.URL + "/feed.xml?err4=1", PollIntervalSecs: 60, BackoffFactor: 1.0}
+		// The feed must exist for its items to be stored before they are marked seen
+		feed := &types.Feed{URL: ctrl.server.URL + "/feed.xml?err4=1", PollIntervalSecs: 60, BackoffFactor: 1.0, NextPollAt: time.Now()}
+		if err := database.NewRepository(db).CreateFeed(context.Background(), feed); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
 
 		output := captureStdout(func() {
 			s.processFeed(context.Background(), feed)
@@ -755,3 +1694,270 @@ func TestSchedulerStartPollErrors(t *testing.T) {
 		t.Errorf("expected poll error log, got: %q", output)
 	}
 }
+
+func TestAttachmentsHTML(t *testing.T) {
+	got := attachmentsHTML([]*gofeed.Enclosure{
+		{URL: "https://cdn.example.org/ep/12.mp3?src=feed&x=1", Type: "audio/mpeg"},
+		{URL: ""},
+		{URL: "https://cdn.example.org/notes.pdf"},
+	})
+	want := `<p>Attachments:</p><ul>` +
+		`<li><a href="https://cdn.example.org/ep/12.mp3?src=feed&amp;x=1">12.mp3 (audio/mpeg)</a></li>` +
+		`<li><a href="https://cdn.example.org/notes.pdf">notes.pdf</a></li></ul>`
+	if got != want {
+		t.Errorf("attachmentsHTML() = %s, want %s", got, want)
+	}
+	if got := attachmentsHTML(nil); got != "" {
+		t.Errorf("expected no markup without enclosures, got %q", got)
+	}
+}
+
+func TestSchedulerIngestPush(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Article fetches hang until their request is cancelled
+	fetching := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case fetching <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	parsed, err := gofeed.NewParser().ParseString(fmt.Sprintf(mockFeedXML, server.URL))
+	if err != nil {
+		t.Fatalf("failed to parse feed: %v", err)
+	}
+	disabled := &types.Feed{Title: "Disabled", URL: server.URL + "/disabled.xml", Health: types.FeedDisabled, NextPollAt: time.Now().Add(time.Hour)}
+	pushed := &types.Feed{Title: "Pushed", URL: server.URL + "/feed.xml", ExtractFullArticle: true, NextPollAt: time.Now().Add(time.Hour)}
+	for _, f := range []*types.Feed{disabled, pushed} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+	}
+
+	ex := extractor.NewExtractor(server.Client(), slog.New(slog.DiscardHandler))
+	s := New(repo, crawler.NewCrawler(nil, slog.New(slog.DiscardHandler)), ex, sanitizer.NewSanitizer(600), Config{PollInterval: time.Hour}, slog.New(slog.DiscardHandler))
+	var wg sync.WaitGroup
+	wg.Go(func() { _ = s.Start(ctx) })
+
+	s.IngestPush(disabled, parsed)
+	s.IngestPush(pushed, parsed)
+
+	// The feed is not crawled while its pushed content is processed
+	<-fetching
+	if s.TriggerCrawl(pushed) {
+		t.Error("expected a crawl to wait for pushed content in progress")
+	}
+
+	// Cancelling the scheduler's context cancels the push, so this returns
+	cancel()
+	wg.Wait()
+
+	if stored, _ := repo.ListItemsForFeed(context.Background(), disabled.ID, 10, 0); len(stored) != 0 {
+		t.Errorf("expected a push for a disabled feed to be dropped, got %d items", len(stored))
+	}
+}
+
+func TestCrawlQueue(t *testing.T) {
+	now := time.Now()
+	feed := func(id int64, host string, due time.Duration) *types.Feed {
+		return &types.Feed{ID: id, URL: "https://" + host + "/feed.xml", NextPollAt: now.Add(due)}
+	}
+	ids := func(q *crawlQueue) []int64 {
+		var got []int64
+		for it := q.next(now); it != nil; it = q.next(now) {
+			got = append(got, it.feed.ID)
+			q.done(it)
+		}
+		return got
+	}
+
+	// Feeds come out in NextPollAt order, and are only queued once
+	q := newCrawlQueue()
+	if added := q.schedule([]*types.Feed{feed(1, "a.example", -time.Minute), feed(2, "b.example", -time.Hour), feed(3, "c.example", -2*time.Minute)}, now); added != 3 {
+		t.Fatalf("expected 3 feeds added, got %d", added)
+	}
+	if added := q.schedule([]*types.Feed{feed(1, "a.example", -time.Minute), feed(2, "b.example", -time.Hour), feed(3, "c.example", -2*time.Minute)}, now); added != 0 {
+		t.Fatalf("expected queued feeds not to be added twice, got %d", added)
+	}
+	if got := ids(q); !slices.Equal(got, []int64{2, 3, 1}) {
+		t.Errorf("expected NextPollAt order [2 3 1], got %v", got)
+	}
+
+	// Triggered feeds jump the queue and survive a poll that no longer lists them
+	q = newCrawlQueue()
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour), feed(2, "b.example", -time.Minute)}, now)
+	if !q.trigger(feed(2, "b.example", -time.Minute), now) || !q.trigger(feed(3, "c.example", time.Hour), now) {
+		t.Fatal("expected triggers to be accepted")
+	}
+	if q.trigger(feed(3, "c.example", time.Hour), now) {
+		t.Error("expected a second trigger of a queued feed to be refused")
+	}
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour)}, now)
+	if got := ids(q); !slices.Equal(got, []int64{2, 3, 1}) {
+		t.Errorf("expected triggered feeds first [2 3 1], got %v", got)
+	}
+
+	// Triggering a queued feed wakes a worker, as adding one does
+	q = newCrawlQueue()
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour)}, now)
+	<-q.ready
+	q.trigger(feed(1, "a.example", -time.Hour), now)
+	select {
+	case <-q.ready:
+	default:
+		t.Error("expected triggering a queued feed to wake a worker")
+	}
+
+	// Feeds that are no longer due are dropped
+	q = newCrawlQueue()
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour), feed(2, "b.example", -time.Minute)}, now)
+	q.schedule([]*types.Feed{feed(2, "b.example", -time.Minute)}, now)
+	if got := ids(q); !slices.Equal(got, []int64{2}) {
+		t.Errorf("expected only the still due feed, got %v", got)
+	}
+
+	// Running feeds are neither queued again nor triggered
+	q = newCrawlQueue()
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour)}, now)
+	running := q.next(now)
+	if q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour)}, now) != 0 || q.trigger(running.feed, now) {
+		t.Error("expected a running feed not to be queued again")
+	}
+	q.done(running)
+	if !q.trigger(running.feed, now) {
+		t.Error("expected a finished feed to be triggered again")
+	}
+
+	// A claimed feed is held back from the workers until released, and a
+	// claim waits for a running crawl
+	q = newCrawlQueue()
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour)}, now)
+	claimed, err := q.claim(context.Background(), feed(1, "a.example", -time.Hour))
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if it := q.next(now); it != nil {
+		t.Errorf("expected a claimed feed not to be crawled, got feed %d", it.feed.ID)
+	}
+	q.done(claimed)
+	running = q.next(now)
+	if running == nil || running.feed.ID != 1 {
+		t.Fatalf("expected the feed to be crawled once released, got %v", running)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.claim(cancelled, running.feed); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a claim to wait for the running crawl, got %v", err)
+	}
+	claimedCh := make(chan *queueItem)
+	go func() {
+		it, _ := q.claim(context.Background(), running.feed)
+		claimedCh <- it
+	}()
+	q.done(running)
+	q.done(<-claimedCh)
+
+	// A busy host yields to the next host with work
+	q = newCrawlQueue()
+	q.schedule([]*types.Feed{feed(1, "a.example", -3*time.Hour), feed(2, "a.example", -2*time.Hour), feed(3, "b.example", -time.Hour)}, now)
+	first := q.next(now)
+	second := q.next(now)
+	third := q.next(now)
+	if first.feed.ID != 1 || second.feed.ID != 3 || third.feed.ID != 2 {
+		t.Errorf("expected hosts to take turns [1 3 2], got [%d %d %d]", first.feed.ID, second.feed.ID, third.feed.ID)
+	}
+
+	// Wait times are measured from when a feed was first queued
+	q = newCrawlQueue()
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour), feed(2, "b.example", -time.Hour)}, now)
+	q.schedule([]*types.Feed{feed(1, "a.example", -time.Hour), feed(2, "b.example", -time.Hour)}, now.Add(time.Minute))
+	q.next(now.Add(2 * time.Second))
+	st := q.stats(4, now.Add(3*time.Second))
+	want := types.QueueStats{Workers: 4, Busy: 1, Depth: 1, Started: 1, AvgWaitMs: 2000, MaxWaitMs: 2000, OldestWaitMs: 3000}
+	if st != want {
+		t.Errorf("stats = %+v, want %+v", st, want)
+	}
+}
+
+func TestSchedulerWorkerPool(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The feed server reports each crawl as it arrives and holds it until
+	// told to answer, so the test decides when workers free up.
+	var inFlight, peak atomic.Int32
+	arrived := make(chan struct{}, 5)
+	proceed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		arrived <- struct{}{}
+		<-proceed
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, mockFeedXML, "http://"+r.Host)
+	}))
+	defer server.Close()
+
+	var feeds []*types.Feed
+	for i := range 5 {
+		f := &types.Feed{
+			Title:            fmt.Sprintf("Feed %d", i),
+			URL:              fmt.Sprintf("%s/feed-%d.xml", server.URL, i),
+			PollIntervalSecs: 3600,
+			NextPollAt:       time.Now().Add(-time.Hour),
+		}
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+		feeds = append(feeds, f)
+	}
+
+	cr := crawler.NewCrawler(nil, slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(nil, slog.New(slog.DiscardHandler))
+	s := New(repo, cr, ex, sanitizer.NewSanitizer(600), Config{PollInterval: time.Hour, MaxWorkers: 2}, slog.New(slog.DiscardHandler))
+
+	var wg sync.WaitGroup
+	wg.Go(func() { _ = s.Start(ctx) })
+
+	<-arrived
+	<-arrived
+	if st := s.QueueStats(); st.Busy != 2 || st.Depth != 3 || st.Workers != 2 {
+		t.Errorf("expected 3 feeds to wait for 2 busy workers, got %+v", st)
+	}
+
+	// Feeds beyond the pool size wait their turn rather than being skipped
+	// until the next poll, an hour away: each finished crawl starts the next.
+	for range 3 {
+		proceed <- struct{}{}
+		<-arrived
+	}
+	close(proceed)
+
+	// Stop waits for the crawls in progress to finish
+	s.Stop()
+	wg.Wait()
+	for _, f := range feeds {
+		if got, _ := repo.GetFeed(ctx, f.ID); got.LastPolledAt == nil {
+			t.Errorf("feed %d was never crawled", f.ID)
+		}
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("expected at most 2 concurrent crawls, and to reach it; peak was %d", p)
+	}
+	if st := s.QueueStats(); st.Started != 5 || st.Depth != 0 || st.Busy != 0 {
+		t.Errorf("unexpected final queue stats: %+v", st)
+	}
+}
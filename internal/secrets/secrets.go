@@ -0,0 +1,123 @@
+// Package secrets encrypts the credentials rss2go keeps in its database with
+// a key stored outside it, so a copied database file does not give them away.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeySize is the length of a key in bytes, for AES-256.
+const KeySize = 32
+
+// sealVersion prefixes every sealed value so the scheme can change without
+// misreading older values.
+const sealVersion byte = 1
+
+// ErrDecrypt is returned for sealed values that were tampered with, are
+// bound to other associated data, or were sealed under another key.
+var ErrDecrypt = errors.New("secrets: cannot decrypt value")
+
+// Box seals and opens values with AES-256-GCM.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox returns a Box for a KeySize-byte key.
+func NewBox(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secrets: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: create GCM: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext. The associated data is not stored but must be
+// given again to open the value, binding it to e.g. the row it belongs to.
+func (b *Box) Seal(plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), 1+b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("secrets: generate nonce: %w", err)
+	}
+	sealed := append([]byte{sealVersion}, nonce...)
+	return b.aead.Seal(sealed, nonce, plaintext, aad), nil
+}
+
+// Open decrypts a value made by Seal with the same associated data.
+func (b *Box) Open(sealed, aad []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(sealed) < 1+n+b.aead.Overhead() || sealed[0] != sealVersion {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := b.aead.Open(nil, sealed[1:1+n], sealed[1+n:], aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// LoadKeyFile reads a base64 key from path, generating one readable only by
+// the owner if the file does not exist yet.
+func LoadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return createKeyFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("secrets: read key file: %w", err)
+	}
+	return parseKey(string(data))
+}
+
+// parseKey decodes a base64 key, as kept in a key file.
+func parseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("secrets: decode key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secrets: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+func createKeyFile(path string) ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("secrets: generate key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("secrets: create key directory: %w", err)
+	}
+	// O_EXCL keeps two processes starting at once from each writing a key
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return LoadKeyFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("secrets: create key file: %w", err)
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("secrets: write key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("secrets: write key file: %w", err)
+	}
+	return key, nil
+}
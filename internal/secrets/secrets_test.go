@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testBox(t *testing.T) *Box {
+	t.Helper()
+	box, err := NewBox(bytes.Repeat([]byte{7}, KeySize))
+	if err != nil {
+		t.Fatalf("NewBox: %v", err)
+	}
+	return box
+}
+
+func TestBoxSealOpen(t *testing.T) {
+	box := testBox(t)
+	plaintext := []byte(`{"password":"hunter2"}`)
+
+	sealed, err := box.Seal(plaintext, []byte("feed:1"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("hunter2")) {
+		t.Fatal("sealed value contains the plaintext")
+	}
+
+	opened, err := box.Open(sealed, []byte("feed:1"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open = %q, want %q", opened, plaintext)
+	}
+
+	again, _ := box.Seal(plaintext, []byte("feed:1"))
+	if bytes.Equal(again, sealed) {
+		t.Error("sealing twice gave the same value; nonces are not random")
+	}
+
+	if _, err := box.Open(sealed, []byte("feed:2")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open with other associated data: err = %v, want ErrDecrypt", err)
+	}
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := box.Open(tampered, []byte("feed:1")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open tampered value: err = %v, want ErrDecrypt", err)
+	}
+	if _, err := box.Open([]byte{1, 2, 3}, nil); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open short value: err = %v, want ErrDecrypt", err)
+	}
+
+	other, _ := NewBox(bytes.Repeat([]byte{8}, KeySize))
+	if _, err := other.Open(sealed, []byte("feed:1")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open with another key: err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestNewBoxKeySize(t *testing.T) {
+	if _, err := NewBox(make([]byte, 16)); err == nil {
+		t.Error("NewBox accepted a 16-byte key")
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "rss2go.key")
+
+	key, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile (create): %v", err)
+	}
+	if len(key) != KeySize {
+		t.Fatalf("generated key is %d bytes, want %d", len(key), KeySize)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat key file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("key file mode = %o, want 600", perm)
+	}
+
+	again, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile (existing): %v", err)
+	}
+	if !bytes.Equal(again, key) {
+		t.Error("loading the key file again gave a different key")
+	}
+
+	if err := os.WriteFile(path, []byte("bm90IGEga2V5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyFile(path); err == nil {
+		t.Error("LoadKeyFile accepted a short key")
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss2go/internal/magiclink"
+)
+
+const (
+	sessionCookieName = "rss2go_session"
+	csrfCookieName    = "rss2go_csrf"
+	csrfHeaderName    = "X-CSRF-Token"
+)
+
+type loginRequest struct {
+	Password string `json:"password"`
+}
+
+type authStatusResponse struct {
+	AuthRequired  bool   `json:"auth_required"`
+	Authenticated bool   `json:"authenticated"`
+	CSRFToken     string `json:"csrf_token,omitempty"`
+}
+
+// authEnabled reports whether an operator password is configured. With no
+// password the operator panel stays open and requireAuth is a no-op.
+func (s *Server) authEnabled() bool {
+	return s.cfg.Password != ""
+}
+
+// deriveSessionKey derives the HMAC key used to sign session cookies from the
+// current magic-link signing key. Mixing the operator password into the key
+// means changing the password, or rotating the signing key, revokes all
+// outstanding sessions on the next restart.
+func deriveSessionKey(keys *magiclink.Keyring, password string) []byte {
+	return keys.DeriveKey("rss2go-session:" + password)
+}
+
+// signSession returns the signature over a session's expiry and nonce.
+func (s *Server) signSession(expiry int64, nonce string) string {
+	mac := hmac.New(sha256.New, s.sessionKey)
+	_, _ = mac.Write([]byte(strconv.FormatInt(expiry, 10) + "." + nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// csrfToken derives the CSRF token bound to a session nonce.
+func (s *Server) csrfToken(nonce string) string {
+	mac := hmac.New(sha256.New, s.sessionKey)
+	_, _ = mac.Write([]byte("csrf:" + nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSession mints a session cookie value of the form "<expiry>.<nonce>.<sig>"
+// and returns it together with its nonce.
+func (s *Server) newSession(expiresAt time.Time) (value, nonce string) {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	nonce = hex.EncodeToString(b)
+	expiry := expiresAt.Unix()
+	return strconv.FormatInt(expiry, 10) + "." + nonce + "." + s.signSession(expiry, nonce), nonce
+}
+
+// verifySession validates the session cookie on r and returns its nonce.
+func (s *Server) verifySession(r *http.Request) (string, bool) {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(c.Value, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", false
+	}
+
+	expected := s.signSession(expiry, parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return "", false
+	}
+
+	if s.now().Unix() >= expiry {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// isStateChanging reports whether method can mutate server state and must
+// therefore carry a CSRF token.
+func isStateChanging(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// requireAuth guards operator routes behind a valid session cookie and, for
+// state-changing methods, a matching X-CSRF-Token header. When no password is
+// configured it returns next unchanged.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	if !s.authEnabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce, ok := s.verifySession(r)
+		if !ok {
+			s.writeError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		if isStateChanging(r.Method) {
+			token := r.Header.Get(csrfHeaderName)
+			if token == "" || !hmac.Equal([]byte(token), []byte(s.csrfToken(nonce))) {
+				s.log.Warn("Rejected request with missing or invalid CSRF token", "method", r.Method, "path", r.URL.Path)
+				s.writeError(w, http.StatusForbidden, "Invalid CSRF token")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleAuthStatus reports whether a password is required and whether the
+// caller currently holds a valid session.
+func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.authEnabled() {
+		s.writeJSON(w, http.StatusOK, authStatusResponse{AuthRequired: false, Authenticated: true})
+		return
+	}
+
+	nonce, ok := s.verifySession(r)
+	resp := authStatusResponse{AuthRequired: true, Authenticated: ok}
+	if ok {
+		resp.CSRFToken = s.csrfToken(nonce)
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// handleLogin checks the operator password and issues a signed, expiring
+// session cookie plus a readable CSRF cookie for the SPA.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.authEnabled() {
+		s.writeJSON(w, http.StatusOK, authStatusResponse{AuthRequired: false, Authenticated: true})
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	// Compare digests so the comparison time does not leak the password length.
+	got := sha256.Sum256([]byte(req.Password))
+	want := sha256.Sum256([]byte(s.cfg.Password))
+	if subtle.ConstantTimeCompare(got[:], want[:]) != 1 {
+		s.log.Warn("Operator login failed", "remote", r.RemoteAddr)
+		s.writeError(w, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+
+	expiresAt := s.now().Add(s.cfg.SessionTTL)
+	value, nonce := s.newSession(expiresAt)
+	csrf := s.csrfToken(nonce)
+	secure := r.TLS != nil
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    csrf,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	s.log.Info("Operator logged in", "remote", r.RemoteAddr, "expires_at", expiresAt)
+	s.writeJSON(w, http.StatusOK, authStatusResponse{AuthRequired: true, Authenticated: true, CSRFToken: csrf})
+}
+
+// handleLogout clears the session and CSRF cookies.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	for _, name := range []string{sessionCookieName, csrfCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: name == sessionCookieName,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{"message": "Logged out successfully"})
+}
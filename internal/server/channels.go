@@ -0,0 +1,142 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"rss2go/internal/notifier"
+	"rss2go/internal/types"
+)
+
+// channelRequest is the create/update payload for a notification channel.
+// Token is write-only: responses only report has_token. Omitting it on update
+// keeps the stored secret, while an empty string clears it.
+type channelRequest struct {
+	Kind    types.ChannelKind `json:"kind"`
+	Name    string            `json:"name"`
+	Target  string            `json:"target"`
+	Server  string            `json:"server"`
+	Token   *string           `json:"token"`
+	Enabled *bool             `json:"enabled"`
+}
+
+// applyChannelRequest decodes a channel payload onto ch and validates the result.
+func (s *Server) applyChannelRequest(w http.ResponseWriter, r *http.Request, ch *types.Channel) bool {
+	var req channelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return false
+	}
+
+	ch.Kind = req.Kind
+	ch.Name = req.Name
+	ch.Target = req.Target
+	ch.Server = req.Server
+	if req.Token != nil {
+		ch.Token = *req.Token
+	}
+	if req.Enabled != nil {
+		ch.Enabled = *req.Enabled
+	}
+
+	if err := notifier.ValidateChannel(ch); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// handleGetUserChannels lists a user's notification channels.
+func (s *Server) handleGetUserChannels(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	channels, err := s.repo.ListChannelsForUser(r.Context(), userID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, channels)
+}
+
+// handleCreateChannel adds a notification channel for a user. New channels are enabled unless stated otherwise.
+func (s *Server) handleCreateChannel(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if _, err := s.repo.GetUser(r.Context(), userID); err != nil {
+		s.writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	ch := &types.Channel{UserID: userID, Enabled: true}
+	if !s.applyChannelRequest(w, r, ch) {
+		return
+	}
+
+	if err := s.repo.CreateChannel(r.Context(), ch); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, ch)
+}
+
+// handleUpdateChannel replaces a notification channel's settings.
+func (s *Server) handleUpdateChannel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid channel ID")
+		return
+	}
+
+	ch, err := s.repo.GetChannel(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeError(w, http.StatusNotFound, "Channel not found")
+			return
+		}
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !s.applyChannelRequest(w, r, ch) {
+		return
+	}
+
+	if err := s.repo.UpdateChannel(r.Context(), ch); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, ch)
+}
+
+// handleDeleteChannel removes a notification channel along with its queued deliveries.
+func (s *Server) handleDeleteChannel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid channel ID")
+		return
+	}
+
+	if err := s.repo.DeleteChannel(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeError(w, http.StatusNotFound, "Channel not found")
+			return
+		}
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]string{"message": "Channel deleted successfully"})
+}
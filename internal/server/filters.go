@@ -0,0 +1,253 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rss2go/internal/filter"
+	"rss2go/internal/types"
+)
+
+type filterDryRunRequest struct {
+	// Rules to evaluate instead of the subscription's saved rules, e.g. while editing.
+	Rules []*types.FilterRule `json:"rules"`
+	Limit int                 `json:"limit"`
+}
+
+type filterDryRunItem struct {
+	ItemID      int64      `json:"item_id"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Delivered   bool       `json:"delivered"`
+	// MatchedRules holds indexes into filterDryRunResponse.Rules, since draft rules have no ID.
+	MatchedRules []int `json:"matched_rules"`
+}
+
+type filterDryRunResponse struct {
+	Rules []*types.FilterRule `json:"rules"`
+	Items []filterDryRunItem  `json:"items"`
+}
+
+// subscriptionFromPath loads the subscription named by the {id} (user) and
+// {feed_id} path values, writing an error response when it cannot.
+func (s *Server) subscriptionFromPath(w http.ResponseWriter, r *http.Request) (*types.Subscription, bool) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return nil, false
+	}
+	feedID, err := strconv.ParseInt(r.PathValue("feed_id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid feed ID")
+		return nil, false
+	}
+
+	sub, err := s.repo.GetSubscription(r.Context(), userID, feedID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "Subscription not found")
+		return nil, false
+	}
+	return sub, true
+}
+
+// decodeFilterRule reads and validates a filter rule payload. The match type
+// defaults to a case-insensitive substring.
+func (s *Server) decodeFilterRule(w http.ResponseWriter, r *http.Request) (*types.FilterRule, bool) {
+	var rule types.FilterRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return nil, false
+	}
+	if rule.Match == "" {
+		rule.Match = types.FilterMatchSubstring
+	}
+	if err := filter.Validate(&rule); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return &rule, true
+}
+
+// handleGetFilterRules lists the filter rules of a subscription.
+func (s *Server) handleGetFilterRules(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.subscriptionFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	rules, err := s.repo.ListFilterRules(r.Context(), sub.UserID, sub.FeedID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, rules)
+}
+
+// handleCreateFilterRule adds a filter rule to a subscription.
+func (s *Server) handleCreateFilterRule(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.subscriptionFromPath(w, r)
+	if !ok {
+		return
+	}
+	rule, ok := s.decodeFilterRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule.UserID = sub.UserID
+	rule.FeedID = sub.FeedID
+	if err := s.repo.CreateFilterRule(r.Context(), rule); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, rule)
+}
+
+// handleUpdateFilterRule replaces a filter rule's action, field, match type and pattern.
+func (s *Server) handleUpdateFilterRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid filter rule ID")
+		return
+	}
+
+	existing, err := s.repo.GetFilterRule(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeError(w, http.StatusNotFound, "Filter rule not found")
+			return
+		}
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rule, ok := s.decodeFilterRule(w, r)
+	if !ok {
+		return
+	}
+
+	existing.Action = rule.Action
+	existing.Field = rule.Field
+	existing.Match = rule.Match
+	existing.Pattern = rule.Pattern
+	if err := s.repo.UpdateFilterRule(r.Context(), existing); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, existing)
+}
+
+// handleDeleteFilterRule removes a filter rule.
+func (s *Server) handleDeleteFilterRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid filter rule ID")
+		return
+	}
+
+	if err := s.repo.DeleteFilterRule(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeError(w, http.StatusNotFound, "Filter rule not found")
+			return
+		}
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]string{"message": "Filter rule deleted successfully"})
+}
+
+// handleDryRunFilterRules evaluates a subscription's filter rules, or a draft
+// set supplied in the body, against the feed's most recent stored items.
+func (s *Server) handleDryRunFilterRules(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.subscriptionFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is optional; an empty one evaluates the saved rules.
+	var req filterDryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	rules := req.Rules
+	if rules == nil {
+		saved, err := s.repo.ListFilterRules(r.Context(), sub.UserID, sub.FeedID)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		rules = saved
+	} else {
+		for _, rule := range rules {
+			if rule == nil {
+				s.writeError(w, http.StatusBadRequest, "Invalid filter rule")
+				return
+			}
+			if rule.Match == "" {
+				rule.Match = types.FilterMatchSubstring
+			}
+		}
+	}
+
+	set, err := filter.Compile(rules)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := 20
+	if req.Limit > 0 {
+		limit = min(req.Limit, 100)
+	}
+	items, err := s.repo.ListItemsForFeed(r.Context(), sub.FeedID, limit, 0)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	index := make(map[*types.FilterRule]int, len(rules))
+	for i, rule := range rules {
+		index[rule] = i
+	}
+
+	results := make([]filterDryRunItem, 0, len(items))
+	for _, item := range items {
+		body := item.Content
+		if item.ExtractedContent != "" {
+			body = item.ExtractedContent
+		}
+		delivered, matched := set.Evaluate(&filter.Candidate{
+			Title:      item.Title,
+			Content:    filter.PlainText(body),
+			Author:     item.Author,
+			Categories: item.Categories,
+		})
+
+		res := filterDryRunItem{
+			ItemID:       item.ID,
+			Title:        item.Title,
+			Link:         item.Link,
+			PublishedAt:  item.PublishedAt,
+			Delivered:    delivered,
+			MatchedRules: []int{},
+		}
+		for _, rule := range matched {
+			res.MatchedRules = append(res.MatchedRules, index[rule])
+		}
+		results = append(results, res)
+	}
+
+	s.writeJSON(w, http.StatusOK, filterDryRunResponse{Rules: rules, Items: results})
+}
@@ -1,20 +1,28 @@
 package server
 
 import (
-	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"rss2go/internal/crawler"
 	"rss2go/internal/database"
+	"rss2go/internal/dedupe"
+	"rss2go/internal/digest"
+	"rss2go/internal/extractor"
+	"rss2go/internal/health"
 	"rss2go/internal/logger"
+	"rss2go/internal/magiclink"
+	"rss2go/internal/requestopts"
+	"rss2go/internal/rewrite"
 	"rss2go/internal/types"
 )
 
@@ -41,6 +49,14 @@ type subscriptionPayload struct {
 	FeedID int64 `json:"feed_id"`
 }
 
+type deliveryPayload struct {
+	UserID        int64              `json:"user_id"`
+	FeedID        int64              `json:"feed_id"`
+	DeliveryMode  types.DeliveryMode `json:"delivery_mode"`
+	DigestTime    string             `json:"digest_time"`
+	DigestWeekday time.Weekday       `json:"digest_weekday"`
+}
+
 type rewindPayload struct {
 	Limit int `json:"limit"`
 }
@@ -51,12 +67,19 @@ type testFeedResponse struct {
 	Items       []testFeedItem `json:"items"`
 }
 
+// testFeedRequest optionally supplies content rules to preview in place of
+// the feed's saved ones.
+type testFeedRequest struct {
+	ContentRules *types.ContentRules `json:"content_rules"`
+}
+
 type testFeedItem struct {
-	Title            string `json:"title"`
-	Link             string `json:"link"`
-	GUID             string `json:"guid"`
-	Content          string `json:"content"`
-	ExtractedContent string `json:"extracted_content,omitempty"`
+	Title              string                   `json:"title"`
+	Link               string                   `json:"link"`
+	GUID               string                   `json:"guid"`
+	Content            string                   `json:"content"`
+	ExtractedContent   string                   `json:"extracted_content,omitempty"`
+	ExtractionStrategy types.ExtractionStrategy `json:"extraction_strategy,omitempty"`
 }
 
 // JSON formatting utilities
@@ -70,6 +93,18 @@ func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
 	s.writeJSON(w, status, map[string]string{"error": msg})
 }
 
+// magicTokenError describes a magic-link verification failure to the subscriber.
+func magicTokenError(err error) string {
+	switch {
+	case errors.Is(err, magiclink.ErrExpiredToken):
+		return "Verification token has expired"
+	case errors.Is(err, magiclink.ErrWrongPurpose):
+		return "Verification token is not valid for this action"
+	default:
+		return "Invalid verification token"
+	}
+}
+
 // handleSubscriberManage verifies public magic tokens and returns subscription preferences.
 func (s *Server) handleSubscriberManage(w http.ResponseWriter, r *http.Request) {
 	email := r.URL.Query().Get("email")
@@ -80,8 +115,8 @@ func (s *Server) handleSubscriberManage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if !verifyMagicToken(email, token, s.cfg.MagicSecret) {
-		s.writeError(w, http.StatusForbidden, "Invalid verification token")
+	if _, err := s.cfg.MagicKeys.Verify(token, email, magiclink.PurposeManage); err != nil {
+		s.writeError(w, http.StatusForbidden, magicTokenError(err))
 		return
 	}
 
@@ -130,8 +165,8 @@ func (s *Server) handleSubscriberUnsubscribe(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if !verifyMagicToken(req.Email, req.Token, s.cfg.MagicSecret) {
-		s.writeError(w, http.StatusForbidden, "Invalid verification token")
+	if _, err := s.cfg.MagicKeys.Verify(req.Token, req.Email, magiclink.PurposeManage); err != nil {
+		s.writeError(w, http.StatusForbidden, magicTokenError(err))
 		return
 	}
 
@@ -190,7 +225,29 @@ func (s *Server) handleSubscriberUnsubscribe(w http.ResponseWriter, r *http.Requ
 // handleGetFeeds lists all configured feeds.
 func (s *Server) handleGetFeeds(w http.ResponseWriter, r *http.Request) {
 	s.log.Debug("handleGetFeeds: entered handler", "host", r.Host)
-	feeds, err := s.repo.ListFeeds(r.Context())
+
+	// ?health=failing,disabled narrows the list to feeds in those states
+	var states []types.FeedHealth
+	if val := r.URL.Query().Get("health"); val != "" {
+		for part := range strings.SplitSeq(val, ",") {
+			state := types.FeedHealth(strings.TrimSpace(part))
+			switch state {
+			case types.FeedHealthy, types.FeedDegraded, types.FeedFailing, types.FeedDisabled:
+				states = append(states, state)
+			default:
+				s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid health state %q", state))
+				return
+			}
+		}
+	}
+
+	var feeds []*types.Feed
+	var err error
+	if len(states) > 0 {
+		feeds, err = s.repo.ListFeedsByHealth(r.Context(), states...)
+	} else {
+		feeds, err = s.repo.ListFeeds(r.Context())
+	}
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -201,8 +258,23 @@ func (s *Server) handleGetFeeds(w http.ResponseWriter, r *http.Request) {
 
 type createFeedRequest struct {
 	types.Feed
-	SubscribeUserIDs []int64 `json:"subscribe_user_ids"`
-	SubscribeAll     bool    `json:"subscribe_all"`
+	RequestOptions   *types.RequestOptions `json:"request_options"`
+	SubscribeUserIDs []int64               `json:"subscribe_user_ids"`
+	SubscribeAll     bool                  `json:"subscribe_all"`
+}
+
+// updateFeedRequest is a feed's new configuration. Request options are
+// write-only: omitting them keeps the stored ones, values left as
+// requestopts.Masked keep the stored value, and an empty object clears them.
+type updateFeedRequest struct {
+	types.Feed
+	RequestOptions *types.RequestOptions `json:"request_options"`
+}
+
+// feedResponse is a feed along with its request options, masked.
+type feedResponse struct {
+	*types.Feed
+	RequestOptions *types.RequestOptions `json:"request_options,omitempty"`
 }
 
 // handleCreateFeed enqueues a new feed source.
@@ -217,6 +289,30 @@ func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, "Title and URL are required")
 		return
 	}
+	if !s.knownSourceFormat(req.SourceFormat) {
+		s.writeError(w, http.StatusBadRequest, "Unknown source format")
+		return
+	}
+	if !dedupe.Valid(req.DedupeStrategy) {
+		s.writeError(w, http.StatusBadRequest, "Unknown dedupe strategy")
+		return
+	}
+	if !validChain(req.ExtractionChain) {
+		s.writeError(w, http.StatusBadRequest, "Unknown strategy in extraction chain")
+		return
+	}
+	if req.MaxPages < 0 || req.MaxPages > extractor.MaxPagesLimit {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Max pages must be between 0 and %d", extractor.MaxPagesLimit))
+		return
+	}
+	if err := rewrite.Validate(req.ContentRules); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid content rules: %v", err))
+		return
+	}
+	if err := requestopts.Validate(req.RequestOptions); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request options: %v", err))
+		return
+	}
 
 	req.NextPollAt = time.Now()
 	req.BackoffFactor = 1.0
@@ -225,6 +321,9 @@ func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
 		if err := txRepo.CreateFeed(r.Context(), &req.Feed); err != nil {
 			return err
 		}
+		if err := txRepo.SetFeedRequestOptions(r.Context(), req.ID, req.RequestOptions); err != nil {
+			return err
+		}
 
 		if req.SubscribeAll {
 			users, err := txRepo.ListUsers(r.Context())
@@ -251,7 +350,56 @@ func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.writeJSON(w, http.StatusCreated, req.Feed)
+	s.writeJSON(w, http.StatusCreated, feedResponse{Feed: &req.Feed, RequestOptions: requestopts.Mask(req.RequestOptions)})
+}
+
+type discoverFeedsRequest struct {
+	URL string `json:"url"`
+}
+
+// handleDiscoverFeeds lists the feeds found behind a site or feed URL, so an
+// operator who pastes a homepage can pick the feed to add.
+func (s *Server) handleDiscoverFeeds(w http.ResponseWriter, r *http.Request) {
+	var req discoverFeedsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	target := strings.TrimSpace(req.URL)
+	if target != "" && !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		s.writeError(w, http.StatusBadRequest, "A valid http(s) URL is required")
+		return
+	}
+
+	candidates, err := s.crawler.Discover(r.Context(), u.String())
+	if err != nil {
+		s.writeError(w, http.StatusBadGateway, fmt.Sprintf("Feed discovery failed: %v", err))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, candidates)
+}
+
+// knownSourceFormat reports whether name is empty, for automatic detection,
+// or names a format the crawler can parse.
+func (s *Server) knownSourceFormat(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := s.crawler.Formats().Lookup(name)
+	return ok
+}
+
+// validChain reports whether every strategy of an extraction chain is known.
+func validChain(chain []types.ExtractionStrategy) bool {
+	return !slices.ContainsFunc(chain, func(strategy types.ExtractionStrategy) bool {
+		return !extractor.ValidStrategy(strategy)
+	})
 }
 
 // handleGetFeedDetails returns configuration and logs for a single feed.
@@ -268,8 +416,13 @@ func (s *Server) handleGetFeedDetails(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusNotFound, "Feed not found")
 		return
 	}
+	opts, err := s.repo.GetFeedRequestOptions(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	s.writeJSON(w, http.StatusOK, feed)
+	s.writeJSON(w, http.StatusOK, feedResponse{Feed: feed, RequestOptions: requestopts.Mask(opts)})
 }
 
 // handleUpdateFeed replaces feed configurations.
@@ -281,19 +434,147 @@ func (s *Server) handleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var feed types.Feed
-	if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
+	var req updateFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
+	feed := req.Feed
+
+	if !s.knownSourceFormat(feed.SourceFormat) {
+		s.writeError(w, http.StatusBadRequest, "Unknown source format")
+		return
+	}
+	if !dedupe.Valid(feed.DedupeStrategy) {
+		s.writeError(w, http.StatusBadRequest, "Unknown dedupe strategy")
+		return
+	}
+	if !validChain(feed.ExtractionChain) {
+		s.writeError(w, http.StatusBadRequest, "Unknown strategy in extraction chain")
+		return
+	}
+	if feed.MaxPages < 0 || feed.MaxPages > extractor.MaxPagesLimit {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Max pages must be between 0 and %d", extractor.MaxPagesLimit))
+		return
+	}
+	if err := rewrite.Validate(feed.ContentRules); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid content rules: %v", err))
+		return
+	}
+
+	existing, err := s.repo.GetFeed(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+
+	opts, err := s.repo.GetFeedRequestOptions(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if req.RequestOptions != nil {
+		opts = requestopts.Merge(req.RequestOptions, opts)
+		if err := requestopts.Validate(opts); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request options: %v", err))
+			return
+		}
+	}
 
+	// Health and learned intervals are tracked by the scheduler rather than edited, but any edit of a
+	// disabled feed, typically one that fixes its URL, re-enables it.
 	feed.ID = id
-	if err := s.repo.UpdateFeed(r.Context(), &feed); err != nil {
+	feed.Health = existing.Health
+	feed.ConsecutiveFailures = existing.ConsecutiveFailures
+	feed.NotFoundCount = existing.NotFoundCount
+	feed.FailingSince = existing.FailingSince
+	feed.HealthChangedAt = existing.HealthChangedAt
+	feed.DisabledReason = existing.DisabledReason
+	feed.AdaptiveIntervalSecs = existing.AdaptiveIntervalSecs
+	var change *types.FeedHealthEvent
+	if feed.Health == types.FeedDisabled {
+		change = health.Enable(&feed, time.Now())
+	}
+
+	err = s.repo.WithTx(r.Context(), func(txRepo *database.Repository) error {
+		if err := txRepo.UpdateFeed(r.Context(), &feed); err != nil {
+			return err
+		}
+		if req.RequestOptions != nil {
+			if err := txRepo.SetFeedRequestOptions(r.Context(), id, opts); err != nil {
+				return err
+			}
+		}
+		if change != nil {
+			if err := txRepo.RecordFeedHealthEvent(r.Context(), change); err != nil {
+				return err
+			}
+		}
+		if feed.URL == existing.URL {
+			return nil
+		}
+		return txRepo.RecordFeedURLChange(r.Context(), &types.FeedURLChange{
+			FeedID: id,
+			OldURL: existing.URL,
+			NewURL: feed.URL,
+			Reason: types.URLChangeOperator,
+		})
+	})
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, feedResponse{Feed: &feed, RequestOptions: requestopts.Mask(opts)})
+}
+
+// handleGetFeedURLHistory returns the audit history of a feed's URL changes.
+func (s *Server) handleGetFeedURLHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+
+	if _, err := s.repo.GetFeed(r.Context(), id); err != nil {
+		s.writeError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+
+	history, err := s.repo.ListFeedURLHistory(r.Context(), id)
+	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, feed)
+	s.writeJSON(w, http.StatusOK, history)
+}
+
+// handleGetFeedCrawls returns a feed's most recent crawl runs, newest first.
+func (s *Server) handleGetFeedCrawls(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+
+	limit := 100
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+
+	if _, err := s.repo.GetFeed(r.Context(), id); err != nil {
+		s.writeError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+
+	runs, err := s.repo.ListCrawlRuns(r.Context(), id, limit)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, runs)
 }
 
 // handleDeleteFeed removes a feed and drops related subscriptions.
@@ -374,6 +655,39 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, map[string]string{"message": "User deleted successfully"})
 }
 
+// handleUpdateUser changes a user's delivery settings.
+func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var payload struct {
+		CrossFeedDedupe bool `json:"cross_feed_dedupe"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := s.repo.SetCrossFeedDedupe(r.Context(), id, payload.CrossFeedDedupe); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := s.repo.GetUser(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, user)
+}
+
 // handleSubscribe creates a user subscription mapping.
 func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	var payload subscriptionPayload
@@ -406,6 +720,70 @@ func (s *Server) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, map[string]string{"message": "Unsubscribed successfully"})
 }
 
+// handleUpdateSubscriptionDelivery changes how a subscription's new items are delivered.
+func (s *Server) handleUpdateSubscriptionDelivery(w http.ResponseWriter, r *http.Request) {
+	var payload deliveryPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	switch payload.DeliveryMode {
+	case types.DeliveryImmediate, types.DeliveryHourly:
+	case types.DeliveryDaily, types.DeliveryWeekly:
+		if _, _, err := digest.ParseDigestTime(payload.DigestTime); err != nil {
+			s.writeError(w, http.StatusBadRequest, "digest_time must be formatted as HH:MM")
+			return
+		}
+	default:
+		s.writeError(w, http.StatusBadRequest, "delivery_mode must be one of immediate, hourly, daily or weekly")
+		return
+	}
+	if payload.DigestWeekday < time.Sunday || payload.DigestWeekday > time.Saturday {
+		s.writeError(w, http.StatusBadRequest, "digest_weekday must be between 0 (Sunday) and 6 (Saturday)")
+		return
+	}
+
+	sub, err := s.repo.GetSubscription(r.Context(), payload.UserID, payload.FeedID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "Subscription not found")
+		return
+	}
+
+	sub.DeliveryMode = payload.DeliveryMode
+	if payload.DigestTime != "" {
+		sub.DigestTime = payload.DigestTime
+	}
+	sub.DigestWeekday = payload.DigestWeekday
+	// Clearing the schedule lets the digest worker compute the next run with the new settings.
+	sub.NextDigestAt = nil
+
+	if err := s.repo.UpdateSubscriptionDelivery(r.Context(), sub); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, sub)
+}
+
+// handleGetUserSubscriptions lists a user's subscriptions with their delivery settings.
+func (s *Server) handleGetUserSubscriptions(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	subs, err := s.repo.ListSubscriptionSettingsForUser(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, subs)
+}
+
 // handleGetStats serves system counts and outbox queues.
 func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.repo.GetStats(r.Context())
@@ -415,12 +793,14 @@ func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	}
 	response := struct {
 		*types.DBStats
-		MailerMode string `json:"mailer_mode"`
-		LogLevel   string `json:"log_level"`
+		MailerMode string           `json:"mailer_mode"`
+		LogLevel   string           `json:"log_level"`
+		Queue      types.QueueStats `json:"queue"`
 	}{
 		DBStats:    stats,
 		MailerMode: s.cfg.MailerMode,
 		LogLevel:   logger.GetGlobalLevel(),
+		Queue:      s.scheduler.QueueStats(),
 	}
 	s.writeJSON(w, http.StatusOK, response)
 }
@@ -531,10 +911,32 @@ func (s *Server) handleTestFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The body is optional; an empty one previews the saved content rules.
+	var req testFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if req.ContentRules == nil {
+		req.ContentRules = feed.ContentRules
+	}
+	rules, err := rewrite.Compile(req.ContentRules)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid content rules: %v", err))
+		return
+	}
+
+	opts, err := s.repo.GetFeedRequestOptions(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	// For a test feed dry-run, we bypass cache headers to force a fresh retrieval.
 	testFeed := *feed
 	testFeed.ETag = ""
 	testFeed.LastModified = ""
+	testFeed.RequestOptions = opts
 
 	res, err := s.crawler.Crawl(r.Context(), &testFeed)
 	if err != nil {
@@ -574,20 +976,19 @@ func (s *Server) handleTestFeed(w http.ResponseWriter, r *http.Request) {
 
 		// Preview extraction for the first item to let the operator verify structural CSS rules/heuristics
 		if i == 0 && feed.ExtractFullArticle && link != "" {
-			extracted, err := s.extractor.Extract(r.Context(), link, feed.ExtractionStrategy, feed.CSSSelector)
+			best, err := s.extractor.ExtractChain(r.Context(), link, extractor.Chain(feed), feed.CSSSelector, content, extractor.PagingFor(feed), requestopts.ForLink(feed.URL, link, opts))
 			if err != nil {
 				s.log.Warn("Dry-run article extraction failed", "feed", feed.Title, "link", link, "err", err)
-			} else {
-				tItem.ExtractedContent = extracted
+			}
+			if best != nil {
+				tItem.ExtractionStrategy = best.Strategy
+				if best.Strategy != types.StrategySummary {
+					tItem.ExtractedContent = s.previewContent(rules, best.Content, link, feed.URL)
+				}
 			}
 		}
 
-		sanitized, err := s.sanitizer.Sanitize(content, feed.URL)
-		if err != nil {
-			s.log.Warn("Dry-run sanitize content failed", "err", err)
-			sanitized = content
-		}
-		tItem.Content = sanitized
+		tItem.Content = s.previewContent(rules, content, feed.URL, feed.URL)
 
 		resp.Items = append(resp.Items, tItem)
 	}
@@ -595,6 +996,22 @@ func (s *Server) handleTestFeed(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, resp)
 }
 
+// previewContent cleans up item HTML as a crawl would: content rules first,
+// with relative links resolved against baseURL, then the sanitizer.
+func (s *Server) previewContent(rules *rewrite.Set, html, baseURL, siteURL string) string {
+	cleaned, err := rules.Apply(html, baseURL)
+	if err != nil {
+		s.log.Warn("Dry-run content rules failed", "err", err)
+		cleaned = html
+	}
+	sanitized, err := s.sanitizer.Sanitize(cleaned, siteURL)
+	if err != nil {
+		s.log.Warn("Dry-run sanitize content failed", "err", err)
+		return cleaned
+	}
+	return sanitized
+}
+
 // handleCatchupFeed marks all current crawl items as seen.
 func (s *Server) handleCatchupFeed(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -610,6 +1027,12 @@ func (s *Server) handleCatchupFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	feed.RequestOptions, err = s.repo.GetFeedRequestOptions(r.Context(), id)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	res, err := s.crawler.Crawl(r.Context(), feed)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Crawl fetch failed during catchup: %v", err))
@@ -623,18 +1046,14 @@ func (s *Server) handleCatchupFeed(w http.ResponseWriter, r *http.Request) {
 
 	var count int
 	for _, item := range res.Feed.Items {
-		guid := item.GUID
-		if guid == "" {
-			guid = crawler.ResolveItemLink(item)
-		}
-		if guid == "" {
-			guid = item.Title
-		}
+		link := crawler.ResolveItemLink(item)
+		guid := dedupe.GUID(item, link)
 		if guid == "" {
 			continue
 		}
 
-		if err := s.repo.MarkItemSeen(r.Context(), feed.ID, guid); err == nil {
+		key := dedupe.Key(feed.DedupeStrategy, item, link)
+		if err := s.repo.MarkItemSeenWithKey(r.Context(), feed.ID, guid, key); err == nil {
 			count++
 		}
 	}
@@ -688,7 +1107,7 @@ func (s *Server) handleScanFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	triggered := s.scheduler.TriggerCrawl(context.Background(), feed)
+	triggered := s.scheduler.TriggerCrawl(feed)
 	if !triggered {
 		s.writeError(w, http.StatusConflict, "Feed scan is already in progress")
 		return
@@ -697,19 +1116,6 @@ func (s *Server) handleScanFeed(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, map[string]string{"message": "Feed scan triggered successfully"})
 }
 
-// generateMagicLinkToken yields an HMAC hex string verification key.
-func generateMagicToken(email, secret string) string {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(email))
-	return hex.EncodeToString(mac.Sum(nil))
-}
-
-// verifyMagicToken returns true if the incoming signature matches the expected HMAC.
-func verifyMagicToken(email, token, secret string) bool {
-	expected := generateMagicToken(email, secret)
-	return hmac.Equal([]byte(token), []byte(expected))
-}
-
 // lineLevel classifies a raw log line by its severity level.
 // Returns 0=DEBUG, 1=INFO, 2=WARN, 3=ERROR, or -1 if no level marker is found.
 func lineLevel(line string) int {
@@ -728,15 +1134,14 @@ func lineLevel(line string) int {
 	}
 }
 
-type feedItemResponse struct {
-	Title       string     `json:"title"`
-	Link        string     `json:"link"`
-	PublishedAt *time.Time `json:"published_at,omitempty"`
-	GUID        string     `json:"guid"`
-	Seen        bool       `json:"seen"`
+type feedItemsPage struct {
+	Items  []*types.Item `json:"items"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
 }
 
-// handleGetFeedItems parses the feed's remote URL in real-time and returns the recent 15 items with their "seen" audit status.
+// handleGetFeedItems returns one page of the feed's stored item history, newest first.
 func (s *Server) handleGetFeedItems(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -745,48 +1150,35 @@ func (s *Server) handleGetFeedItems(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	feed, err := s.repo.GetFeed(r.Context(), id)
-	if err != nil {
+	if _, err := s.repo.GetFeed(r.Context(), id); err != nil {
 		s.writeError(w, http.StatusNotFound, "Feed not found")
 		return
 	}
 
-	// Force fresh parse
-	testFeed := *feed
-	testFeed.ETag = ""
-	testFeed.LastModified = ""
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if val, err := strconv.Atoi(limitStr); err == nil && val > 0 {
+			limit = min(val, 100)
+		}
+	}
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if val, err := strconv.Atoi(offsetStr); err == nil && val >= 0 {
+			offset = val
+		}
+	}
 
-	res, err := s.crawler.Crawl(r.Context(), &testFeed)
+	total, err := s.repo.CountItemsForFeed(r.Context(), id)
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to fetch feed items: %v", err))
+		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if res.Feed == nil {
-		s.writeJSON(w, http.StatusOK, []feedItemResponse{})
+	items, err := s.repo.ListItemsForFeed(r.Context(), id, limit, offset)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Retrieve first 15 items
-	limit := min(len(res.Feed.Items), 15)
-
-	resp := make([]feedItemResponse, 0, limit)
-	for i := range limit {
-		item := res.Feed.Items[i]
-		seen, err := s.repo.IsItemSeen(r.Context(), feed.ID, item.GUID)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		resp = append(resp, feedItemResponse{
-			Title:       item.Title,
-			Link:        crawler.ResolveItemLink(item),
-			PublishedAt: item.PublishedParsed,
-			GUID:        item.GUID,
-			Seen:        seen,
-		})
-	}
-
-	s.writeJSON(w, http.StatusOK, resp)
+	s.writeJSON(w, http.StatusOK, feedItemsPage{Items: items, Total: total, Limit: limit, Offset: offset})
 }
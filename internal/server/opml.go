@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rss2go/internal/opml"
+)
+
+// maxOPMLBodyBytes caps the size of an uploaded OPML document.
+const maxOPMLBodyBytes = 5 << 20
+
+// handleExportOPML downloads every feed as an OPML 2.0 document.
+func (s *Server) handleExportOPML(w http.ResponseWriter, r *http.Request) {
+	feeds, err := s.repo.ListFeeds(r.Context())
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := opml.Export(&buf, feeds, time.Now()); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="rss2go.opml"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// handleImportOPML creates the feeds in an uploaded OPML document, optionally
+// subscribing the user given by the user_id query parameter, and reports the
+// outcome of every outline.
+func (s *Server) handleImportOPML(w http.ResponseWriter, r *http.Request) {
+	var userID int64
+	if val := r.URL.Query().Get("user_id"); val != "" {
+		id, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		if _, err := s.repo.GetUser(r.Context(), id); err != nil {
+			s.writeError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		userID = id
+	}
+
+	report, err := opml.Import(r.Context(), s.repo, s.crawler.Formats(), http.MaxBytesReader(w, r.Body, maxOPMLBodyBytes), userID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid OPML document")
+		return
+	}
+
+	s.log.Info("Imported OPML", "created", report.Created, "existing", report.Existing, "failed", report.Failed, "user_id", userID)
+	s.writeJSON(w, http.StatusOK, report)
+}
@@ -2,8 +2,6 @@ package server
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -17,19 +15,27 @@ import (
 	"rss2go/internal/crawler"
 	"rss2go/internal/database"
 	"rss2go/internal/extractor"
+	"rss2go/internal/magiclink"
 	"rss2go/internal/sanitizer"
 	"rss2go/internal/scheduler"
 	"rss2go/internal/server/ui"
+	"rss2go/internal/websub"
 )
 
 // Config holds the HTTP server configurations.
 type Config struct {
-	Addr              string
-	MagicSecret       string
+	Addr string
+	// MagicKeys signs and verifies subscriber magic links. Without one the
+	// server uses an in-memory keyring, so links die with the process.
+	MagicKeys         *magiclink.Keyring
+	Password          string
+	SessionTTL        time.Duration
 	HeartbeatInterval time.Duration
 	ShutdownTimeout   time.Duration
 	Broadcaster       *LogBroadcaster
 	MailerMode        string
+	// WebSub, when set, serves hub verification and content distribution callbacks.
+	WebSub *websub.Manager
 }
 
 // Server wraps the API routes, embedded SPA, and daemon references.
@@ -42,6 +48,8 @@ type Server struct {
 	broadcaster *LogBroadcaster
 	cfg         Config
 	httpServer  *http.Server
+	sessionKey  []byte
+	now         func() time.Time
 	log         *slog.Logger
 }
 
@@ -58,11 +66,10 @@ func New(
 	if cfg.Addr == "" {
 		cfg.Addr = ":8080"
 	}
-	if cfg.MagicSecret == "" {
-		// Use a random default secret if none provided to keep magic links secure
-		b := make([]byte, 16)
-		_, _ = rand.Read(b)
-		cfg.MagicSecret = hex.EncodeToString(b)
+	if cfg.MagicKeys == nil {
+		cfg.MagicKeys = magiclink.NewKeyring(nil, magiclink.Config{}, nil)
+		// An in-memory keyring only generates its key and cannot fail.
+		_ = cfg.MagicKeys.Load(context.Background())
 	}
 	if cfg.HeartbeatInterval <= 0 {
 		cfg.HeartbeatInterval = 15 * time.Second
@@ -70,6 +77,9 @@ func New(
 	if cfg.ShutdownTimeout <= 0 {
 		cfg.ShutdownTimeout = 5 * time.Second
 	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = 12 * time.Hour
+	}
 	if log == nil {
 		log = slog.Default().With("component", "api")
 	}
@@ -87,6 +97,8 @@ func New(
 		sanitizer:   sa,
 		broadcaster: b,
 		cfg:         cfg,
+		sessionKey:  deriveSessionKey(cfg.MagicKeys, cfg.Password),
+		now:         time.Now,
 		log:         log,
 	}
 }
@@ -95,32 +107,69 @@ func New(
 func (s *Server) Handler() (http.Handler, error) {
 	mux := http.NewServeMux()
 
-	// Register endpoints directly (no auth required)
+	// Public magic-link subscriber endpoints (no operator session required)
 	mux.HandleFunc("GET /api/v1/subscriber/manage", s.handleSubscriberManage)
 	mux.HandleFunc("POST /api/v1/subscriber/unsubscribe", s.handleSubscriberUnsubscribe)
 
-	mux.HandleFunc("GET /api/v1/feeds", s.handleGetFeeds)
-	mux.HandleFunc("POST /api/v1/feeds", s.handleCreateFeed)
-	mux.HandleFunc("GET /api/v1/feeds/{id}", s.handleGetFeedDetails)
-	mux.HandleFunc("GET /api/v1/feeds/{id}/items", s.handleGetFeedItems)
-	mux.HandleFunc("PUT /api/v1/feeds/{id}", s.handleUpdateFeed)
-	mux.HandleFunc("DELETE /api/v1/feeds/{id}", s.handleDeleteFeed)
-
-	mux.HandleFunc("GET /api/v1/users", s.handleGetUsers)
-	mux.HandleFunc("POST /api/v1/users", s.handleCreateUser)
-	mux.HandleFunc("DELETE /api/v1/users/{id}", s.handleDeleteUser)
-
-	mux.HandleFunc("POST /api/v1/subscriptions", s.handleSubscribe)
-	mux.HandleFunc("DELETE /api/v1/subscriptions", s.handleUnsubscribe)
-
-	mux.HandleFunc("GET /api/v1/stats", s.handleGetStats)
-	mux.HandleFunc("GET /api/v1/logs", s.handleGetLogs)
-	mux.HandleFunc("GET /api/v1/outbox", s.handleGetOutbox)
-
-	mux.HandleFunc("POST /api/v1/feeds/{id}/test", s.handleTestFeed)
-	mux.HandleFunc("POST /api/v1/feeds/{id}/scan", s.handleScanFeed)
-	mux.HandleFunc("POST /api/v1/feeds/{id}/catchup", s.handleCatchupFeed)
-	mux.HandleFunc("POST /api/v1/feeds/{id}/rewind", s.handleRewindFeed)
+	// Emailed unsubscribe links (RFC 8058 one-click POST; GET shows a confirmation form)
+	mux.HandleFunc("GET "+magiclink.UnsubscribePath, s.handleUnsubscribePage)
+	mux.HandleFunc("POST "+magiclink.UnsubscribePath, s.handleOneClickUnsubscribe)
+
+	// Operator session management
+	mux.HandleFunc("GET /api/v1/auth/status", s.handleAuthStatus)
+	mux.HandleFunc("POST /api/v1/auth/login", s.handleLogin)
+	mux.HandleFunc("POST /api/v1/auth/logout", s.handleLogout)
+
+	// WebSub hub callbacks (authenticated by topic match and HMAC signature)
+	mux.HandleFunc("GET "+websub.CallbackPath+"{id}", s.handleWebSubVerify)
+	mux.HandleFunc("POST "+websub.CallbackPath+"{id}", s.handleWebSubDistribute)
+
+	// Operator endpoints (guarded by requireAuth when a password is configured)
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/feeds", s.handleGetFeeds)
+	api.HandleFunc("POST /api/v1/feeds", s.handleCreateFeed)
+	api.HandleFunc("POST /api/v1/feeds/discover", s.handleDiscoverFeeds)
+	api.HandleFunc("GET /api/v1/feeds/{id}", s.handleGetFeedDetails)
+	api.HandleFunc("GET /api/v1/feeds/{id}/items", s.handleGetFeedItems)
+	api.HandleFunc("GET /api/v1/feeds/{id}/url-history", s.handleGetFeedURLHistory)
+	api.HandleFunc("GET /api/v1/feeds/{id}/crawls", s.handleGetFeedCrawls)
+	api.HandleFunc("PUT /api/v1/feeds/{id}", s.handleUpdateFeed)
+	api.HandleFunc("DELETE /api/v1/feeds/{id}", s.handleDeleteFeed)
+
+	api.HandleFunc("GET /api/v1/opml", s.handleExportOPML)
+	api.HandleFunc("POST /api/v1/opml", s.handleImportOPML)
+
+	api.HandleFunc("GET /api/v1/users", s.handleGetUsers)
+	api.HandleFunc("POST /api/v1/users", s.handleCreateUser)
+	api.HandleFunc("PUT /api/v1/users/{id}", s.handleUpdateUser)
+	api.HandleFunc("DELETE /api/v1/users/{id}", s.handleDeleteUser)
+	api.HandleFunc("GET /api/v1/users/{id}/subscriptions", s.handleGetUserSubscriptions)
+
+	api.HandleFunc("POST /api/v1/subscriptions", s.handleSubscribe)
+	api.HandleFunc("DELETE /api/v1/subscriptions", s.handleUnsubscribe)
+	api.HandleFunc("PUT /api/v1/subscriptions", s.handleUpdateSubscriptionDelivery)
+
+	api.HandleFunc("GET /api/v1/users/{id}/subscriptions/{feed_id}/filters", s.handleGetFilterRules)
+	api.HandleFunc("POST /api/v1/users/{id}/subscriptions/{feed_id}/filters", s.handleCreateFilterRule)
+	api.HandleFunc("POST /api/v1/users/{id}/subscriptions/{feed_id}/filters/test", s.handleDryRunFilterRules)
+	api.HandleFunc("PUT /api/v1/filters/{id}", s.handleUpdateFilterRule)
+	api.HandleFunc("DELETE /api/v1/filters/{id}", s.handleDeleteFilterRule)
+
+	api.HandleFunc("GET /api/v1/users/{id}/channels", s.handleGetUserChannels)
+	api.HandleFunc("POST /api/v1/users/{id}/channels", s.handleCreateChannel)
+	api.HandleFunc("PUT /api/v1/channels/{id}", s.handleUpdateChannel)
+	api.HandleFunc("DELETE /api/v1/channels/{id}", s.handleDeleteChannel)
+
+	api.HandleFunc("GET /api/v1/stats", s.handleGetStats)
+	api.HandleFunc("GET /api/v1/logs", s.handleGetLogs)
+	api.HandleFunc("GET /api/v1/outbox", s.handleGetOutbox)
+
+	api.HandleFunc("POST /api/v1/feeds/{id}/test", s.handleTestFeed)
+	api.HandleFunc("POST /api/v1/feeds/{id}/scan", s.handleScanFeed)
+	api.HandleFunc("POST /api/v1/feeds/{id}/catchup", s.handleCatchupFeed)
+	api.HandleFunc("POST /api/v1/feeds/{id}/rewind", s.handleRewindFeed)
+
+	mux.Handle("/api/v1/", s.requireAuth(api))
 
 	// Mount Svelte SPA static files (with SPA fallback routing)
 	subFS, err := fs.Sub(ui.Files, "dist")
@@ -3,6 +3,9 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,7 +13,9 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -19,9 +24,14 @@ import (
 	"rss2go/internal/crawler"
 	"rss2go/internal/database"
 	"rss2go/internal/extractor"
+	"rss2go/internal/magiclink"
+	"rss2go/internal/opml"
+	"rss2go/internal/requestopts"
 	"rss2go/internal/sanitizer"
 	"rss2go/internal/scheduler"
+	"rss2go/internal/secrets"
 	"rss2go/internal/types"
+	"rss2go/internal/websub"
 )
 
 func setupTestDB(t *testing.T) *database.Repository {
@@ -35,7 +45,13 @@ func setupTestDB(t *testing.T) *database.Repository {
 	t.Cleanup(func() {
 		_ = db.Close()
 	})
-	return database.NewRepository(db)
+	box, err := secrets.NewBox(make([]byte, secrets.KeySize))
+	if err != nil {
+		t.Fatalf("failed to create secrets box: %v", err)
+	}
+	repo := database.NewRepository(db)
+	repo.SetSecrets(box)
+	return repo
 }
 
 func makeTestServer(t *testing.T, repo *database.Repository) (*Server, *httptest.Server) {
@@ -47,7 +63,6 @@ func makeTestServer(t *testing.T, repo *database.Repository) (*Server, *httptest
 
 	cfg := Config{
 		Addr:              "127.0.0.1:0",
-		MagicSecret:       "test-secret-key-12345",
 		HeartbeatInterval: 5 * time.Millisecond,
 		ShutdownTimeout:   50 * time.Millisecond,
 		MailerMode:        "mock",
@@ -105,7 +120,7 @@ func TestServerFeedsAndUsersCRUD(t *testing.T) {
 	}
 
 	// 4. Update Feed
-	updatePayload := `{"title": "Updated Title", "url": "http://dev.url/rss", "poll_interval_secs": 1800}`
+	updatePayload := `{"title": "Updated Title", "url": "http://dev.url/rss", "poll_interval_secs": 1800, "dedupe_strategy": "link", "extraction_chain": ["selector", "heuristic"]}`
 	req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, createdFeed.ID), strings.NewReader(updatePayload))
 	resp, err = http.DefaultClient.Do(req)
 	if err != nil {
@@ -117,10 +132,46 @@ func TestServerFeedsAndUsersCRUD(t *testing.T) {
 
 	// Verify update in DB
 	dbFeed, _ := repo.GetFeed(context.Background(), createdFeed.ID)
-	if dbFeed.Title != "Updated Title" || dbFeed.PollIntervalSecs != 1800 {
+	if dbFeed.Title != "Updated Title" || dbFeed.PollIntervalSecs != 1800 || dbFeed.DedupeStrategy != types.DedupeLink || len(dbFeed.ExtractionChain) != 2 {
 		t.Errorf("feed not updated correctly in DB: %+v", dbFeed)
 	}
 
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, createdFeed.ID), strings.NewReader(`{"title": "T", "url": "http://dev.url/rss", "dedupe_strategy": "fuzzy"}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /feed/:id failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown dedupe strategy, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, createdFeed.ID), strings.NewReader(`{"title": "T", "url": "http://dev.url/rss", "extraction_chain": ["heuristic", "readability"]}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /feed/:id failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown chain strategy, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, createdFeed.ID), strings.NewReader(`{"title": "T", "url": "http://dev.url/rss", "follow_pages": true, "max_pages": 500}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /feed/:id failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for too many pages, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, createdFeed.ID), strings.NewReader(`{"title": "T", "url": "http://dev.url/rss", "content_rules": {"rewrites": [{"pattern": "(unclosed"}]}}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /feed/:id failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid content rules, got %d", resp.StatusCode)
+	}
+
 	// 5. Create User
 	uPayload := `{"email": "user@test.com"}`
 	resp, err = http.Post(ts.URL+"/api/v1/users", "application/json", strings.NewReader(uPayload))
@@ -146,6 +197,26 @@ func TestServerFeedsAndUsersCRUD(t *testing.T) {
 		t.Errorf("expected user list size 1, got %d", len(usersList))
 	}
 
+	// Update User settings
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/users/%d", ts.URL, createdUser.ID), strings.NewReader(`{"cross_feed_dedupe": true}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /users/:id failed: %v", err)
+	}
+	var updatedUser types.User
+	_ = json.NewDecoder(resp.Body).Decode(&updatedUser)
+	if resp.StatusCode != http.StatusOK || !updatedUser.CrossFeedDedupe {
+		t.Errorf("expected cross-feed dedupe to be enabled, got %d %+v", resp.StatusCode, updatedUser)
+	}
+	req, _ = http.NewRequest("PUT", ts.URL+"/api/v1/users/9999", strings.NewReader(`{"cross_feed_dedupe": true}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /users/:id failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing user, got %d", resp.StatusCode)
+	}
+
 	// 6. Subscribe User to Feed
 	subPayload := fmt.Sprintf(`{"user_id": %d, "feed_id": %d}`, createdUser.ID, createdFeed.ID)
 	resp, err = http.Post(ts.URL+"/api/v1/subscriptions", "application/json", strings.NewReader(subPayload))
@@ -209,7 +280,7 @@ func TestServerSubscriberMagicLinks(t *testing.T) {
 
 	_ = repo.Subscribe(ctx, user.ID, feed1.ID)
 
-	token := generateMagicToken(user.Email, s.cfg.MagicSecret)
+	token := s.cfg.MagicKeys.Issue(user.Email, magiclink.PurposeManage)
 
 	// 1. GET /subscriber/manage with valid token
 	url := fmt.Sprintf("%s/api/v1/subscriber/manage?email=%s&token=%s", ts.URL, user.Email, token)
@@ -483,6 +554,22 @@ func TestServerControlActions(t *testing.T) {
 		t.Errorf("expected script tag to be sanitized and stripped, got %q", testRes.Items[0].Content)
 	}
 
+	// Content rules sent with the request are previewed before sanitizing
+	rulesPayload := `{"content_rules": {"remove": ["script"], "rewrites": [{"pattern": "Extracted (Article)", "replacement": "Cleaned $1"}]}}`
+	resp, err = http.Post(fmt.Sprintf("%s/api/v1/feeds/%d/test", ts.URL, feed.ID), "application/json", strings.NewReader(rulesPayload))
+	if err != nil {
+		t.Fatalf("POST /feeds/:id/test failed: %v", err)
+	}
+	testRes = testFeedResponse{}
+	_ = json.NewDecoder(resp.Body).Decode(&testRes)
+	if resp.StatusCode != http.StatusOK || len(testRes.Items) == 0 || !strings.Contains(testRes.Items[0].ExtractedContent, "Cleaned Article Body") {
+		t.Errorf("expected the previewed rules to rewrite the extraction, got %d %+v", resp.StatusCode, testRes.Items)
+	}
+	resp, _ = http.Post(fmt.Sprintf("%s/api/v1/feeds/%d/test", ts.URL, feed.ID), "application/json", strings.NewReader(`{"content_rules": {"remove": ["div[["]}}`))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid preview rules, got %d", resp.StatusCode)
+	}
+
 	// 1a. Test extraction preview failure path (uses article-11 which returns HTTP 500)
 	feedFail := &types.Feed{
 		Title:              "Crawl Fail Feed",
@@ -646,6 +733,19 @@ func TestServerControlActions(t *testing.T) {
 	if respConflict.StatusCode != http.StatusConflict {
 		t.Errorf("expected scan conflict status 409, got %d", respConflict.StatusCode)
 	}
+
+	// 9. The triggered feed waits in the crawl queue, as no workers are running
+	resp, err = http.Get(ts.URL + "/api/v1/stats")
+	if err != nil {
+		t.Fatalf("GET /stats failed: %v", err)
+	}
+	var stats struct {
+		Queue types.QueueStats `json:"queue"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&stats)
+	if stats.Queue.Depth != 1 || stats.Queue.Busy != 0 {
+		t.Errorf("expected the triggered feed to be queued, got %+v", stats.Queue)
+	}
 }
 
 func TestServerStartStop(t *testing.T) {
@@ -805,7 +905,7 @@ func TestServerSubscriberUnsubscribeError(t *testing.T) {
 	mockTX := &erroringDBTX{DBTX: db}
 	repo := database.NewRepository(mockTX)
 
-	_, ts := makeTestServer(t, repo)
+	s, ts := makeTestServer(t, repo)
 	defer ts.Close()
 
 	// Create user first using direct DB repo
@@ -813,7 +913,7 @@ func TestServerSubscriberUnsubscribeError(t *testing.T) {
 	user := &types.User{Email: "txfail@test.com"}
 	_ = cleanRepo.CreateUser(context.Background(), user)
 
-	token := generateMagicToken(user.Email, "test-secret-key-12345")
+	token := s.cfg.MagicKeys.Issue(user.Email, magiclink.PurposeManage)
 
 	unsubBody, _ := json.Marshal(unsubscribeRequest{
 		Email:   user.Email,
@@ -1066,3 +1166,1055 @@ func TestServerCreateFeedWithSubscribers(t *testing.T) {
 		t.Errorf("expected user 2 to be subscribed to f2, got %v", u2Fetched.SubscribedFeedIDs)
 	}
 }
+
+func makeLockedTestServer(t *testing.T, repo *database.Repository, password string) (*Server, *httptest.Server) {
+	t.Helper()
+	cr := crawler.NewCrawler(nil, slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(nil, slog.New(slog.DiscardHandler))
+	sa := sanitizer.NewSanitizer(600)
+	sched := scheduler.New(repo, cr, ex, sa, scheduler.Config{}, nil)
+
+	s := New(repo, sched, cr, ex, sa, Config{
+		Addr:       "127.0.0.1:0",
+		Password:   password,
+		SessionTTL: time.Hour,
+		MailerMode: "mock",
+	}, slog.New(slog.DiscardHandler))
+	handler, err := s.Handler()
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func cookieByName(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, c := range cookies {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestServerAuthLockedMode(t *testing.T) {
+	repo := setupTestDB(t)
+	s, ts := makeLockedTestServer(t, repo, "operator-pass")
+	ctx := context.Background()
+
+	// 1. Operator routes reject anonymous requests
+	for _, path := range []string{"/api/v1/feeds", "/api/v1/users", "/api/v1/outbox", "/api/v1/logs", "/api/v1/stats"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 for anonymous GET %s, got %d", path, resp.StatusCode)
+		}
+	}
+
+	// 2. Public subscriber magic-link routes stay open
+	user := &types.User{Email: "public@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	token := s.cfg.MagicKeys.Issue(user.Email, magiclink.PurposeManage)
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/subscriber/manage?email=%s&token=%s", ts.URL, user.Email, token))
+	if err != nil {
+		t.Fatalf("GET /subscriber/manage failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected public subscriber route to return 200, got %d", resp.StatusCode)
+	}
+
+	// 3. Status reports a locked, unauthenticated panel
+	resp, err = http.Get(ts.URL + "/api/v1/auth/status")
+	if err != nil {
+		t.Fatalf("GET /auth/status failed: %v", err)
+	}
+	var status authStatusResponse
+	_ = json.NewDecoder(resp.Body).Decode(&status)
+	_ = resp.Body.Close()
+	if !status.AuthRequired || status.Authenticated {
+		t.Errorf("expected locked unauthenticated status, got %+v", status)
+	}
+
+	// 4. Wrong password is rejected without issuing cookies
+	resp, err = http.Post(ts.URL+"/api/v1/auth/login", "application/json", strings.NewReader(`{"password": "wrong"}`))
+	if err != nil {
+		t.Fatalf("POST /auth/login failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for wrong password, got %d", resp.StatusCode)
+	}
+	if cookieByName(resp.Cookies(), sessionCookieName) != nil {
+		t.Error("expected no session cookie on failed login")
+	}
+
+	// 5. Correct password issues session and CSRF cookies
+	resp, err = http.Post(ts.URL+"/api/v1/auth/login", "application/json", strings.NewReader(`{"password": "operator-pass"}`))
+	if err != nil {
+		t.Fatalf("POST /auth/login failed: %v", err)
+	}
+	var loginRes authStatusResponse
+	_ = json.NewDecoder(resp.Body).Decode(&loginRes)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 on login, got %d", resp.StatusCode)
+	}
+	session := cookieByName(resp.Cookies(), sessionCookieName)
+	csrf := cookieByName(resp.Cookies(), csrfCookieName)
+	if session == nil || csrf == nil {
+		t.Fatalf("expected session and csrf cookies, got %v", resp.Cookies())
+	}
+	if !session.HttpOnly {
+		t.Error("expected session cookie to be HttpOnly")
+	}
+	if loginRes.CSRFToken != csrf.Value {
+		t.Errorf("expected login response CSRF token to match cookie")
+	}
+
+	do := func(method, path, body string, withCSRF bool) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.AddCookie(session)
+		if withCSRF {
+			req.Header.Set(csrfHeaderName, csrf.Value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	// 6. Authenticated reads succeed
+	if resp := do(http.MethodGet, "/api/v1/feeds", "", false); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for authenticated GET /feeds, got %d", resp.StatusCode)
+	}
+
+	// 7. State-changing requests need the CSRF header
+	payload := `{"title": "Locked Feed", "url": "http://locked.url/rss"}`
+	if resp := do(http.MethodPost, "/api/v1/feeds", payload, false); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for POST without CSRF token, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, "/api/v1/feeds", payload, true); resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected 201 for POST with CSRF token, got %d", resp.StatusCode)
+	}
+
+	// 8. Tampered session cookies are rejected
+	tampered := *session
+	tampered.Value = strings.Replace(session.Value, ".", "9.", 1)
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/feeds", nil)
+	req.AddCookie(&tampered)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /feeds failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for tampered session, got %d", resp.StatusCode)
+	}
+
+	// 9. Sessions expire after SessionTTL
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if resp := do(http.MethodGet, "/api/v1/feeds", "", false); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for expired session, got %d", resp.StatusCode)
+	}
+	s.now = time.Now
+
+	// 10. Logout clears the cookies
+	resp = do(http.MethodPost, "/api/v1/auth/logout", "", false)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 on logout, got %d", resp.StatusCode)
+	}
+	cleared := cookieByName(resp.Cookies(), sessionCookieName)
+	if cleared == nil || cleared.MaxAge >= 0 {
+		t.Errorf("expected logout to expire the session cookie, got %+v", cleared)
+	}
+}
+
+func TestServerAuthUnlockedMode(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/auth/status")
+	if err != nil {
+		t.Fatalf("GET /auth/status failed: %v", err)
+	}
+	var status authStatusResponse
+	_ = json.NewDecoder(resp.Body).Decode(&status)
+	_ = resp.Body.Close()
+	if status.AuthRequired || !status.Authenticated {
+		t.Errorf("expected open panel status, got %+v", status)
+	}
+
+	// Without a password no session or CSRF token is required
+	resp, err = http.Post(ts.URL+"/api/v1/feeds", "application/json", strings.NewReader(`{"title": "Open Feed", "url": "http://open.url/rss"}`))
+	if err != nil {
+		t.Fatalf("POST /feeds failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected 201 in unlocked mode, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL+"/api/v1/auth/login", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST /auth/login failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected login to be a no-op 200 in unlocked mode, got %d", resp.StatusCode)
+	}
+	if cookieByName(resp.Cookies(), sessionCookieName) != nil {
+		t.Error("expected no session cookie in unlocked mode")
+	}
+}
+
+func TestServerSubscriptionDelivery(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	user := &types.User{Email: "digest@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	feed := &types.Feed{Title: "Digest Feed", URL: "http://digest.url/rss", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+
+	put := func(body string) *http.Response {
+		req, _ := http.NewRequest("PUT", ts.URL+"/api/v1/subscriptions", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT /subscriptions failed: %v", err)
+		}
+		return resp
+	}
+
+	invalid := []string{
+		`not json`,
+		fmt.Sprintf(`{"user_id": %d, "feed_id": %d, "delivery_mode": "monthly"}`, user.ID, feed.ID),
+		fmt.Sprintf(`{"user_id": %d, "feed_id": %d, "delivery_mode": "daily", "digest_time": "25:00"}`, user.ID, feed.ID),
+		fmt.Sprintf(`{"user_id": %d, "feed_id": %d, "delivery_mode": "weekly", "digest_time": "08:00", "digest_weekday": 9}`, user.ID, feed.ID),
+	}
+	for _, body := range invalid {
+		resp := put(body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+
+	resp := put(fmt.Sprintf(`{"user_id": %d, "feed_id": 9999, "delivery_mode": "hourly"}`, user.ID))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for missing subscription, got %d", resp.StatusCode)
+	}
+
+	resp = put(fmt.Sprintf(`{"user_id": %d, "feed_id": %d, "delivery_mode": "weekly", "digest_time": "18:15", "digest_weekday": 5}`, user.ID, feed.ID))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/users/%d/subscriptions", ts.URL, user.ID))
+	if err != nil {
+		t.Fatalf("GET /users/:id/subscriptions failed: %v", err)
+	}
+	var subs []types.Subscription
+	_ = json.NewDecoder(resp.Body).Decode(&subs)
+	if len(subs) != 1 {
+		t.Fatalf("expected 1 subscription, got %d", len(subs))
+	}
+	if subs[0].DeliveryMode != types.DeliveryWeekly || subs[0].DigestTime != "18:15" || subs[0].DigestWeekday != time.Friday {
+		t.Errorf("delivery settings not persisted: %+v", subs[0])
+	}
+}
+
+func TestServerFeedItemsHistory(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	feed := &types.Feed{Title: "History Feed", URL: "http://history.invalid/rss", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+	for i := range 25 {
+		_ = repo.SaveItem(ctx, &types.Item{FeedID: feed.ID, GUID: fmt.Sprintf("guid-%d", i), Title: fmt.Sprintf("Item %d", i)})
+	}
+
+	// The unreachable feed URL proves history is served from the database.
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/feeds/%d/items", ts.URL, feed.ID))
+	if err != nil {
+		t.Fatalf("GET /feeds/:id/items failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var page feedItemsPage
+	_ = json.NewDecoder(resp.Body).Decode(&page)
+	if page.Total != 25 || page.Limit != 20 || page.Offset != 0 || len(page.Items) != 20 {
+		t.Errorf("unexpected first page: total=%d limit=%d offset=%d len=%d", page.Total, page.Limit, page.Offset, len(page.Items))
+	}
+
+	resp, err = http.Get(fmt.Sprintf("%s/api/v1/feeds/%d/items?limit=10&offset=20", ts.URL, feed.ID))
+	if err != nil {
+		t.Fatalf("GET /feeds/:id/items page 2 failed: %v", err)
+	}
+	page = feedItemsPage{}
+	_ = json.NewDecoder(resp.Body).Decode(&page)
+	if page.Limit != 10 || page.Offset != 20 || len(page.Items) != 5 {
+		t.Errorf("unexpected last page: limit=%d offset=%d len=%d", page.Limit, page.Offset, len(page.Items))
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/feeds/9999/items")
+	if err != nil {
+		t.Fatalf("GET /feeds/:id/items missing failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for missing feed, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerWebSubCallbacks(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	cr := crawler.NewCrawler(nil, slog.New(slog.DiscardHandler))
+	ex := extractor.NewExtractor(nil, slog.New(slog.DiscardHandler))
+	sa := sanitizer.NewSanitizer(600)
+	sched := scheduler.New(repo, cr, ex, sa, scheduler.Config{}, nil)
+	mgr := websub.NewManager(repo, nil, websub.Config{CallbackBase: "https://rss2go.example.com"}, slog.New(slog.DiscardHandler))
+
+	// A locked server proves hub callbacks bypass operator authentication.
+	s := New(repo, sched, cr, ex, sa, Config{
+		Password:   "operator-pass",
+		MailerMode: "mock",
+		WebSub:     mgr,
+	}, slog.New(slog.DiscardHandler))
+	handler, err := s.Handler()
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	user := &types.User{Email: "push@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	feed := &types.Feed{Title: "Push Feed", URL: "https://example.com/feed.xml", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+	_ = repo.SaveWebSubSubscription(ctx, &types.WebSubSubscription{
+		FeedID: feed.ID, Hub: "https://hub.example.com", Topic: feed.URL, Secret: "push-secret",
+		State: types.WebSubPending, LeaseSeconds: 3600,
+	})
+	callback := fmt.Sprintf("%s/api/v1/websub/%d", ts.URL, feed.ID)
+
+	// 1. Intent verification echoes the challenge
+	q := url.Values{"hub.mode": {"subscribe"}, "hub.topic": {feed.URL}, "hub.challenge": {"abc123"}, "hub.lease_seconds": {"600"}}
+	resp, err := http.Get(callback + "?" + q.Encode())
+	if err != nil {
+		t.Fatalf("GET websub callback failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "abc123" {
+		t.Fatalf("expected challenge echo, got %d %q", resp.StatusCode, body)
+	}
+
+	q.Set("hub.topic", "https://attacker.example.com/feed")
+	resp, _ = http.Get(callback + "?" + q.Encode())
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for topic mismatch, got %d", resp.StatusCode)
+	}
+
+	// 2. Unsigned content is acknowledged but ignored
+	pushed := generateMockFeedXML("https://example.com", 1)
+	resp, err = http.Post(callback, "application/rss+xml", strings.NewReader(pushed))
+	if err != nil {
+		t.Fatalf("POST websub callback failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202 for unsigned push, got %d", resp.StatusCode)
+	}
+
+	// 3. Signed content goes through the item pipeline
+	mac := hmac.New(sha256.New, []byte("push-secret"))
+	mac.Write([]byte(pushed))
+	req, _ := http.NewRequest("POST", callback, strings.NewReader(pushed))
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST signed websub callback failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202 for signed push, got %d", resp.StatusCode)
+	}
+
+	// Stop waits for the background ingestion to finish.
+	sched.Stop()
+
+	outbox, _ := repo.ListOutboxItems(ctx, 10)
+	if len(outbox) != 1 || outbox[0].Recipients[0] != "push@test.com" {
+		t.Fatalf("expected pushed item to be queued once, got %+v", outbox)
+	}
+	stored, _ := repo.ListItemsForFeed(ctx, feed.ID, 10, 0)
+	if len(stored) != 1 {
+		t.Errorf("expected pushed item to be persisted, got %d", len(stored))
+	}
+
+	// 4. Pushes for feeds without a subscription are refused with 410
+	resp, _ = http.Post(ts.URL+"/api/v1/websub/9999", "application/rss+xml", strings.NewReader(pushed))
+	if resp.StatusCode != http.StatusGone {
+		t.Errorf("expected 410 for unknown subscription, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerOPML(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	user := &types.User{Email: "opml@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	feed := &types.Feed{Title: "Exported", URL: "http://exported.url/rss", Category: "News", PollIntervalSecs: 600, NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+
+	resp, err := http.Get(ts.URL + "/api/v1/opml")
+	if err != nil {
+		t.Fatalf("GET /opml failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/x-opml") {
+		t.Fatalf("unexpected export response %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), `xmlUrl="http://exported.url/rss"`) || !strings.Contains(string(body), `<outline text="News" title="News">`) {
+		t.Errorf("expected feed grouped under its category, got:\n%s", body)
+	}
+
+	// Importing the export alongside a new feed subscribes the user to both.
+	doc := strings.Replace(string(body), "</body>", `<outline text="Imported" xmlUrl="http://imported.url/rss"/><outline text="Bad" xmlUrl="not a url"/></body>`, 1)
+	resp, err = http.Post(fmt.Sprintf("%s/api/v1/opml?user_id=%d", ts.URL, user.ID), "text/x-opml", strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("POST /opml failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var report opml.ImportReport
+	_ = json.NewDecoder(resp.Body).Decode(&report)
+	if report.Created != 1 || report.Existing != 1 || report.Failed != 1 {
+		t.Errorf("unexpected import report %+v", report)
+	}
+	subs, _ := repo.ListSubscriptionsForUser(ctx, user.ID)
+	if len(subs) != 2 {
+		t.Errorf("expected 2 subscriptions, got %d", len(subs))
+	}
+
+	resp, _ = http.Post(ts.URL+"/api/v1/opml?user_id=9999", "text/x-opml", strings.NewReader(doc))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown user, got %d", resp.StatusCode)
+	}
+	resp, _ = http.Post(ts.URL+"/api/v1/opml", "text/x-opml", strings.NewReader("<html>"))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid document, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerFeedURLHistory(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	feed := &types.Feed{Title: "Moving", URL: "http://old.url/rss", NextPollAt: time.Now(), Health: types.FeedDisabled, DisabledReason: "gone", NotFoundCount: 3}
+	_ = repo.CreateFeed(ctx, feed)
+
+	put := func(body string) *http.Response {
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, feed.ID), strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT /feeds/{id} failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	// Editing the title alone leaves no URL history.
+	if resp := put(`{"title": "Moving", "url": "http://old.url/rss", "poll_interval_secs": 600}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := put(`{"title": "Moving", "url": "http://new.url/rss", "poll_interval_secs": 600}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if updated, _ := repo.GetFeed(ctx, feed.ID); updated.Health != types.FeedHealthy || updated.NotFoundCount != 0 {
+		t.Errorf("expected an edit to re-enable a disabled feed, got %+v", updated)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/feeds/%d/url-history", ts.URL, feed.ID))
+	if err != nil {
+		t.Fatalf("GET /url-history failed: %v", err)
+	}
+	var history []types.FeedURLChange
+	_ = json.NewDecoder(resp.Body).Decode(&history)
+	_ = resp.Body.Close()
+	if len(history) != 1 || history[0].OldURL != "http://old.url/rss" || history[0].NewURL != "http://new.url/rss" || history[0].Reason != types.URLChangeOperator {
+		t.Errorf("unexpected URL history %+v", history)
+	}
+
+	req, _ := http.NewRequest("PUT", ts.URL+"/api/v1/feeds/9999", strings.NewReader(`{"title": "Missing", "url": "http://x.url/rss"}`))
+	if resp, _ := http.DefaultClient.Do(req); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 updating a missing feed, got %d", resp.StatusCode)
+	}
+	if resp, _ := http.Get(ts.URL + "/api/v1/feeds/9999/url-history"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing feed's history, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerFeedHealth(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	for _, f := range []*types.Feed{
+		{Title: "A", URL: "http://a.url/rss", NextPollAt: time.Now()},
+		{Title: "B", URL: "http://b.url/rss", NextPollAt: time.Now(), Health: types.FeedFailing, ConsecutiveFailures: 4},
+		{Title: "C", URL: "http://c.url/rss", NextPollAt: time.Now(), Health: types.FeedDisabled, DisabledReason: "gone"},
+	} {
+		if err := repo.CreateFeed(ctx, f); err != nil {
+			t.Fatalf("failed to create feed: %v", err)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/feeds?health=failing,disabled")
+	if err != nil {
+		t.Fatalf("GET /feeds failed: %v", err)
+	}
+	var feeds []types.Feed
+	_ = json.NewDecoder(resp.Body).Decode(&feeds)
+	_ = resp.Body.Close()
+	if len(feeds) != 2 || feeds[0].Title != "B" || feeds[1].Title != "C" || feeds[1].DisabledReason != "gone" {
+		t.Errorf("unexpected filtered feeds %+v", feeds)
+	}
+
+	if resp, _ := http.Get(ts.URL + "/api/v1/feeds?health=sick"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown health state, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/stats")
+	if err != nil {
+		t.Fatalf("GET /stats failed: %v", err)
+	}
+	var stats types.DBStats
+	_ = json.NewDecoder(resp.Body).Decode(&stats)
+	_ = resp.Body.Close()
+	if stats.FeedsHealthy != 1 || stats.FeedsDegraded != 0 || stats.FeedsFailing != 1 || stats.FeedsDisabled != 1 {
+		t.Errorf("unexpected feed health stats %+v", stats)
+	}
+
+	// An edit keeps a failing feed's health but re-enables a disabled one.
+	for _, f := range feeds {
+		body := fmt.Sprintf(`{"title": %q, "url": %q, "poll_interval_secs": 600}`, f.Title, f.URL)
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, f.ID), strings.NewReader(body))
+		if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("PUT /feeds/%d failed: %v", f.ID, err)
+		}
+	}
+	if updated, _ := repo.GetFeed(ctx, feeds[0].ID); updated.Health != types.FeedFailing || updated.ConsecutiveFailures != 4 {
+		t.Errorf("expected an edit to keep a failing feed's health, got %+v", updated)
+	}
+	if updated, _ := repo.GetFeed(ctx, feeds[1].ID); updated.Health != types.FeedHealthy || updated.DisabledReason != "" {
+		t.Errorf("expected an edit to re-enable a disabled feed, got %+v", updated)
+	}
+	events, _ := repo.ListUnalertedFeedHealthEvents(ctx)
+	if len(events) != 1 || events[0].FeedID != feeds[1].ID || events[0].To != types.FeedHealthy {
+		t.Errorf("expected the re-enable to be recorded, got %+v", events)
+	}
+}
+
+func TestServerFeedSourceFormat(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	body := `{"title": "Notes", "url": "https://notes.example.com/", "poll_interval_secs": 600, "source_format": "hfeed"}`
+	resp, err := http.Post(ts.URL+"/api/v1/feeds", "application/json", strings.NewReader(body))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /feeds failed: %v", err)
+	}
+	var created types.Feed
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+	_ = resp.Body.Close()
+	if stored, _ := repo.GetFeed(context.Background(), created.ID); stored.SourceFormat != "hfeed" {
+		t.Errorf("expected the source format to be stored, got %q", stored.SourceFormat)
+	}
+
+	body = `{"title": "Bad", "url": "https://bad.example.com/", "source_format": "gopher"}`
+	if resp, _ := http.Post(ts.URL+"/api/v1/feeds", "application/json", strings.NewReader(body)); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown source format on create, got %d", resp.StatusCode)
+	}
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, created.ID), strings.NewReader(body))
+	if resp, _ := http.DefaultClient.Do(req); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown source format on update, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerFeedRequestOptions(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+	ctx := context.Background()
+
+	// The upstream serves the feed and its article only to authenticated requests
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.Header.Get("PRIVATE-TOKEN") != "glpat" || user != "reader" || pass != "hunter2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/article" {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><article><p>Private article body</p></article></body></html>`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<rss version="2.0"><channel><title>Private</title><item><title>One</title><link>http://%s/article</link><guid>1</guid></item></channel></rss>`, r.Host)
+	}))
+	defer upstream.Close()
+
+	body := fmt.Sprintf(`{"title": "Private", "url": %q, "extract_full_article": true, "extraction_strategy": "selector", "css_selector": "article",
+		"request_options": {"headers": {"PRIVATE-TOKEN": "glpat"}, "username": "reader", "password": "hunter2"}}`, upstream.URL+"/feed")
+	resp, err := http.Post(ts.URL+"/api/v1/feeds", "application/json", strings.NewReader(body))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /feeds failed: %v", err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	var created struct {
+		types.Feed
+		RequestOptions *types.RequestOptions `json:"request_options"`
+	}
+	_ = json.Unmarshal(raw, &created)
+	if strings.Contains(string(raw), "glpat") || strings.Contains(string(raw), "hunter2") {
+		t.Errorf("create response leaked secrets: %s", raw)
+	}
+	if created.RequestOptions == nil || created.RequestOptions.Headers["PRIVATE-TOKEN"] != requestopts.Masked || created.RequestOptions.Username != "reader" {
+		t.Errorf("expected masked options in the create response, got %+v", created.RequestOptions)
+	}
+
+	feedURL := fmt.Sprintf("%s/api/v1/feeds/%d", ts.URL, created.ID)
+	for _, u := range []string{feedURL, ts.URL + "/api/v1/feeds"} {
+		resp, _ := http.Get(u)
+		raw, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if strings.Contains(string(raw), "glpat") || strings.Contains(string(raw), "hunter2") {
+			t.Errorf("GET %s leaked secrets: %s", u, raw)
+		}
+	}
+
+	// The dry run crawls and extracts with the stored credentials
+	resp, _ = http.Post(feedURL+"/test", "application/json", nil)
+	var dryRun testFeedResponse
+	_ = json.NewDecoder(resp.Body).Decode(&dryRun)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(dryRun.Items) != 1 || !strings.Contains(dryRun.Items[0].ExtractedContent, "Private article body") {
+		t.Errorf("expected an authenticated dry run, got %d %+v", resp.StatusCode, dryRun)
+	}
+
+	// Masked values sent back keep the stored secrets; an edit without options leaves them alone
+	update := func(body string) int {
+		req, _ := http.NewRequest("PUT", feedURL, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT /feeds failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := update(fmt.Sprintf(`{"title": "Private", "url": %q, "request_options": {"headers": {"PRIVATE-TOKEN": %q}, "cookies": {"theme": "dark"}, "username": "reader", "password": %q}}`,
+		upstream.URL+"/feed", requestopts.Masked, requestopts.Masked)); code != http.StatusOK {
+		t.Fatalf("expected 200 updating options, got %d", code)
+	}
+	if code := update(fmt.Sprintf(`{"title": "Renamed", "url": %q}`, upstream.URL+"/feed")); code != http.StatusOK {
+		t.Fatalf("expected 200 updating the feed, got %d", code)
+	}
+	opts, err := repo.GetFeedRequestOptions(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("failed to load request options: %v", err)
+	}
+	want := &types.RequestOptions{
+		Headers:  map[string]string{"PRIVATE-TOKEN": "glpat"},
+		Cookies:  map[string]string{"theme": "dark"},
+		Username: "reader",
+		Password: "hunter2",
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("expected stored options %+v, got %+v", want, opts)
+	}
+
+	if code := update(fmt.Sprintf(`{"title": "Private", "url": %q, "request_options": {"headers": {"Bad Header": "x"}}}`, upstream.URL+"/feed")); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid header name, got %d", code)
+	}
+	if code := update(fmt.Sprintf(`{"title": "Private", "url": %q, "request_options": {}}`, upstream.URL+"/feed")); code != http.StatusOK {
+		t.Fatalf("expected 200 clearing options, got %d", code)
+	}
+	if opts, _ := repo.GetFeedRequestOptions(ctx, created.ID); opts != nil {
+		t.Errorf("expected options to be cleared, got %+v", opts)
+	}
+}
+
+func TestServerFeedCrawls(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	feed := &types.Feed{Title: "Charted", URL: "http://chart.url/rss", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+	for i := range 3 {
+		run := &types.CrawlRun{FeedID: feed.ID, StartedAt: time.Now(), DurationMs: int64(100 * (i + 1)), StatusCode: http.StatusOK}
+		if err := repo.RecordCrawlRun(ctx, run); err != nil {
+			t.Fatalf("failed to record crawl run: %v", err)
+		}
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/feeds/%d/crawls?limit=2", ts.URL, feed.ID))
+	if err != nil {
+		t.Fatalf("GET /crawls failed: %v", err)
+	}
+	var runs []types.CrawlRun
+	_ = json.NewDecoder(resp.Body).Decode(&runs)
+	_ = resp.Body.Close()
+	if len(runs) != 2 || runs[0].DurationMs != 300 || runs[1].DurationMs != 200 {
+		t.Errorf("expected the two newest runs, got %+v", runs)
+	}
+
+	if resp, _ := http.Get(ts.URL + "/api/v1/feeds/9999/crawls"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing feed's crawls, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerDiscoverFeeds(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.rss"></head></html>`))
+		case "/posts.rss":
+			_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Site Posts</title><item><title>A</title></item><item><title>B</title></item></channel></rss>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	post := func(body string) *http.Response {
+		resp, err := http.Post(ts.URL+"/api/v1/feeds/discover", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /feeds/discover failed: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	resp := post(fmt.Sprintf(`{"url": %q}`, site.URL))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var candidates []crawler.Candidate
+	_ = json.NewDecoder(resp.Body).Decode(&candidates)
+	if len(candidates) != 1 || candidates[0].URL != site.URL+"/posts.rss" || candidates[0].Title != "Site Posts" || candidates[0].Items != 2 {
+		t.Errorf("unexpected candidates %+v", candidates)
+	}
+
+	if resp := post(`{"url": "ftp://example.com"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-http URL, got %d", resp.StatusCode)
+	}
+	if resp := post(fmt.Sprintf(`{"url": %q}`, site.URL+"/gone")); resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected 502 when the site cannot be fetched, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerFilterRules(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	user := &types.User{Email: "filters@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	feed := &types.Feed{Title: "Filter Feed", URL: "http://filter.url/rss", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, feed)
+	_ = repo.Subscribe(ctx, user.ID, feed.ID)
+	_ = repo.SaveItem(ctx, &types.Item{FeedID: feed.ID, GUID: "a", Title: "Widget Pro launch", Content: "<p>new</p>"})
+	_ = repo.SaveItem(ctx, &types.Item{FeedID: feed.ID, GUID: "b", Title: "Weekly roundup", Content: "<p>Mentions <b>Widget</b> briefly</p>", Categories: []string{"Sponsored"}})
+
+	do := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		return resp
+	}
+	base := fmt.Sprintf("/api/v1/users/%d/subscriptions/%d/filters", user.ID, feed.ID)
+
+	// Invalid rules are rejected at save time.
+	for _, body := range []string{
+		`not json`,
+		`{"action": "include", "field": "title", "match": "regex", "pattern": "(unclosed"}`,
+		`{"action": "keep", "field": "title", "pattern": "x"}`,
+		`{"action": "include", "field": "title", "pattern": ""}`,
+	} {
+		if resp := do("POST", base, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+	if resp := do("POST", fmt.Sprintf("/api/v1/users/%d/subscriptions/9999/filters", user.ID), `{}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for missing subscription, got %d", resp.StatusCode)
+	}
+
+	resp := do("POST", base, `{"action": "include", "field": "content", "pattern": "mentions widget"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var include types.FilterRule
+	_ = json.NewDecoder(resp.Body).Decode(&include)
+	if include.ID == 0 || include.Match != types.FilterMatchSubstring || include.UserID != user.ID {
+		t.Errorf("unexpected created rule %+v", include)
+	}
+	resp = do("POST", base, `{"action": "exclude", "field": "category", "match": "regex", "pattern": "^Sponsored$"}`)
+	var exclude types.FilterRule
+	_ = json.NewDecoder(resp.Body).Decode(&exclude)
+
+	resp = do("GET", base, "")
+	var rules []types.FilterRule
+	_ = json.NewDecoder(resp.Body).Decode(&rules)
+	if len(rules) != 2 {
+		t.Errorf("expected 2 rules, got %d", len(rules))
+	}
+
+	// Dry run with the saved rules.
+	resp = do("POST", base+"/test", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from dry run, got %d", resp.StatusCode)
+	}
+	var dry filterDryRunResponse
+	_ = json.NewDecoder(resp.Body).Decode(&dry)
+	if len(dry.Rules) != 2 || len(dry.Items) != 2 {
+		t.Fatalf("unexpected dry run %+v", dry)
+	}
+	delivered := make(map[string]filterDryRunItem)
+	for _, item := range dry.Items {
+		delivered[item.Title] = item
+	}
+	if launch := delivered["Widget Pro launch"]; launch.Delivered || len(launch.MatchedRules) != 0 {
+		t.Errorf("expected launch to miss the content include rule, got %+v", launch)
+	}
+	// The content rule matches the visible text across the <b> markup.
+	if roundup := delivered["Weekly roundup"]; roundup.Delivered || len(roundup.MatchedRules) != 2 {
+		t.Errorf("expected roundup to be excluded after matching both rules, got %+v", roundup)
+	}
+
+	// Dry run with a draft rule set.
+	resp = do("POST", base+"/test", `{"rules": [{"action": "include", "field": "title", "pattern": "roundup"}]}`)
+	dry = filterDryRunResponse{}
+	_ = json.NewDecoder(resp.Body).Decode(&dry)
+	if len(dry.Items) != 2 || dry.Items[0].Delivered == dry.Items[1].Delivered {
+		t.Errorf("expected draft rule to select exactly one item, got %+v", dry.Items)
+	}
+	if resp := do("POST", base+"/test", `{"rules": [{"action": "include", "field": "title", "match": "regex", "pattern": "["}]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid draft rule, got %d", resp.StatusCode)
+	}
+
+	resp = do("PUT", fmt.Sprintf("/api/v1/filters/%d", include.ID), `{"action": "include", "field": "title", "match": "regex", "pattern": "^Widget"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from update, got %d", resp.StatusCode)
+	}
+	got, _ := repo.GetFilterRule(ctx, include.ID)
+	if got.Field != types.FilterFieldTitle || got.Pattern != "^Widget" {
+		t.Errorf("rule not updated: %+v", got)
+	}
+	if resp := do("PUT", fmt.Sprintf("/api/v1/filters/%d", include.ID), `{"action": "include", "field": "title", "match": "regex", "pattern": "("}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid update, got %d", resp.StatusCode)
+	}
+	if resp := do("PUT", "/api/v1/filters/9999", `{"action": "include", "field": "title", "pattern": "x"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 updating missing rule, got %d", resp.StatusCode)
+	}
+
+	if resp := do("DELETE", fmt.Sprintf("/api/v1/filters/%d", exclude.ID), ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 from delete, got %d", resp.StatusCode)
+	}
+	if resp := do("DELETE", fmt.Sprintf("/api/v1/filters/%d", exclude.ID), ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 deleting twice, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerChannels(t *testing.T) {
+	repo := setupTestDB(t)
+	_, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	user := &types.User{Email: "channels@test.com"}
+	_ = repo.CreateUser(ctx, user)
+
+	do := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		return resp
+	}
+	base := fmt.Sprintf("/api/v1/users/%d/channels", user.ID)
+
+	for _, body := range []string{
+		`not json`,
+		`{"kind": "pager", "target": "https://example.com"}`,
+		`{"kind": "webhook", "target": "ftp://example.com"}`,
+		`{"kind": "matrix", "server": "https://matrix.example.org", "target": "!room:example.org"}`,
+	} {
+		if resp := do("POST", base, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+	if resp := do("POST", "/api/v1/users/9999/channels", `{"kind": "email", "target": "a@b.com"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for missing user, got %d", resp.StatusCode)
+	}
+
+	resp := do("POST", base, `{"kind": "matrix", "name": "Room", "server": "https://matrix.example.org", "target": "!room:example.org", "token": "syt_secret"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(raw), "syt_secret") {
+		t.Errorf("expected token to be omitted from the response, got %s", raw)
+	}
+	var created types.Channel
+	_ = json.Unmarshal(raw, &created)
+	if created.ID == 0 || !created.Enabled || !created.HasToken || created.UserID != user.ID {
+		t.Errorf("unexpected created channel %+v", created)
+	}
+
+	// Updating without a token keeps the stored one.
+	resp = do("PUT", fmt.Sprintf("/api/v1/channels/%d", created.ID), `{"kind": "matrix", "name": "Renamed", "server": "https://matrix.example.org", "target": "!room:example.org", "enabled": false}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from update, got %d", resp.StatusCode)
+	}
+	stored, _ := repo.GetChannel(ctx, created.ID)
+	if stored.Name != "Renamed" || stored.Enabled || stored.Token != "syt_secret" {
+		t.Errorf("channel not updated correctly: %+v", stored)
+	}
+	if resp := do("PUT", "/api/v1/channels/9999", `{"kind": "email", "target": "a@b.com"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 updating a missing channel, got %d", resp.StatusCode)
+	}
+
+	resp = do("GET", base, "")
+	var channels []types.Channel
+	_ = json.NewDecoder(resp.Body).Decode(&channels)
+	if len(channels) != 1 || channels[0].Name != "Renamed" {
+		t.Errorf("unexpected channel list %+v", channels)
+	}
+
+	if resp := do("DELETE", fmt.Sprintf("/api/v1/channels/%d", created.ID), ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 from delete, got %d", resp.StatusCode)
+	}
+	if resp := do("DELETE", fmt.Sprintf("/api/v1/channels/%d", created.ID), ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing channel, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerOneClickUnsubscribe(t *testing.T) {
+	repo := setupTestDB(t)
+	srv, ts := makeTestServer(t, repo)
+	defer ts.Close()
+
+	ctx := context.Background()
+	user := &types.User{Email: "reader@test.com"}
+	_ = repo.CreateUser(ctx, user)
+	news := &types.Feed{Title: "News <Daily>", URL: "http://news.url/rss", NextPollAt: time.Now()}
+	blog := &types.Feed{Title: "Blog", URL: "http://blog.url/rss", NextPollAt: time.Now()}
+	_ = repo.CreateFeed(ctx, news)
+	_ = repo.CreateFeed(ctx, blog)
+	_ = repo.Subscribe(ctx, user.ID, news.ID)
+	_ = repo.Subscribe(ctx, user.ID, blog.ID)
+
+	links := magiclink.NewLinks(ts.URL+"/", srv.cfg.MagicKeys)
+	link := links.Unsubscribe(user.Email, news.ID)
+	if !strings.HasPrefix(link, ts.URL+magiclink.UnsubscribePath+"?") {
+		t.Fatalf("unexpected unsubscribe link %q", link)
+	}
+	if magiclink.NewLinks("", srv.cfg.MagicKeys).Unsubscribe(user.Email, news.ID) != "" {
+		t.Errorf("expected no link without a public base URL")
+	}
+
+	// GET only shows a confirmation form, so link scanners cannot unsubscribe anyone.
+	resp, err := http.Get(link)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), "<form method=\"post\"") || !strings.Contains(string(page), "News &lt;Daily&gt;") {
+		t.Errorf("expected an escaped confirmation form, got %d: %s", resp.StatusCode, page)
+	}
+	if _, err := repo.GetSubscription(ctx, user.ID, news.ID); err != nil {
+		t.Errorf("expected GET to leave the subscription in place, got %v", err)
+	}
+
+	// An unsubscribe token cannot be used to manage every subscription.
+	unsubToken, _ := url.Parse(link)
+	resp, err = http.Get(fmt.Sprintf("%s/api/v1/subscriber/manage?%s", ts.URL, unsubToken.RawQuery))
+	if err != nil {
+		t.Fatalf("GET /subscriber/manage failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 using an unsubscribe token to manage subscriptions, got %d", resp.StatusCode)
+	}
+
+	// Tampering with the address or token is rejected.
+	tampered := strings.Replace(link, "reader%40test.com", "other%40test.com", 1)
+	for _, bad := range []string{tampered, link + "0"} {
+		resp, err := http.Post(bad, "application/x-www-form-urlencoded", strings.NewReader("List-Unsubscribe=One-Click"))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403 for a tampered link, got %d", resp.StatusCode)
+		}
+	}
+
+	// RFC 8058 one-click POST unsubscribes only the linked feed.
+	resp, err = http.Post(link, "application/x-www-form-urlencoded", strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 from one-click POST, got %d", resp.StatusCode)
+	}
+	if _, err := repo.GetSubscription(ctx, user.ID, news.ID); err == nil {
+		t.Errorf("expected the news subscription to be removed")
+	}
+	if _, err := repo.GetSubscription(ctx, user.ID, blog.ID); err != nil {
+		t.Errorf("expected the blog subscription to remain, got %v", err)
+	}
+
+	// Repeating the request is harmless.
+	resp, _ = http.Post(link, "application/x-www-form-urlencoded", strings.NewReader("List-Unsubscribe=One-Click"))
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 repeating one-click POST, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"rss2go/internal/database"
+	"rss2go/internal/magiclink"
+	"rss2go/internal/types"
+)
+
+// unsubscribePage serves both the confirmation form shown to people following
+// the footer link and the result of the unsubscribe itself.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Unsubscribe</title>
+<style>body{font-family:system-ui,sans-serif;max-width:32rem;margin:4rem auto;padding:0 1rem;color:#222}button{font-size:1rem;padding:.5rem 1rem}</style>
+</head>
+<body>
+{{- if .Error}}
+<h1>Unsubscribe</h1>
+<p>{{.Error}}</p>
+{{- else if .Done}}
+<h1>You have been unsubscribed</h1>
+{{- if .Feeds}}
+<p>{{.Email}} will no longer receive updates from:</p>
+<ul>{{range .Feeds}}<li>{{.Title}}</li>{{end}}</ul>
+{{- else}}
+<p>{{.Email}} is no longer subscribed to these feeds.</p>
+{{- end}}
+{{- else}}
+<h1>Unsubscribe</h1>
+<p>Stop sending updates to {{.Email}} from:</p>
+<ul>{{range .Feeds}}<li>{{.Title}}</li>{{end}}</ul>
+<form method="post" action="{{.Action}}"><button type="submit">Unsubscribe</button></form>
+{{- end}}
+</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Email  string
+	Feeds  []*types.Feed
+	Action string
+	Done   bool
+	Error  string
+}
+
+// unsubscribeRequestFromQuery verifies the signed link parameters and loads
+// the user and the feeds named in the token that they are still subscribed
+// to, rendering an error page when the link is unusable.
+func (s *Server) unsubscribeRequestFromQuery(w http.ResponseWriter, r *http.Request) (*types.User, []*types.Feed, bool) {
+	q := r.URL.Query()
+	claims, err := s.cfg.MagicKeys.Verify(q.Get("token"), q.Get("email"), magiclink.PurposeUnsubscribe)
+	if errors.Is(err, magiclink.ErrExpiredToken) {
+		s.renderUnsubscribePage(w, http.StatusForbidden, unsubscribePageData{Error: "This unsubscribe link has expired. Use the link in a more recent email."})
+		return nil, nil, false
+	}
+	if err != nil || claims.Email == "" {
+		s.renderUnsubscribePage(w, http.StatusForbidden, unsubscribePageData{Error: "This unsubscribe link is invalid."})
+		return nil, nil, false
+	}
+
+	user, err := s.repo.GetUserByEmail(r.Context(), claims.Email)
+	if err != nil {
+		s.renderUnsubscribePage(w, http.StatusNotFound, unsubscribePageData{Error: "No subscriber with this address was found."})
+		return nil, nil, false
+	}
+
+	var feeds []*types.Feed
+	for _, feedID := range claims.FeedIDs {
+		if _, err := s.repo.GetSubscription(r.Context(), user.ID, feedID); err != nil {
+			continue // Already unsubscribed, or the feed is gone
+		}
+		feed, err := s.repo.GetFeed(r.Context(), feedID)
+		if err != nil {
+			continue
+		}
+		feeds = append(feeds, feed)
+	}
+	return user, feeds, true
+}
+
+// handleUnsubscribePage shows a confirmation form for an emailed unsubscribe
+// link. It never unsubscribes by itself, so link scanners that prefetch URLs
+// cannot unsubscribe anyone.
+func (s *Server) handleUnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	user, feeds, ok := s.unsubscribeRequestFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	data := unsubscribePageData{Email: user.Email, Feeds: feeds, Action: r.URL.RequestURI()}
+	if len(feeds) == 0 {
+		data.Done = true
+	}
+	s.renderUnsubscribePage(w, http.StatusOK, data)
+}
+
+// handleOneClickUnsubscribe removes the subscriptions named by a signed link.
+// It serves both the confirmation form and RFC 8058 one-click POSTs from mail
+// clients, whose "List-Unsubscribe=One-Click" body carries nothing we need.
+func (s *Server) handleOneClickUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	user, feeds, ok := s.unsubscribeRequestFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	txErr := s.repo.WithTx(r.Context(), func(txRepo *database.Repository) error {
+		for _, feed := range feeds {
+			if err := txRepo.Unsubscribe(r.Context(), user.ID, feed.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if txErr != nil {
+		s.log.Error("One-click unsubscribe failed", "user_id", user.ID, "err", txErr)
+		s.renderUnsubscribePage(w, http.StatusInternalServerError, unsubscribePageData{Error: "Something went wrong. Please try again later."})
+		return
+	}
+
+	s.log.Info("Subscriber unsubscribed via link", "user_id", user.ID, "feeds", len(feeds))
+	s.renderUnsubscribePage(w, http.StatusOK, unsubscribePageData{Email: user.Email, Feeds: feeds, Done: true})
+}
+
+func (s *Server) renderUnsubscribePage(w http.ResponseWriter, status int, data unsubscribePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := unsubscribePage.Execute(w, data); err != nil {
+		s.log.Error("Failed to render unsubscribe page", "err", err)
+	}
+}
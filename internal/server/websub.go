@@ -0,0 +1,97 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"rss2go/internal/crawler"
+	"rss2go/internal/types"
+	"rss2go/internal/websub"
+)
+
+// maxPushBodyBytes caps the size of content a hub may push in one request.
+const maxPushBodyBytes = 10 << 20
+
+// handleWebSubVerify answers a hub's intent verification by echoing the challenge.
+func (s *Server) handleWebSubVerify(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.WebSub == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	challenge, err := s.cfg.WebSub.Verify(r.Context(), id, r.URL.Query())
+	if err != nil {
+		s.log.Warn("Rejected WebSub verification", "feed_id", id, "mode", r.URL.Query().Get("hub.mode"), "err", err)
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, challenge)
+}
+
+// handleWebSubDistribute accepts content pushed by a hub, checks its HMAC
+// signature and hands the parsed items to the scheduler pipeline.
+func (s *Server) handleWebSubDistribute(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.WebSub == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	sub, err := s.repo.GetWebSubSubscription(r.Context(), id)
+	if err != nil || sub.State != types.WebSubActive {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// 410 tells the hub to drop a subscription we no longer hold.
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushBodyBytes))
+	if err != nil {
+		s.writeError(w, http.StatusRequestEntityTooLarge, "Pushed content too large")
+		return
+	}
+
+	// Per the spec, content failing signature checks is acknowledged but ignored.
+	if !websub.VerifySignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		s.log.Warn("Ignoring WebSub push with missing or invalid signature", "feed_id", id)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := s.repo.GetFeed(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	parsed, err := crawler.ParseFeed(body)
+	if err != nil {
+		s.log.Warn("Ignoring unparseable WebSub push", "feed_id", id, "err", err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	s.log.Info("Received WebSub push", "feed_id", id, "items", len(parsed.Items))
+	s.scheduler.IngestPush(feed, parsed)
+	w.WriteHeader(http.StatusAccepted)
+}
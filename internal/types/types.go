@@ -13,29 +13,123 @@ const (
 	StrategySelector  ExtractionStrategy = "selector"  // Target structural element via CSS selector
 )
 
+// DedupeStrategy defines how a feed recognizes items it has already delivered.
+type DedupeStrategy string
+
+const (
+	DedupeGUID    DedupeStrategy = "guid"    // Item GUID, falling back to link then title
+	DedupeLink    DedupeStrategy = "link"    // Also the link, with tracking parameters stripped
+	DedupeContent DedupeStrategy = "content" // Also a hash of the title and text content
+)
+
 // Feed represents a tracked RSS/Atom feed source.
 type Feed struct {
-	ID                         int64              `json:"id"`
-	Title                      string             `json:"title"`
-	URL                        string             `json:"url"`
-	ETag                       string             `json:"etag"`
-	LastModified               string             `json:"last_modified"`
-	NextPollAt                 time.Time          `json:"next_poll_at"`
-	PollIntervalSecs           int                `json:"poll_interval_secs"`
-	BackoffFactor              float64            `json:"backoff_factor"`
-	LastErrorStr               string             `json:"last_error_str,omitempty"`
-	LastErrorTime              *time.Time         `json:"last_error_time,omitempty"`
-	LastErrorSnippet           string             `json:"last_error_snippet,omitempty"`
-	LastPolledAt               *time.Time         `json:"last_polled_at,omitempty"`
-	ExtractFullArticle         bool               `json:"extract_full_article"`
-	ExtractionStrategy         ExtractionStrategy `json:"extraction_strategy"`
-	CSSSelector                string             `json:"css_selector"`
-	ScraperItemSelector        string             `json:"scraper_item_selector"`
-	ScraperTitleSelector       string             `json:"scraper_title_selector"`
-	ScraperLinkSelector        string             `json:"scraper_link_selector"`
-	ScraperDescriptionSelector string             `json:"scraper_description_selector"`
-	CreatedAt                  time.Time          `json:"created_at"`
-	UpdatedAt                  time.Time          `json:"updated_at"`
+	ID                         int64                `json:"id"`
+	Title                      string               `json:"title"`
+	URL                        string               `json:"url"`
+	ETag                       string               `json:"etag"`
+	LastModified               string               `json:"last_modified"`
+	NextPollAt                 time.Time            `json:"next_poll_at"`
+	PollIntervalSecs           int                  `json:"poll_interval_secs"`
+	BackoffFactor              float64              `json:"backoff_factor"`
+	LastErrorStr               string               `json:"last_error_str,omitempty"`
+	LastErrorTime              *time.Time           `json:"last_error_time,omitempty"`
+	LastErrorSnippet           string               `json:"last_error_snippet,omitempty"`
+	LastPolledAt               *time.Time           `json:"last_polled_at,omitempty"`
+	ExtractFullArticle         bool                 `json:"extract_full_article"`
+	ExtractionStrategy         ExtractionStrategy   `json:"extraction_strategy"`
+	ExtractionChain            []ExtractionStrategy `json:"extraction_chain,omitempty"` // Strategies to try, best result wins; empty uses ExtractionStrategy alone
+	CSSSelector                string               `json:"css_selector"`
+	FollowPages                bool                 `json:"follow_pages"`            // Stitch articles split across several pages
+	NextPageSelector           string               `json:"next_page_selector"`      // Next-page link; empty follows rel="next"
+	MaxPages                   int                  `json:"max_pages"`               // Pages stitched at most; 0 uses the default
+	ContentRules               *ContentRules        `json:"content_rules,omitempty"` // Cleanup applied to item HTML before sanitizing
+	ScraperItemSelector        string               `json:"scraper_item_selector"`
+	ScraperTitleSelector       string               `json:"scraper_title_selector"`
+	ScraperLinkSelector        string               `json:"scraper_link_selector"`
+	ScraperDescriptionSelector string               `json:"scraper_description_selector"`
+	SourceFormat               string               `json:"source_format"`   // Parser to use; empty detects it per crawl
+	DedupeStrategy             DedupeStrategy       `json:"dedupe_strategy"` // How repeated items are recognized; empty means GUID
+	Category                   string               `json:"category"`
+	NotFoundCount              int                  `json:"not_found_count"`             // Consecutive 404 responses
+	Health                     FeedHealth           `json:"health"`                      // Disabled feeds are not polled
+	ConsecutiveFailures        int                  `json:"consecutive_failures"`        // Failed crawls since the last success
+	FailingSince               *time.Time           `json:"failing_since,omitempty"`     // First failure of the current run
+	HealthChangedAt            *time.Time           `json:"health_changed_at,omitempty"` // Last health transition
+	DisabledReason             string               `json:"disabled_reason,omitempty"`   // Why polling stopped
+	AdaptivePolling            bool                 `json:"adaptive_polling"`            // Poll interval learned from the feed's update rate
+	AdaptiveIntervalSecs       int                  `json:"adaptive_interval_secs"`      // Last learned interval, before jitter
+	RequestOptions             *RequestOptions      `json:"-"`                           // Loaded for crawling; stored encrypted apart from the feed
+	CreatedAt                  time.Time            `json:"created_at"`
+	UpdatedAt                  time.Time            `json:"updated_at"`
+}
+
+// RequestOptions customize the HTTP requests made for a feed, for sources
+// behind authentication or that expect particular headers. Every value but
+// the user agent and username is a secret.
+type RequestOptions struct {
+	UserAgent   string            `json:"user_agent,omitempty"`   // Replaces the default User-Agent
+	Headers     map[string]string `json:"headers,omitempty"`      // Extra request headers, e.g. PRIVATE-TOKEN
+	Cookies     map[string]string `json:"cookies,omitempty"`      // Cookie name to value
+	Username    string            `json:"username,omitempty"`     // HTTP Basic auth
+	Password    string            `json:"password,omitempty"`     // HTTP Basic auth
+	BearerToken string            `json:"bearer_token,omitempty"` // Sent as Authorization: Bearer; wins over Basic auth
+}
+
+// FeedHealth summarizes how reliably a feed has been crawling.
+type FeedHealth string
+
+const (
+	FeedHealthy  FeedHealth = "healthy"  // The last crawl succeeded
+	FeedDegraded FeedHealth = "degraded" // Recent crawls failed, but not enough to be failing
+	FeedFailing  FeedHealth = "failing"  // Enough consecutive crawls failed to need attention
+	FeedDisabled FeedHealth = "disabled" // Polling stopped until an operator edits the feed
+)
+
+// FeedHealthEvent records one change of a feed's health. Events feed the
+// operator alert digest and are marked once they have been reported.
+type FeedHealthEvent struct {
+	ID        int64      `json:"id"`
+	FeedID    int64      `json:"feed_id"`
+	FeedTitle string     `json:"feed_title"`
+	FeedURL   string     `json:"feed_url"`
+	From      FeedHealth `json:"from"`
+	To        FeedHealth `json:"to"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	AlertedAt *time.Time `json:"alerted_at,omitempty"`
+}
+
+// CrawlRun records the timing and outcome of one crawl of a feed.
+type CrawlRun struct {
+	ID           int64     `json:"id"`
+	FeedID       int64     `json:"feed_id"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMs   int64     `json:"duration_ms"`
+	StatusCode   int       `json:"status_code"` // 0 when no HTTP response was received
+	Bytes        int64     `json:"bytes"`
+	ItemCount    int       `json:"item_count"`
+	NewItemCount int       `json:"new_item_count"`
+	NotModified  bool      `json:"not_modified"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// URLChangeReason records why a feed's URL changed.
+type URLChangeReason string
+
+const (
+	URLChangeRedirect URLChangeReason = "redirect" // The feed answered with a permanent redirect
+	URLChangeOperator URLChangeReason = "operator" // An operator edited the feed
+)
+
+// FeedURLChange is one entry in a feed's URL audit history.
+type FeedURLChange struct {
+	ID        int64           `json:"id"`
+	FeedID    int64           `json:"feed_id"`
+	OldURL    string          `json:"old_url"`
+	NewURL    string          `json:"new_url"`
+	Reason    URLChangeReason `json:"reason"`
+	CreatedAt time.Time       `json:"created_at"`
 }
 
 // User represents a recipient of email notifications.
@@ -43,13 +137,132 @@ type User struct {
 	ID                int64     `json:"id"`
 	Email             string    `json:"email"`
 	SubscribedFeedIDs []int64   `json:"subscribed_feed_ids"`
+	CrossFeedDedupe   bool      `json:"cross_feed_dedupe"` // Send an article once even if several subscribed feeds carry it
 	CreatedAt         time.Time `json:"created_at"`
 }
 
+// DeliveryMode defines how new items of a subscription reach the subscriber.
+type DeliveryMode string
+
+const (
+	DeliveryImmediate DeliveryMode = "immediate" // One email per new item
+	DeliveryHourly    DeliveryMode = "hourly"    // Batched at the top of every hour
+	DeliveryDaily     DeliveryMode = "daily"     // Batched once a day at DigestTime
+	DeliveryWeekly    DeliveryMode = "weekly"    // Batched once a week on DigestWeekday at DigestTime
+)
+
 // Subscription represents a mapping between a User and a Feed.
 type Subscription struct {
-	UserID int64 `json:"user_id"`
-	FeedID int64 `json:"feed_id"`
+	UserID        int64        `json:"user_id"`
+	FeedID        int64        `json:"feed_id"`
+	DeliveryMode  DeliveryMode `json:"delivery_mode"`
+	DigestTime    string       `json:"digest_time"`    // Local "HH:MM" for daily/weekly digests
+	DigestWeekday time.Weekday `json:"digest_weekday"` // 0=Sunday, used by weekly digests
+	NextDigestAt  *time.Time   `json:"next_digest_at,omitempty"`
+	UserEmail     string       `json:"user_email,omitempty"`
+	// CrossFeedDedupe mirrors the subscriber's User.CrossFeedDedupe.
+	CrossFeedDedupe bool `json:"-"`
+}
+
+// FilterAction decides what a matching filter rule does with an item.
+type FilterAction string
+
+const (
+	FilterInclude FilterAction = "include" // Deliver only items matching at least one include rule
+	FilterExclude FilterAction = "exclude" // Never deliver items matching an exclude rule
+)
+
+// FilterField names the part of an item a filter rule inspects.
+type FilterField string
+
+const (
+	FilterFieldTitle    FilterField = "title"
+	FilterFieldContent  FilterField = "content"
+	FilterFieldAuthor   FilterField = "author"
+	FilterFieldCategory FilterField = "category"
+)
+
+// FilterMatch defines how a filter rule's pattern is compared.
+type FilterMatch string
+
+const (
+	FilterMatchSubstring FilterMatch = "substring" // Case-insensitive substring
+	FilterMatchRegex     FilterMatch = "regex"     // RE2 regular expression
+)
+
+// FilterRule restricts which of a feed's items are delivered to one subscriber.
+type FilterRule struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	FeedID    int64        `json:"feed_id"`
+	Action    FilterAction `json:"action"`
+	Field     FilterField  `json:"field"`
+	Match     FilterMatch  `json:"match"`
+	Pattern   string       `json:"pattern"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// ContentRules clean up a feed's item HTML, whether extracted or from the
+// feed itself. They run in field order, before the HTML is sanitized.
+type ContentRules struct {
+	Remove   []string      `json:"remove,omitempty"`   // CSS selectors of elements to delete
+	Links    []LinkRule    `json:"links,omitempty"`    // Rewrites of link URLs
+	Rewrites []RewriteRule `json:"rewrites,omitempty"` // Regex replacements on the HTML
+}
+
+// LinkAction is how a LinkRule rewrites a link.
+type LinkAction string
+
+const (
+	LinkStripTracking LinkAction = "strip_tracking" // Drop utm_* and other click-tracking parameters
+	LinkCanonicalAMP  LinkAction = "amp_canonical"  // Point AMP pages at their canonical article
+	LinkRegex         LinkAction = "regex"          // Replace Pattern with Replacement in the URL
+)
+
+// LinkRule rewrites the URLs of links in item HTML.
+type LinkRule struct {
+	Action      LinkAction `json:"action"`
+	Pattern     string     `json:"pattern,omitempty"`
+	Replacement string     `json:"replacement,omitempty"`
+}
+
+// RewriteRule replaces every match of an RE2 pattern in item HTML.
+// Replacement may refer to submatches as $1 or ${name}.
+type RewriteRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// DigestItem is a rendered feed item held back for a batched digest email.
+type DigestItem struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	FeedID    int64     `json:"feed_id"`
+	FeedTitle string    `json:"feed_title"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Item is a crawled feed entry persisted for history, independent of delivery.
+type Item struct {
+	ID                 int64              `json:"id"`
+	FeedID             int64              `json:"feed_id"`
+	GUID               string             `json:"guid"`
+	Title              string             `json:"title"`
+	Link               string             `json:"link"`
+	Author             string             `json:"author,omitempty"`
+	Categories         []string           `json:"categories,omitempty"`
+	PublishedAt        *time.Time         `json:"published_at,omitempty"`
+	Content            string             `json:"content"`                       // Sanitized feed-provided content
+	ExtractedContent   string             `json:"extracted_content,omitempty"`   // Sanitized full-text extraction, if any
+	ExtractionStrategy ExtractionStrategy `json:"extraction_strategy,omitempty"` // Strategy whose result was delivered
+	ContentHash        string             `json:"content_hash"`                  // SHA-256 of the delivered body
+	Seen               bool               `json:"seen"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
 }
 
 // SeenItem tracks which feed items have already been processed/emailed.
@@ -59,6 +272,80 @@ type SeenItem struct {
 	SeenAt time.Time `json:"seen_at"`
 }
 
+// Extraction is a cached full-article extraction, keyed by the article's
+// normalized URL. ETag and LastModified come from the article response and
+// are sent back to revalidate the entry once it is stale.
+type Extraction struct {
+	Key          string             `json:"key"`
+	URL          string             `json:"url"`
+	Strategy     ExtractionStrategy `json:"strategy"`
+	Selector     string             `json:"selector"`
+	Content      string             `json:"content"`
+	ETag         string             `json:"etag"`
+	LastModified string             `json:"last_modified"`
+	FetchedAt    time.Time          `json:"fetched_at"`
+}
+
+// WebSubState defines the lifecycle of a WebSub push subscription.
+type WebSubState string
+
+const (
+	WebSubPending WebSubState = "pending" // Subscription requested, awaiting hub verification
+	WebSubActive  WebSubState = "active"  // Hub verified the subscription and is pushing updates
+	WebSubDenied  WebSubState = "denied"  // Hub refused the subscription
+)
+
+// WebSubSubscription tracks a feed's push subscription with a WebSub hub.
+type WebSubSubscription struct {
+	FeedID         int64       `json:"feed_id"`
+	Hub            string      `json:"hub"`
+	Topic          string      `json:"topic"`
+	Secret         string      `json:"-"`
+	State          WebSubState `json:"state"`
+	LeaseSeconds   int         `json:"lease_seconds"`
+	LeaseExpiresAt *time.Time  `json:"lease_expires_at,omitempty"`
+	LastError      string      `json:"last_error,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// MagicKey is a signing key for subscriber magic links. The newest key without
+// RetiredAt signs new tokens; retired keys only verify tokens until their
+// grace period ends.
+type MagicKey struct {
+	ID        int64      `json:"id"`
+	Secret    string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+// ChannelKind identifies how a notification channel delivers messages.
+type ChannelKind string
+
+const (
+	ChannelEmail   ChannelKind = "email"   // Email via the daemon's configured mailer
+	ChannelWebhook ChannelKind = "webhook" // Generic JSON POST
+	ChannelSlack   ChannelKind = "slack"   // Slack/Mattermost incoming webhook
+	ChannelMatrix  ChannelKind = "matrix"  // Matrix room message via the client-server API
+	ChannelNtfy    ChannelKind = "ntfy"    // ntfy topic
+)
+
+// Channel is a notification destination belonging to a user. Target holds the
+// email address, webhook/topic URL or Matrix room ID depending on Kind.
+type Channel struct {
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	Kind      ChannelKind `json:"kind"`
+	Name      string      `json:"name"`
+	Target    string      `json:"target"`
+	Server    string      `json:"server,omitempty"` // Matrix homeserver base URL
+	Token     string      `json:"-"`                // Matrix access token, ntfy bearer token or webhook signing secret
+	HasToken  bool        `json:"has_token"`
+	Enabled   bool        `json:"enabled"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
 // OutboxStatus defines the state of a pending email.
 type OutboxStatus string
 
@@ -71,16 +358,30 @@ const (
 
 // OutboxItem represents a message queued for SMTP/sendmail dispatch.
 type OutboxItem struct {
-	ID            int64        `json:"id"`
-	Subject       string       `json:"subject"`
-	Body          string       `json:"body"`
-	Recipients    []string     `json:"recipients"`
-	Status        OutboxStatus `json:"status"`
-	RetryCount    int          `json:"retry_count"`
-	NextAttemptAt time.Time    `json:"next_attempt_at"`
-	LastAttemptAt *time.Time   `json:"last_attempt_at,omitempty"`
-	LastError     string       `json:"last_error,omitempty"`
-	CreatedAt     time.Time    `json:"created_at"`
+	ID              int64        `json:"id"`
+	Subject         string       `json:"subject"`
+	Body            string       `json:"body"`
+	Recipients      []string     `json:"recipients"`
+	ChannelID       *int64       `json:"channel_id,omitempty"`       // Destination channel; nil for plain email to Recipients
+	ListUnsubscribe string       `json:"list_unsubscribe,omitempty"` // One-click unsubscribe URL for email headers
+	Status          OutboxStatus `json:"status"`
+	RetryCount      int          `json:"retry_count"`
+	NextAttemptAt   time.Time    `json:"next_attempt_at"`
+	LastAttemptAt   *time.Time   `json:"last_attempt_at,omitempty"`
+	LastError       string       `json:"last_error,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+// QueueStats describes the crawl queue and its worker pool. Wait times run
+// from when a feed is queued to when a worker starts crawling it.
+type QueueStats struct {
+	Workers      int   `json:"workers"`
+	Busy         int   `json:"busy"`
+	Depth        int   `json:"depth"`
+	Started      int64 `json:"started"`
+	AvgWaitMs    int64 `json:"avg_wait_ms"`
+	MaxWaitMs    int64 `json:"max_wait_ms"`
+	OldestWaitMs int64 `json:"oldest_wait_ms"`
 }
 
 // DBStats holds high-level telemetry and status counters.
@@ -90,4 +391,8 @@ type DBStats struct {
 	OutboxPending   int `json:"outbox_pending"`
 	OutboxFailed    int `json:"outbox_failed"`
 	OutboxDelivered int `json:"outbox_delivered"`
+	FeedsHealthy    int `json:"feeds_healthy"`
+	FeedsDegraded   int `json:"feeds_degraded"`
+	FeedsFailing    int `json:"feeds_failing"`
+	FeedsDisabled   int `json:"feeds_disabled"`
 }
@@ -0,0 +1,350 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rss2go/internal/database"
+	"rss2go/internal/types"
+)
+
+// CallbackPath is the public route prefix hubs use to reach the subscriber.
+const CallbackPath = "/api/v1/websub/"
+
+// Config configures the WebSub subscriber.
+type Config struct {
+	// CallbackBase is the public base URL of this server, e.g. "https://rss2go.example.com".
+	CallbackBase string
+	// LeaseSeconds is the lease duration requested from hubs.
+	LeaseSeconds int
+	// RenewBefore renews active leases this long before they expire.
+	RenewBefore time.Duration
+	// CheckInterval is how often the lease renewal sweep runs.
+	CheckInterval time.Duration
+	// RetryAfter is how long a pending or denied subscription is left alone before re-subscribing.
+	RetryAfter time.Duration
+	// Now returns the current time. Tests substitute a fake clock.
+	Now func() time.Time
+}
+
+// Manager subscribes feeds to their advertised WebSub hubs, verifies hub
+// callbacks and keeps leases renewed.
+type Manager struct {
+	repo         *database.Repository
+	client       *http.Client
+	cfg          Config
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+	log          *slog.Logger
+}
+
+// NewManager creates a new WebSub Manager.
+func NewManager(repo *database.Repository, client *http.Client, cfg Config, log *slog.Logger) *Manager {
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+	cfg.CallbackBase = strings.TrimSuffix(cfg.CallbackBase, "/")
+	if cfg.LeaseSeconds <= 0 {
+		cfg.LeaseSeconds = 10 * 24 * 60 * 60 // 10 days
+	}
+	if cfg.RenewBefore <= 0 {
+		cfg.RenewBefore = 24 * time.Hour
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Hour
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 24 * time.Hour
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if log == nil {
+		log = slog.Default().With("component", "websub")
+	}
+
+	return &Manager{
+		repo:       repo,
+		client:     client,
+		cfg:        cfg,
+		shutdownCh: make(chan struct{}),
+		log:        log,
+	}
+}
+
+// Start runs the lease renewal loop. It blocks until context is cancelled or Stop is called.
+func (m *Manager) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.RenewDue(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			m.log.Error("WebSub lease renewal error", "err", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			m.Stop()
+			return ctx.Err()
+		case <-m.shutdownCh:
+			return nil
+		}
+	}
+}
+
+// Stop signals the renewal loop to exit.
+func (m *Manager) Stop() {
+	m.shutdownOnce.Do(func() {
+		close(m.shutdownCh)
+	})
+}
+
+// CallbackURL returns the callback URL registered with the hub for a feed.
+func (m *Manager) CallbackURL(feedID int64) string {
+	return m.cfg.CallbackBase + CallbackPath + strconv.FormatInt(feedID, 10)
+}
+
+// Active reports whether the feed currently holds a verified, unexpired lease.
+func (m *Manager) Active(ctx context.Context, feedID int64) bool {
+	sub, err := m.repo.GetWebSubSubscription(ctx, feedID)
+	if err != nil {
+		return false
+	}
+	return isActive(sub, m.cfg.Now())
+}
+
+// Ensure subscribes the feed to hub for topic unless an equivalent subscription
+// is already active, or was recently requested or denied.
+func (m *Manager) Ensure(ctx context.Context, feed *types.Feed, hub, topic string) error {
+	if topic == "" {
+		topic = feed.URL
+	}
+
+	existing, err := m.repo.GetWebSubSubscription(ctx, feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("websub: load subscription: %w", err)
+	}
+	if existing != nil && existing.Hub == hub && existing.Topic == topic {
+		now := m.cfg.Now()
+		switch existing.State {
+		case types.WebSubActive:
+			if isActive(existing, now) {
+				return nil
+			}
+		case types.WebSubPending, types.WebSubDenied:
+			if now.Sub(existing.UpdatedAt) < m.cfg.RetryAfter {
+				return nil
+			}
+		}
+	}
+
+	return m.Subscribe(ctx, feed.ID, hub, topic)
+}
+
+// Subscribe sends a subscription request to hub. Renewals of an active
+// subscription keep the existing secret and state so pushes continue to verify
+// until the hub confirms the new lease.
+func (m *Manager) Subscribe(ctx context.Context, feedID int64, hub, topic string) error {
+	existing, err := m.repo.GetWebSubSubscription(ctx, feedID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("websub: load subscription: %w", err)
+	}
+
+	sub := &types.WebSubSubscription{
+		FeedID:       feedID,
+		Hub:          hub,
+		Topic:        topic,
+		State:        types.WebSubPending,
+		LeaseSeconds: m.cfg.LeaseSeconds,
+	}
+	if existing != nil && existing.Hub == hub && existing.Topic == topic {
+		sub.Secret = existing.Secret
+		if existing.State == types.WebSubActive {
+			sub.State = types.WebSubActive
+			sub.LeaseExpiresAt = existing.LeaseExpiresAt
+		}
+	}
+	if sub.Secret == "" {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		sub.Secret = hex.EncodeToString(b)
+	}
+
+	// Persist before contacting the hub: it may verify before this request returns.
+	if err := m.repo.SaveWebSubSubscription(ctx, sub); err != nil {
+		return fmt.Errorf("websub: save subscription: %w", err)
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {m.CallbackURL(feedID)},
+		"hub.lease_seconds": {strconv.Itoa(m.cfg.LeaseSeconds)},
+		"hub.secret":        {sub.Secret},
+	}
+
+	reqErr := m.postHub(ctx, hub, form)
+	if reqErr != nil {
+		sub.LastError = reqErr.Error()
+		if err := m.repo.SaveWebSubSubscription(ctx, sub); err != nil {
+			m.log.Error("Failed to record WebSub subscription error", "feed_id", feedID, "err", err)
+		}
+		return reqErr
+	}
+
+	m.log.Info("Requested WebSub subscription", "feed_id", feedID, "hub", hub, "topic", topic)
+	return nil
+}
+
+// postHub sends a form-encoded subscription request and expects a 2xx reply.
+func (m *Manager) postHub(ctx context.Context, hub string, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("websub: create hub request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "rss2go/1.0 (Syndication Aggregator Daemon)")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("websub: hub request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("websub: hub returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Verify handles a hub's intent verification (or denial) callback for a feed and
+// returns the challenge to echo. An error means the request must be rejected with 404.
+func (m *Manager) Verify(ctx context.Context, feedID int64, query url.Values) (string, error) {
+	sub, err := m.repo.GetWebSubSubscription(ctx, feedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("websub: no subscription for feed %d", feedID)
+		}
+		return "", fmt.Errorf("websub: load subscription: %w", err)
+	}
+
+	if topic := query.Get("hub.topic"); topic != sub.Topic {
+		return "", fmt.Errorf("websub: topic mismatch %q", topic)
+	}
+
+	switch mode := query.Get("hub.mode"); mode {
+	case "subscribe":
+		if sub.State != types.WebSubPending && sub.State != types.WebSubActive {
+			return "", fmt.Errorf("websub: unexpected verification in state %q", sub.State)
+		}
+		challenge := query.Get("hub.challenge")
+		if challenge == "" {
+			return "", fmt.Errorf("websub: missing challenge")
+		}
+
+		lease := sub.LeaseSeconds
+		if val, err := strconv.Atoi(query.Get("hub.lease_seconds")); err == nil && val > 0 {
+			lease = val
+		}
+		expires := m.cfg.Now().Add(time.Duration(lease) * time.Second)
+
+		sub.State = types.WebSubActive
+		sub.LeaseSeconds = lease
+		sub.LeaseExpiresAt = &expires
+		sub.LastError = ""
+		if err := m.repo.SaveWebSubSubscription(ctx, sub); err != nil {
+			return "", fmt.Errorf("websub: save subscription: %w", err)
+		}
+		m.log.Info("WebSub subscription verified", "feed_id", feedID, "hub", sub.Hub, "lease_expires_at", expires)
+		return challenge, nil
+
+	case "denied":
+		sub.State = types.WebSubDenied
+		sub.LeaseExpiresAt = nil
+		sub.LastError = query.Get("hub.reason")
+		if err := m.repo.SaveWebSubSubscription(ctx, sub); err != nil {
+			return "", fmt.Errorf("websub: save subscription: %w", err)
+		}
+		m.log.Warn("WebSub subscription denied by hub", "feed_id", feedID, "hub", sub.Hub, "reason", sub.LastError)
+		return "", nil
+
+	default:
+		// rss2go never requests unsubscription; refuse to confirm one.
+		return "", fmt.Errorf("websub: unsupported mode %q", mode)
+	}
+}
+
+// RenewDue re-subscribes every active subscription whose lease expires within RenewBefore.
+func (m *Manager) RenewDue(ctx context.Context) error {
+	subs, err := m.repo.ListWebSubRenewals(ctx, m.cfg.Now().Add(m.cfg.RenewBefore))
+	if err != nil {
+		return fmt.Errorf("websub: list renewals: %w", err)
+	}
+
+	for _, sub := range subs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := m.Subscribe(ctx, sub.FeedID, sub.Hub, sub.Topic); err != nil {
+			m.log.Error("Failed to renew WebSub lease", "feed_id", sub.FeedID, "hub", sub.Hub, "err", err)
+		}
+	}
+	return nil
+}
+
+// VerifySignature checks an X-Hub-Signature header ("<algo>=<hex>") against
+// the HMAC of body keyed with secret.
+func VerifySignature(secret, header string, body []byte) bool {
+	algo, sig, found := strings.Cut(strings.TrimSpace(header), "=")
+	if !found || secret == "" {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(algo) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	_, _ = mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// isActive reports whether the hub is currently expected to push updates.
+func isActive(sub *types.WebSubSubscription, now time.Time) bool {
+	return sub.State == types.WebSubActive && sub.LeaseExpiresAt != nil && sub.LeaseExpiresAt.After(now)
+}
@@ -0,0 +1,329 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rss2go/internal/database"
+	"rss2go/internal/types"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// testHub is a minimal WebSub hub that verifies intent synchronously by
+// calling the subscriber's callback before acknowledging the request.
+type testHub struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	requests []url.Values
+	status   int
+	mode     string // verification mode sent back: "subscribe" or "denied"
+	lease    string
+	verified []bool
+}
+
+func newTestHub(t *testing.T) *testHub {
+	h := &testHub{status: http.StatusAccepted, mode: "subscribe", lease: "3600"}
+	h.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		h.mu.Lock()
+		h.requests = append(h.requests, r.PostForm)
+		status, mode, lease := h.status, h.mode, h.lease
+		h.mu.Unlock()
+
+		if status != http.StatusAccepted {
+			w.WriteHeader(status)
+			return
+		}
+
+		q := url.Values{
+			"hub.mode":          {mode},
+			"hub.topic":         {r.PostForm.Get("hub.topic")},
+			"hub.challenge":     {"challenge-123"},
+			"hub.lease_seconds": {lease},
+			"hub.reason":        {"not allowed"},
+		}
+		resp, err := http.Get(r.PostForm.Get("hub.callback") + "?" + q.Encode())
+		ok := false
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			ok = resp.StatusCode == http.StatusOK && string(body) == "challenge-123"
+		}
+		h.mu.Lock()
+		h.verified = append(h.verified, ok)
+		h.mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+func setupTestDB(t *testing.T) *database.Repository {
+	t.Helper()
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+
+	db, err := database.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return database.NewRepository(db)
+}
+
+// setupManager returns a Manager whose callback URL points at a local server
+// that forwards verification requests to Manager.Verify.
+func setupManager(t *testing.T, repo *database.Repository, clock *fakeClock) *Manager {
+	t.Helper()
+	var m *Manager
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, CallbackPath), 10, 64)
+		challenge, err := m.Verify(r.Context(), id, r.URL.Query())
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, challenge)
+	}))
+	t.Cleanup(callback.Close)
+
+	m = NewManager(repo, nil, Config{
+		CallbackBase: callback.URL + "/",
+		LeaseSeconds: 7200,
+		RenewBefore:  10 * time.Minute,
+		Now:          clock.Now,
+	}, slog.New(slog.DiscardHandler))
+	return m
+}
+
+func createFeed(t *testing.T, repo *database.Repository) *types.Feed {
+	t.Helper()
+	feed := &types.Feed{Title: "Push Feed", URL: "https://example.com/feed.xml", NextPollAt: time.Now()}
+	if err := repo.CreateFeed(context.Background(), feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+	return feed
+}
+
+func TestManagerSubscribeVerifyAndRenew(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)}
+	m := setupManager(t, repo, clock)
+	hub := newTestHub(t)
+	feed := createFeed(t, repo)
+
+	if err := m.Ensure(ctx, feed, hub.server.URL, ""); err != nil {
+		t.Fatalf("Ensure failed: %v", err)
+	}
+
+	if len(hub.requests) != 1 {
+		t.Fatalf("expected 1 hub request, got %d", len(hub.requests))
+	}
+	req := hub.requests[0]
+	if req.Get("hub.mode") != "subscribe" || req.Get("hub.topic") != feed.URL || req.Get("hub.lease_seconds") != "7200" {
+		t.Errorf("unexpected subscription request: %v", req)
+	}
+	if !strings.HasSuffix(req.Get("hub.callback"), CallbackPath+strconv.FormatInt(feed.ID, 10)) {
+		t.Errorf("unexpected callback URL %q", req.Get("hub.callback"))
+	}
+	if len(req.Get("hub.secret")) != 64 {
+		t.Errorf("expected a 32 byte hex secret, got %q", req.Get("hub.secret"))
+	}
+	if !hub.verified[0] {
+		t.Fatalf("expected callback to echo the challenge")
+	}
+
+	sub, err := repo.GetWebSubSubscription(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("failed to load subscription: %v", err)
+	}
+	wantExpiry := clock.Now().Add(time.Hour)
+	if sub.State != types.WebSubActive || sub.LeaseSeconds != 3600 || sub.LeaseExpiresAt == nil || !sub.LeaseExpiresAt.Equal(wantExpiry) {
+		t.Errorf("expected active subscription with hub-granted lease, got %+v", sub)
+	}
+	if !m.Active(ctx, feed.ID) {
+		t.Errorf("expected feed to be active")
+	}
+
+	// An active subscription to the same hub is left alone.
+	if err := m.Ensure(ctx, feed, hub.server.URL, feed.URL); err != nil {
+		t.Fatalf("Ensure failed: %v", err)
+	}
+	if len(hub.requests) != 1 {
+		t.Errorf("expected no new hub request, got %d", len(hub.requests))
+	}
+
+	// Nothing is due for renewal yet.
+	if err := m.RenewDue(ctx); err != nil {
+		t.Fatalf("RenewDue failed: %v", err)
+	}
+	if len(hub.requests) != 1 {
+		t.Errorf("expected no renewal yet, got %d requests", len(hub.requests))
+	}
+
+	// Within the renewal window the lease is renewed with the same secret.
+	clock.Advance(55 * time.Minute)
+	if err := m.RenewDue(ctx); err != nil {
+		t.Fatalf("RenewDue failed: %v", err)
+	}
+	if len(hub.requests) != 2 {
+		t.Fatalf("expected renewal request, got %d requests", len(hub.requests))
+	}
+	if hub.requests[1].Get("hub.secret") != req.Get("hub.secret") {
+		t.Errorf("expected renewal to keep the existing secret")
+	}
+	sub, _ = repo.GetWebSubSubscription(ctx, feed.ID)
+	if !sub.LeaseExpiresAt.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("expected lease to be extended, got %v", sub.LeaseExpiresAt)
+	}
+
+	// Once the lease lapses the feed is no longer considered active.
+	clock.Advance(2 * time.Hour)
+	if m.Active(ctx, feed.ID) {
+		t.Errorf("expected expired lease to be inactive")
+	}
+}
+
+func TestManagerDeniedAndHubErrors(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	m := setupManager(t, repo, clock)
+	hub := newTestHub(t)
+	feed := createFeed(t, repo)
+
+	hub.mode = "denied"
+	if err := m.Subscribe(ctx, feed.ID, hub.server.URL, feed.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	sub, _ := repo.GetWebSubSubscription(ctx, feed.ID)
+	if sub.State != types.WebSubDenied || sub.LastError != "not allowed" {
+		t.Errorf("expected denied subscription, got %+v", sub)
+	}
+
+	// A recently denied subscription is not retried on every crawl.
+	if err := m.Ensure(ctx, feed, hub.server.URL, feed.URL); err != nil {
+		t.Fatalf("Ensure failed: %v", err)
+	}
+	if len(hub.requests) != 1 {
+		t.Errorf("expected denied subscription to be left alone, got %d requests", len(hub.requests))
+	}
+
+	// A hub rejecting the request records the error.
+	hub.status = http.StatusInternalServerError
+	if err := m.Subscribe(ctx, feed.ID, hub.server.URL, feed.URL); err == nil {
+		t.Fatalf("expected error from failing hub")
+	}
+	sub, _ = repo.GetWebSubSubscription(ctx, feed.ID)
+	if sub.State != types.WebSubPending || !strings.Contains(sub.LastError, "500") {
+		t.Errorf("expected pending subscription with recorded error, got %+v", sub)
+	}
+}
+
+func TestManagerVerifyRejects(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	m := NewManager(repo, nil, Config{CallbackBase: "https://rss2go.example.com"}, slog.New(slog.DiscardHandler))
+	feed := createFeed(t, repo)
+
+	if _, err := m.Verify(ctx, feed.ID, url.Values{"hub.mode": {"subscribe"}, "hub.topic": {feed.URL}, "hub.challenge": {"x"}}); err == nil {
+		t.Errorf("expected error for unknown subscription")
+	}
+
+	_ = repo.SaveWebSubSubscription(ctx, &types.WebSubSubscription{
+		FeedID: feed.ID, Hub: "https://hub", Topic: feed.URL, Secret: "s", State: types.WebSubPending, LeaseSeconds: 60,
+	})
+
+	cases := []url.Values{
+		{"hub.mode": {"subscribe"}, "hub.topic": {"https://other/feed"}, "hub.challenge": {"x"}},
+		{"hub.mode": {"subscribe"}, "hub.topic": {feed.URL}},
+		{"hub.mode": {"unsubscribe"}, "hub.topic": {feed.URL}, "hub.challenge": {"x"}},
+	}
+	for _, q := range cases {
+		if _, err := m.Verify(ctx, feed.ID, q); err == nil {
+			t.Errorf("expected verification to be rejected for %v", q)
+		}
+	}
+
+	if got := m.CallbackURL(feed.ID); got != "https://rss2go.example.com/api/v1/websub/"+strconv.FormatInt(feed.ID, 10) {
+		t.Errorf("unexpected callback URL %q", got)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte("<feed/>")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   bool
+	}{
+		{"valid sha256", "secret", valid, true},
+		{"wrong secret", "other", valid, false},
+		{"missing header", "secret", "", false},
+		{"unknown algorithm", "secret", "md5=abcd", false},
+		{"bad hex", "secret", "sha256=zz", false},
+		{"empty secret", "", valid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.header, body); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerStartStop(t *testing.T) {
+	repo := setupTestDB(t)
+	m := NewManager(repo, nil, Config{CheckInterval: time.Millisecond}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Start(ctx) }()
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	m.Stop()
+}
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions ADD COLUMN delivery_mode TEXT NOT NULL DEFAULT 'immediate';
+ALTER TABLE subscriptions ADD COLUMN digest_time TEXT NOT NULL DEFAULT '08:00';
+ALTER TABLE subscriptions ADD COLUMN digest_weekday INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE subscriptions ADD COLUMN next_digest_at DATETIME;
+
+CREATE TABLE pending_digest_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    feed_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    link TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id, feed_id) REFERENCES subscriptions(user_id, feed_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_pending_digest_items_user ON pending_digest_items(user_id, feed_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_pending_digest_items_user;
+DROP TABLE IF EXISTS pending_digest_items;
+ALTER TABLE subscriptions DROP COLUMN next_digest_at;
+ALTER TABLE subscriptions DROP COLUMN digest_weekday;
+ALTER TABLE subscriptions DROP COLUMN digest_time;
+ALTER TABLE subscriptions DROP COLUMN delivery_mode;
+-- +goose StatementEnd
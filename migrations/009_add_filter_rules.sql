@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE filter_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    feed_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    field TEXT NOT NULL,
+    match_type TEXT NOT NULL DEFAULT 'substring',
+    pattern TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id, feed_id) REFERENCES subscriptions(user_id, feed_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_filter_rules_subscription ON filter_rules(feed_id, user_id);
+
+ALTER TABLE items ADD COLUMN categories TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE items DROP COLUMN categories;
+DROP INDEX IF EXISTS idx_filter_rules_subscription;
+DROP TABLE IF EXISTS filter_rules;
+-- +goose StatementEnd
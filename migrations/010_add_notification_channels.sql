@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE channels (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL,
+    server TEXT NOT NULL DEFAULT '',
+    token TEXT NOT NULL DEFAULT '',
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_channels_user_id ON channels(user_id);
+
+ALTER TABLE outbox ADD COLUMN channel_id INTEGER REFERENCES channels(id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox DROP COLUMN channel_id;
+DROP INDEX IF EXISTS idx_channels_user_id;
+DROP TABLE IF EXISTS channels;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN gone_at DATETIME;
+ALTER TABLE feeds ADD COLUMN not_found_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE feed_url_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    old_url TEXT NOT NULL,
+    new_url TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_feed_url_history_feed_id ON feed_url_history(feed_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_feed_url_history_feed_id;
+DROP TABLE IF EXISTS feed_url_history;
+ALTER TABLE feeds DROP COLUMN not_found_count;
+ALTER TABLE feeds DROP COLUMN gone_at;
+-- +goose StatementEnd
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN health TEXT NOT NULL DEFAULT 'healthy';
+ALTER TABLE feeds ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN failing_since DATETIME;
+ALTER TABLE feeds ADD COLUMN health_changed_at DATETIME;
+ALTER TABLE feeds ADD COLUMN disabled_reason TEXT NOT NULL DEFAULT '';
+
+-- Gone feeds become disabled; feeds with an outstanding error start out degraded.
+UPDATE feeds SET health = 'disabled', health_changed_at = gone_at, disabled_reason = last_error_str
+WHERE gone_at IS NOT NULL;
+UPDATE feeds SET health = 'degraded', consecutive_failures = 1, failing_since = last_error_time, health_changed_at = last_error_time
+WHERE gone_at IS NULL AND last_error_str != '';
+
+ALTER TABLE feeds DROP COLUMN gone_at;
+
+CREATE INDEX idx_feeds_health ON feeds(health);
+
+CREATE TABLE feed_health_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    from_health TEXT NOT NULL,
+    to_health TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    alerted_at DATETIME,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_feed_health_events_alerted_at ON feed_health_events(alerted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_feed_health_events_alerted_at;
+DROP TABLE IF EXISTS feed_health_events;
+DROP INDEX IF EXISTS idx_feeds_health;
+
+ALTER TABLE feeds ADD COLUMN gone_at DATETIME;
+UPDATE feeds SET gone_at = health_changed_at WHERE health = 'disabled';
+
+ALTER TABLE feeds DROP COLUMN disabled_reason;
+ALTER TABLE feeds DROP COLUMN health_changed_at;
+ALTER TABLE feeds DROP COLUMN failing_since;
+ALTER TABLE feeds DROP COLUMN consecutive_failures;
+ALTER TABLE feeds DROP COLUMN health;
+-- +goose StatementEnd
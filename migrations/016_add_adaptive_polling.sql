@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN adaptive_polling INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN adaptive_interval_secs INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN adaptive_interval_secs;
+ALTER TABLE feeds DROP COLUMN adaptive_polling;
+-- +goose StatementEnd
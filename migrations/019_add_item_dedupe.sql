@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN dedupe_strategy TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE seen_items ADD COLUMN dedupe_key TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_seen_items_dedupe_key ON seen_items(feed_id, dedupe_key) WHERE dedupe_key != '';
+
+ALTER TABLE users ADD COLUMN cross_feed_dedupe INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE user_deliveries (
+    user_id INTEGER NOT NULL,
+    fingerprint TEXT NOT NULL,
+    feed_id INTEGER NOT NULL,
+    delivered_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, fingerprint),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_deliveries;
+ALTER TABLE users DROP COLUMN cross_feed_dedupe;
+DROP INDEX IF EXISTS idx_seen_items_dedupe_key;
+ALTER TABLE seen_items DROP COLUMN dedupe_key;
+ALTER TABLE feeds DROP COLUMN dedupe_strategy;
+-- +goose StatementEnd
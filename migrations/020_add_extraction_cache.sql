@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE extraction_cache (
+    url_key TEXT PRIMARY KEY,
+    url TEXT NOT NULL,
+    strategy TEXT NOT NULL,
+    selector TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT '',
+    last_modified TEXT NOT NULL DEFAULT '',
+    fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_extraction_cache_fetched_at ON extraction_cache(fetched_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS extraction_cache;
+-- +goose StatementEnd
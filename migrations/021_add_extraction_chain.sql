@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN extraction_chain TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE items ADD COLUMN extraction_strategy TEXT NOT NULL DEFAULT '';
+
+-- A chain extracts an article with several strategies, so the cache now keeps
+-- one extraction per strategy and selector. SQLite cannot change a primary
+-- key in place, so the table is rebuilt and the cached rows copied across.
+CREATE TABLE extraction_cache_new (
+    url_key TEXT NOT NULL,
+    strategy TEXT NOT NULL,
+    selector TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL,
+    content TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT '',
+    last_modified TEXT NOT NULL DEFAULT '',
+    fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (url_key, strategy, selector)
+);
+INSERT INTO extraction_cache_new (url_key, strategy, selector, url, content, etag, last_modified, fetched_at)
+    SELECT url_key, strategy, selector, url, content, etag, last_modified, fetched_at FROM extraction_cache;
+DROP TABLE extraction_cache;
+ALTER TABLE extraction_cache_new RENAME TO extraction_cache;
+CREATE INDEX idx_extraction_cache_fetched_at ON extraction_cache(fetched_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Only one extraction per URL fits the old key; keep the newest.
+CREATE TABLE extraction_cache_old (
+    url_key TEXT PRIMARY KEY,
+    url TEXT NOT NULL,
+    strategy TEXT NOT NULL,
+    selector TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT '',
+    last_modified TEXT NOT NULL DEFAULT '',
+    fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT OR IGNORE INTO extraction_cache_old (url_key, url, strategy, selector, content, etag, last_modified, fetched_at)
+    SELECT url_key, url, strategy, selector, content, etag, last_modified, fetched_at FROM extraction_cache
+    ORDER BY fetched_at DESC;
+DROP TABLE extraction_cache;
+ALTER TABLE extraction_cache_old RENAME TO extraction_cache;
+CREATE INDEX idx_extraction_cache_fetched_at ON extraction_cache(fetched_at);
+
+ALTER TABLE items DROP COLUMN extraction_strategy;
+ALTER TABLE feeds DROP COLUMN extraction_chain;
+-- +goose StatementEnd
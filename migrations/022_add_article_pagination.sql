@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE feeds ADD COLUMN follow_pages INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN next_page_selector TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN max_pages INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE feeds DROP COLUMN max_pages;
+ALTER TABLE feeds DROP COLUMN next_page_selector;
+ALTER TABLE feeds DROP COLUMN follow_pages;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Channel tokens are encrypted like feed request options. Plaintext tokens
+-- left in the token column are sealed into sealed_token on startup.
+ALTER TABLE channels ADD COLUMN sealed_token BLOB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE channels DROP COLUMN sealed_token;
+-- +goose StatementEnd
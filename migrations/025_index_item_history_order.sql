@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Feed history is listed newest first by publication time, falling back to
+-- when the item was stored; index that order rather than published_at alone.
+DROP INDEX IF EXISTS idx_items_feed_published;
+CREATE INDEX idx_items_feed_history ON items(feed_id, COALESCE(published_at, created_at), id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_items_feed_history;
+CREATE INDEX idx_items_feed_published ON items(feed_id, published_at);
+-- +goose StatementEnd